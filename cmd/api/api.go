@@ -3,12 +3,14 @@ package api
 import (
 	"context"
 	"fmt"
+	"net/url"
 	"runtime/debug"
 	"strings"
 
 	paramscmd "github.com/wakatime/wakatime-cli/cmd/params"
 	"github.com/wakatime/wakatime-cli/pkg/api"
 	"github.com/wakatime/wakatime-cli/pkg/log"
+	"github.com/wakatime/wakatime-cli/pkg/oauth"
 
 	tz "github.com/gandarez/go-olson-timezone"
 )
@@ -16,6 +18,15 @@ import (
 // NewClient initializes a new api client with all options following the
 // passed in parameters.
 func NewClient(ctx context.Context, params paramscmd.API) (*api.Client, error) {
+	if params.OAuth.Enabled() {
+		withBearerAuth, err := bearerAuthOption(ctx, params)
+		if err != nil {
+			return nil, fmt.Errorf("failed to set up bearer auth option on api client: %w", err)
+		}
+
+		return newClient(ctx, params, withBearerAuth)
+	}
+
 	withAuth, err := api.WithAuth(api.BasicAuth{
 		Secret: params.Key,
 	})
@@ -27,15 +38,55 @@ func NewClient(ctx context.Context, params paramscmd.API) (*api.Client, error) {
 }
 
 // NewClientWithoutAuth initializes a new api client with all options following the
-// passed in parameters and disabled authentication.
+// passed in parameters and disabled authentication, as the api key is set per request.
+// With oauth, requests are authenticated by the bearer token instead.
 func NewClientWithoutAuth(ctx context.Context, params paramscmd.API) (*api.Client, error) {
+	if params.OAuth.Enabled() {
+		withBearerAuth, err := bearerAuthOption(ctx, params)
+		if err != nil {
+			return nil, fmt.Errorf("failed to set up bearer auth option on api client: %w", err)
+		}
+
+		return newClient(ctx, params, withBearerAuth)
+	}
+
+	return newClient(ctx, params)
+}
+
+// NewOAuthClient initializes a new api client with all options following the
+// passed in parameters and disabled authentication, to request oauth tokens.
+// Bearer auth is left out, as it needs the tokens this client requests.
+func NewOAuthClient(ctx context.Context, params paramscmd.API) (*api.Client, error) {
 	return newClient(ctx, params)
 }
 
 // newClient contains the logic of client initialization, except auth initialization.
 func newClient(ctx context.Context, params paramscmd.API, opts ...api.Option) (*api.Client, error) {
+	// set up first, to replay or record requests as sent by the transport
+	var debugOpts []api.Option
+
+	if params.ReplayHTTPDir != "" {
+		withReplay, err := api.WithReplayHTTP(params.ReplayHTTPDir)
+		if err != nil {
+			return nil, fmt.Errorf("failed to set up replay http option on api client: %s", err)
+		}
+
+		debugOpts = append(debugOpts, withReplay)
+	}
+
+	if params.RecordHTTPDir != "" {
+		debugOpts = append(debugOpts, api.WithRecordHTTP(ctx, params.RecordHTTPDir))
+	}
+
+	opts = append(debugOpts, opts...)
 	opts = append(opts, api.WithTimeout(params.Timeout))
 	opts = append(opts, api.WithHostname(strings.TrimSpace(params.Hostname)))
+	opts = append(opts, api.WithCompression(ctx, params.Compression))
+
+	// set up before other transport options, as ntlm wraps the transport
+	if params.SendWorkers > 1 {
+		opts = append(opts, api.WithConcurrency(params.SendWorkers))
+	}
 
 	logger := log.Extract(ctx)
 
@@ -61,22 +112,56 @@ func newClient(ctx context.Context, params paramscmd.API, opts ...api.Option) (*
 		opts = append(opts, api.WithSSLCertPool(api.CACerts(ctx)))
 	}
 
-	if params.ProxyURL != "" {
-		withProxy, err := api.WithProxy(params.ProxyURL)
+	if params.SSLClientCertFilepath != "" {
+		withSSLClientCert, err := api.WithSSLClientCertFile(
+			params.SSLClientCertFilepath,
+			params.SSLClientKeyFilepath,
+			params.SSLClientCertPassphrase,
+		)
 		if err != nil {
-			return nil, fmt.Errorf("failed to set up proxy option on api client: %w", err)
+			return nil, fmt.Errorf("failed to set up ssl client cert option on api client: %s", err)
 		}
 
-		opts = append(opts, withProxy)
+		opts = append(opts, withSSLClientCert)
+	}
+
+	if len(params.SSLPins) > 0 {
+		withSSLPins, err := api.WithSSLPins(ctx, params.SSLPins, params.SSLPinsReportOnly)
+		if err != nil {
+			return nil, fmt.Errorf("failed to set up ssl pins option on api client: %s", err)
+		}
 
-		if strings.Contains(params.ProxyURL, `\\`) {
-			withNTLMRetry, err := api.WithNTLMRequestRetry(ctx, params.ProxyURL)
-			if err != nil {
-				return nil, fmt.Errorf("failed to set up ntlm request retry option on api client: %w", err)
-			}
+		opts = append(opts, withSSLPins)
+	}
 
-			opts = append(opts, withNTLMRetry)
+	var resolver *api.Resolver
+
+	// set up before the proxy option, which dials with the resolver
+	if params.Resolver.Enabled() {
+		resolver, err = api.NewResolver(params.Resolver)
+		if err != nil {
+			return nil, fmt.Errorf("failed to set up resolver option on api client: %w", err)
 		}
+
+		opts = append(opts, api.WithResolver(resolver))
+	}
+
+	withProxy, err := proxyOption(ctx, params, resolver)
+	if err != nil {
+		return nil, err
+	}
+
+	if withProxy != nil {
+		opts = append(opts, withProxy)
+	}
+
+	if strings.Contains(params.ProxyURL, `\\`) {
+		withNTLMRetry, err := api.WithNTLMRequestRetry(ctx, params.ProxyURL)
+		if err != nil {
+			return nil, fmt.Errorf("failed to set up ntlm request retry option on api client: %w", err)
+		}
+
+		opts = append(opts, withNTLMRetry)
 	}
 
 	opts = append(opts, api.WithUserAgent(ctx, params.Plugin))
@@ -84,6 +169,67 @@ func newClient(ctx context.Context, params paramscmd.API, opts ...api.Option) (*
 	return api.NewClient(params.URL, opts...), nil
 }
 
+// bearerAuthOption returns the oauth bearer auth option. Tokens are refreshed by a
+// separate client, sharing all options except authentication.
+func bearerAuthOption(ctx context.Context, params paramscmd.API) (api.Option, error) {
+	store, err := oauth.NewStore(ctx, params.OAuth.StoreType)
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := NewOAuthClient(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+
+	return api.WithBearerAuth(ctx, oauth.NewSource(params.OAuth, client, store)), nil
+}
+
+// proxyOption returns the proxy option following the passed in parameters, or nil if
+// requests should not be proxied. A pac script takes precedence over the proxy url,
+// and resolves hostnames with the resolver, if not nil.
+func proxyOption(ctx context.Context, params paramscmd.API, resolver *api.Resolver) (api.Option, error) {
+	var proxyFunc api.ProxyFunc
+
+	switch {
+	case params.ProxyPAC != "":
+		var pacOpts []api.PACOption
+		if resolver != nil {
+			pacOpts = append(pacOpts, api.WithPACResolver(resolver))
+		}
+
+		pac, err := api.LoadPAC(ctx, params.ProxyPAC, pacOpts...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to set up proxy pac option on api client: %w", err)
+		}
+
+		proxyFunc = pac.ProxyFunc()
+	case params.ProxyURL != "" && strings.Contains(params.ProxyURL, `\\`):
+		// ntlm proxies can't be bypassed, as the ntlm retry wraps the transport
+		withProxy, err := api.WithProxy(params.ProxyURL)
+		if err != nil {
+			return nil, fmt.Errorf("failed to set up proxy option on api client: %w", err)
+		}
+
+		return withProxy, nil
+	case params.ProxyURL != "":
+		proxyURL, err := url.Parse(params.ProxyURL)
+		if err != nil {
+			return nil, fmt.Errorf("failed to set up proxy option on api client: %w", err)
+		}
+
+		proxyFunc = func(*url.URL) (*url.URL, error) { return proxyURL, nil }
+	default:
+		return nil, nil
+	}
+
+	if len(params.NoProxy) > 0 {
+		proxyFunc = api.ProxyBypass(proxyFunc, params.NoProxy)
+	}
+
+	return api.WithProxyFunc(proxyFunc), nil
+}
+
 func timezone() (name string, err error) {
 	defer func() {
 		if r := recover(); r != nil {
@@ -0,0 +1,88 @@
+package backoffstatus
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"time"
+
+	paramscmd "github.com/wakatime/wakatime-cli/cmd/params"
+	"github.com/wakatime/wakatime-cli/pkg/backoff"
+	"github.com/wakatime/wakatime-cli/pkg/exitcode"
+	"github.com/wakatime/wakatime-cli/pkg/ini"
+	"github.com/wakatime/wakatime-cli/pkg/output"
+	"github.com/wakatime/wakatime-cli/pkg/vipertools"
+
+	"github.com/spf13/viper"
+)
+
+// Status contains the current backoff state.
+type Status struct {
+	// RemainingSeconds is the number of seconds until heartbeats are sent again.
+	RemainingSeconds int `json:"remaining_seconds"`
+	// Until is the time heartbeats are sent again, empty when not backing off.
+	Until string `json:"until"`
+	// RateLimited is true when the api rate limited sending heartbeats.
+	RateLimited bool `json:"rate_limited"`
+	// Retries is the number of failed attempts of exponential backoff.
+	Retries int `json:"retries"`
+}
+
+// Run executes the backoff-status command.
+func Run(ctx context.Context, v *viper.Viper) (int, error) {
+	var out output.Output
+
+	if outputStr := vipertools.GetString(v, "output"); outputStr != "" {
+		parsed, err := output.Parse(outputStr)
+		if err != nil {
+			return exitcode.ErrGeneric, fmt.Errorf("failed to parse output: %s", err)
+		}
+
+		out = parsed
+	}
+
+	status := Load(paramscmd.LoadBackoffParams(ctx, v), time.Now())
+
+	rendered, err := Render(status, out)
+	if err != nil {
+		return exitcode.ErrGeneric, fmt.Errorf("failed to render backoff status: %s", err)
+	}
+
+	fmt.Println(rendered)
+
+	return exitcode.Success, nil
+}
+
+// Load returns the backoff status at the given time.
+func Load(params paramscmd.Backoff, now time.Time) Status {
+	status := Status{
+		Retries: params.Retries,
+	}
+
+	deadline := backoff.Deadline(params.Retries, params.At, params.Until)
+	if !deadline.After(now) {
+		return status
+	}
+
+	status.RemainingSeconds = int(math.Ceil(deadline.Sub(now).Seconds()))
+	status.Until = deadline.Format(ini.DateFormat)
+	status.RateLimited = !params.Until.Before(deadline)
+
+	return status
+}
+
+// Render returns the remaining seconds, or all details as json.
+func Render(status Status, out output.Output) (string, error) {
+	switch out {
+	case output.JSONOutput, output.RawJSONOutput:
+		data, err := json.Marshal(status)
+		if err != nil {
+			return "", fmt.Errorf("failed to json marshal status: %s", err)
+		}
+
+		return string(data), nil
+	default:
+		return fmt.Sprint(status.RemainingSeconds), nil
+	}
+}
@@ -0,0 +1,96 @@
+package backoffstatus_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/wakatime/wakatime-cli/cmd/backoffstatus"
+	paramscmd "github.com/wakatime/wakatime-cli/cmd/params"
+	"github.com/wakatime/wakatime-cli/pkg/output"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoad(t *testing.T) {
+	now := time.Date(2024, 3, 10, 12, 0, 0, 0, time.UTC)
+
+	tests := map[string]struct {
+		Params   paramscmd.Backoff
+		Expected backoffstatus.Status
+	}{
+		"not backing off": {
+			Expected: backoffstatus.Status{},
+		},
+		"exponential backoff": {
+			Params: paramscmd.Backoff{
+				At:      now.Add(-10 * time.Second),
+				Retries: 1,
+			},
+			Expected: backoffstatus.Status{
+				RemainingSeconds: 20,
+				Until:            "2024-03-10T12:00:20Z",
+				Retries:          1,
+			},
+		},
+		"exponential backoff expired": {
+			Params: paramscmd.Backoff{
+				At:      now.Add(-time.Minute),
+				Retries: 1,
+			},
+			Expected: backoffstatus.Status{
+				Retries: 1,
+			},
+		},
+		"rate limited": {
+			Params: paramscmd.Backoff{
+				At:      now.Add(-10 * time.Second),
+				Retries: 1,
+				Until:   now.Add(90 * time.Second),
+			},
+			Expected: backoffstatus.Status{
+				RemainingSeconds: 90,
+				Until:            "2024-03-10T12:01:30Z",
+				RateLimited:      true,
+				Retries:          1,
+			},
+		},
+		"rate limit expired": {
+			Params: paramscmd.Backoff{
+				Until: now.Add(-time.Second),
+			},
+			Expected: backoffstatus.Status{},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			status := backoffstatus.Load(test.Params, now)
+
+			assert.Equal(t, test.Expected, status)
+		})
+	}
+}
+
+func TestRender(t *testing.T) {
+	status := backoffstatus.Status{
+		RemainingSeconds: 90,
+		Until:            "2024-03-10T12:01:30Z",
+		RateLimited:      true,
+		Retries:          1,
+	}
+
+	rendered, err := backoffstatus.Render(status, output.TextOutput)
+	require.NoError(t, err)
+
+	assert.Equal(t, "90", rendered)
+
+	rendered, err = backoffstatus.Render(status, output.JSONOutput)
+	require.NoError(t, err)
+
+	assert.JSONEq(
+		t,
+		`{"remaining_seconds":90,"until":"2024-03-10T12:01:30Z","rate_limited":true,"retries":1}`,
+		rendered,
+	)
+}
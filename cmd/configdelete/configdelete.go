@@ -0,0 +1,74 @@
+package configdelete
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/wakatime/wakatime-cli/pkg/exitcode"
+	"github.com/wakatime/wakatime-cli/pkg/ini"
+	"github.com/wakatime/wakatime-cli/pkg/vipertools"
+
+	"github.com/spf13/viper"
+)
+
+// Params contains config delete parameters.
+type Params struct {
+	Section string
+	Keys    []string
+}
+
+// Run loads wakatime config file and call Delete().
+func Run(ctx context.Context, v *viper.Viper) (int, error) {
+	w, err := ini.NewWriter(ctx, v, ini.FilePath)
+	if err != nil {
+		return exitcode.ErrConfigFileParse, fmt.Errorf(
+			"failed to parse config file: %s",
+			err,
+		)
+	}
+
+	if err := Delete(ctx, v, w); err != nil {
+		return exitcode.ErrConfigFileWrite, fmt.Errorf(
+			"failed to delete from config file: %s",
+			err,
+		)
+	}
+
+	return exitcode.Success, nil
+}
+
+// Delete removes the given config key(s) and persist on disk.
+func Delete(ctx context.Context, v *viper.Viper, d ini.Deleter) error {
+	params, err := LoadParams(v)
+	if err != nil {
+		return fmt.Errorf("failed to load command parameters: %w", err)
+	}
+
+	return d.Delete(ctx, params.Section, params.Keys)
+}
+
+// LoadParams loads needed data from the configuration file.
+func LoadParams(v *viper.Viper) (Params, error) {
+	section := strings.TrimSpace(vipertools.GetString(v, "config-section"))
+
+	var keys []string
+
+	for _, key := range v.GetStringSlice("config-delete") {
+		if key = strings.TrimSpace(key); key != "" {
+			keys = append(keys, key)
+		}
+	}
+
+	if section == "" || len(keys) == 0 {
+		return Params{}, errors.New(
+			"neither section nor key can be empty",
+		)
+	}
+
+	return Params{
+		Section: section,
+		Keys:    keys,
+	}, nil
+}
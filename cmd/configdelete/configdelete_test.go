@@ -0,0 +1,106 @@
+package configdelete_test
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/wakatime/wakatime-cli/cmd/configdelete"
+	"github.com/wakatime/wakatime-cli/pkg/ini"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadParams(t *testing.T) {
+	v := viper.New()
+	v.Set("config-section", "projectmap")
+	v.Set("config-delete", []string{"projects/foo", " ", "projects/bar"})
+
+	params, err := configdelete.LoadParams(v)
+	require.NoError(t, err)
+
+	assert.Equal(t, configdelete.Params{
+		Section: "projectmap",
+		Keys:    []string{"projects/foo", "projects/bar"},
+	}, params)
+}
+
+func TestLoadParamsErr(t *testing.T) {
+	tests := map[string]struct {
+		Keys    []string
+		Section string
+	}{
+		"section_missing": {
+			Keys: []string{"debug"},
+		},
+		"key_missing": {
+			Section: "settings",
+		},
+		"all_missing": {},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			v := viper.New()
+			v.Set("config-section", test.Section)
+			v.Set("config-delete", test.Keys)
+
+			_, err := configdelete.LoadParams(v)
+			assert.EqualError(t, err, "neither section nor key can be empty")
+		})
+	}
+}
+
+func TestDelete(t *testing.T) {
+	ctx := context.Background()
+
+	fp := filepath.Join(t.TempDir(), "wakatime.cfg")
+
+	v := viper.New()
+	w, err := ini.NewWriter(ctx, v, func(_ context.Context, _ *viper.Viper) (string, error) {
+		return fp, nil
+	})
+	require.NoError(t, err)
+
+	err = w.Write(ctx, "settings", map[string]string{"debug": "true", "hostname": "my-computer"})
+	require.NoError(t, err)
+
+	v.Set("config-section", "settings")
+	v.Set("config-delete", []string{"debug"})
+
+	err = configdelete.Delete(ctx, v, w)
+	require.NoError(t, err)
+
+	settings, err := ini.ReadSettings(fp)
+	require.NoError(t, err)
+
+	require.Len(t, settings, 1)
+	assert.Equal(t, "hostname", settings[0].Key)
+}
+
+func TestDeleteErr(t *testing.T) {
+	v := viper.New()
+	v.Set("config-section", "settings")
+	v.Set("config-delete", []string{"debug"})
+
+	err := configdelete.Delete(context.Background(), v, &mockDeleter{
+		DeleteFn: func(_ context.Context, section string, keys []string) error {
+			assert.Equal(t, "settings", section)
+			assert.Equal(t, []string{"debug"}, keys)
+
+			return errors.New("error")
+		},
+	})
+	assert.Error(t, err)
+}
+
+type mockDeleter struct {
+	DeleteFn func(ctx context.Context, section string, keys []string) error
+}
+
+func (m *mockDeleter) Delete(ctx context.Context, section string, keys []string) error {
+	return m.DeleteFn(ctx, section, keys)
+}
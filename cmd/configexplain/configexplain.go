@@ -0,0 +1,154 @@
+package configexplain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/wakatime/wakatime-cli/pkg/exitcode"
+	"github.com/wakatime/wakatime-cli/pkg/ini"
+	"github.com/wakatime/wakatime-cli/pkg/vipertools"
+
+	"github.com/mitchellh/go-homedir"
+	"github.com/spf13/viper"
+)
+
+// Params contains config explain parameters.
+type Params struct {
+	Filepath string
+}
+
+// Run prints the effective [settings] values for the given file and the config
+// file each value was read from.
+func Run(ctx context.Context, v *viper.Viper) (int, error) {
+	settings, err := Explain(ctx, v)
+	if err != nil {
+		return exitcode.ErrConfigFileRead, fmt.Errorf(
+			"failed to explain config: %s",
+			err,
+		)
+	}
+
+	fmt.Print(Format(settings))
+
+	return exitcode.Success, nil
+}
+
+// Explain returns the effective [settings] values for the given file, sorted by key.
+// Values from the global config file are overridden by the import config file,
+// which in turn are overridden by per-directory config files from the outermost
+// to the innermost directory. Environment variables take precedence over all files.
+func Explain(ctx context.Context, v *viper.Viper) ([]ini.Setting, error) {
+	params, err := LoadParams(v)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load command parameters: %w", err)
+	}
+
+	effective := map[string]ini.Setting{}
+
+	for _, fn := range []func(context.Context, *viper.Viper) (string, error){ini.FilePath, ini.ImportFilePath} {
+		configFile, err := fn(ctx, v)
+		if err != nil {
+			return nil, fmt.Errorf("error getting config file path: %s", err)
+		}
+
+		if configFile == "" {
+			continue
+		}
+
+		if _, err := os.Stat(configFile); os.IsNotExist(err) {
+			continue
+		}
+
+		settings, err := ini.ReadSettings(configFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read config file %q: %s", configFile, err)
+		}
+
+		for _, s := range settings {
+			effective[s.Key] = s
+		}
+	}
+
+	localFiles, err := ini.LocalFilePaths(ctx, v, params.Filepath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to discover local config files: %s", err)
+	}
+
+	for _, configFile := range localFiles {
+		settings, err := ini.ReadLocalSettings(ctx, configFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read local config file %q: %s", configFile, err)
+		}
+
+		for _, s := range settings {
+			effective[s.Key] = s
+		}
+	}
+
+	for _, o := range ini.EnvOverrides(os.Environ()) {
+		if o.Section != "settings" {
+			continue
+		}
+
+		effective[o.Key] = ini.Setting{
+			Key:    o.Key,
+			Value:  o.Value,
+			Source: "$" + o.EnvVar,
+		}
+	}
+
+	result := make([]ini.Setting, 0, len(effective))
+	for _, s := range effective {
+		result = append(result, s)
+	}
+
+	sort.Slice(result, func(i, j int) bool { return result[i].Key < result[j].Key })
+
+	return result, nil
+}
+
+// Format returns a human readable representation of the given settings, one per line.
+// Api keys are hidden except for the last 4 characters.
+func Format(settings []ini.Setting) string {
+	var b strings.Builder
+
+	for _, s := range settings {
+		value := s.Value
+
+		if (s.Key == "api_key" || s.Key == "apikey") && len(value) > 4 {
+			value = fmt.Sprintf("<hidden>%s", value[len(value)-4:])
+		}
+
+		lines := strings.Split(strings.ReplaceAll(value, "\r", ""), "\n")
+		for i := range lines {
+			lines[i] = strings.TrimSpace(lines[i])
+		}
+
+		value = strings.Trim(strings.Join(lines, " "), " ")
+
+		fmt.Fprintf(&b, "%s = %s (from %s)\n", s.Key, value, s.Source)
+	}
+
+	return b.String()
+}
+
+// LoadParams loads needed data from the configuration file.
+func LoadParams(v *viper.Viper) (Params, error) {
+	fp := strings.TrimSpace(vipertools.GetString(v, "config-explain"))
+	if fp == "" {
+		return Params{}, errors.New("file path can't be empty")
+	}
+
+	expanded, err := homedir.Expand(fp)
+	if err != nil {
+		return Params{}, fmt.Errorf("failed expanding file path: %s", err)
+	}
+
+	return Params{
+		Filepath: expanded,
+	}, nil
+}
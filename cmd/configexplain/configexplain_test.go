@@ -0,0 +1,93 @@
+package configexplain_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/wakatime/wakatime-cli/cmd/configexplain"
+	"github.com/wakatime/wakatime-cli/pkg/ini"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadParams(t *testing.T) {
+	v := viper.New()
+	v.Set("config-explain", "/path/to/file.go")
+
+	params, err := configexplain.LoadParams(v)
+	require.NoError(t, err)
+
+	assert.Equal(t, configexplain.Params{
+		Filepath: "/path/to/file.go",
+	}, params)
+}
+
+func TestLoadParams_Empty(t *testing.T) {
+	v := viper.New()
+	v.Set("config-explain", " ")
+
+	_, err := configexplain.LoadParams(v)
+	require.Error(t, err)
+}
+
+func TestExplain(t *testing.T) {
+	entity, err := filepath.Abs("testdata/project/src/main.go")
+	require.NoError(t, err)
+
+	v := viper.New()
+	v.Set("config", "testdata/wakatime.cfg")
+	v.Set("config-explain", entity)
+
+	settings, err := configexplain.Explain(context.Background(), v)
+	require.NoError(t, err)
+
+	localFile, err := filepath.Abs("testdata/project/.wakatime.cfg")
+	require.NoError(t, err)
+
+	assert.Equal(t, []ini.Setting{
+		{
+			Key:    "api_key",
+			Value:  "00000000-0000-4000-8000-000000000000",
+			Source: "testdata/wakatime.cfg",
+		},
+		{
+			Key:    "exclude",
+			Value:  "\n  ^vendor/\n  ^build/",
+			Source: localFile,
+		},
+		{
+			Key:    "hide_file_names",
+			Value:  "true",
+			Source: localFile,
+		},
+		{
+			Key:    "proxy",
+			Value:  "https://global:8080",
+			Source: "testdata/wakatime.cfg",
+		},
+	}, settings)
+}
+
+func TestFormat(t *testing.T) {
+	output := configexplain.Format([]ini.Setting{
+		{
+			Key:    "api_key",
+			Value:  "00000000-0000-4000-8000-000000000000",
+			Source: "/home/user/.wakatime.cfg",
+		},
+		{
+			Key:    "exclude",
+			Value:  "\n  ^vendor/\n  ^build/",
+			Source: "/home/user/project/.wakatime.cfg",
+		},
+	})
+
+	assert.Equal(t,
+		"api_key = <hidden>0000 (from /home/user/.wakatime.cfg)\n"+
+			"exclude = ^vendor/ ^build/ (from /home/user/project/.wakatime.cfg)\n",
+		output,
+	)
+}
@@ -0,0 +1,175 @@
+package configlist
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/wakatime/wakatime-cli/pkg/exitcode"
+	"github.com/wakatime/wakatime-cli/pkg/ini"
+	"github.com/wakatime/wakatime-cli/pkg/output"
+	"github.com/wakatime/wakatime-cli/pkg/vipertools"
+
+	"github.com/spf13/viper"
+)
+
+// AllSections is the flag value used when --config-list is passed without a section.
+const AllSections = "*"
+
+// Params contains config list parameters.
+type Params struct {
+	Section string
+	Output  output.Output
+}
+
+// Section is a config file section along with its values.
+type Section struct {
+	Name     string
+	Settings []ini.Setting
+}
+
+// Run prints the keys and values of one or all sections of the config file.
+func Run(ctx context.Context, v *viper.Viper) (int, error) {
+	params, err := LoadParams(v)
+	if err != nil {
+		return exitcode.ErrGeneric, fmt.Errorf("failed to load command parameters: %w", err)
+	}
+
+	configFile, err := ini.FilePath(ctx, v)
+	if err != nil {
+		return exitcode.ErrConfigFileRead, fmt.Errorf("error getting config file path: %s", err)
+	}
+
+	sections, err := List(configFile, params.Section)
+	if err != nil {
+		return exitcode.ErrConfigFileRead, fmt.Errorf(
+			"failed to list config: %s",
+			err,
+		)
+	}
+
+	rendered, err := Render(sections, params.Output)
+	if err != nil {
+		return exitcode.ErrGeneric, fmt.Errorf("failed to render config: %s", err)
+	}
+
+	fmt.Print(rendered)
+
+	return exitcode.Success, nil
+}
+
+// List returns the values of the given section from the config file, or of
+// all sections if section is AllSections. A missing config file has no sections.
+func List(configFile, section string) ([]Section, error) {
+	if _, err := os.Stat(configFile); os.IsNotExist(err) {
+		return nil, nil
+	}
+
+	names := []string{section}
+
+	if section == AllSections {
+		var err error
+
+		names, err = ini.SectionNames(configFile)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var sections []Section
+
+	for _, name := range names {
+		settings, err := ini.ReadSection(configFile, name)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(settings) == 0 {
+			continue
+		}
+
+		sections = append(sections, Section{
+			Name:     name,
+			Settings: settings,
+		})
+	}
+
+	return sections, nil
+}
+
+// Render formats sections in ini format, or as a json object of sections
+// mapping keys to values.
+func Render(sections []Section, out output.Output) (string, error) {
+	switch out {
+	case output.JSONOutput, output.RawJSONOutput:
+		result := map[string]map[string]string{}
+
+		for _, s := range sections {
+			values := map[string]string{}
+
+			for _, setting := range s.Settings {
+				values[setting.Key] = setting.Value
+
+				if strings.Contains(setting.Value, "\n") {
+					values[setting.Key] = strings.Join(ini.ParseList(setting.Value), "\n")
+				}
+			}
+
+			result[s.Name] = values
+		}
+
+		data, err := json.Marshal(result)
+		if err != nil {
+			return "", fmt.Errorf("failed to json marshal sections: %s", err)
+		}
+
+		return string(data) + "\n", nil
+	default:
+		var b strings.Builder
+
+		for i, s := range sections {
+			if i > 0 {
+				b.WriteString("\n")
+			}
+
+			fmt.Fprintf(&b, "[%s]\n", s.Name)
+
+			for _, setting := range s.Settings {
+				if strings.Contains(setting.Value, "\n") {
+					fmt.Fprintf(&b, "%s =%s\n", setting.Key, ini.FormatList(ini.ParseList(setting.Value)))
+					continue
+				}
+
+				fmt.Fprintf(&b, "%s = %s\n", setting.Key, setting.Value)
+			}
+		}
+
+		return b.String(), nil
+	}
+}
+
+// LoadParams loads needed data from the configuration file.
+func LoadParams(v *viper.Viper) (Params, error) {
+	section := strings.TrimSpace(vipertools.GetString(v, "config-list"))
+	if section == "" {
+		section = AllSections
+	}
+
+	var out output.Output
+
+	if outputStr := vipertools.GetString(v, "output"); outputStr != "" {
+		parsed, err := output.Parse(outputStr)
+		if err != nil {
+			return Params{}, fmt.Errorf("failed to parse output: %s", err)
+		}
+
+		out = parsed
+	}
+
+	return Params{
+		Section: section,
+		Output:  out,
+	}, nil
+}
@@ -0,0 +1,104 @@
+package configlist_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/wakatime/wakatime-cli/cmd/configlist"
+	"github.com/wakatime/wakatime-cli/pkg/output"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadParams(t *testing.T) {
+	tests := map[string]struct {
+		Value    string
+		Output   string
+		Expected configlist.Params
+	}{
+		"all sections": {
+			Value:    configlist.AllSections,
+			Expected: configlist.Params{Section: configlist.AllSections},
+		},
+		"single section": {
+			Value:    "git",
+			Output:   "json",
+			Expected: configlist.Params{Section: "git", Output: output.JSONOutput},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			v := viper.New()
+			v.Set("config-list", test.Value)
+			v.Set("output", test.Output)
+
+			params, err := configlist.LoadParams(v)
+			require.NoError(t, err)
+
+			assert.Equal(t, test.Expected, params)
+		})
+	}
+}
+
+func TestList(t *testing.T) {
+	sections, err := configlist.List("testdata/wakatime.cfg", configlist.AllSections)
+	require.NoError(t, err)
+
+	require.Len(t, sections, 2)
+	assert.Equal(t, "projectmap", sections[0].Name)
+	assert.Equal(t, "settings", sections[1].Name)
+	assert.Len(t, sections[1].Settings, 2)
+}
+
+func TestList_Section(t *testing.T) {
+	sections, err := configlist.List("testdata/wakatime.cfg", "projectmap")
+	require.NoError(t, err)
+
+	require.Len(t, sections, 1)
+	assert.Equal(t, "projects/foo", sections[0].Settings[0].Key)
+	assert.Equal(t, "new project name", sections[0].Settings[0].Value)
+}
+
+func TestList_MissingFile(t *testing.T) {
+	sections, err := configlist.List(filepath.Join(t.TempDir(), "missing.cfg"), configlist.AllSections)
+	require.NoError(t, err)
+
+	assert.Empty(t, sections)
+}
+
+func TestRender(t *testing.T) {
+	sections, err := configlist.List("testdata/wakatime.cfg", configlist.AllSections)
+	require.NoError(t, err)
+
+	rendered, err := configlist.Render(sections, output.TextOutput)
+	require.NoError(t, err)
+
+	assert.Equal(t,
+		"[projectmap]\n"+
+			"projects/foo = new project name\n"+
+			"\n"+
+			"[settings]\n"+
+			"debug = true\n"+
+			"exclude =\n    ^vendor/\n    ^build/\n",
+		rendered,
+	)
+}
+
+func TestRender_JSON(t *testing.T) {
+	sections, err := configlist.List("testdata/wakatime.cfg", configlist.AllSections)
+	require.NoError(t, err)
+
+	rendered, err := configlist.Render(sections, output.JSONOutput)
+	require.NoError(t, err)
+
+	assert.JSONEq(t,
+		`{
+			"projectmap": {"projects/foo": "new project name"},
+			"settings": {"debug": "true", "exclude": "^vendor/\n^build/"}
+		}`,
+		rendered,
+	)
+}
@@ -0,0 +1,681 @@
+package configvalidate
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	paramscmd "github.com/wakatime/wakatime-cli/cmd/params"
+	"github.com/wakatime/wakatime-cli/pkg/api"
+	"github.com/wakatime/wakatime-cli/pkg/exitcode"
+	"github.com/wakatime/wakatime-cli/pkg/heartbeat"
+	"github.com/wakatime/wakatime-cli/pkg/ini"
+	"github.com/wakatime/wakatime-cli/pkg/keystore"
+	"github.com/wakatime/wakatime-cli/pkg/log"
+	"github.com/wakatime/wakatime-cli/pkg/output"
+	"github.com/wakatime/wakatime-cli/pkg/schedule"
+	"github.com/wakatime/wakatime-cli/pkg/vipertools"
+
+	"github.com/spf13/viper"
+)
+
+// Severity is the severity of a config issue.
+type Severity string
+
+const (
+	// SeverityError is used for values which are invalid and will be ignored or fail heartbeats.
+	SeverityError Severity = "error"
+	// SeverityWarning is used for values which are most likely a mistake, like unknown keys.
+	SeverityWarning Severity = "warning"
+)
+
+// kind describes how the value of a config key is validated.
+type kind int
+
+const (
+	kindString kind = iota
+	kindBool
+	kindInt
+	kindBoolOrRegexList
+	kindAPIKey
+	kindURL
+	kindProxy
+	kindKeyStore
+	kindCompression
+	kindSSLPinList
+	kindIPPreference
+	kindGlobList
+	kindHideMode
+	kindRedactRuleList
+	kindCategoryRuleList
+)
+
+// nolint:gochecknoglobals
+var knownKeys = map[string]map[string]kind{
+	"settings": {
+		"api_key":                        kindAPIKey,
+		"apikey":                         kindAPIKey,
+		"api_key_store":                  kindKeyStore,
+		"api_key_store_entry":            kindString,
+		"api_key_vault_cmd":              kindString,
+		"api_url":                        kindURL,
+		"category_rules":                 kindCategoryRuleList,
+		"debug":                          kindBool,
+		"dns_over_https":                 kindURL,
+		"dns_servers":                    kindString,
+		"exclude":                        kindBoolOrRegexList,
+		"exclude_globs":                  kindGlobList,
+		"exclude_unknown_project":        kindBool,
+		"guess_language":                 kindBool,
+		"heartbeat_max_age_days":         kindInt,
+		"heartbeat_rate_limit_seconds":   kindInt,
+		"happy_eyeballs_delay":           kindInt,
+		"heartbeat_send_workers":         kindInt,
+		"hide_branch_names":              kindBoolOrRegexList,
+		"hide_branchnames":               kindBoolOrRegexList,
+		"hide_domains":                   kindBoolOrRegexList,
+		"hidebranchnames":                kindBoolOrRegexList,
+		"hide_file_names":                kindBoolOrRegexList,
+		"hide_filenames":                 kindBoolOrRegexList,
+		"hidefilenames":                  kindBoolOrRegexList,
+		"hide_mode":                      kindHideMode,
+		"hide_project_folder":            kindBool,
+		"hide_project_names":             kindBoolOrRegexList,
+		"hide_projectnames":              kindBoolOrRegexList,
+		"hideprojectnames":               kindBoolOrRegexList,
+		"hide_secret_files":              kindBool,
+		"hide_url_paths":                 kindBoolOrRegexList,
+		"hide_url_query_params":          kindBoolOrRegexList,
+		"honor_ignore_files":             kindBool,
+		"hostname":                       kindString,
+		"ignore":                         kindBoolOrRegexList,
+		"import_cfg":                     kindString,
+		"include":                        kindBoolOrRegexList,
+		"include_globs":                  kindGlobList,
+		"include_only_with_project_file": kindBool,
+		"infer_category":                 kindBool,
+		"ip_preference":                  kindIPPreference,
+		"keep_url_query_params":          kindString,
+		"log_file":                       kindString,
+		"metrics":                        kindBool,
+		"no_proxy":                       kindString,
+		"no_ssl_verify":                  kindBool,
+		"oauth_client_id":                kindString,
+		"oauth_device_auth_url":          kindString,
+		"oauth_scope":                    kindString,
+		"oauth_token_url":                kindString,
+		"offline":                        kindBool,
+		"proxy":                          kindProxy,
+		"proxy_pac":                      kindString,
+		"request_compression":            kindCompression,
+		"resolve":                        kindString,
+		"send_diagnostics_on_errors":     kindBool,
+		"ssl_certs_file":                 kindString,
+		"ssl_client_cert":                kindString,
+		"ssl_client_cert_passphrase_cmd": kindString,
+		"ssl_client_key":                 kindString,
+		"ssl_pins_report_only":           kindBool,
+		"status_bar_coding_activity":     kindBool,
+		"status_bar_enabled":             kindBool,
+		"status_bar_hide_categories":     kindBool,
+		"timeout":                        kindInt,
+	},
+	"git": {
+		"project_from_git_remote": kindBool,
+		"submodules_disabled":     kindBoolOrRegexList,
+	},
+	"redact": {
+		"rules": kindRedactRuleList,
+	},
+}
+
+// mapSections contain regex patterns as keys.
+// nolint:gochecknoglobals
+var mapSections = map[string]kind{
+	"git_submodule_projectmap": kindString,
+	"project_api_key":          kindAPIKey,
+	"projectmap":               kindString,
+}
+
+// hostSections contain api hostnames as keys.
+// nolint:gochecknoglobals
+var hostSections = map[string]kind{
+	"ssl_pins": kindSSLPinList,
+}
+
+// scheduleSection contains named time windows as keys, besides the rules and
+// timezone keys.
+const scheduleSection = "schedule"
+
+// ignoredSections are sections not meant to be edited by users.
+// nolint:gochecknoglobals
+var ignoredSections = map[string]struct{}{
+	"internal": {},
+}
+
+// Issue is a problem found in the config.
+type Issue struct {
+	Severity   Severity `json:"severity"`
+	Section    string   `json:"section,omitempty"`
+	Key        string   `json:"key,omitempty"`
+	Message    string   `json:"message"`
+	Suggestion string   `json:"suggestion,omitempty"`
+}
+
+// Result contains the outcome of a config validation.
+type Result struct {
+	Valid  bool    `json:"valid"`
+	Issues []Issue `json:"issues"`
+}
+
+// Run validates the loaded config and prints all found issues.
+func Run(ctx context.Context, v *viper.Viper) (int, error) {
+	var out output.Output
+
+	if outputStr := vipertools.GetString(v, "output"); outputStr != "" {
+		parsed, err := output.Parse(outputStr)
+		if err != nil {
+			return exitcode.ErrGeneric, fmt.Errorf("failed to parse output: %s", err)
+		}
+
+		out = parsed
+	}
+
+	result := Validate(ctx, v)
+
+	rendered, err := Render(result, out)
+	if err != nil {
+		return exitcode.ErrGeneric, fmt.Errorf("failed to render result: %s", err)
+	}
+
+	fmt.Print(rendered)
+
+	if !result.Valid {
+		return exitcode.ErrConfigFileParse, nil
+	}
+
+	return exitcode.Success, nil
+}
+
+// Validate checks all loaded config values for unknown keys, invalid regexes,
+// invalid proxy urls, api keys and timezones. It also runs the api and heartbeat
+// params loaders, to report errors which would make sending heartbeats fail.
+func Validate(ctx context.Context, v *viper.Viper) Result {
+	var issues []Issue
+
+	for _, k := range v.AllKeys() {
+		section, key, ok := strings.Cut(k, ".")
+		if !ok {
+			// command line flag
+			continue
+		}
+
+		if _, ok := ignoredSections[section]; ok {
+			continue
+		}
+
+		value := vipertools.GetString(v, k)
+
+		if valueKind, ok := mapSections[section]; ok {
+			if err := paramscmd.ValidateRegex(key); err != nil {
+				issues = append(issues, Issue{
+					Severity: SeverityError,
+					Section:  section,
+					Key:      key,
+					Message:  fmt.Sprintf("invalid regex pattern: %s", err),
+				})
+			}
+
+			issues = append(issues, validateValue(section, key, value, valueKind)...)
+
+			continue
+		}
+
+		if valueKind, ok := hostSections[section]; ok {
+			issues = append(issues, validateValue(section, key, value, valueKind)...)
+			continue
+		}
+
+		if isTimezoneKey(key) {
+			issues = append(issues, validateTimezone(section, key, value)...)
+			continue
+		}
+
+		if section == scheduleSection {
+			issues = append(issues, validateSchedule(v, key, value)...)
+			continue
+		}
+
+		keys, ok := knownKeys[section]
+		if !ok {
+			issues = append(issues, Issue{
+				Severity:   SeverityWarning,
+				Section:    section,
+				Key:        key,
+				Message:    "unknown section",
+				Suggestion: suggest(section, sectionNames()),
+			})
+
+			continue
+		}
+
+		valueKind, ok := keys[key]
+		if !ok {
+			issues = append(issues, Issue{
+				Severity:   SeverityWarning,
+				Section:    section,
+				Key:        key,
+				Message:    "unknown key",
+				Suggestion: suggest(key, keyNames(keys)),
+			})
+
+			continue
+		}
+
+		issues = append(issues, validateValue(section, key, value, valueKind)...)
+	}
+
+	if _, err := paramscmd.LoadAPIParams(ctx, v); err != nil {
+		issues = append(issues, Issue{
+			Severity: SeverityError,
+			Message:  fmt.Sprintf("failed to load api params: %s", err),
+		})
+	}
+
+	issues = append(issues, validateHeartbeatParams(ctx, v)...)
+
+	sort.SliceStable(issues, func(i, j int) bool {
+		if issues[i].Section != issues[j].Section {
+			return issues[i].Section < issues[j].Section
+		}
+
+		return issues[i].Key < issues[j].Key
+	})
+
+	valid := true
+
+	for _, issue := range issues {
+		if issue.Severity == SeverityError {
+			valid = false
+			break
+		}
+	}
+
+	return Result{
+		Valid:  valid,
+		Issues: issues,
+	}
+}
+
+// validateHeartbeatParams runs the heartbeat params loader, which parses settings
+// like heartbeat_max_age_days, redact, category and schedule rules and glob filters
+// only when sending heartbeats. Errors and logged warnings are returned as issues.
+// The loader runs on a copy of the config with an app entity, so it doesn't read
+// extra heartbeats from stdin or per-directory config files.
+func validateHeartbeatParams(ctx context.Context, v *viper.Viper) []Issue {
+	config := viper.New()
+
+	for _, k := range v.AllKeys() {
+		config.Set(k, v.Get(k))
+	}
+
+	config.Set("entity", "wakatime-cli")
+	config.Set("entity-type", "app")
+	config.Set("extra-heartbeats", false)
+
+	var buf bytes.Buffer
+
+	logger := log.New(&buf)
+
+	_, err := paramscmd.LoadHeartbeatParams(log.ToContext(ctx, logger), config)
+
+	logger.Flush()
+
+	var issues []Issue
+
+	scanner := bufio.NewScanner(&buf)
+	for scanner.Scan() {
+		var entry struct {
+			Level   string `json:"level"`
+			Message string `json:"message"`
+		}
+
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil || entry.Level != "warn" {
+			continue
+		}
+
+		issues = append(issues, Issue{
+			Severity: SeverityWarning,
+			Message:  fmt.Sprintf("heartbeat params: %s", entry.Message),
+		})
+	}
+
+	if err != nil {
+		issues = append(issues, Issue{
+			Severity: SeverityError,
+			Message:  fmt.Sprintf("failed to load heartbeat params: %s", err),
+		})
+	}
+
+	return issues
+}
+
+// Render formats the result following the passed in output format.
+func Render(result Result, out output.Output) (string, error) {
+	switch out {
+	case output.JSONOutput, output.RawJSONOutput:
+		if result.Issues == nil {
+			result.Issues = []Issue{}
+		}
+
+		data, err := json.Marshal(result)
+		if err != nil {
+			return "", fmt.Errorf("failed to json marshal result: %s", err)
+		}
+
+		return string(data) + "\n", nil
+	default:
+		var b strings.Builder
+
+		for _, issue := range result.Issues {
+			b.WriteString(issue.String())
+			b.WriteString("\n")
+		}
+
+		if result.Valid {
+			b.WriteString("config is valid\n")
+		} else {
+			b.WriteString("config is invalid\n")
+		}
+
+		return b.String(), nil
+	}
+}
+
+// String implements fmt.Stringer interface.
+func (i Issue) String() string {
+	var b strings.Builder
+
+	b.WriteString(string(i.Severity))
+	b.WriteString(": ")
+
+	if i.Section != "" {
+		fmt.Fprintf(&b, "[%s] ", i.Section)
+	}
+
+	if i.Key != "" {
+		fmt.Fprintf(&b, "%s: ", i.Key)
+	}
+
+	b.WriteString(i.Message)
+
+	if i.Suggestion != "" {
+		fmt.Fprintf(&b, ", did you mean %q?", i.Suggestion)
+	}
+
+	return b.String()
+}
+
+func validateValue(section, key, value string, valueKind kind) []Issue {
+	newIssue := func(msg string) Issue {
+		return Issue{
+			Severity: SeverityError,
+			Section:  section,
+			Key:      key,
+			Message:  msg,
+		}
+	}
+
+	switch valueKind {
+	case kindBool:
+		if value == "" {
+			return nil
+		}
+
+		if _, err := strconv.ParseBool(value); err != nil {
+			return []Issue{newIssue(fmt.Sprintf("invalid boolean %q", value))}
+		}
+	case kindInt:
+		if value == "" {
+			return nil
+		}
+
+		if _, err := strconv.Atoi(value); err != nil {
+			return []Issue{newIssue(fmt.Sprintf("invalid integer %q", value))}
+		}
+	case kindBoolOrRegexList:
+		var issues []Issue
+
+		for _, err := range paramscmd.ValidateBoolOrRegexList(value) {
+			issues = append(issues, newIssue(fmt.Sprintf("invalid regex pattern, it will be ignored: %s", err)))
+		}
+
+		return issues
+	case kindGlobList:
+		var issues []Issue
+
+		for _, err := range paramscmd.ValidateGlobList(value) {
+			issues = append(issues, newIssue(err.Error()))
+		}
+
+		return issues
+	case kindAPIKey:
+		if value == "" {
+			return nil
+		}
+
+		if err := paramscmd.ValidateAPIKey(value); err != nil {
+			return []Issue{newIssue(err.Error())}
+		}
+	case kindURL:
+		if value == "" {
+			return nil
+		}
+
+		if u, err := url.Parse(value); err != nil || u.Scheme == "" || u.Host == "" {
+			return []Issue{newIssue(fmt.Sprintf("invalid url %q", value))}
+		}
+	case kindProxy:
+		if err := paramscmd.ValidateProxyURL(value); err != nil {
+			return []Issue{newIssue(err.Error())}
+		}
+	case kindKeyStore:
+		if value == "" {
+			return nil
+		}
+
+		if _, err := keystore.New(value, ""); err != nil {
+			return []Issue{newIssue(err.Error())}
+		}
+	case kindCompression:
+		if _, err := api.ParseCompression(value); err != nil {
+			return []Issue{newIssue(err.Error())}
+		}
+	case kindIPPreference:
+		if _, err := api.ParseIPPreference(value); err != nil {
+			return []Issue{newIssue(err.Error())}
+		}
+	case kindHideMode:
+		if _, err := heartbeat.ParseHideMode(value); err != nil {
+			return []Issue{newIssue(err.Error())}
+		}
+	case kindRedactRuleList:
+		var issues []Issue
+
+		for _, line := range ini.ParseList(value) {
+			if _, err := heartbeat.ParseRedactRule(line); err != nil {
+				issues = append(issues, newIssue(err.Error()))
+			}
+		}
+
+		return issues
+	case kindCategoryRuleList:
+		var issues []Issue
+
+		for _, line := range ini.ParseList(value) {
+			if _, err := heartbeat.ParseCategoryRule(line); err != nil {
+				issues = append(issues, newIssue(err.Error()))
+			}
+		}
+
+		return issues
+	case kindSSLPinList:
+		var issues []Issue
+
+		for _, pin := range ini.ParseList(value) {
+			if _, err := api.ParseSSLPin(pin); err != nil {
+				issues = append(issues, newIssue(err.Error()))
+			}
+		}
+
+		return issues
+	case kindString:
+	}
+
+	return nil
+}
+
+func validateSchedule(v *viper.Viper, key, value string) []Issue {
+	newIssue := func(msg string) Issue {
+		return Issue{
+			Severity: SeverityError,
+			Section:  scheduleSection,
+			Key:      key,
+			Message:  msg,
+		}
+	}
+
+	if key == "rules" {
+		var issues []Issue
+
+		for _, err := range paramscmd.ValidateScheduleRules(v) {
+			issues = append(issues, newIssue(err.Error()))
+		}
+
+		return issues
+	}
+
+	if _, err := schedule.ParseWindow(key, value, time.UTC); err != nil {
+		return []Issue{newIssue(err.Error())}
+	}
+
+	return nil
+}
+
+func isTimezoneKey(key string) bool {
+	return key == "timezone" || strings.HasSuffix(key, "_timezone")
+}
+
+func validateTimezone(section, key, value string) []Issue {
+	if value == "" {
+		return nil
+	}
+
+	if _, err := time.LoadLocation(value); err != nil {
+		return []Issue{{
+			Severity: SeverityError,
+			Section:  section,
+			Key:      key,
+			Message:  fmt.Sprintf("invalid timezone %q", value),
+		}}
+	}
+
+	return nil
+}
+
+func sectionNames() []string {
+	var names []string
+
+	for name := range knownKeys {
+		names = append(names, name)
+	}
+
+	for name := range mapSections {
+		names = append(names, name)
+	}
+
+	for name := range hostSections {
+		names = append(names, name)
+	}
+
+	for name := range ignoredSections {
+		names = append(names, name)
+	}
+
+	return append(names, scheduleSection)
+}
+
+func keyNames(keys map[string]kind) []string {
+	names := make([]string, 0, len(keys))
+
+	for name := range keys {
+		names = append(names, name)
+	}
+
+	return names
+}
+
+// suggest returns the closest candidate to s, or an empty string if none
+// of the candidates is close enough to be a likely typo.
+func suggest(s string, candidates []string) string {
+	sort.Strings(candidates)
+
+	var (
+		best     string
+		bestDist = -1
+	)
+
+	for _, c := range candidates {
+		d := levenshtein(s, c)
+		if bestDist == -1 || d < bestDist {
+			best, bestDist = c, d
+		}
+	}
+
+	maxDist := len(s) / 3
+	if maxDist < 1 {
+		maxDist = 1
+	}
+
+	if bestDist == -1 || bestDist > maxDist {
+		return ""
+	}
+
+	return best
+}
+
+// levenshtein returns the edit distance between two strings.
+func levenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+
+		prev, curr = curr, prev
+	}
+
+	return prev[len(rb)]
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"sort"
 	"strings"
 
 	"github.com/wakatime/wakatime-cli/pkg/exitcode"
@@ -13,10 +14,22 @@ import (
 	"github.com/spf13/viper"
 )
 
+// mapSections contain regex patterns as keys, so list items are entries of the
+// section instead of items of a multi-line value.
+// nolint:gochecknoglobals
+var mapSections = map[string]struct{}{
+	"git_submodule_projectmap": {},
+	"project_api_key":          {},
+	"projectmap":               {},
+}
+
 // Params contains config write parameters.
 type Params struct {
 	Section  string
 	KeyValue map[string]string
+	// Append and Remove map keys of multi-line list values to the items to add or remove.
+	Append map[string][]string
+	Remove map[string][]string
 }
 
 // Run loads wakatime config file and call Write().
@@ -39,14 +52,77 @@ func Run(ctx context.Context, v *viper.Viper) (int, error) {
 	return exitcode.Success, nil
 }
 
-// Write writes value(s) to given config key(s) and persist on disk.
+// Write writes value(s) to given config key(s) and persist on disk. List items
+// are appended and removed afterwards, which requires w to implement ini.ListWriter.
 func Write(ctx context.Context, v *viper.Viper, w ini.Writer) error {
 	params, err := LoadParams(v)
 	if err != nil {
 		return fmt.Errorf("failed to load command parameters: %w", err)
 	}
 
-	return w.Write(ctx, params.Section, params.KeyValue)
+	if len(params.KeyValue) > 0 {
+		if err := w.Write(ctx, params.Section, params.KeyValue); err != nil {
+			return err
+		}
+	}
+
+	if len(params.Append) == 0 && len(params.Remove) == 0 {
+		return nil
+	}
+
+	if _, ok := mapSections[params.Section]; ok {
+		return writeEntries(ctx, params, w)
+	}
+
+	lw, ok := w.(ini.ListWriter)
+	if !ok {
+		return errors.New("writer does not support list values")
+	}
+
+	for _, key := range sortedKeys(params.Append) {
+		if err := lw.AppendToList(ctx, params.Section, key, params.Append[key]); err != nil {
+			return err
+		}
+	}
+
+	for _, key := range sortedKeys(params.Remove) {
+		if err := lw.RemoveFromList(ctx, params.Section, key, params.Remove[key]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// writeEntries adds and removes the entries of a section with regex pattern keys,
+// like pattern=name for the [projectmap] section.
+func writeEntries(ctx context.Context, params Params, w ini.Writer) error {
+	if len(params.Append) > 0 {
+		entries := make(map[string]string, len(params.Append))
+
+		for key, values := range params.Append {
+			if len(values) > 1 {
+				return fmt.Errorf("multiple values to append for %q in [%s]", key, params.Section)
+			}
+
+			entries[key] = values[0]
+		}
+
+		if err := w.Write(ctx, params.Section, entries); err != nil {
+			return err
+		}
+	}
+
+	if len(params.Remove) == 0 {
+		return nil
+	}
+
+	mw, ok := w.(ini.MapWriter)
+	if !ok {
+		return errors.New("writer does not support removing entries")
+	}
+
+	return mw.RemoveEntries(ctx, params.Section, params.Remove)
 }
 
 // LoadParams loads needed data from the configuration file.
@@ -54,7 +130,17 @@ func LoadParams(v *viper.Viper) (Params, error) {
 	section := strings.TrimSpace(vipertools.GetString(v, "config-section"))
 	kv := v.GetStringMapString("config-write")
 
-	if section == "" || len(kv) == 0 {
+	appendItems, err := parseListItems(v.GetStringSlice("config-append"))
+	if err != nil {
+		return Params{}, fmt.Errorf("invalid config-append: %s", err)
+	}
+
+	removeItems, err := parseListItems(v.GetStringSlice("config-remove"))
+	if err != nil {
+		return Params{}, fmt.Errorf("invalid config-remove: %s", err)
+	}
+
+	if section == "" || (len(kv) == 0 && len(appendItems) == 0 && len(removeItems) == 0) {
 		return Params{}, errors.New(
 			"neither section nor key/value can be empty",
 		)
@@ -63,5 +149,41 @@ func LoadParams(v *viper.Viper) (Params, error) {
 	return Params{
 		Section:  section,
 		KeyValue: kv,
+		Append:   appendItems,
+		Remove:   removeItems,
 	}, nil
 }
+
+// parseListItems groups key=value arguments by key. Only the first equal sign
+// is used as separator, as values are often regex patterns.
+func parseListItems(args []string) (map[string][]string, error) {
+	if len(args) == 0 {
+		return nil, nil
+	}
+
+	items := map[string][]string{}
+
+	for _, arg := range args {
+		key, value, ok := strings.Cut(arg, "=")
+
+		key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+		if !ok || key == "" || value == "" {
+			return nil, fmt.Errorf("expected key=value but got %q", arg)
+		}
+
+		items[key] = append(items[key], value)
+	}
+
+	return items, nil
+}
+
+func sortedKeys(m map[string][]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	return keys
+}
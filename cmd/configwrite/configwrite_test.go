@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/wakatime/wakatime-cli/cmd/configwrite"
@@ -151,6 +152,112 @@ func TestWriteSaveErr(t *testing.T) {
 	assert.Error(t, err)
 }
 
+func TestLoadParams_ListItems(t *testing.T) {
+	v := viper.New()
+	v.Set("config-section", "settings")
+	v.Set("config-append", []string{"exclude=^vendor/", "exclude=^build/", "include=.*"})
+	v.Set("config-remove", []string{"exclude=a=b"})
+
+	params, err := configwrite.LoadParams(v)
+	require.NoError(t, err)
+
+	assert.Equal(t, map[string][]string{
+		"exclude": {"^vendor/", "^build/"},
+		"include": {".*"},
+	}, params.Append)
+	assert.Equal(t, map[string][]string{"exclude": {"a=b"}}, params.Remove)
+}
+
+func TestLoadParams_ListItemsErr(t *testing.T) {
+	v := viper.New()
+	v.Set("config-section", "settings")
+	v.Set("config-append", []string{"exclude"})
+
+	_, err := configwrite.LoadParams(v)
+	assert.EqualError(t, err, `invalid config-append: expected key=value but got "exclude"`)
+}
+
+func TestWrite_ListItems(t *testing.T) {
+	ctx := context.Background()
+
+	fp := filepath.Join(t.TempDir(), "wakatime.cfg")
+
+	v := viper.New()
+	w, err := ini.NewWriter(ctx, v, func(_ context.Context, _ *viper.Viper) (string, error) {
+		return fp, nil
+	})
+	require.NoError(t, err)
+
+	v.Set("config-section", "settings")
+	v.Set("config-append", []string{"exclude=^vendor/", "exclude=^build/"})
+
+	err = configwrite.Write(ctx, v, w)
+	require.NoError(t, err)
+
+	v.Set("config-append", nil)
+	v.Set("config-remove", []string{"exclude=^vendor/"})
+
+	err = configwrite.Write(ctx, v, w)
+	require.NoError(t, err)
+
+	settings, err := ini.ReadSettings(fp)
+	require.NoError(t, err)
+
+	require.Len(t, settings, 1)
+	assert.Equal(t, "exclude", settings[0].Key)
+	assert.Equal(t, []string{"^build/"}, ini.ParseList(settings[0].Value))
+}
+
+func TestWrite_ProjectMapEntries(t *testing.T) {
+	ctx := context.Background()
+
+	fp := filepath.Join(t.TempDir(), "wakatime.cfg")
+
+	v := viper.New()
+	w, err := ini.NewWriter(ctx, v, func(_ context.Context, _ *viper.Viper) (string, error) {
+		return fp, nil
+	})
+	require.NoError(t, err)
+
+	v.Set("config-section", "projectmap")
+	v.Set("config-append", []string{`projects/foo=new project name`, `^/home/user/projects/bar(\d+)/=project{0}`})
+
+	err = configwrite.Write(ctx, v, w)
+	require.NoError(t, err)
+
+	v.Set("config-append", nil)
+	// entries are only removed when the value matches
+	v.Set("config-remove", []string{"projects/foo=new project name", `^/home/user/projects/bar(\d+)/=other`})
+
+	err = configwrite.Write(ctx, v, w)
+	require.NoError(t, err)
+
+	settings, err := ini.ReadSection(fp, "projectmap")
+	require.NoError(t, err)
+
+	require.Len(t, settings, 1)
+	assert.Equal(t, `^/home/user/projects/bar(\d+)/`, settings[0].Key)
+	assert.Equal(t, "project{0}", settings[0].Value)
+}
+
+func TestWrite_ProjectMapEntriesConflict(t *testing.T) {
+	v := viper.New()
+	v.Set("config-section", "projectmap")
+	v.Set("config-append", []string{"projects/foo=first", "projects/foo=second"})
+
+	err := configwrite.Write(context.Background(), v, &mockWriter{})
+	assert.EqualError(t, err, `multiple values to append for "projects/foo" in [projectmap]`)
+}
+
+func TestWrite_ListItemsUnsupported(t *testing.T) {
+	v := viper.New()
+	v.Set("config-section", "settings")
+	v.Set("config-append", []string{"exclude=^vendor/"})
+
+	err := configwrite.Write(context.Background(), v, &mockWriter{})
+	assert.EqualError(t, err, "writer does not support list values")
+}
+
 type mockWriter struct {
 	WriteFn func(ctx context.Context, section string, keyValue map[string]string) error
 }
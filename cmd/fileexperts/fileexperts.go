@@ -111,7 +111,10 @@ func initHandleOptions(params paramscmd.Params) []heartbeat.HandleOption {
 		heartbeat.WithEntityModifier(),
 		filter.WithFiltering(filter.Config{
 			Exclude:                    params.Heartbeat.Filter.Exclude,
+			ExcludeGlobs:               params.Heartbeat.Filter.ExcludeGlobs,
+			HonorIgnoreFiles:           params.Heartbeat.Filter.HonorIgnoreFiles,
 			Include:                    params.Heartbeat.Filter.Include,
+			IncludeGlobs:               params.Heartbeat.Filter.IncludeGlobs,
 			IncludeOnlyWithProjectFile: params.Heartbeat.Filter.IncludeOnlyWithProjectFile,
 		}),
 		apikey.WithReplacing(apikey.Config{
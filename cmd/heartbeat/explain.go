@@ -0,0 +1,350 @@
+package heartbeat
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"runtime"
+	"strings"
+
+	"github.com/wakatime/wakatime-cli/pkg/exitcode"
+	"github.com/wakatime/wakatime-cli/pkg/heartbeat"
+	"github.com/wakatime/wakatime-cli/pkg/log"
+	"github.com/wakatime/wakatime-cli/pkg/output"
+	"github.com/wakatime/wakatime-cli/pkg/vipertools"
+
+	"github.com/spf13/viper"
+)
+
+// Explanation describes how the heartbeat processing pipeline handled heartbeats.
+type Explanation struct {
+	// Stages are the executed stages in order. A stage can stop processing,
+	// in which case later stages are missing.
+	Stages []Stage `json:"stages"`
+	// DroppedBy is the stage which dropped the last heartbeats, empty when
+	// heartbeats would have been sent.
+	DroppedBy string `json:"dropped_by,omitempty"`
+	// Sent are the heartbeats which would have been sent to the api.
+	Sent []heartbeat.Heartbeat `json:"sent"`
+}
+
+// Stage describes a single stage of the heartbeat processing pipeline.
+type Stage struct {
+	Name   string                `json:"name"`
+	Before []heartbeat.Heartbeat `json:"before"`
+	After  []heartbeat.Heartbeat `json:"after"`
+	// Rules are the messages logged by the stage, like the matching exclude pattern.
+	Rules []string `json:"rules,omitempty"`
+}
+
+// RunExplain executes the explain command. It runs heartbeats through the
+// heartbeat processing pipeline without sending them, and prints what each
+// stage did.
+func RunExplain(ctx context.Context, v *viper.Viper) (int, error) {
+	var out output.Output
+
+	if outputStr := vipertools.GetString(v, "output"); outputStr != "" {
+		parsed, err := output.Parse(outputStr)
+		if err != nil {
+			return exitcode.ErrGeneric, fmt.Errorf("failed to parse output: %s", err)
+		}
+
+		out = parsed
+	}
+
+	params, err := LoadParams(ctx, v)
+	if err != nil {
+		return exitcode.ErrGeneric, fmt.Errorf("failed to load command parameters: %w", err)
+	}
+
+	// invalid heartbeats must not be moved to the quarantine of the offline db
+	params.Offline.Disabled = true
+
+	// dry run stages don't write .wakatime-project files or download remote files
+	explanation, err := Explain(ctx, buildHeartbeats(ctx, params), initHandleOptions(params, "", true))
+	if err != nil {
+		return exitcode.ErrGeneric, fmt.Errorf("failed to explain heartbeat processing: %s", err)
+	}
+
+	rendered, err := RenderExplanation(explanation, out)
+	if err != nil {
+		return exitcode.ErrGeneric, fmt.Errorf("failed to render explanation: %s", err)
+	}
+
+	fmt.Print(rendered)
+
+	return exitcode.Success, nil
+}
+
+// Explain runs the heartbeats through the handle options, recording the
+// heartbeats before and after each stage, instead of sending them.
+func Explain(ctx context.Context, hh []heartbeat.Heartbeat, opts []heartbeat.HandleOption) (Explanation, error) {
+	var explanation Explanation
+
+	traced := make([]heartbeat.HandleOption, len(opts))
+	for i, opt := range opts {
+		traced[i] = withTrace(stageName(opt), opt, &explanation)
+	}
+
+	handle := heartbeat.NewHandle(noopSender{explanation: &explanation}, traced...)
+
+	if _, err := handle(ctx, hh); err != nil {
+		return Explanation{}, err
+	}
+
+	if len(explanation.Sent) == 0 {
+		for i := len(explanation.Stages) - 1; i >= 0; i-- {
+			if len(explanation.Stages[i].Before) > len(explanation.Stages[i].After) {
+				explanation.DroppedBy = explanation.Stages[i].Name
+				break
+			}
+		}
+	}
+
+	return explanation, nil
+}
+
+// withTrace records the heartbeats passed in and out of the handle option,
+// and the messages logged by it.
+func withTrace(name string, opt heartbeat.HandleOption, explanation *Explanation) heartbeat.HandleOption {
+	return func(next heartbeat.Handle) heartbeat.Handle {
+		return func(ctx context.Context, hh []heartbeat.Heartbeat) ([]heartbeat.Result, error) {
+			var buf bytes.Buffer
+
+			index := len(explanation.Stages)
+			explanation.Stages = append(explanation.Stages, Stage{
+				Name:   name,
+				Before: append([]heartbeat.Heartbeat{}, hh...),
+				After:  []heartbeat.Heartbeat{},
+			})
+
+			traced := opt(func(_ context.Context, hh []heartbeat.Heartbeat) ([]heartbeat.Result, error) {
+				explanation.Stages[index].After = append([]heartbeat.Heartbeat{}, hh...)
+				explanation.Stages[index].Rules = parseMessages(&buf)
+
+				return next(ctx, hh)
+			})
+
+			results, err := traced(log.ToContext(ctx, log.New(&buf, log.WithVerbose(true))), hh)
+
+			// the stage returned without calling the next stage
+			if explanation.Stages[index].Rules == nil {
+				explanation.Stages[index].Rules = parseMessages(&buf)
+			}
+
+			return results, err
+		}
+	}
+}
+
+// parseMessages returns the messages of the json log lines and resets the buffer.
+func parseMessages(buf *bytes.Buffer) []string {
+	messages := []string{}
+
+	scanner := bufio.NewScanner(buf)
+	for scanner.Scan() {
+		var line struct {
+			Message string `json:"message"`
+		}
+
+		if err := json.Unmarshal(scanner.Bytes(), &line); err != nil || line.Message == "" {
+			continue
+		}
+
+		// every stage logs its name first, which adds nothing to the explanation
+		if strings.HasPrefix(line.Message, "execute ") {
+			continue
+		}
+
+		messages = append(messages, line.Message)
+	}
+
+	buf.Reset()
+
+	return messages
+}
+
+// stageName returns the package qualified name of the function returning the
+// handle option, like "filter.WithFiltering".
+func stageName(opt heartbeat.HandleOption) string {
+	fn := runtime.FuncForPC(reflect.ValueOf(opt).Pointer())
+	if fn == nil {
+		return "unknown"
+	}
+
+	name := fn.Name()
+	name = name[strings.LastIndex(name, "/")+1:]
+
+	if i := strings.Index(name, ".func"); i >= 0 {
+		name = name[:i]
+	}
+
+	return name
+}
+
+// noopSender records heartbeats instead of sending them to the api.
+type noopSender struct {
+	explanation *Explanation
+}
+
+// SendHeartbeats records the heartbeats and returns a created status for each of them.
+func (s noopSender) SendHeartbeats(_ context.Context, hh []heartbeat.Heartbeat) ([]heartbeat.Result, error) {
+	s.explanation.Sent = append([]heartbeat.Heartbeat{}, hh...)
+
+	results := make([]heartbeat.Result, len(hh))
+	for i, h := range hh {
+		results[i] = heartbeat.Result{
+			Status:    201,
+			Heartbeat: h,
+		}
+	}
+
+	return results, nil
+}
+
+// RenderExplanation returns the explanation as json, or as text listing the
+// changes of each stage.
+func RenderExplanation(explanation Explanation, out output.Output) (string, error) {
+	switch out {
+	case output.JSONOutput, output.RawJSONOutput:
+		data, err := json.Marshal(explanation)
+		if err != nil {
+			return "", fmt.Errorf("failed to json marshal explanation: %s", err)
+		}
+
+		return string(data) + "\n", nil
+	default:
+		return renderExplanationText(explanation), nil
+	}
+}
+
+func renderExplanationText(explanation Explanation) string {
+	var b strings.Builder
+
+	if len(explanation.Stages) > 0 {
+		for _, h := range explanation.Stages[0].Before {
+			b.WriteString("heartbeat:\n")
+
+			for _, field := range explainFields(h) {
+				fmt.Fprintf(&b, "  %s: %s\n", field.name, field.value)
+			}
+		}
+	}
+
+	for i, stage := range explanation.Stages {
+		fmt.Fprintf(&b, "\n%d. %s\n", i+1, stage.Name)
+
+		for _, rule := range stage.Rules {
+			fmt.Fprintf(&b, "  rule: %s\n", rule)
+		}
+
+		changes := explainChanges(stage.Before, stage.After)
+
+		switch {
+		case len(stage.Before) == 0:
+			b.WriteString("  no heartbeats left\n")
+		case len(changes) == 0:
+			b.WriteString("  unchanged\n")
+		}
+
+		for _, change := range changes {
+			fmt.Fprintf(&b, "  %s\n", change)
+		}
+	}
+
+	b.WriteString("\n")
+
+	if explanation.DroppedBy != "" {
+		fmt.Fprintf(&b, "result: dropped by %s\n", explanation.DroppedBy)
+	} else {
+		fmt.Fprintf(&b, "result: %d heartbeat(s) would be sent\n", len(explanation.Sent))
+	}
+
+	return b.String()
+}
+
+// explainChanges lists changed fields when the stage kept all heartbeats, or
+// the dropped heartbeats otherwise.
+func explainChanges(before, after []heartbeat.Heartbeat) []string {
+	var changes []string
+
+	if len(before) != len(after) {
+		kept := make(map[string]bool, len(after))
+		for _, h := range after {
+			kept[h.ID()] = true
+		}
+
+		for _, h := range before {
+			if !kept[h.ID()] {
+				changes = append(changes, fmt.Sprintf("dropped: %s", h.Entity))
+			}
+		}
+
+		return changes
+	}
+
+	for i := range before {
+		fieldsBefore, fieldsAfter := explainFields(before[i]), explainFields(after[i])
+
+		for j := range fieldsBefore {
+			if fieldsBefore[j].value == fieldsAfter[j].value {
+				continue
+			}
+
+			changes = append(changes, fmt.Sprintf(
+				"%s: %s -> %s",
+				fieldsBefore[j].name,
+				fieldsBefore[j].value,
+				fieldsAfter[j].value,
+			))
+		}
+	}
+
+	return changes
+}
+
+type explainField struct {
+	name  string
+	value string
+}
+
+// explainFields returns the printable fields of a heartbeat, with a hidden api key.
+func explainFields(h heartbeat.Heartbeat) []explainField {
+	apiKey := h.APIKey
+	if len(apiKey) > 4 {
+		apiKey = fmt.Sprintf("<hidden>%s", apiKey[len(apiKey)-4:])
+	}
+
+	return []explainField{
+		{"entity", fmt.Sprintf("%q", h.Entity)},
+		{"type", h.EntityType.String()},
+		{"category", h.Category.String()},
+		{"project", stringPtr(h.Project)},
+		{"branch", stringPtr(h.Branch)},
+		{"language", stringPtr(h.Language)},
+		{"dependencies", fmt.Sprintf("%q", h.Dependencies)},
+		{"lines", intPtr(h.Lines)},
+		{"lineno", intPtr(h.LineNumber)},
+		{"cursorpos", intPtr(h.CursorPosition)},
+		{"api_key", apiKey},
+	}
+}
+
+func stringPtr(s *string) string {
+	if s == nil {
+		return "<none>"
+	}
+
+	return fmt.Sprintf("%q", *s)
+}
+
+func intPtr(i *int) string {
+	if i == nil {
+		return "<none>"
+	}
+
+	return fmt.Sprint(*i)
+}
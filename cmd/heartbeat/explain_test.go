@@ -0,0 +1,222 @@
+package heartbeat_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	cmdheartbeat "github.com/wakatime/wakatime-cli/cmd/heartbeat"
+	"github.com/wakatime/wakatime-cli/pkg/exitcode"
+	"github.com/wakatime/wakatime-cli/pkg/filter"
+	"github.com/wakatime/wakatime-cli/pkg/heartbeat"
+	"github.com/wakatime/wakatime-cli/pkg/output"
+	"github.com/wakatime/wakatime-cli/pkg/regex"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExplain(t *testing.T) {
+	opts := []heartbeat.HandleOption{
+		heartbeat.WithFormatting(),
+		heartbeat.WithSanitization(heartbeat.SanitizeConfig{
+			FilePatterns: []regex.Regex{regex.MustCompile("main")},
+		}),
+	}
+
+	explanation, err := cmdheartbeat.Explain(context.Background(), []heartbeat.Heartbeat{
+		{
+			Category:   heartbeat.CodingCategory,
+			Entity:     "testdata/main.go",
+			EntityType: heartbeat.FileType,
+			Lines:      heartbeat.PointerTo(11),
+			Time:       1585598060,
+		},
+	}, opts)
+	require.NoError(t, err)
+
+	require.Len(t, explanation.Stages, 2)
+
+	assert.Equal(t, "heartbeat.WithFormatting", explanation.Stages[0].Name)
+	assert.Empty(t, explanation.Stages[0].Rules)
+
+	assert.Equal(t, "heartbeat.WithSanitization", explanation.Stages[1].Name)
+	assert.Equal(t, []string{`hiding entity because matches hide file names pattern "main"`}, explanation.Stages[1].Rules)
+	assert.Equal(t, 11, *explanation.Stages[1].Before[0].Lines)
+	assert.Equal(t, "HIDDEN.go", explanation.Stages[1].After[0].Entity)
+	assert.Nil(t, explanation.Stages[1].After[0].Lines)
+
+	assert.Empty(t, explanation.DroppedBy)
+	require.Len(t, explanation.Sent, 1)
+	assert.Equal(t, "HIDDEN.go", explanation.Sent[0].Entity)
+}
+
+func TestExplain_Dropped(t *testing.T) {
+	opts := []heartbeat.HandleOption{
+		filter.WithFiltering(filter.Config{
+			Exclude: []regex.Regex{regex.MustCompile("^/tmp/excluded/")},
+		}),
+		heartbeat.WithSanitization(heartbeat.SanitizeConfig{}),
+	}
+
+	explanation, err := cmdheartbeat.Explain(context.Background(), []heartbeat.Heartbeat{
+		{
+			Category:   heartbeat.CodingCategory,
+			Entity:     "/tmp/excluded/main.go",
+			EntityType: heartbeat.FileType,
+			Time:       1585598060,
+		},
+	}, opts)
+	require.NoError(t, err)
+
+	require.Len(t, explanation.Stages, 2)
+
+	assert.Equal(t, "filter.WithFiltering", explanation.Stages[0].Name)
+	assert.Equal(t, []string{
+		`filter by pattern: skipping because matches exclude pattern "^/tmp/excluded/"`,
+	}, explanation.Stages[0].Rules)
+	assert.Len(t, explanation.Stages[0].Before, 1)
+	assert.Empty(t, explanation.Stages[0].After)
+
+	assert.Equal(t, "heartbeat.WithSanitization", explanation.Stages[1].Name)
+	assert.Empty(t, explanation.Stages[1].Before)
+
+	assert.Equal(t, "filter.WithFiltering", explanation.DroppedBy)
+	assert.Empty(t, explanation.Sent)
+}
+
+func TestRunExplain_NoSideEffects(t *testing.T) {
+	dir := t.TempDir()
+
+	entity := filepath.Join(dir, "main.go")
+
+	err := os.WriteFile(entity, []byte("package main\n"), 0600)
+	require.NoError(t, err)
+
+	tmpFile, err := os.CreateTemp(t.TempDir(), "wakatime-config")
+	require.NoError(t, err)
+
+	defer tmpFile.Close()
+
+	v := viper.New()
+	v.Set("config", tmpFile.Name())
+	v.Set("entity", entity)
+	v.Set("entity-type", "file")
+	v.Set("key", "00000000-0000-4000-8000-000000000000")
+	v.Set("output", "json")
+	v.Set("project", "secret-project")
+	v.Set("project-folder", dir)
+	v.Set("settings.hide_project_names", "true")
+	v.Set("time", float64(time.Now().Unix()))
+
+	code, err := cmdheartbeat.RunExplain(context.Background(), v)
+	require.NoError(t, err)
+
+	assert.Equal(t, exitcode.Success, code)
+
+	// an obfuscated project name is not saved to the project folder
+	assert.NoFileExists(t, filepath.Join(dir, ".wakatime-project"))
+}
+
+func TestRenderExplanation(t *testing.T) {
+	before := heartbeat.Heartbeat{
+		APIKey:     "00000000-0000-4000-8000-000000000000",
+		Category:   heartbeat.CodingCategory,
+		Entity:     "/tmp/main.go",
+		EntityType: heartbeat.FileType,
+	}
+
+	after := before
+	after.Entity = "HIDDEN.go"
+
+	explanation := cmdheartbeat.Explanation{
+		Stages: []cmdheartbeat.Stage{
+			{
+				Name:   "heartbeat.WithSanitization",
+				Before: []heartbeat.Heartbeat{before},
+				After:  []heartbeat.Heartbeat{after},
+				Rules:  []string{`hiding entity because matches hide file names pattern "main"`},
+			},
+			{
+				Name:   "filter.WithLengthValidator",
+				Before: []heartbeat.Heartbeat{after},
+				After:  []heartbeat.Heartbeat{},
+			},
+		},
+		DroppedBy: "filter.WithLengthValidator",
+	}
+
+	rendered, err := cmdheartbeat.RenderExplanation(explanation, output.TextOutput)
+	require.NoError(t, err)
+
+	assert.Equal(t, `heartbeat:
+  entity: "/tmp/main.go"
+  type: file
+  category: coding
+  project: <none>
+  branch: <none>
+  language: <none>
+  dependencies: []
+  lines: <none>
+  lineno: <none>
+  cursorpos: <none>
+  api_key: <hidden>0000
+
+1. heartbeat.WithSanitization
+  rule: hiding entity because matches hide file names pattern "main"
+  entity: "/tmp/main.go" -> "HIDDEN.go"
+
+2. filter.WithLengthValidator
+  dropped: HIDDEN.go
+
+result: dropped by filter.WithLengthValidator
+`, rendered)
+}
+
+func TestRenderExplanation_JSON(t *testing.T) {
+	explanation := cmdheartbeat.Explanation{
+		Stages: []cmdheartbeat.Stage{
+			{
+				Name: "project.WithFiltering",
+				Before: []heartbeat.Heartbeat{
+					{
+						Category:   heartbeat.CodingCategory,
+						Entity:     "/tmp/main.go",
+						EntityType: heartbeat.FileType,
+						Time:       1585598060,
+					},
+				},
+				After: []heartbeat.Heartbeat{},
+				Rules: []string{"skipping because of unknown project"},
+			},
+		},
+		DroppedBy: "project.WithFiltering",
+	}
+
+	rendered, err := cmdheartbeat.RenderExplanation(explanation, output.JSONOutput)
+	require.NoError(t, err)
+
+	assert.JSONEq(t, `{
+		"stages": [
+			{
+				"name": "project.WithFiltering",
+				"before": [
+					{
+						"category": "coding",
+						"entity": "/tmp/main.go",
+						"type": "file",
+						"time": 1585598060,
+						"user_agent": ""
+					}
+				],
+				"after": [],
+				"rules": ["skipping because of unknown project"]
+			}
+		],
+		"dropped_by": "project.WithFiltering",
+		"sent": null
+	}`, rendered)
+}
@@ -15,6 +15,7 @@ import (
 	"github.com/wakatime/wakatime-cli/pkg/backoff"
 	"github.com/wakatime/wakatime-cli/pkg/deps"
 	"github.com/wakatime/wakatime-cli/pkg/exitcode"
+	"github.com/wakatime/wakatime-cli/pkg/file"
 	"github.com/wakatime/wakatime-cli/pkg/filestats"
 	"github.com/wakatime/wakatime-cli/pkg/filter"
 	"github.com/wakatime/wakatime-cli/pkg/heartbeat"
@@ -22,24 +23,65 @@ import (
 	"github.com/wakatime/wakatime-cli/pkg/language"
 	_ "github.com/wakatime/wakatime-cli/pkg/lexer" // force to load all lexers
 	"github.com/wakatime/wakatime-cli/pkg/log"
+	"github.com/wakatime/wakatime-cli/pkg/namehash"
 	"github.com/wakatime/wakatime-cli/pkg/offline"
+	"github.com/wakatime/wakatime-cli/pkg/output"
 	"github.com/wakatime/wakatime-cli/pkg/project"
 	"github.com/wakatime/wakatime-cli/pkg/remote"
+	"github.com/wakatime/wakatime-cli/pkg/schedule"
+	"github.com/wakatime/wakatime-cli/pkg/secret"
+	"github.com/wakatime/wakatime-cli/pkg/vipertools"
 	"github.com/wakatime/wakatime-cli/pkg/wakaerror"
 
 	"github.com/spf13/viper"
 )
 
-// Run executes the heartbeat command.
+// Run executes the heartbeat command. With json output, it prints the outcome
+// of each heartbeat and the invalid extra heartbeats.
 func Run(ctx context.Context, v *viper.Viper) (int, error) {
 	logger := log.Extract(ctx)
 
+	var out output.Output
+
+	if outputStr := vipertools.GetString(v, "output"); outputStr != "" {
+		parsed, err := output.Parse(outputStr)
+		if err != nil {
+			return exitcode.ErrGeneric, fmt.Errorf("failed to parse output: %s", err)
+		}
+
+		out = parsed
+	}
+
 	queueFilepath, err := offline.QueueFilepath(ctx, v)
 	if err != nil {
 		logger.Warnf("failed to load offline queue filepath: %s", err)
 	}
 
-	err = SendHeartbeats(ctx, v, queueFilepath)
+	outcomes := heartbeat.NewOutcomes()
+	ctx = heartbeat.OutcomesToContext(ctx, outcomes)
+
+	report, sendErr := sendHeartbeats(ctx, v, queueFilepath)
+
+	code, err := handleSendError(ctx, v, queueFilepath, sendErr)
+
+	if out == output.JSONOutput || out == output.RawJSONOutput {
+		report.setOutcomes(outcomes, sendErr)
+
+		rendered, renderErr := RenderReport(report)
+		if renderErr != nil {
+			return exitcode.ErrGeneric, renderErr
+		}
+
+		fmt.Print(rendered)
+	}
+
+	return code, err
+}
+
+// handleSendError returns the exit code and error of the heartbeat command.
+func handleSendError(ctx context.Context, v *viper.Viper, queueFilepath string, err error) (int, error) {
+	logger := log.Extract(ctx)
+
 	if err != nil {
 		var errauth api.ErrAuth
 
@@ -73,9 +115,15 @@ func Run(ctx context.Context, v *viper.Viper) (int, error) {
 // heartbeats from the offline queue, if available and offline sync is not
 // explicitly disabled.
 func SendHeartbeats(ctx context.Context, v *viper.Viper, queueFilepath string) error {
+	_, err := sendHeartbeats(ctx, v, queueFilepath)
+
+	return err
+}
+
+func sendHeartbeats(ctx context.Context, v *viper.Viper, queueFilepath string) (Report, error) {
 	params, err := LoadParams(ctx, v)
 	if err != nil {
-		return fmt.Errorf("failed to load command parameters: %w", err)
+		return Report{}, fmt.Errorf("failed to load command parameters: %w", err)
 	}
 
 	logger := log.Extract(ctx)
@@ -83,25 +131,30 @@ func SendHeartbeats(ctx context.Context, v *viper.Viper, queueFilepath string) e
 	setLogFields(ctx, params)
 	logger.Debugf("params: %s", params)
 
+	heartbeats := buildHeartbeats(ctx, params)
+
+	report := newReport(heartbeats, params.Heartbeat.ExtraHeartbeatErrors)
+
 	if RateLimited(RateLimitParams{
 		Disabled:   params.Offline.Disabled,
 		LastSentAt: params.Offline.LastSentAt,
 		Timeout:    params.Offline.RateLimit,
 	}) {
 		if err = offlinecmd.SaveHeartbeats(ctx, v, nil, queueFilepath); err == nil {
-			return nil
+			return report, nil
 		}
 
 		// log offline db error then try to send heartbeats to API so they're not lost
 		logger.Errorf("failed to save rate limited heartbeats: %s", err)
 	}
 
-	heartbeats := buildHeartbeats(ctx, params)
-
-	var chOfflineSave = make(chan bool)
+	var (
+		chOfflineSave = make(chan bool)
+		savingExtra   = len(heartbeats) > offline.SendLimit
+	)
 
 	// only send at once the maximum amount of `offline.SendLimit`.
-	if len(heartbeats) > offline.SendLimit {
+	if savingExtra {
 		extraHeartbeats := heartbeats[offline.SendLimit:]
 
 		logger.Debugf("save %d extra heartbeat(s) to offline queue", len(extraHeartbeats))
@@ -117,7 +170,7 @@ func SendHeartbeats(ctx context.Context, v *viper.Viper, queueFilepath string) e
 		heartbeats = heartbeats[:offline.SendLimit]
 	}
 
-	handleOpts := initHandleOptions(params)
+	handleOpts := initHandleOptions(params, queueFilepath, false)
 
 	if !params.Offline.Disabled {
 		handleOpts = append(handleOpts, offline.WithQueue(queueFilepath))
@@ -128,6 +181,7 @@ func SendHeartbeats(ctx context.Context, v *viper.Viper, queueFilepath string) e
 		At:       params.API.BackoffAt,
 		Retries:  params.API.BackoffRetries,
 		HasProxy: params.API.ProxyURL != "",
+		Until:    params.API.BackoffUntil,
 	}))
 
 	apiClient, err := apicmd.NewClientWithoutAuth(ctx, params.API)
@@ -138,19 +192,19 @@ func SendHeartbeats(ctx context.Context, v *viper.Viper, queueFilepath string) e
 			}
 		}
 
-		return fmt.Errorf("failed to initialize api client: %w", err)
+		return report, fmt.Errorf("failed to initialize api client: %w", err)
 	}
 
-	handle := heartbeat.NewHandle(apiClient, handleOpts...)
+	handle := heartbeat.NewHandle(recordingSender{sender: apiClient}, handleOpts...)
 	results, err := handle(ctx, heartbeats)
 
 	// wait for offline queue save to finish
-	if len(heartbeats) > offline.SendLimit {
+	if savingExtra {
 		<-chOfflineSave
 	}
 
 	if err != nil {
-		return err
+		return report, err
 	}
 
 	for _, result := range results {
@@ -163,7 +217,7 @@ func SendHeartbeats(ctx context.Context, v *viper.Viper, queueFilepath string) e
 		logger.Errorf("failed to reset rate limit: %s", err)
 	}
 
-	return nil
+	return report, nil
 }
 
 // LoadParams loads params from viper.Viper instance. Returns ErrAuth
@@ -240,6 +294,7 @@ func buildHeartbeats(ctx context.Context, params paramscmd.Params) []heartbeat.H
 	heartbeats = append(heartbeats, heartbeat.New(
 		params.Heartbeat.Project.BranchAlternate,
 		params.Heartbeat.Category,
+		params.Heartbeat.CategoryUnset,
 		params.Heartbeat.CursorPosition,
 		params.Heartbeat.Entity,
 		params.Heartbeat.EntityType,
@@ -264,10 +319,11 @@ func buildHeartbeats(ctx context.Context, params paramscmd.Params) []heartbeat.H
 		logger := log.Extract(ctx)
 		logger.Debugf("include %d extra heartbeat(s) from stdin", len(params.Heartbeat.ExtraHeartbeats))
 
-		for _, h := range params.Heartbeat.ExtraHeartbeats {
-			heartbeats = append(heartbeats, heartbeat.New(
+		for i, h := range params.Heartbeat.ExtraHeartbeats {
+			extra := heartbeat.New(
 				h.BranchAlternate,
 				h.Category,
+				h.CategoryUnset,
 				h.CursorPosition,
 				h.Entity,
 				h.EntityType,
@@ -286,23 +342,44 @@ func buildHeartbeats(ctx context.Context, params paramscmd.Params) []heartbeat.H
 				h.ProjectPathOverride,
 				h.Time,
 				userAgent,
-			))
+			)
+
+			// the main heartbeat has ref 0
+			extra.Ref = i + 1
+
+			heartbeats = append(heartbeats, extra)
 		}
 	}
 
 	return heartbeats
 }
 
-func initHandleOptions(params paramscmd.Params) []heartbeat.HandleOption {
+// initHandleOptions returns the heartbeat processing pipeline. With dry run, stages
+// skip side effects, like writing .wakatime-project files or downloading remote files.
+func initHandleOptions(params paramscmd.Params, queueFilepath string, dryRun bool) []heartbeat.HandleOption {
+	// file contents are read once for language and secret detection
+	heads := file.NewHeads()
+
+	var hasher heartbeat.Hasher
+	if params.Heartbeat.Sanitize.HashFilepath != "" {
+		hasher = namehash.NewHasher(params.Heartbeat.Sanitize.HashFilepath)
+	}
+
 	return []heartbeat.HandleOption{
+		filter.WithLocalScope(params.Heartbeat.Filter.LocalScope),
 		heartbeat.WithFormatting(),
 		heartbeat.WithEntityModifier(),
 		filter.WithFiltering(filter.Config{
 			Exclude:                    params.Heartbeat.Filter.Exclude,
+			ExcludeGlobs:               params.Heartbeat.Filter.ExcludeGlobs,
+			HonorIgnoreFiles:           params.Heartbeat.Filter.HonorIgnoreFiles,
 			Include:                    params.Heartbeat.Filter.Include,
+			IncludeGlobs:               params.Heartbeat.Filter.IncludeGlobs,
 			IncludeOnlyWithProjectFile: params.Heartbeat.Filter.IncludeOnlyWithProjectFile,
 		}),
-		remote.WithDetection(),
+		remote.WithDetection(remote.Config{
+			DryRun: dryRun,
+		}),
 		apikey.WithReplacing(apikey.Config{
 			DefaultAPIKey: params.API.Key,
 			MapPatterns:   params.API.KeyPatterns,
@@ -310,11 +387,18 @@ func initHandleOptions(params paramscmd.Params) []heartbeat.HandleOption {
 		filestats.WithDetection(),
 		language.WithDetection(language.Config{
 			GuessLanguage: params.Heartbeat.GuessLanguage,
+			Heads:         heads,
+		}),
+		heartbeat.WithCategoryInference(heartbeat.CategoryConfig{
+			Enabled: params.Heartbeat.InferCategory,
+			Rules:   params.Heartbeat.CategoryRules,
 		}),
 		deps.WithDetection(deps.Config{
 			FilePatterns: params.Heartbeat.Sanitize.HideFileNames,
 		}),
 		project.WithDetection(project.Config{
+			DryRun:               dryRun,
+			Hasher:               hasher,
 			HideProjectNames:     params.Heartbeat.Sanitize.HideProjectNames,
 			MapPatterns:          params.Heartbeat.Project.MapPatterns,
 			ProjectFromGitRemote: params.Heartbeat.Project.ProjectFromGitRemote,
@@ -326,17 +410,47 @@ func initHandleOptions(params paramscmd.Params) []heartbeat.HandleOption {
 		project.WithFiltering(project.FilterConfig{
 			ExcludeUnknownProject: params.Heartbeat.Filter.ExcludeUnknownProject,
 		}),
+		schedule.WithFiltering(schedule.Config{
+			Rules: params.Heartbeat.Filter.ScheduleRules,
+		}),
+		secret.WithDetection(secret.Config{
+			BranchPatterns: params.Heartbeat.Sanitize.HideBranchNames,
+			Enabled:        params.Heartbeat.Sanitize.HideSecretFiles,
+			Hasher:         hasher,
+			Heads:          heads,
+		}),
 		heartbeat.WithSanitization(heartbeat.SanitizeConfig{
 			BranchPatterns:    params.Heartbeat.Sanitize.HideBranchNames,
+			DomainPatterns:    params.Heartbeat.Sanitize.HideDomains,
 			FilePatterns:      params.Heartbeat.Sanitize.HideFileNames,
+			Hasher:            hasher,
 			HideProjectFolder: params.Heartbeat.Sanitize.HideProjectFolder,
 			ProjectPatterns:   params.Heartbeat.Sanitize.HideProjectNames,
+			RedactRules:       params.Heartbeat.Sanitize.RedactRules,
+			URLPathPatterns:   params.Heartbeat.Sanitize.HideURLPaths,
+			URLQueryKeep:      params.Heartbeat.Sanitize.KeepURLQueryParams,
+			URLQueryPatterns:  params.Heartbeat.Sanitize.HideURLQueryParams,
 		}),
 		remote.WithCleanup(),
+		validationOption(params, queueFilepath),
 		filter.WithLengthValidator(),
 	}
 }
 
+// validationOption moves invalid heartbeats to the quarantine of the offline db,
+// or drops them if the offline db is disabled.
+func validationOption(params paramscmd.Params, queueFilepath string) heartbeat.HandleOption {
+	config := heartbeat.ValidateConfig{
+		MaxAge: params.Heartbeat.MaxAge,
+	}
+
+	if params.Offline.Disabled {
+		return heartbeat.WithValidation(config)
+	}
+
+	return offline.WithQuarantine(queueFilepath, config)
+}
+
 func setLogFields(ctx context.Context, params paramscmd.Params) {
 	log.AddField(ctx, "file", params.Heartbeat.Entity)
 	log.AddField(ctx, "time", params.Heartbeat.Time)
@@ -1,6 +1,7 @@
 package heartbeat_test
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
@@ -19,6 +20,7 @@ import (
 	cmdheartbeat "github.com/wakatime/wakatime-cli/cmd/heartbeat"
 	cmdparams "github.com/wakatime/wakatime-cli/cmd/params"
 	"github.com/wakatime/wakatime-cli/pkg/api"
+	"github.com/wakatime/wakatime-cli/pkg/exitcode"
 	"github.com/wakatime/wakatime-cli/pkg/heartbeat"
 	"github.com/wakatime/wakatime-cli/pkg/ini"
 	"github.com/wakatime/wakatime-cli/pkg/log"
@@ -121,6 +123,7 @@ func TestSendHeartbeats(t *testing.T) {
 	v.Set("local-file", "testdata/localfile.go")
 	v.Set("plugin", plugin)
 	v.Set("time", 1585598059.1)
+	v.Set("settings.heartbeat_max_age_days", 0)
 	v.Set("timeout", 5)
 	v.Set("write", true)
 
@@ -135,6 +138,69 @@ func TestSendHeartbeats(t *testing.T) {
 	assert.Eventually(t, func() bool { return numCalls == 1 }, time.Second, 50*time.Millisecond)
 }
 
+func TestSendHeartbeats_OAuth(t *testing.T) {
+	resetSingleton(t)
+
+	testServerURL, router, tearDown := setupTestServer()
+	defer tearDown()
+
+	var numCalls int
+
+	router.HandleFunc("/users/current/heartbeats.bulk", func(w http.ResponseWriter, req *http.Request) {
+		numCalls++
+
+		assert.Equal(t, []string{"Bearer access-token"}, req.Header["Authorization"])
+
+		w.WriteHeader(http.StatusCreated)
+
+		f, err := os.Open("testdata/api_heartbeats_response.json")
+		require.NoError(t, err)
+		defer f.Close()
+
+		_, err = io.Copy(w, f)
+		require.NoError(t, err)
+	})
+
+	home := t.TempDir()
+	t.Setenv("WAKATIME_HOME", home)
+	t.Setenv("WAKATIME_API_KEY", "")
+
+	err := os.WriteFile(
+		filepath.Join(home, "wakatime-oauth-token.json"),
+		[]byte(`{"access_token":"access-token","refresh_token":"refresh-token"}`),
+		0600,
+	)
+	require.NoError(t, err)
+
+	tmpFile, err := os.CreateTemp(t.TempDir(), "wakatime-config")
+	require.NoError(t, err)
+
+	defer tmpFile.Close()
+
+	v := viper.New()
+	v.SetDefault("sync-offline-activity", 1000)
+	v.Set("api-url", testServerURL)
+	v.Set("config", tmpFile.Name())
+	v.Set("entity", "testdata/main.go")
+	v.Set("entity-type", "file")
+	v.Set("plugin", "plugin/0.0.1")
+	v.Set("settings.oauth_client_id", "client-id")
+	v.Set("settings.oauth_token_url", testServerURL+"/oauth/token")
+	v.Set("time", 1585598059.1)
+	v.Set("settings.heartbeat_max_age_days", 0)
+	v.Set("timeout", 5)
+
+	offlineQueueFile, err := os.CreateTemp(t.TempDir(), "")
+	require.NoError(t, err)
+
+	defer offlineQueueFile.Close()
+
+	err = cmdheartbeat.SendHeartbeats(context.Background(), v, offlineQueueFile.Name())
+	require.NoError(t, err)
+
+	assert.Equal(t, 1, numCalls)
+}
+
 func TestSendHeartbeats_RateLimited(t *testing.T) {
 	resetSingleton(t)
 
@@ -182,6 +248,7 @@ func TestSendHeartbeats_RateLimited(t *testing.T) {
 	v.Set("local-file", "testdata/localfile.go")
 	v.Set("plugin", plugin)
 	v.Set("time", 1585598059.1)
+	v.Set("settings.heartbeat_max_age_days", 0)
 	v.Set("timeout", 5)
 	v.Set("write", true)
 	v.Set("heartbeat-rate-limit-seconds", 500)
@@ -220,6 +287,7 @@ func TestSendHeartbeats_WithFiltering_Exclude(t *testing.T) {
 	v.Set("key", "00000000-0000-4000-8000-000000000000")
 	v.Set("plugin", "plugin")
 	v.Set("time", 1585598059.1)
+	v.Set("settings.heartbeat_max_age_days", 0)
 	v.Set("timeout", 5)
 	v.Set("write", true)
 
@@ -258,6 +326,7 @@ func TestSendHeartbeats_WithFiltering_Exclude_All(t *testing.T) {
 	v.Set("key", "00000000-0000-4000-8000-000000000000")
 	v.Set("plugin", "plugin")
 	v.Set("time", 1585598059.1)
+	v.Set("settings.heartbeat_max_age_days", 0)
 	v.Set("timeout", 5)
 	v.Set("write", true)
 
@@ -399,6 +468,7 @@ func TestSendHeartbeats_ExtraHeartbeats(t *testing.T) {
 	v.Set("lineno", 2)
 	v.Set("plugin", plugin)
 	v.Set("time", 1585598059.1)
+	v.Set("settings.heartbeat_max_age_days", 0)
 	v.Set("timeout", 5)
 	v.Set("write", true)
 
@@ -486,6 +556,7 @@ func TestSendHeartbeats_ExtraHeartbeats_Sanitize(t *testing.T) {
 	v.Set("lineno", 13)
 	v.Set("plugin", plugin)
 	v.Set("time", 1585598059.1)
+	v.Set("settings.heartbeat_max_age_days", 0)
 	v.Set("timeout", 5)
 	v.Set("write", true)
 
@@ -707,6 +778,7 @@ func TestSendHeartbeats_IsUnsavedEntity(t *testing.T) {
 	v.Set("lines-in-file", 91)
 	v.Set("plugin", plugin)
 	v.Set("time", 1585598051)
+	v.Set("settings.heartbeat_max_age_days", 0)
 	v.Set("timeout", 5)
 	v.Set("extra-heartbeats", true)
 	v.Set("log-file", logFile.Name())
@@ -834,6 +906,7 @@ func TestSendHeartbeats_NonExistingExtraHeartbeatsEntity(t *testing.T) {
 	v.Set("key", "00000000-0000-4000-8000-000000000000")
 	v.Set("plugin", plugin)
 	v.Set("time", 1585598059.1)
+	v.Set("settings.heartbeat_max_age_days", 0)
 	v.Set("log-file", logFile.Name())
 	v.Set("verbose", true)
 
@@ -1095,6 +1168,7 @@ func TestSendHeartbeats_ObfuscateProject(t *testing.T) {
 	v.Set("local-file", "testdata/localfile.go")
 	v.Set("plugin", plugin)
 	v.Set("time", 1585598059.1)
+	v.Set("settings.heartbeat_max_age_days", 0)
 	v.Set("timeout", 5)
 	v.Set("write", true)
 
@@ -1186,6 +1260,7 @@ func TestSendHeartbeats_ObfuscateProjectNotBranch(t *testing.T) {
 	v.Set("local-file", "testdata/localfile.go")
 	v.Set("plugin", plugin)
 	v.Set("time", 1585598059.1)
+	v.Set("settings.heartbeat_max_age_days", 0)
 	v.Set("timeout", 5)
 	v.Set("write", true)
 
@@ -1200,6 +1275,241 @@ func TestSendHeartbeats_ObfuscateProjectNotBranch(t *testing.T) {
 	assert.Eventually(t, func() bool { return numCalls == 1 }, time.Second, 50*time.Millisecond)
 }
 
+func TestRun_OutputJSON(t *testing.T) {
+	resetSingleton(t)
+
+	testServerURL, router, tearDown := setupTestServer()
+	defer tearDown()
+
+	router.HandleFunc("/users/current/heartbeats.bulk", func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+
+		_, err := w.Write([]byte(`{"responses": [
+			[{"data": {"id": "845a922e-9e65-4775-bd68-bb3196d2e06a"}}, 201],
+			[{"errors": {"time": ["invalid time"]}}, 400]
+		]}`))
+		require.NoError(t, err)
+	})
+
+	inr, inw, err := os.Pipe()
+	require.NoError(t, err)
+
+	defer func() {
+		inr.Close()
+		inw.Close()
+	}()
+
+	origStdin := os.Stdin
+
+	defer func() { os.Stdin = origStdin }()
+
+	os.Stdin = inr
+
+	go func() {
+		_, err := inw.Write([]byte(
+			`{"entity": "testdata/main.py", "type": "file", "category": "debugging", "time": 1585598060}` + "\n" +
+				`{"entity": "/tmp/nonexisting.go", "type": "file", "category": "coding", "time": 1585598061}` + "\n" +
+				`{"entity": "testdata/main.go", "type": "file"}` + "\n",
+		))
+		require.NoError(t, err)
+
+		inw.Close()
+	}()
+
+	offlineQueueFile, err := os.CreateTemp(t.TempDir(), "")
+	require.NoError(t, err)
+
+	defer offlineQueueFile.Close()
+
+	v := viper.New()
+	v.SetDefault("sync-offline-activity", 1000)
+	v.Set("api-url", testServerURL)
+	v.Set("category", "coding")
+	v.Set("entity", "testdata/main.go")
+	v.Set("entity-type", "file")
+	v.Set("extra-heartbeats", true)
+	v.Set("key", "00000000-0000-4000-8000-000000000000")
+	v.Set("offline-queue-file", offlineQueueFile.Name())
+	v.Set("output", "json")
+	v.Set("plugin", "plugin")
+	v.Set("time", 1585598059)
+	v.Set("settings.heartbeat_max_age_days", 0)
+	v.Set("timeout", 5)
+	v.Set("log-file", filepath.Join(t.TempDir(), "wakatime.log"))
+
+	ctx := context.Background()
+
+	logger, err := cmd.SetupLogging(ctx, v)
+	require.NoError(t, err)
+
+	defer logger.Flush()
+
+	ctx = log.ToContext(ctx, logger)
+
+	stdout := os.Stdout // keep backup of the real stdout
+	r, w, _ := os.Pipe()
+	os.Stdout = w
+
+	code, err := cmdheartbeat.Run(ctx, v)
+
+	outC := make(chan string)
+	// copy the output in a separate goroutine so printing can't block indefinitely
+	go func() {
+		var buf bytes.Buffer
+		_, err := io.Copy(&buf, r)
+		require.NoError(t, err)
+		outC <- buf.String()
+	}()
+
+	w.Close()
+
+	os.Stdout = stdout
+	output := <-outC
+
+	require.NoError(t, err)
+	assert.Equal(t, exitcode.Success, code)
+
+	assert.JSONEq(t, `{
+		"heartbeats": [
+			{
+				"index": 0,
+				"entity": "testdata/main.go",
+				"status": "sent"
+			},
+			{
+				"index": 1,
+				"entity": "testdata/main.py",
+				"status": "rejected",
+				"reason": "api returned status 400",
+				"errors": ["time: invalid time"]
+			},
+			{
+				"index": 2,
+				"entity": "/tmp/nonexisting.go",
+				"status": "filtered",
+				"reason": "filter file: skipping because of non-existing file \"/tmp/nonexisting.go\""
+			}
+		],
+		"invalid_extra_heartbeats": [
+			{
+				"index": 2,
+				"error": "skipping extra heartbeat, as no valid timestamp was defined"
+			}
+		]
+	}`, output)
+}
+
+func TestRun_OutputJSON_FilteredReasons(t *testing.T) {
+	resetSingleton(t)
+
+	testServerURL, router, tearDown := setupTestServer()
+	defer tearDown()
+
+	router.HandleFunc("/users/current/heartbeats.bulk", func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+
+		_, err := w.Write([]byte(`{"responses": [
+			[{"data": {"id": "845a922e-9e65-4775-bd68-bb3196d2e06a"}}, 201]
+		]}`))
+		require.NoError(t, err)
+	})
+
+	inr, inw, err := os.Pipe()
+	require.NoError(t, err)
+
+	defer func() {
+		inr.Close()
+		inw.Close()
+	}()
+
+	origStdin := os.Stdin
+
+	defer func() { os.Stdin = origStdin }()
+
+	os.Stdin = inr
+
+	future := time.Now().Add(24 * time.Hour).Unix()
+
+	go func() {
+		_, err := inw.Write([]byte(
+			`{"entity": "testdata/excluded/main.go", "type": "file", "category": "coding", "time": 1585598060}` + "\n" +
+				`{"entity": "/tmp/nonexisting.go", "type": "file", "category": "coding", "time": 1585598061}` + "\n" +
+				fmt.Sprintf(`{"entity": "testdata/main.py", "type": "file", "category": "coding", "time": %d}`, future) + "\n",
+		))
+		require.NoError(t, err)
+
+		inw.Close()
+	}()
+
+	offlineQueueFile, err := os.CreateTemp(t.TempDir(), "")
+	require.NoError(t, err)
+
+	defer offlineQueueFile.Close()
+
+	v := viper.New()
+	v.SetDefault("sync-offline-activity", 1000)
+	v.Set("api-url", testServerURL)
+	v.Set("category", "coding")
+	v.Set("entity", "testdata/main.go")
+	v.Set("entity-type", "file")
+	v.Set("exclude", `excluded`)
+	v.Set("extra-heartbeats", true)
+	v.Set("key", "00000000-0000-4000-8000-000000000000")
+	v.Set("offline-queue-file", offlineQueueFile.Name())
+	v.Set("output", "json")
+	v.Set("plugin", "plugin")
+	v.Set("time", 1585598059)
+	v.Set("settings.heartbeat_max_age_days", 0)
+	v.Set("timeout", 5)
+	v.Set("log-file", filepath.Join(t.TempDir(), "wakatime.log"))
+
+	ctx := context.Background()
+
+	logger, err := cmd.SetupLogging(ctx, v)
+	require.NoError(t, err)
+
+	defer logger.Flush()
+
+	ctx = log.ToContext(ctx, logger)
+
+	stdout := os.Stdout // keep backup of the real stdout
+	r, w, _ := os.Pipe()
+	os.Stdout = w
+
+	code, err := cmdheartbeat.Run(ctx, v)
+
+	outC := make(chan string)
+	// copy the output in a separate goroutine so printing can't block indefinitely
+	go func() {
+		var buf bytes.Buffer
+		_, err := io.Copy(&buf, r)
+		require.NoError(t, err)
+		outC <- buf.String()
+	}()
+
+	w.Close()
+
+	os.Stdout = stdout
+	output := <-outC
+
+	require.NoError(t, err)
+	assert.Equal(t, exitcode.Success, code)
+
+	var report cmdheartbeat.Report
+
+	err = json.Unmarshal([]byte(output), &report)
+	require.NoError(t, err)
+
+	require.Len(t, report.Heartbeats, 4)
+
+	assert.Equal(t, heartbeat.OutcomeSent, report.Heartbeats[0].Status)
+
+	for _, h := range report.Heartbeats[1:] {
+		assert.Equal(t, heartbeat.OutcomeFiltered, h.Status, h.Entity)
+		assert.NotEmpty(t, h.Reason, h.Entity)
+	}
+}
+
 func TestRateLimited(t *testing.T) {
 	resetSingleton(t)
 
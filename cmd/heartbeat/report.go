@@ -0,0 +1,141 @@
+package heartbeat
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	paramscmd "github.com/wakatime/wakatime-cli/cmd/params"
+	"github.com/wakatime/wakatime-cli/pkg/api"
+	"github.com/wakatime/wakatime-cli/pkg/heartbeat"
+)
+
+// Report is the json output of the heartbeat command.
+type Report struct {
+	// Heartbeats are the outcomes of the main heartbeat and the valid extra heartbeats.
+	Heartbeats []HeartbeatReport `json:"heartbeats"`
+	// InvalidExtraHeartbeats are the extra heartbeats read from stdin, which
+	// were skipped because they couldn't be parsed.
+	InvalidExtraHeartbeats []paramscmd.ExtraHeartbeatError `json:"invalid_extra_heartbeats"`
+}
+
+// HeartbeatReport is the outcome of a single heartbeat.
+type HeartbeatReport struct {
+	// Index is 0 for the main heartbeat, followed by the valid extra heartbeats in order.
+	Index int `json:"index"`
+	// Entity is the entity passed in, before any processing.
+	Entity string `json:"entity"`
+	heartbeat.Outcome
+}
+
+func newReport(hh []heartbeat.Heartbeat, invalid []paramscmd.ExtraHeartbeatError) Report {
+	report := Report{
+		Heartbeats:             make([]HeartbeatReport, len(hh)),
+		InvalidExtraHeartbeats: invalid,
+	}
+
+	for i, h := range hh {
+		report.Heartbeats[i] = HeartbeatReport{
+			Index:  h.Ref,
+			Entity: h.Entity,
+		}
+	}
+
+	return report
+}
+
+// setOutcomes sets the recorded outcome of each heartbeat. Heartbeats without
+// outcome failed with the passed in error, or are unknown when nothing failed,
+// instead of passing them off as filtered.
+func (r *Report) setOutcomes(outcomes *heartbeat.Outcomes, err error) {
+	for i := range r.Heartbeats {
+		if outcome, ok := outcomes.Get(r.Heartbeats[i].Index); ok {
+			r.Heartbeats[i].Outcome = outcome
+			continue
+		}
+
+		if err != nil {
+			r.Heartbeats[i].Outcome = heartbeat.Outcome{
+				Status: heartbeat.OutcomeFailed,
+				Reason: err.Error(),
+			}
+
+			continue
+		}
+
+		r.Heartbeats[i].Outcome = heartbeat.Outcome{
+			Status: heartbeat.OutcomeUnknown,
+			Reason: "no outcome was recorded",
+		}
+	}
+}
+
+// RenderReport returns the report as json.
+func RenderReport(report Report) (string, error) {
+	if report.Heartbeats == nil {
+		report.Heartbeats = []HeartbeatReport{}
+	}
+
+	if report.InvalidExtraHeartbeats == nil {
+		report.InvalidExtraHeartbeats = []paramscmd.ExtraHeartbeatError{}
+	}
+
+	data, err := json.Marshal(report)
+	if err != nil {
+		return "", fmt.Errorf("failed to json marshal report: %s", err)
+	}
+
+	return string(data) + "\n", nil
+}
+
+// recordingSender records the outcome of heartbeats from the results returned
+// by the api. Heartbeats queued afterwards, like for server errors, are
+// recorded again by the offline queue.
+type recordingSender struct {
+	sender heartbeat.Sender
+}
+
+// SendHeartbeats sends the heartbeats and records their outcome.
+func (s recordingSender) SendHeartbeats(ctx context.Context, hh []heartbeat.Heartbeat) ([]heartbeat.Result, error) {
+	results, err := s.sender.SendHeartbeats(ctx, hh)
+
+	var errpartial api.ErrPartial
+	if errors.As(err, &errpartial) {
+		for _, g := range errpartial.Groups {
+			if g.Err == nil {
+				recordResults(ctx, g.Heartbeats, g.Results)
+			}
+		}
+
+		return results, err
+	}
+
+	if err == nil {
+		recordResults(ctx, hh, results)
+	}
+
+	return results, err
+}
+
+// recordResults records the heartbeats as sent or rejected, by the result of
+// the same index.
+func recordResults(ctx context.Context, hh []heartbeat.Heartbeat, results []heartbeat.Result) {
+	for n, result := range results {
+		if n >= len(hh) {
+			break
+		}
+
+		if result.Status >= http.StatusOK && result.Status <= 299 {
+			heartbeat.RecordOutcome(ctx, hh[n], heartbeat.Outcome{Status: heartbeat.OutcomeSent})
+			continue
+		}
+
+		heartbeat.RecordOutcome(ctx, hh[n], heartbeat.Outcome{
+			Status: heartbeat.OutcomeRejected,
+			Reason: fmt.Sprintf("api returned status %d", result.Status),
+			Errors: result.Errors,
+		})
+	}
+}
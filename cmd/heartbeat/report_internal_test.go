@@ -0,0 +1,42 @@
+package heartbeat
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/wakatime/wakatime-cli/pkg/heartbeat"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReport_SetOutcomes(t *testing.T) {
+	outcomes := heartbeat.NewOutcomes()
+	ctx := heartbeat.OutcomesToContext(context.Background(), outcomes)
+
+	heartbeat.RecordOutcome(ctx, heartbeat.Heartbeat{Ref: 0}, heartbeat.Outcome{Status: heartbeat.OutcomeSent})
+
+	report := newReport([]heartbeat.Heartbeat{
+		{Ref: 0, Entity: "/tmp/main.go"},
+		{Ref: 1, Entity: "/tmp/lost.go"},
+	}, nil)
+
+	report.setOutcomes(outcomes, nil)
+
+	assert.Equal(t, heartbeat.Outcome{Status: heartbeat.OutcomeSent}, report.Heartbeats[0].Outcome)
+	assert.Equal(t, heartbeat.Outcome{
+		Status: heartbeat.OutcomeUnknown,
+		Reason: "no outcome was recorded",
+	}, report.Heartbeats[1].Outcome)
+}
+
+func TestReport_SetOutcomes_Err(t *testing.T) {
+	report := newReport([]heartbeat.Heartbeat{{Ref: 0, Entity: "/tmp/main.go"}}, nil)
+
+	report.setOutcomes(heartbeat.NewOutcomes(), errors.New("failed"))
+
+	assert.Equal(t, heartbeat.Outcome{
+		Status: heartbeat.OutcomeFailed,
+		Reason: "failed",
+	}, report.Heartbeats[0].Outcome)
+}
@@ -0,0 +1,84 @@
+package heartbeat_test
+
+import (
+	"testing"
+
+	cmdheartbeat "github.com/wakatime/wakatime-cli/cmd/heartbeat"
+	cmdparams "github.com/wakatime/wakatime-cli/cmd/params"
+	"github.com/wakatime/wakatime-cli/pkg/heartbeat"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRenderReport(t *testing.T) {
+	rendered, err := cmdheartbeat.RenderReport(cmdheartbeat.Report{
+		Heartbeats: []cmdheartbeat.HeartbeatReport{
+			{
+				Index:   0,
+				Entity:  "/tmp/main.go",
+				Outcome: heartbeat.Outcome{Status: heartbeat.OutcomeSent},
+			},
+			{
+				Index:  1,
+				Entity: "/tmp/excluded.go",
+				Outcome: heartbeat.Outcome{
+					Status: heartbeat.OutcomeFiltered,
+					Reason: "skipping because matches exclude pattern",
+				},
+			},
+			{
+				Index:  2,
+				Entity: "/tmp/rejected.go",
+				Outcome: heartbeat.Outcome{
+					Status: heartbeat.OutcomeRejected,
+					Reason: "api returned status 400",
+					Errors: []string{"invalid time"},
+				},
+			},
+		},
+		InvalidExtraHeartbeats: []cmdparams.ExtraHeartbeatError{
+			{
+				Index: 3,
+				Error: "skipping extra heartbeat, as no valid timestamp was defined",
+			},
+		},
+	})
+	require.NoError(t, err)
+
+	assert.JSONEq(t, `{
+		"heartbeats": [
+			{
+				"index": 0,
+				"entity": "/tmp/main.go",
+				"status": "sent"
+			},
+			{
+				"index": 1,
+				"entity": "/tmp/excluded.go",
+				"status": "filtered",
+				"reason": "skipping because matches exclude pattern"
+			},
+			{
+				"index": 2,
+				"entity": "/tmp/rejected.go",
+				"status": "rejected",
+				"reason": "api returned status 400",
+				"errors": ["invalid time"]
+			}
+		],
+		"invalid_extra_heartbeats": [
+			{
+				"index": 3,
+				"error": "skipping extra heartbeat, as no valid timestamp was defined"
+			}
+		]
+	}`, rendered)
+}
+
+func TestRenderReport_Empty(t *testing.T) {
+	rendered, err := cmdheartbeat.RenderReport(cmdheartbeat.Report{})
+	require.NoError(t, err)
+
+	assert.Equal(t, "{\"heartbeats\":[],\"invalid_extra_heartbeats\":[]}\n", rendered)
+}
@@ -0,0 +1,76 @@
+package login
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	cmdapi "github.com/wakatime/wakatime-cli/cmd/api"
+	paramscmd "github.com/wakatime/wakatime-cli/cmd/params"
+	"github.com/wakatime/wakatime-cli/pkg/exitcode"
+	"github.com/wakatime/wakatime-cli/pkg/keystore"
+	"github.com/wakatime/wakatime-cli/pkg/log"
+	"github.com/wakatime/wakatime-cli/pkg/oauth"
+
+	"github.com/spf13/viper"
+)
+
+// Run executes the login command.
+func Run(ctx context.Context, v *viper.Viper) (int, error) {
+	params, err := paramscmd.LoadAPIParams(ctx, v)
+	if err != nil {
+		return exitcode.ErrAuth, fmt.Errorf("failed to load api parameters: %w", err)
+	}
+
+	if !params.OAuth.Enabled() || params.OAuth.DeviceAuthURL == "" {
+		return exitcode.ErrAuth, errors.New("oauth_client_id and oauth_device_auth_url are required to login")
+	}
+
+	client, err := cmdapi.NewOAuthClient(ctx, params)
+	if err != nil {
+		return exitcode.ErrGeneric, fmt.Errorf("failed to initialize api client: %w", err)
+	}
+
+	store, err := oauth.NewStore(ctx, params.OAuth.StoreType, keystore.WithPrompt())
+	if err != nil {
+		return exitcode.ErrGeneric, fmt.Errorf("failed to initialize oauth token store: %w", err)
+	}
+
+	if err := Login(ctx, params.OAuth, client, store, os.Stdout); err != nil {
+		return exitcode.ErrAuth, err
+	}
+
+	return exitcode.Success, nil
+}
+
+// Login runs the oauth device code flow, printing the instructions for the user to w,
+// and saves the token in the store once the user authorized this device.
+func Login(ctx context.Context, config oauth.Config, doer oauth.Doer, store oauth.Store, w io.Writer) error {
+	code, err := oauth.RequestDeviceCode(ctx, doer, config)
+	if err != nil {
+		return fmt.Errorf("failed to request device code: %w", err)
+	}
+
+	if code.VerificationURIComplete != "" {
+		_, _ = fmt.Fprintf(w, "To login, open %s and confirm the code %s\n", code.VerificationURIComplete, code.UserCode)
+	} else {
+		_, _ = fmt.Fprintf(w, "To login, open %s and enter the code %s\n", code.VerificationURI, code.UserCode)
+	}
+
+	token, err := oauth.PollToken(ctx, doer, config, code)
+	if err != nil {
+		return fmt.Errorf("failed to login: %w", err)
+	}
+
+	if err := store.Save(ctx, token); err != nil {
+		return err
+	}
+
+	log.Extract(ctx).Debugln("stored oauth token")
+
+	_, _ = fmt.Fprintln(w, "Logged in.")
+
+	return nil
+}
@@ -0,0 +1,141 @@
+package login_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/wakatime/wakatime-cli/cmd/login"
+	"github.com/wakatime/wakatime-cli/pkg/api"
+	"github.com/wakatime/wakatime-cli/pkg/exitcode"
+	"github.com/wakatime/wakatime-cli/pkg/oauth"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRun(t *testing.T) {
+	router := http.NewServeMux()
+
+	srv := httptest.NewServer(router)
+	defer srv.Close()
+
+	var numCalls int
+
+	router.HandleFunc("/device/code", func(w http.ResponseWriter, req *http.Request) {
+		numCalls++
+
+		// no token exists yet, so requests are not authenticated
+		assert.Empty(t, req.Header.Get("Authorization"))
+		assert.Equal(t, "client-id", req.PostFormValue("client_id"))
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"device_code":      "device-code",
+			"user_code":        "ABCD-EFGH",
+			"verification_uri": srv.URL + "/device",
+			"expires_in":       60,
+			"interval":         1,
+		})
+	})
+
+	router.HandleFunc("/token", func(w http.ResponseWriter, req *http.Request) {
+		numCalls++
+
+		assert.Empty(t, req.Header.Get("Authorization"))
+		assert.Equal(t, "device-code", req.PostFormValue("device_code"))
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"access_token":  "access",
+			"token_type":    "bearer",
+			"refresh_token": "refresh",
+		})
+	})
+
+	home := t.TempDir()
+	t.Setenv("WAKATIME_HOME", home)
+	t.Setenv("WAKATIME_API_KEY", "")
+
+	tmpFile, err := os.CreateTemp(t.TempDir(), "wakatime-config")
+	require.NoError(t, err)
+
+	defer tmpFile.Close()
+
+	v := viper.New()
+	v.Set("api-url", srv.URL)
+	v.Set("config", tmpFile.Name())
+	v.Set("settings.oauth_client_id", "client-id")
+	v.Set("settings.oauth_device_auth_url", srv.URL+"/device/code")
+	v.Set("settings.oauth_token_url", srv.URL+"/token")
+	v.Set("timeout", 5)
+
+	code, err := login.Run(context.Background(), v)
+	require.NoError(t, err)
+
+	assert.Equal(t, exitcode.Success, code)
+	assert.Equal(t, 2, numCalls)
+
+	data, err := os.ReadFile(filepath.Join(home, "wakatime-oauth-token.json"))
+	require.NoError(t, err)
+
+	assert.Contains(t, string(data), `"access_token":"access"`)
+}
+
+func TestLogin(t *testing.T) {
+	router := http.NewServeMux()
+
+	srv := httptest.NewServer(router)
+	defer srv.Close()
+
+	router.HandleFunc("/device/code", func(w http.ResponseWriter, req *http.Request) {
+		assert.Equal(t, "wakatime-cli", req.PostFormValue("client_id"))
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"device_code":      "device-code",
+			"user_code":        "ABCD-EFGH",
+			"verification_uri": srv.URL + "/device",
+			"expires_in":       60,
+			"interval":         1,
+		})
+	})
+
+	router.HandleFunc("/token", func(w http.ResponseWriter, req *http.Request) {
+		assert.Equal(t, "urn:ietf:params:oauth:grant-type:device_code", req.PostFormValue("grant_type"))
+		assert.Equal(t, "device-code", req.PostFormValue("device_code"))
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"access_token":  "access",
+			"token_type":    "bearer",
+			"refresh_token": "refresh",
+		})
+	})
+
+	config := oauth.Config{
+		ClientID:      "wakatime-cli",
+		DeviceAuthURL: srv.URL + "/device/code",
+		TokenURL:      srv.URL + "/token",
+	}
+
+	store := &oauth.FileStore{Filepath: filepath.Join(t.TempDir(), "token.json")}
+
+	var out bytes.Buffer
+
+	err := login.Login(context.Background(), config, api.NewClient(""), store, &out)
+	require.NoError(t, err)
+
+	assert.Equal(t, "To login, open "+srv.URL+"/device and enter the code ABCD-EFGH\nLogged in.\n", out.String())
+
+	token, err := store.Load(context.Background())
+	require.NoError(t, err)
+
+	assert.Equal(t, oauth.Token{AccessToken: "access", RefreshToken: "refresh"}, token)
+}
@@ -7,14 +7,18 @@ import (
 
 	paramscmd "github.com/wakatime/wakatime-cli/cmd/params"
 	"github.com/wakatime/wakatime-cli/pkg/deps"
+	"github.com/wakatime/wakatime-cli/pkg/file"
 	"github.com/wakatime/wakatime-cli/pkg/filestats"
 	"github.com/wakatime/wakatime-cli/pkg/filter"
 	"github.com/wakatime/wakatime-cli/pkg/heartbeat"
 	"github.com/wakatime/wakatime-cli/pkg/language"
 	"github.com/wakatime/wakatime-cli/pkg/log"
+	"github.com/wakatime/wakatime-cli/pkg/namehash"
 	"github.com/wakatime/wakatime-cli/pkg/offline"
 	"github.com/wakatime/wakatime-cli/pkg/project"
 	"github.com/wakatime/wakatime-cli/pkg/remote"
+	"github.com/wakatime/wakatime-cli/pkg/schedule"
+	"github.com/wakatime/wakatime-cli/pkg/secret"
 
 	"github.com/spf13/viper"
 )
@@ -43,7 +47,7 @@ func SaveHeartbeats(ctx context.Context, v *viper.Viper, heartbeats []heartbeat.
 		heartbeats = buildHeartbeats(ctx, params)
 	}
 
-	handleOpts := initHandleOptions(params)
+	handleOpts := initHandleOptions(params, queueFilepath)
 
 	handleOpts = append(handleOpts, offline.WithQueue(queueFilepath))
 
@@ -83,6 +87,7 @@ func buildHeartbeats(ctx context.Context, params paramscmd.Params) []heartbeat.H
 	heartbeats = append(heartbeats, heartbeat.New(
 		params.Heartbeat.Project.BranchAlternate,
 		params.Heartbeat.Category,
+		params.Heartbeat.CategoryUnset,
 		params.Heartbeat.CursorPosition,
 		params.Heartbeat.Entity,
 		params.Heartbeat.EntityType,
@@ -107,10 +112,11 @@ func buildHeartbeats(ctx context.Context, params paramscmd.Params) []heartbeat.H
 		logger := log.Extract(ctx)
 		logger.Debugf("include %d extra heartbeat(s) from stdin", len(params.Heartbeat.ExtraHeartbeats))
 
-		for _, h := range params.Heartbeat.ExtraHeartbeats {
-			heartbeats = append(heartbeats, heartbeat.New(
+		for i, h := range params.Heartbeat.ExtraHeartbeats {
+			extra := heartbeat.New(
 				h.BranchAlternate,
 				h.Category,
+				h.CategoryUnset,
 				h.CursorPosition,
 				h.Entity,
 				h.EntityType,
@@ -129,31 +135,54 @@ func buildHeartbeats(ctx context.Context, params paramscmd.Params) []heartbeat.H
 				h.ProjectPathOverride,
 				h.Time,
 				userAgent,
-			))
+			)
+
+			// the main heartbeat has ref 0
+			extra.Ref = i + 1
+
+			heartbeats = append(heartbeats, extra)
 		}
 	}
 
 	return heartbeats
 }
 
-func initHandleOptions(params paramscmd.Params) []heartbeat.HandleOption {
+func initHandleOptions(params paramscmd.Params, queueFilepath string) []heartbeat.HandleOption {
+	// file contents are read once for language and secret detection
+	heads := file.NewHeads()
+
+	var hasher heartbeat.Hasher
+	if params.Heartbeat.Sanitize.HashFilepath != "" {
+		hasher = namehash.NewHasher(params.Heartbeat.Sanitize.HashFilepath)
+	}
+
 	return []heartbeat.HandleOption{
+		filter.WithLocalScope(params.Heartbeat.Filter.LocalScope),
 		heartbeat.WithFormatting(),
 		heartbeat.WithEntityModifier(),
 		filter.WithFiltering(filter.Config{
 			Exclude:                    params.Heartbeat.Filter.Exclude,
+			ExcludeGlobs:               params.Heartbeat.Filter.ExcludeGlobs,
+			HonorIgnoreFiles:           params.Heartbeat.Filter.HonorIgnoreFiles,
 			Include:                    params.Heartbeat.Filter.Include,
+			IncludeGlobs:               params.Heartbeat.Filter.IncludeGlobs,
 			IncludeOnlyWithProjectFile: params.Heartbeat.Filter.IncludeOnlyWithProjectFile,
 		}),
-		remote.WithDetection(),
+		remote.WithDetection(remote.Config{}),
 		filestats.WithDetection(),
 		language.WithDetection(language.Config{
 			GuessLanguage: params.Heartbeat.GuessLanguage,
+			Heads:         heads,
+		}),
+		heartbeat.WithCategoryInference(heartbeat.CategoryConfig{
+			Enabled: params.Heartbeat.InferCategory,
+			Rules:   params.Heartbeat.CategoryRules,
 		}),
 		deps.WithDetection(deps.Config{
 			FilePatterns: params.Heartbeat.Sanitize.HideFileNames,
 		}),
 		project.WithDetection(project.Config{
+			Hasher:               hasher,
 			HideProjectNames:     params.Heartbeat.Sanitize.HideProjectNames,
 			MapPatterns:          params.Heartbeat.Project.MapPatterns,
 			ProjectFromGitRemote: params.Heartbeat.Project.ProjectFromGitRemote,
@@ -165,13 +194,31 @@ func initHandleOptions(params paramscmd.Params) []heartbeat.HandleOption {
 		project.WithFiltering(project.FilterConfig{
 			ExcludeUnknownProject: params.Heartbeat.Filter.ExcludeUnknownProject,
 		}),
+		schedule.WithFiltering(schedule.Config{
+			Rules: params.Heartbeat.Filter.ScheduleRules,
+		}),
+		secret.WithDetection(secret.Config{
+			BranchPatterns: params.Heartbeat.Sanitize.HideBranchNames,
+			Enabled:        params.Heartbeat.Sanitize.HideSecretFiles,
+			Hasher:         hasher,
+			Heads:          heads,
+		}),
 		heartbeat.WithSanitization(heartbeat.SanitizeConfig{
 			BranchPatterns:    params.Heartbeat.Sanitize.HideBranchNames,
+			DomainPatterns:    params.Heartbeat.Sanitize.HideDomains,
 			FilePatterns:      params.Heartbeat.Sanitize.HideFileNames,
+			Hasher:            hasher,
 			HideProjectFolder: params.Heartbeat.Sanitize.HideProjectFolder,
 			ProjectPatterns:   params.Heartbeat.Sanitize.HideProjectNames,
+			RedactRules:       params.Heartbeat.Sanitize.RedactRules,
+			URLPathPatterns:   params.Heartbeat.Sanitize.HideURLPaths,
+			URLQueryKeep:      params.Heartbeat.Sanitize.KeepURLQueryParams,
+			URLQueryPatterns:  params.Heartbeat.Sanitize.HideURLQueryParams,
 		}),
 		remote.WithCleanup(),
+		offline.WithQuarantine(queueFilepath, heartbeat.ValidateConfig{
+			MaxAge: params.Heartbeat.MaxAge,
+		}),
 		filter.WithLengthValidator(),
 	}
 }
@@ -4,7 +4,9 @@ import (
 	"context"
 	"encoding/json"
 	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
 	cmdoffline "github.com/wakatime/wakatime-cli/cmd/offline"
 	"github.com/wakatime/wakatime-cli/pkg/heartbeat"
@@ -41,6 +43,7 @@ func TestSaveHeartbeats(t *testing.T) {
 	v.Set("project", "wakatime-cli")
 	v.Set("lineno", 13)
 	v.Set("time", 1585598059.1)
+	v.Set("settings.heartbeat_max_age_days", 0)
 	v.Set("timeout", 5)
 	v.Set("write", true)
 
@@ -78,6 +81,7 @@ func TestSaveHeartbeats_ExtraHeartbeats(t *testing.T) {
 	v.Set("config", tmpFile.Name())
 	v.Set("entity", "testdata/main.go")
 	v.Set("key", "00000000-0000-4000-8000-000000000000")
+	v.Set("settings.heartbeat_max_age_days", 0)
 
 	err = cmdoffline.SaveHeartbeats(ctx, v, hh, offlineQueueFile.Name())
 	require.NoError(t, err)
@@ -85,7 +89,88 @@ func TestSaveHeartbeats_ExtraHeartbeats(t *testing.T) {
 	offlineCount, err := offline.CountHeartbeats(ctx, offlineQueueFile.Name())
 	require.NoError(t, err)
 
-	assert.Equal(t, 25, offlineCount)
+	// the second heartbeat is quarantined, see TestSaveHeartbeats_ExtraHeartbeats_InvalidTime
+	assert.Equal(t, 24, offlineCount)
+}
+
+func TestSaveHeartbeats_ExtraHeartbeats_InvalidTime(t *testing.T) {
+	tmpFile, err := os.CreateTemp(t.TempDir(), "")
+	require.NoError(t, err)
+
+	defer tmpFile.Close()
+
+	offlineQueueFile, err := os.CreateTemp(t.TempDir(), "")
+	require.NoError(t, err)
+
+	defer offlineQueueFile.Close()
+
+	ctx := context.Background()
+
+	data, err := os.ReadFile("testdata/extra_heartbeats.json")
+	require.NoError(t, err)
+
+	var hh []heartbeat.Heartbeat
+
+	err = json.Unmarshal(data, &hh)
+	require.NoError(t, err)
+
+	v := viper.New()
+	v.Set("config", tmpFile.Name())
+	v.Set("entity", "testdata/main.go")
+	v.Set("key", "00000000-0000-4000-8000-000000000000")
+	v.Set("settings.heartbeat_max_age_days", 0)
+
+	err = cmdoffline.SaveHeartbeats(ctx, v, hh, offlineQueueFile.Name())
+	require.NoError(t, err)
+
+	// the second heartbeat has a timestamp field instead of time, so its time is 0
+	quarantined, err := offline.ReadQuarantined(ctx, offlineQueueFile.Name(), 10)
+	require.NoError(t, err)
+
+	require.Len(t, quarantined, 1)
+	assert.Equal(t, "main.py", filepath.Base(quarantined[0].Heartbeat.Entity))
+	assert.Equal(t, "invalid time 0.000000", quarantined[0].Reason)
+}
+
+func TestSaveHeartbeats_DefaultMaxAge(t *testing.T) {
+	tmpFile, err := os.CreateTemp(t.TempDir(), "")
+	require.NoError(t, err)
+
+	defer tmpFile.Close()
+
+	offlineQueueFile, err := os.CreateTemp(t.TempDir(), "")
+	require.NoError(t, err)
+
+	defer offlineQueueFile.Close()
+
+	ctx := context.Background()
+
+	v := viper.New()
+	v.Set("config", tmpFile.Name())
+	v.Set("entity", "testdata/main.go")
+	v.Set("key", "00000000-0000-4000-8000-000000000000")
+
+	hh := []heartbeat.Heartbeat{{
+		Category:   heartbeat.CodingCategory,
+		Entity:     "testdata/main.go",
+		EntityType: heartbeat.FileType,
+		Time:       float64(time.Now().Add(-366 * 24 * time.Hour).Unix()),
+	}}
+
+	err = cmdoffline.SaveHeartbeats(ctx, v, hh, offlineQueueFile.Name())
+	require.NoError(t, err)
+
+	offlineCount, err := offline.CountHeartbeats(ctx, offlineQueueFile.Name())
+	require.NoError(t, err)
+
+	// without heartbeat_max_age_days, heartbeats older than 365 days are quarantined
+	assert.Zero(t, offlineCount)
+
+	quarantined, err := offline.ReadQuarantined(ctx, offlineQueueFile.Name(), 10)
+	require.NoError(t, err)
+
+	require.Len(t, quarantined, 1)
+	assert.Contains(t, quarantined[0].Reason, "is older than 365 days")
 }
 
 func TestSaveHeartbeats_OfflineDisabled(t *testing.T) {
@@ -0,0 +1,51 @@
+package quarantineprint
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/wakatime/wakatime-cli/pkg/exitcode"
+	"github.com/wakatime/wakatime-cli/pkg/offline"
+
+	"github.com/spf13/viper"
+)
+
+// Run executes the print-quarantined-heartbeats command.
+func Run(ctx context.Context, v *viper.Viper) (int, error) {
+	queueFilepath, err := offline.QueueFilepath(ctx, v)
+	if err != nil {
+		return exitcode.ErrGeneric, fmt.Errorf(
+			"failed to load offline queue filepath: %s",
+			err,
+		)
+	}
+
+	quarantined, err := offline.ReadQuarantined(ctx, queueFilepath, v.GetInt("print-quarantined-heartbeats"))
+	if err != nil {
+		fmt.Println(err)
+		return exitcode.ErrGeneric, fmt.Errorf("failed to read quarantined heartbeats: %w", err)
+	}
+
+	data, err := jsonWithoutEscaping(quarantined)
+	if err != nil {
+		fmt.Println(err)
+		return exitcode.ErrGeneric, fmt.Errorf("failed to json marshal quarantined heartbeats: %w", err)
+	}
+
+	fmt.Print(string(data))
+
+	return exitcode.Success, nil
+}
+
+// jsonWithoutEscaping returns a string representation of the given quarantined heartbeats.
+// It does not escape the angle brackets "<", ">" and "&".
+func jsonWithoutEscaping(quarantined []offline.Quarantined) ([]byte, error) {
+	buffer := &bytes.Buffer{}
+	encoder := json.NewEncoder(buffer)
+	encoder.SetEscapeHTML(false)
+	err := encoder.Encode(quarantined)
+
+	return buffer.Bytes(), err
+}
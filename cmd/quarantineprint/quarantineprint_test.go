@@ -0,0 +1,133 @@
+package quarantineprint_test
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/wakatime/wakatime-cli/cmd/quarantineprint"
+	"github.com/wakatime/wakatime-cli/pkg/exitcode"
+	"github.com/wakatime/wakatime-cli/pkg/heartbeat"
+	"github.com/wakatime/wakatime-cli/pkg/offline"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	bolt "go.etcd.io/bbolt"
+)
+
+func TestPrintQuarantinedHeartbeats(t *testing.T) {
+	// setup offline queue
+	f, err := os.CreateTemp(t.TempDir(), "")
+	require.NoError(t, err)
+
+	defer f.Close()
+
+	db, err := bolt.Open(f.Name(), 0600, nil)
+	require.NoError(t, err)
+
+	tx, err := db.Begin(true)
+	require.NoError(t, err)
+
+	err = offline.NewQuarantine(tx).PushMany([]heartbeat.Invalid{
+		{
+			Heartbeat: heartbeat.Heartbeat{
+				Category:   heartbeat.CodingCategory,
+				Entity:     "/tmp/<main>.go",
+				EntityType: heartbeat.FileType,
+				LineNumber: heartbeat.PointerTo(-1),
+				Time:       1592868367.219124,
+				UserAgent:  "wakatime/13.0.6",
+			},
+			Reason: "lineno -1 is negative",
+		},
+	}, time.Date(2020, 6, 23, 0, 0, 0, 0, time.UTC))
+	require.NoError(t, err)
+
+	err = tx.Commit()
+	require.NoError(t, err)
+
+	err = db.Close()
+	require.NoError(t, err)
+
+	v := viper.New()
+	v.Set("print-quarantined-heartbeats", 10)
+	v.Set("offline-queue-file", f.Name())
+
+	stdout := os.Stdout // keep backup of the real stdout
+	r, w, _ := os.Pipe()
+	os.Stdout = w
+
+	code, err := quarantineprint.Run(context.Background(), v)
+	require.NoError(t, err)
+
+	outC := make(chan string)
+	// copy the output in a separate goroutine so printing can't block indefinitely
+	go func() {
+		var buf bytes.Buffer
+		_, err = io.Copy(&buf, r)
+		require.NoError(t, err)
+		outC <- buf.String()
+	}()
+
+	w.Close()
+
+	os.Stdout = stdout
+	output := <-outC
+
+	assert.Equal(t, exitcode.Success, code)
+	assert.Contains(t, output, `"entity":"/tmp/<main>.go"`)
+	assert.JSONEq(t, `[
+		{
+			"heartbeat": {
+				"category": "coding",
+				"entity": "/tmp/<main>.go",
+				"lineno": -1,
+				"time": 1592868367.219124,
+				"type": "file",
+				"user_agent": "wakatime/13.0.6"
+			},
+			"reason": "lineno -1 is negative",
+			"quarantined_at": "2020-06-23T00:00:00Z"
+		}
+	]`, output)
+}
+
+func TestPrintQuarantinedHeartbeats_Empty(t *testing.T) {
+	// setup offline queue
+	f, err := os.CreateTemp(t.TempDir(), "")
+	require.NoError(t, err)
+
+	defer f.Close()
+
+	v := viper.New()
+	v.Set("print-quarantined-heartbeats", 10)
+	v.Set("offline-queue-file", f.Name())
+
+	stdout := os.Stdout // keep backup of the real stdout
+	r, w, _ := os.Pipe()
+	os.Stdout = w
+
+	code, err := quarantineprint.Run(context.Background(), v)
+	require.NoError(t, err)
+
+	outC := make(chan string)
+	// copy the output in a separate goroutine so printing can't block indefinitely
+	go func() {
+		var buf bytes.Buffer
+		_, err = io.Copy(&buf, r)
+		require.NoError(t, err)
+		outC <- buf.String()
+	}()
+
+	w.Close()
+
+	os.Stdout = stdout
+	output := <-outC
+
+	assert.Equal(t, exitcode.Success, code)
+	assert.Equal(t, "[]\n", output)
+}
@@ -0,0 +1,35 @@
+package reveal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/wakatime/wakatime-cli/pkg/exitcode"
+	"github.com/wakatime/wakatime-cli/pkg/namehash"
+	"github.com/wakatime/wakatime-cli/pkg/vipertools"
+
+	"github.com/spf13/viper"
+)
+
+// Run executes the reveal command.
+func Run(ctx context.Context, v *viper.Viper) (int, error) {
+	hashed := vipertools.GetString(v, "reveal")
+	if hashed == "" {
+		return exitcode.ErrGeneric, errors.New("failed to reveal name: missing hash")
+	}
+
+	filepath, err := namehash.Filepath(ctx)
+	if err != nil {
+		return exitcode.ErrGeneric, fmt.Errorf("failed to load hashed names db filepath: %s", err)
+	}
+
+	name, err := namehash.Reveal(ctx, filepath, hashed)
+	if err != nil {
+		return exitcode.ErrGeneric, fmt.Errorf("failed to reveal name of %q: %w", hashed, err)
+	}
+
+	fmt.Println(name)
+
+	return exitcode.Success, nil
+}
@@ -0,0 +1,76 @@
+package reveal_test
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/wakatime/wakatime-cli/cmd/reveal"
+	"github.com/wakatime/wakatime-cli/pkg/exitcode"
+	"github.com/wakatime/wakatime-cli/pkg/namehash"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRun(t *testing.T) {
+	ctx := context.Background()
+
+	t.Setenv("WAKATIME_HOME", t.TempDir())
+
+	fp, err := namehash.Filepath(ctx)
+	require.NoError(t, err)
+
+	hashed, err := namehash.NewHasher(fp).Hash(ctx, "/path/to/secret.go")
+	require.NoError(t, err)
+
+	v := viper.New()
+	v.Set("reveal", hashed+".go")
+
+	r, w, err := os.Pipe()
+	require.NoError(t, err)
+
+	defer func() {
+		r.Close()
+		w.Close()
+	}()
+
+	origStdout := os.Stdout
+
+	defer func() {
+		os.Stdout = origStdout
+	}()
+
+	os.Stdout = w
+
+	code, err := reveal.Run(ctx, v)
+	require.NoError(t, err)
+
+	w.Close()
+
+	output, err := io.ReadAll(r)
+	require.NoError(t, err)
+
+	assert.Equal(t, exitcode.Success, code)
+	assert.Equal(t, "/path/to/secret.go\n", string(output))
+}
+
+func TestRun_NotFound(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	t.Setenv("WAKATIME_HOME", tmpDir)
+
+	v := viper.New()
+	v.Set("reveal", "0123456789abcdef")
+
+	code, err := reveal.Run(context.Background(), v)
+	require.Error(t, err)
+
+	assert.Equal(t, exitcode.ErrGeneric, code)
+	assert.ErrorIs(t, err, namehash.ErrNotFound)
+
+	assert.NoFileExists(t, filepath.Join(tmpDir, "hashed_names.bdb"))
+}
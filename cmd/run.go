@@ -12,16 +12,25 @@ import (
 	"strings"
 
 	cmdapi "github.com/wakatime/wakatime-cli/cmd/api"
+	"github.com/wakatime/wakatime-cli/cmd/backoffstatus"
+	"github.com/wakatime/wakatime-cli/cmd/configdelete"
+	"github.com/wakatime/wakatime-cli/cmd/configexplain"
+	"github.com/wakatime/wakatime-cli/cmd/configlist"
 	"github.com/wakatime/wakatime-cli/cmd/configread"
+	"github.com/wakatime/wakatime-cli/cmd/configvalidate"
 	"github.com/wakatime/wakatime-cli/cmd/configwrite"
 	"github.com/wakatime/wakatime-cli/cmd/fileexperts"
 	cmdheartbeat "github.com/wakatime/wakatime-cli/cmd/heartbeat"
 	"github.com/wakatime/wakatime-cli/cmd/logfile"
+	"github.com/wakatime/wakatime-cli/cmd/login"
 	cmdoffline "github.com/wakatime/wakatime-cli/cmd/offline"
 	"github.com/wakatime/wakatime-cli/cmd/offlinecount"
 	"github.com/wakatime/wakatime-cli/cmd/offlineprint"
 	"github.com/wakatime/wakatime-cli/cmd/offlinesync"
 	"github.com/wakatime/wakatime-cli/cmd/params"
+	"github.com/wakatime/wakatime-cli/cmd/quarantineprint"
+	"github.com/wakatime/wakatime-cli/cmd/reveal"
+	"github.com/wakatime/wakatime-cli/cmd/storeapikey"
 	"github.com/wakatime/wakatime-cli/cmd/today"
 	"github.com/wakatime/wakatime-cli/cmd/todaygoal"
 	"github.com/wakatime/wakatime-cli/pkg/diagnostic"
@@ -35,6 +44,7 @@ import (
 	"github.com/wakatime/wakatime-cli/pkg/vipertools"
 	"github.com/wakatime/wakatime-cli/pkg/wakaerror"
 
+	"github.com/mitchellh/go-homedir"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 	"go.uber.org/zap/zapcore"
@@ -104,18 +114,54 @@ func RunE(cmd *cobra.Command, v *viper.Viper) error {
 		return RunCmd(ctx, v, logger.IsVerboseEnabled(), logger.SendDiagsOnErrors(), runVersion)
 	}
 
+	if v.IsSet("config-delete") {
+		logger.Debugln("command: config-delete")
+
+		return RunCmd(ctx, v, logger.IsVerboseEnabled(), logger.SendDiagsOnErrors(), configdelete.Run)
+	}
+
+	if v.IsSet("config-explain") {
+		logger.Debugln("command: config-explain")
+
+		return RunCmd(ctx, v, logger.IsVerboseEnabled(), logger.SendDiagsOnErrors(), configexplain.Run)
+	}
+
+	if v.IsSet("config-list") {
+		logger.Debugln("command: config-list")
+
+		return RunCmd(ctx, v, logger.IsVerboseEnabled(), logger.SendDiagsOnErrors(), configlist.Run)
+	}
+
 	if v.IsSet("config-read") {
 		logger.Debugln("command: config-read")
 
 		return RunCmd(ctx, v, logger.IsVerboseEnabled(), logger.SendDiagsOnErrors(), configread.Run)
 	}
 
-	if v.IsSet("config-write") {
+	if v.GetBool("config-validate") {
+		logger.Debugln("command: config-validate")
+
+		return RunCmd(ctx, v, logger.IsVerboseEnabled(), logger.SendDiagsOnErrors(), configvalidate.Run)
+	}
+
+	if v.IsSet("config-write") || v.IsSet("config-append") || v.IsSet("config-remove") {
 		logger.Debugln("command: config-write")
 
 		return RunCmd(ctx, v, logger.IsVerboseEnabled(), logger.SendDiagsOnErrors(), configwrite.Run)
 	}
 
+	if v.GetBool("login") {
+		logger.Debugln("command: login")
+
+		return RunCmd(ctx, v, logger.IsVerboseEnabled(), logger.SendDiagsOnErrors(), login.Run)
+	}
+
+	if v.IsSet("store-api-key") {
+		logger.Debugln("command: store-api-key")
+
+		return RunCmd(ctx, v, logger.IsVerboseEnabled(), logger.SendDiagsOnErrors(), storeapikey.Run)
+	}
+
 	if v.GetBool("today") {
 		logger.Debugln("command: today")
 
@@ -134,18 +180,36 @@ func RunE(cmd *cobra.Command, v *viper.Viper) error {
 		return RunCmd(ctx, v, logger.IsVerboseEnabled(), logger.SendDiagsOnErrors(), fileexperts.Run)
 	}
 
+	if v.GetBool("explain") {
+		logger.Debugln("command: explain")
+
+		return RunCmd(ctx, v, logger.IsVerboseEnabled(), logger.SendDiagsOnErrors(), cmdheartbeat.RunExplain)
+	}
+
 	if v.IsSet("entity") {
 		logger.Debugln("command: heartbeat")
 
 		return RunCmdWithOfflineSync(ctx, v, logger.IsVerboseEnabled(), logger.SendDiagsOnErrors(), cmdheartbeat.Run)
 	}
 
+	if v.IsSet("reveal") {
+		logger.Debugln("command: reveal")
+
+		return RunCmd(ctx, v, logger.IsVerboseEnabled(), logger.SendDiagsOnErrors(), reveal.Run)
+	}
+
 	if v.IsSet("sync-offline-activity") {
 		logger.Debugln("command: sync-offline-activity")
 
 		return RunCmd(ctx, v, logger.IsVerboseEnabled(), logger.SendDiagsOnErrors(), offlinesync.RunWithoutRateLimiting)
 	}
 
+	if v.GetBool("backoff-status") {
+		logger.Debugln("command: backoff-status")
+
+		return RunCmd(ctx, v, logger.IsVerboseEnabled(), logger.SendDiagsOnErrors(), backoffstatus.Run)
+	}
+
 	if v.GetBool("offline-count") {
 		logger.Debugln("command: offline-count")
 
@@ -158,13 +222,31 @@ func RunE(cmd *cobra.Command, v *viper.Viper) error {
 		return RunCmd(ctx, v, logger.IsVerboseEnabled(), logger.SendDiagsOnErrors(), offlineprint.Run)
 	}
 
+	if v.IsSet("print-quarantined-heartbeats") {
+		logger.Debugln("command: print-quarantined-heartbeats")
+
+		return RunCmd(ctx, v, logger.IsVerboseEnabled(), logger.SendDiagsOnErrors(), quarantineprint.Run)
+	}
+
 	logger.Warnf("one of the following parameters has to be provided: %s", strings.Join([]string{
+		"--backoff-status",
+		"--config-append",
+		"--config-delete",
+		"--config-explain",
+		"--config-list",
 		"--config-read",
+		"--config-remove",
+		"--config-validate",
 		"--config-write",
 		"--entity",
+		"--explain",
 		"--file-experts",
+		"--login",
 		"--offline-count",
 		"--print-offline-heartbeats",
+		"--print-quarantined-heartbeats",
+		"--reveal",
+		"--store-api-key",
 		"--sync-offline-activity",
 		"--today",
 		"--today-goal",
@@ -215,9 +297,35 @@ func parseConfigFiles(ctx context.Context, v *viper.Viper) error {
 		}
 	}
 
+	if err := parseLocalConfigFiles(ctx, v); err != nil {
+		return err
+	}
+
+	ini.ReadInEnv(ctx, v)
+
 	return nil
 }
 
+// parseLocalConfigFiles applies per-directory config files found upwards from
+// the entity's directory. Only file entities are considered.
+func parseLocalConfigFiles(ctx context.Context, v *viper.Viper) error {
+	if entityType := vipertools.GetString(v, "entity-type"); entityType != "" && entityType != "file" {
+		return nil
+	}
+
+	entity := vipertools.FirstNonEmptyString(v, "entity", "file")
+	if entity == "" {
+		return nil
+	}
+
+	entity, err := homedir.Expand(entity)
+	if err != nil {
+		return fmt.Errorf("failed expanding entity: %s", err)
+	}
+
+	return ini.ReadInLocalConfigs(ctx, v, entity)
+}
+
 // SetupLogging uses the --log-file param to configure logging to file or stdout.
 // It returns a logger with the configured settings or the default settings if it's not set.
 func SetupLogging(ctx context.Context, v *viper.Viper) (*log.Logger, error) {
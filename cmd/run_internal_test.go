@@ -442,6 +442,24 @@ func TestParseConfigFiles_APIKey_FlagTakesPrecedence(t *testing.T) {
 		v.GetString("key"))
 }
 
+func TestParseConfigFiles_EnvOverride(t *testing.T) {
+	v := viper.New()
+	v.Set("config", "testdata/.wakatime.cfg")
+	v.Set("internal-config", "testdata/.wakatime-internal.cfg")
+
+	t.Setenv("WAKATIME_SETTINGS_DEBUG", "false")
+	t.Setenv("WAKATIME_GIT_PROJECT_FROM_GIT_REMOTE", "true")
+
+	err := parseConfigFiles(context.Background(), v)
+	require.NoError(t, err)
+
+	assert.Equal(t, "false", v.GetString("settings.debug"))
+	assert.Equal(t, "true", v.GetString("git.project_from_git_remote"))
+	assert.Equal(t,
+		"00000000-0000-4000-8000-000000000000",
+		v.GetString("settings.api_key"))
+}
+
 func jsonEscape(t *testing.T, i string) string {
 	b, err := json.Marshal(i)
 	require.NoError(t, err)
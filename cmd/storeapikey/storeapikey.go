@@ -0,0 +1,116 @@
+package storeapikey
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	paramscmd "github.com/wakatime/wakatime-cli/cmd/params"
+	"github.com/wakatime/wakatime-cli/pkg/api"
+	"github.com/wakatime/wakatime-cli/pkg/exitcode"
+	"github.com/wakatime/wakatime-cli/pkg/ini"
+	"github.com/wakatime/wakatime-cli/pkg/keystore"
+	"github.com/wakatime/wakatime-cli/pkg/log"
+	"github.com/wakatime/wakatime-cli/pkg/vipertools"
+
+	"github.com/spf13/viper"
+)
+
+// readFromStdin is the flag value to read the api key from stdin,
+// which keeps it out of the process list and shell history.
+const readFromStdin = "-"
+
+// Params contains store api key parameters.
+type Params struct {
+	APIKey    string
+	StoreType string
+	Entry     string
+}
+
+// configWriter writes and deletes config file keys.
+type configWriter interface {
+	ini.Writer
+	ini.Deleter
+}
+
+// Run stores the api key in the configured secret store and removes any
+// plaintext api key from the config file.
+func Run(ctx context.Context, v *viper.Viper) (int, error) {
+	params, err := LoadParams(v, os.Stdin)
+	if err != nil {
+		return exitcode.ErrAuth, fmt.Errorf("failed to load command parameters: %w", err)
+	}
+
+	store, err := keystore.New(params.StoreType, params.Entry, keystore.WithPrompt())
+	if err != nil {
+		return exitcode.ErrGeneric, err
+	}
+
+	w, err := ini.NewWriter(ctx, v, ini.FilePath)
+	if err != nil {
+		return exitcode.ErrConfigFileParse, fmt.Errorf(
+			"failed to parse config file: %s",
+			err,
+		)
+	}
+
+	if err := Store(ctx, params, store, w); err != nil {
+		return exitcode.ErrConfigFileWrite, err
+	}
+
+	return exitcode.Success, nil
+}
+
+// Store saves the api key in the store, then persists the store type in the config
+// file and deletes the plaintext api key from it.
+func Store(ctx context.Context, params Params, store keystore.Store, w configWriter) error {
+	if err := store.Set(ctx, params.APIKey); err != nil {
+		return fmt.Errorf("failed to store api key in %s: %s", params.StoreType, err)
+	}
+
+	log.Extract(ctx).Debugf("stored api key in %s", params.StoreType)
+
+	if err := w.Write(ctx, "settings", map[string]string{"api_key_store": params.StoreType}); err != nil {
+		return fmt.Errorf("failed to write to config file: %s", err)
+	}
+
+	if err := w.Delete(ctx, "settings", []string{"api_key", "apikey"}); err != nil {
+		return fmt.Errorf("failed to delete api key from config file: %s", err)
+	}
+
+	return nil
+}
+
+// LoadParams loads needed data from the configuration file. The api key is
+// read from stdin when the flag value is "-".
+func LoadParams(v *viper.Viper, stdin io.Reader) (Params, error) {
+	apiKey := strings.TrimSpace(vipertools.GetString(v, "store-api-key"))
+
+	if apiKey == readFromStdin {
+		line, err := bufio.NewReader(stdin).ReadString('\n')
+		if err != nil && !errors.Is(err, io.EOF) {
+			return Params{}, fmt.Errorf("failed to read api key from stdin: %s", err)
+		}
+
+		apiKey = strings.TrimSpace(line)
+	}
+
+	if err := paramscmd.ValidateAPIKey(apiKey); err != nil {
+		return Params{}, api.ErrAuth{Err: err}
+	}
+
+	storeType := strings.TrimSpace(vipertools.GetString(v, "settings.api_key_store"))
+	if storeType == "" {
+		storeType = keystore.TypeSecretService
+	}
+
+	return Params{
+		APIKey:    apiKey,
+		StoreType: storeType,
+		Entry:     vipertools.GetString(v, "settings.api_key_store_entry"),
+	}, nil
+}
@@ -0,0 +1,133 @@
+package storeapikey_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/wakatime/wakatime-cli/cmd/storeapikey"
+	"github.com/wakatime/wakatime-cli/pkg/ini"
+	"github.com/wakatime/wakatime-cli/pkg/keystore"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadParams(t *testing.T) {
+	v := viper.New()
+	v.Set("store-api-key", "00000000-0000-4000-8000-000000000000")
+	v.Set("settings.api_key_store", "pass")
+	v.Set("settings.api_key_store_entry", "work/wakatime")
+
+	params, err := storeapikey.LoadParams(v, strings.NewReader(""))
+	require.NoError(t, err)
+
+	assert.Equal(t, storeapikey.Params{
+		APIKey:    "00000000-0000-4000-8000-000000000000",
+		StoreType: keystore.TypePass,
+		Entry:     "work/wakatime",
+	}, params)
+}
+
+func TestLoadParams_Stdin(t *testing.T) {
+	v := viper.New()
+	v.Set("store-api-key", "-")
+
+	params, err := storeapikey.LoadParams(v, strings.NewReader("00000000-0000-4000-8000-000000000000\n"))
+	require.NoError(t, err)
+
+	assert.Equal(t, "00000000-0000-4000-8000-000000000000", params.APIKey)
+	assert.Equal(t, keystore.TypeSecretService, params.StoreType)
+}
+
+func TestLoadParams_Invalid(t *testing.T) {
+	v := viper.New()
+	v.Set("store-api-key", "invalid")
+
+	_, err := storeapikey.LoadParams(v, strings.NewReader(""))
+	assert.EqualError(t, err, "invalid api key format")
+}
+
+func TestStore(t *testing.T) {
+	ctx := context.Background()
+
+	fp := filepath.Join(t.TempDir(), "wakatime.cfg")
+
+	err := os.WriteFile(fp, []byte("[settings]\napi_key = 00000000-0000-4000-8000-000000000000\ndebug = true\n"), 0600)
+	require.NoError(t, err)
+
+	w, err := ini.NewWriter(ctx, viper.New(), func(_ context.Context, _ *viper.Viper) (string, error) {
+		return fp, nil
+	})
+	require.NoError(t, err)
+
+	store := &mockStore{}
+
+	err = storeapikey.Store(ctx, storeapikey.Params{
+		APIKey:    "10000000-0000-4000-8000-000000000000",
+		StoreType: keystore.TypePass,
+	}, store, w)
+	require.NoError(t, err)
+
+	assert.Equal(t, "10000000-0000-4000-8000-000000000000", store.apiKey)
+
+	settings, err := ini.ReadSettings(fp)
+	require.NoError(t, err)
+
+	assert.Equal(t, []ini.Setting{
+		{Key: "api_key_store", Value: "pass", Source: fp},
+		{Key: "debug", Value: "true", Source: fp},
+	}, settings)
+}
+
+func TestStore_Err(t *testing.T) {
+	fp := filepath.Join(t.TempDir(), "wakatime.cfg")
+
+	err := os.WriteFile(fp, []byte("[settings]\napi_key = 00000000-0000-4000-8000-000000000000\n"), 0600)
+	require.NoError(t, err)
+
+	w, err := ini.NewWriter(context.Background(), viper.New(), func(_ context.Context, _ *viper.Viper) (string, error) {
+		return fp, nil
+	})
+	require.NoError(t, err)
+
+	err = storeapikey.Store(context.Background(), storeapikey.Params{
+		APIKey:    "10000000-0000-4000-8000-000000000000",
+		StoreType: keystore.TypeSecretService,
+	}, &mockStore{err: errors.New("no session bus")}, w)
+	require.EqualError(t, err, "failed to store api key in secret-service: no session bus")
+
+	// the plaintext api key must be kept when storing failed
+	settings, err := ini.ReadSettings(fp)
+	require.NoError(t, err)
+
+	require.Len(t, settings, 1)
+	assert.Equal(t, "api_key", settings[0].Key)
+}
+
+type mockStore struct {
+	apiKey string
+	err    error
+}
+
+func (m *mockStore) Get(_ context.Context) (string, error) {
+	if m.apiKey == "" {
+		return "", keystore.ErrNotFound
+	}
+
+	return m.apiKey, nil
+}
+
+func (m *mockStore) Set(_ context.Context, apiKey string) error {
+	if m.err != nil {
+		return m.err
+	}
+
+	m.apiKey = apiKey
+
+	return nil
+}
@@ -124,6 +124,10 @@ func testSendHeartbeats(t *testing.T, projectFolder, entity, p string) {
 
 	defer tmpConfigFile.Close()
 
+	// test heartbeats are older than the default max age
+	_, err = tmpConfigFile.WriteString("[settings]\nheartbeat_max_age_days = 0\n")
+	require.NoError(t, err)
+
 	tmpInternalConfigFile, err := os.CreateTemp(tmpDir, "wakatime-internal.cfg")
 	require.NoError(t, err)
 
@@ -303,6 +307,10 @@ func TestSendHeartbeats_ExtraHeartbeats(t *testing.T) {
 
 	defer tmpConfigFile.Close()
 
+	// test heartbeats are older than the default max age
+	_, err = tmpConfigFile.WriteString("[settings]\nheartbeat_max_age_days = 0\n")
+	require.NoError(t, err)
+
 	tmpInternalConfigFile, err := os.CreateTemp(tmpDir, "wakatime-internal.cfg")
 	require.NoError(t, err)
 
@@ -431,6 +439,10 @@ func TestSendHeartbeats_ExtraHeartbeats_SyncLegacyOfflineActivity(t *testing.T)
 
 	defer tmpConfigFile.Close()
 
+	// test heartbeats are older than the default max age
+	_, err = tmpConfigFile.WriteString("[settings]\nheartbeat_max_age_days = 0\n")
+	require.NoError(t, err)
+
 	tmpInternalConfigFile, err := os.CreateTemp(tmpDir, "wakatime-internal.cfg")
 	require.NoError(t, err)
 
@@ -538,6 +550,10 @@ func TestSendHeartbeats_Err(t *testing.T) {
 
 	defer tmpConfigFile.Close()
 
+	// test heartbeats are older than the default max age
+	_, err = tmpConfigFile.WriteString("[settings]\nheartbeat_max_age_days = 0\n")
+	require.NoError(t, err)
+
 	tmpInternalConfigFile, err := os.CreateTemp(tmpDir, "wakatime-internal.cfg")
 	require.NoError(t, err)
 
@@ -600,6 +616,10 @@ func TestSendHeartbeats_ErrAuth_InvalidAPIKEY(t *testing.T) {
 
 	defer tmpConfigFile.Close()
 
+	// test heartbeats are older than the default max age
+	_, err = tmpConfigFile.WriteString("[settings]\nheartbeat_max_age_days = 0\n")
+	require.NoError(t, err)
+
 	tmpInternalConfigFile, err := os.CreateTemp(tmpDir, "wakatime-internal.cfg")
 	require.NoError(t, err)
 
@@ -933,6 +953,10 @@ func TestOfflineCount(t *testing.T) {
 
 	defer tmpConfigFile.Close()
 
+	// test heartbeats are older than the default max age
+	_, err = tmpConfigFile.WriteString("[settings]\nheartbeat_max_age_days = 0\n")
+	require.NoError(t, err)
+
 	tmpInternalConfigFile, err := os.CreateTemp(tmpDir, "wakatime-internal.cfg")
 	require.NoError(t, err)
 
@@ -1030,6 +1054,10 @@ func TestPrintOfflineHeartbeats(t *testing.T) {
 
 	defer tmpConfigFile.Close()
 
+	// test heartbeats are older than the default max age
+	_, err = tmpConfigFile.WriteString("[settings]\nheartbeat_max_age_days = 0\n")
+	require.NoError(t, err)
+
 	tmpInternalConfigFile, err := os.CreateTemp(tmpDir, "wakatime-internal.cfg")
 	require.NoError(t, err)
 
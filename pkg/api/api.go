@@ -7,6 +7,7 @@ import (
 	"net"
 	"net/http"
 	"strings"
+	"sync"
 
 	"github.com/wakatime/wakatime-cli/pkg/log"
 )
@@ -26,6 +27,8 @@ const (
 type Client struct {
 	baseURL string
 	client  *http.Client
+	// clientMu guards replacing client on dns errors, as requests may be sent concurrently.
+	clientMu sync.RWMutex
 	// doFunc allows api client options to manipulate request/response handling.
 	// default function will be set in constructor.
 	//
@@ -39,6 +42,14 @@ type Client struct {
 	//		return resp, err
 	//	}
 	doFunc func(c *Client, req *http.Request) (*http.Response, error)
+	// pinned is true when certificate pins are enforced, which rules out
+	// retrying with the api ip address on dns errors.
+	pinned bool
+	// resolver resolves hostnames and dials connections, if set.
+	resolver *Resolver
+	// workers is the maximum number of requests sent concurrently when
+	// sending heartbeats of multiple api keys.
+	workers int
 }
 
 // NewClient creates a new Client. Any number of Options can be provided.
@@ -50,7 +61,7 @@ func NewClient(baseURL string, opts ...Option) *Client {
 		},
 		doFunc: func(c *Client, req *http.Request) (*http.Response, error) {
 			req.Header.Set("Accept", "application/json")
-			return c.client.Do(req)
+			return c.httpClient().Do(req)
 		},
 	}
 
@@ -76,16 +87,37 @@ func (c *Client) Do(ctx context.Context, req *http.Request) (*http.Response, err
 			return nil, err
 		}
 
-		c.client = &http.Client{
-			Transport: NewTransportWithHostVerificationDisabled(ctx),
+		logger := log.Extract(ctx)
+
+		if c.pinned {
+			logger.Debugf("dns error, not retrying with host ip because certificate pins are set: %s", err)
+
+			return nil, err
+		}
+
+		c.clientMu.Lock()
+		transport, ok := fallbackTransport(ctx, c.client.Transport)
+		if ok {
+			c.client = &http.Client{
+				CheckRedirect: c.client.CheckRedirect,
+				Jar:           c.client.Jar,
+				Timeout:       c.client.Timeout,
+				Transport:     transport,
+			}
+		}
+		c.clientMu.Unlock()
+
+		if !ok {
+			logger.Debugf("dns error, not retrying with host ip because of unsupported transport: %s", err)
+
+			return nil, err
 		}
 
 		req.URL.Host = BaseIPAddrv4
-		if isLocalIPv6(ctx) {
+		if c.resolver.preferIPv6(ctx) {
 			req.URL.Host = BaseIPAddrv6
 		}
 
-		logger := log.Extract(ctx)
 		logger.Debugf("dns error, will retry with host ip '%s': %s", req.URL.Host, err)
 
 		resp, errRetry := c.doFunc(c, req)
@@ -99,6 +131,14 @@ func (c *Client) Do(ctx context.Context, req *http.Request) (*http.Response, err
 	return resp, nil
 }
 
+// httpClient returns the http client used to send requests.
+func (c *Client) httpClient() *http.Client {
+	c.clientMu.RLock()
+	defer c.clientMu.RUnlock()
+
+	return c.client
+}
+
 func isLocalIPv6(ctx context.Context) bool {
 	logger := log.Extract(ctx)
 
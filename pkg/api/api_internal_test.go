@@ -0,0 +1,86 @@
+package api
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClient_Do_DNSErrorFallback(t *testing.T) {
+	pool := CACerts(context.Background())
+
+	withSSLPins, err := WithSSLPins(context.Background(), map[string][]string{
+		"api.wakatime.com": {"sha256/47DEQpj8HBSa+/TImW+5JCeuQeRkm5NMpJWZG3hSuFU="},
+	}, true)
+	require.NoError(t, err)
+
+	c := NewClient(BaseURL, WithSSLCertPool(pool), WithSSLClientCert(tls.Certificate{}), withSSLPins)
+
+	var hosts []string
+
+	c.doFunc = func(c *Client, req *http.Request) (*http.Response, error) {
+		hosts = append(hosts, req.URL.Host)
+
+		if len(hosts) == 1 {
+			return nil, &net.DNSError{Err: "no such host", Name: req.URL.Host, IsNotFound: true}
+		}
+
+		return &http.Response{StatusCode: http.StatusCreated, Body: http.NoBody}, nil
+	}
+
+	req, err := http.NewRequest(http.MethodGet, BaseURL+"/users/current", nil)
+	require.NoError(t, err)
+
+	resp, err := c.Do(context.Background(), req)
+	require.NoError(t, err)
+
+	defer resp.Body.Close()
+
+	require.Len(t, hosts, 2)
+	assert.Contains(t, []string{BaseIPAddrv4, BaseIPAddrv6}, hosts[1])
+
+	transport, ok := c.httpClient().Transport.(*http.Transport)
+	require.True(t, ok)
+
+	// custom tls settings are kept, verifying the api host name
+	assert.Equal(t, serverName, transport.TLSClientConfig.ServerName)
+	assert.Same(t, pool, transport.TLSClientConfig.RootCAs)
+	assert.Len(t, transport.TLSClientConfig.Certificates, 1)
+	assert.NotNil(t, transport.TLSClientConfig.VerifyConnection)
+}
+
+func TestClient_Do_DNSErrorPinned(t *testing.T) {
+	withSSLPins, err := WithSSLPins(context.Background(), map[string][]string{
+		"api.wakatime.com": {"sha256/47DEQpj8HBSa+/TImW+5JCeuQeRkm5NMpJWZG3hSuFU="},
+	}, false)
+	require.NoError(t, err)
+
+	c := NewClient(BaseURL, withSSLPins)
+	client := c.httpClient()
+
+	var numCalls int
+
+	c.doFunc = func(_ *Client, req *http.Request) (*http.Response, error) {
+		numCalls++
+
+		return nil, &net.DNSError{Err: "no such host", Name: req.URL.Host, IsNotFound: true}
+	}
+
+	req, err := http.NewRequest(http.MethodGet, BaseURL+"/users/current", nil)
+	require.NoError(t, err)
+
+	_, err = c.Do(context.Background(), req) // nolint:bodyclose
+	require.Error(t, err)
+
+	var dnsErr *net.DNSError
+
+	assert.ErrorAs(t, err, &dnsErr)
+	assert.Equal(t, 1, numCalls)
+	assert.Same(t, client, c.httpClient())
+	assert.Equal(t, "api.wakatime.com", req.URL.Host)
+}
@@ -0,0 +1,73 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/wakatime/wakatime-cli/pkg/log"
+)
+
+// TokenSource provides bearer access tokens.
+type TokenSource interface {
+	// Token returns a valid access token.
+	Token(ctx context.Context) (string, error)
+	// Refresh returns a new access token after the api rejected the passed in one.
+	Refresh(ctx context.Context, rejected string) (string, error)
+}
+
+// WithBearerAuth authenticates requests with bearer access tokens of the passed in source,
+// replacing basic auth of api keys. When the api responds with 401 Unauthorized, the token
+// is refreshed and the request is retried once. If refreshing fails, the 401 response is
+// returned. ErrAuth is returned if no access token is available.
+func WithBearerAuth(ctx context.Context, source TokenSource) Option {
+	return func(c *Client) {
+		logger := log.Extract(ctx)
+
+		next := c.doFunc
+		c.doFunc = func(c *Client, req *http.Request) (*http.Response, error) {
+			token, err := source.Token(req.Context())
+			if err != nil {
+				return nil, ErrAuth{Err: fmt.Errorf("failed to get oauth access token: %s", err)}
+			}
+
+			req.Header.Set("Authorization", "Bearer "+token)
+
+			resp, err := next(c, req)
+			if err != nil || resp.StatusCode != http.StatusUnauthorized {
+				return resp, err
+			}
+
+			if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+				// the body was consumed and can't be sent again
+				return resp, nil
+			}
+
+			refreshed, err := source.Refresh(req.Context(), token)
+			if err != nil {
+				logger.Warnf("api rejected oauth access token: %s", err)
+				return resp, nil
+			}
+
+			// drain and close, so the connection can be reused
+			_, _ = io.Copy(io.Discard, resp.Body)
+			_ = resp.Body.Close()
+
+			retry := req.Clone(req.Context())
+
+			if req.GetBody != nil {
+				body, err := req.GetBody()
+				if err != nil {
+					return nil, fmt.Errorf("failed to get request body: %s", err)
+				}
+
+				retry.Body = body
+			}
+
+			retry.Header.Set("Authorization", "Bearer "+refreshed)
+
+			return next(c, retry)
+		}
+	}
+}
@@ -0,0 +1,154 @@
+package api_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"sync"
+	"testing"
+
+	"github.com/wakatime/wakatime-cli/pkg/api"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOption_WithBearerAuth(t *testing.T) {
+	url, router, tearDown := setupTestServer()
+	defer tearDown()
+
+	var numCalls int
+
+	router.HandleFunc("/users/current/heartbeats.bulk", func(w http.ResponseWriter, req *http.Request) {
+		numCalls++
+
+		assert.Equal(t, "Bearer access-0", req.Header.Get("Authorization"))
+
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte(`{"responses": []}`))
+	})
+
+	source := &mockTokenSource{token: "access-0"}
+
+	c := api.NewClient(url, api.WithBearerAuth(context.Background(), source))
+
+	_, err := c.SendHeartbeats(context.Background(), testHeartbeats())
+	require.NoError(t, err)
+
+	assert.Equal(t, 1, numCalls)
+	assert.Zero(t, source.refreshes)
+}
+
+func TestOption_WithBearerAuth_RefreshOnUnauthorized(t *testing.T) {
+	url, router, tearDown := setupTestServer()
+	defer tearDown()
+
+	var authHeaders []string
+
+	router.HandleFunc("/users/current/heartbeats.bulk", func(w http.ResponseWriter, req *http.Request) {
+		authHeaders = append(authHeaders, req.Header.Get("Authorization"))
+
+		// the body is sent again on retry
+		body, err := io.ReadAll(req.Body)
+		require.NoError(t, err)
+		assert.NotEmpty(t, body)
+
+		if req.Header.Get("Authorization") != "Bearer access-1" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte(`{"responses": []}`))
+	})
+
+	source := &mockTokenSource{token: "access-0"}
+
+	c := api.NewClient(url, api.WithBearerAuth(context.Background(), source))
+
+	_, err := c.SendHeartbeats(context.Background(), testHeartbeats())
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"Bearer access-0", "Bearer access-1"}, authHeaders)
+	assert.Equal(t, 1, source.refreshes)
+}
+
+func TestOption_WithBearerAuth_RefreshFailed(t *testing.T) {
+	url, router, tearDown := setupTestServer()
+	defer tearDown()
+
+	var numCalls int
+
+	router.HandleFunc("/users/current/heartbeats.bulk", func(w http.ResponseWriter, _ *http.Request) {
+		numCalls++
+
+		w.WriteHeader(http.StatusUnauthorized)
+	})
+
+	source := &mockTokenSource{token: "access-0", refreshErr: errors.New("invalid_grant")}
+
+	c := api.NewClient(url, api.WithBearerAuth(context.Background(), source))
+
+	_, err := c.SendHeartbeats(context.Background(), testHeartbeats())
+
+	var errauth api.ErrAuth
+
+	assert.ErrorAs(t, err, &errauth)
+	assert.Equal(t, 1, numCalls)
+	assert.Equal(t, 1, source.refreshes)
+}
+
+func TestOption_WithBearerAuth_NoToken(t *testing.T) {
+	url, router, tearDown := setupTestServer()
+	defer tearDown()
+
+	var numCalls int
+
+	router.HandleFunc("/users/current/heartbeats.bulk", func(_ http.ResponseWriter, _ *http.Request) {
+		numCalls++
+	})
+
+	source := &mockTokenSource{tokenErr: errors.New("no oauth token found")}
+
+	c := api.NewClient(url, api.WithBearerAuth(context.Background(), source))
+
+	_, err := c.SendHeartbeats(context.Background(), testHeartbeats())
+
+	var errauth api.ErrAuth
+
+	require.ErrorAs(t, err, &errauth)
+	assert.EqualError(t, err, "failed to get oauth access token: no oauth token found")
+	assert.Zero(t, numCalls)
+}
+
+// mockTokenSource returns token, which is replaced by access-1 on refresh.
+type mockTokenSource struct {
+	mu         sync.Mutex
+	token      string
+	tokenErr   error
+	refreshErr error
+	refreshes  int
+}
+
+func (m *mockTokenSource) Token(_ context.Context) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.token, m.tokenErr
+}
+
+func (m *mockTokenSource) Refresh(_ context.Context, _ string) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.refreshes++
+
+	if m.refreshErr != nil {
+		return "", m.refreshErr
+	}
+
+	m.token = "access-1"
+
+	return m.token, nil
+}
@@ -0,0 +1,282 @@
+package api
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/wakatime/wakatime-cli/pkg/log"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// Compression is the compression mode of request bodies.
+type Compression string
+
+const (
+	// CompressionAuto sends request bodies uncompressed until the api advertises
+	// supported encodings via the Accept-Encoding response header, preferring zstd.
+	CompressionAuto Compression = "auto"
+	// CompressionGzip compresses request bodies with gzip.
+	CompressionGzip Compression = "gzip"
+	// CompressionZstd compresses request bodies with zstd.
+	CompressionZstd Compression = "zstd"
+	// CompressionNone sends request bodies uncompressed.
+	CompressionNone Compression = "none"
+)
+
+// compressionMinSize is the minimum body size in bytes worth compressing.
+const compressionMinSize = 1024
+
+// ParseCompression parses a compression mode. Empty string is returned as is,
+// and defaults to auto.
+func ParseCompression(s string) (Compression, error) {
+	switch c := Compression(strings.ToLower(strings.TrimSpace(s))); c {
+	case "", CompressionAuto, CompressionGzip, CompressionZstd, CompressionNone:
+		return c, nil
+	default:
+		return "", fmt.Errorf(
+			"invalid compression %q, expected one of %q, %q, %q or %q",
+			s, CompressionAuto, CompressionGzip, CompressionZstd, CompressionNone,
+		)
+	}
+}
+
+// WithCompression compresses request bodies following the passed in compression
+// mode. When the api responds with 415 Unsupported Media Type, the request is retried
+// once with an encoding advertised in the response, or uncompressed, and following
+// requests use that encoding. Empty compression defaults to auto.
+func WithCompression(ctx context.Context, compression Compression) Option {
+	if compression == CompressionNone {
+		return func(*Client) {}
+	}
+
+	n := &negotiator{
+		encoding: string(compression),
+		auto:     compression == "" || compression == CompressionAuto,
+	}
+
+	if n.auto {
+		n.encoding = ""
+	}
+
+	return func(c *Client) {
+		logger := log.Extract(ctx)
+
+		next := c.doFunc
+		c.doFunc = func(c *Client, req *http.Request) (*http.Response, error) {
+			raw, ok, err := readRequestBody(req)
+			if err != nil {
+				return nil, err
+			}
+
+			if !ok {
+				return next(c, req)
+			}
+
+			encoding := n.current()
+
+			compressed, err := newCompressedRequest(req, raw, encoding)
+			if err != nil {
+				return nil, err
+			}
+
+			resp, err := next(c, compressed)
+			if err != nil {
+				return nil, err
+			}
+
+			n.learn(resp.Header)
+
+			if resp.StatusCode != http.StatusUnsupportedMediaType || compressed.Header.Get("Content-Encoding") == "" {
+				return resp, nil
+			}
+
+			// drain and close, so the connection can be reused
+			_, _ = io.Copy(io.Discard, resp.Body)
+			_ = resp.Body.Close()
+
+			fallback := n.fallback(encoding, resp.Header)
+
+			logger.Debugf(
+				"api does not support %s request bodies, will retry with encoding %q",
+				encoding,
+				fallback,
+			)
+
+			retry, err := newCompressedRequest(req, raw, fallback)
+			if err != nil {
+				return nil, err
+			}
+
+			return next(c, retry)
+		}
+	}
+}
+
+// negotiator keeps track of the encoding used for request bodies, which
+// is shared by concurrently sent requests.
+type negotiator struct {
+	mu sync.Mutex
+	// encoding is the content coding used for request bodies, empty for none.
+	encoding string
+	// auto is true when encoding can be upgraded by api advertisements.
+	auto bool
+}
+
+func (n *negotiator) current() string {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	return n.encoding
+}
+
+// learn picks the preferred encoding advertised by the api, when in auto mode.
+func (n *negotiator) learn(header http.Header) {
+	if !n.auto {
+		return
+	}
+
+	values, ok := header["Accept-Encoding"]
+	if !ok {
+		return
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	n.encoding = preferredEncoding(values, "")
+}
+
+// fallback returns the encoding to retry with after the api rejected the given
+// encoding, and uses it for following requests.
+func (n *negotiator) fallback(rejected string, header http.Header) string {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	n.encoding = preferredEncoding(header.Values("Accept-Encoding"), rejected)
+
+	return n.encoding
+}
+
+// preferredEncoding returns the preferred supported encoding of the given Accept-Encoding
+// header values, skipping the excluded one. Returns empty string for none.
+func preferredEncoding(values []string, exclude string) string {
+	advertised := map[string]bool{}
+
+	for _, value := range values {
+		for _, part := range strings.Split(value, ",") {
+			name, params, _ := strings.Cut(part, ";")
+			if strings.ReplaceAll(strings.TrimSpace(params), " ", "") == "q=0" {
+				continue
+			}
+
+			advertised[strings.ToLower(strings.TrimSpace(name))] = true
+		}
+	}
+
+	for _, encoding := range []string{string(CompressionZstd), string(CompressionGzip)} {
+		if encoding != exclude && advertised[encoding] {
+			return encoding
+		}
+	}
+
+	return ""
+}
+
+// readRequestBody returns the request body without consuming the original request's
+// body, when possible. Returns false if there is no body or it's already encoded.
+func readRequestBody(req *http.Request) ([]byte, bool, error) {
+	if req.Body == nil || req.Body == http.NoBody || req.Header.Get("Content-Encoding") != "" {
+		return nil, false, nil
+	}
+
+	body := req.Body
+
+	if req.GetBody != nil {
+		b, err := req.GetBody()
+		if err != nil {
+			return nil, false, fmt.Errorf("failed to get request body: %s", err)
+		}
+
+		body = b
+	}
+
+	defer body.Close() // nolint:errcheck
+
+	raw, err := io.ReadAll(body)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to read request body: %s", err)
+	}
+
+	return raw, true, nil
+}
+
+// newCompressedRequest returns a copy of the request with the body compressed with
+// the given encoding. Bodies smaller than compressionMinSize are sent uncompressed.
+func newCompressedRequest(req *http.Request, raw []byte, encoding string) (*http.Request, error) {
+	body := raw
+
+	if encoding != "" && len(raw) >= compressionMinSize {
+		compressed, err := compress(raw, encoding)
+		if err != nil {
+			return nil, fmt.Errorf("failed to compress request body with %s: %s", encoding, err)
+		}
+
+		body = compressed
+	} else {
+		encoding = ""
+	}
+
+	clone := req.Clone(req.Context())
+	clone.Body = io.NopCloser(bytes.NewReader(body))
+	clone.ContentLength = int64(len(body))
+	clone.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(body)), nil
+	}
+
+	if encoding != "" {
+		clone.Header.Set("Content-Encoding", encoding)
+	}
+
+	return clone, nil
+}
+
+func compress(data []byte, encoding string) ([]byte, error) {
+	var buf bytes.Buffer
+
+	switch Compression(encoding) {
+	case CompressionGzip:
+		w := gzip.NewWriter(&buf)
+
+		if _, err := w.Write(data); err != nil {
+			return nil, err
+		}
+
+		if err := w.Close(); err != nil {
+			return nil, err
+		}
+	case CompressionZstd:
+		w, err := zstd.NewWriter(&buf)
+		if err != nil {
+			return nil, err
+		}
+
+		if _, err := w.Write(data); err != nil {
+			return nil, err
+		}
+
+		if err := w.Close(); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unsupported encoding %q", encoding)
+	}
+
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,230 @@
+package api_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/wakatime/wakatime-cli/pkg/api"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOption_WithCompression(t *testing.T) {
+	tests := map[string]struct {
+		Compression api.Compression
+		Body        string
+		Expected    string
+	}{
+		"gzip": {
+			Compression: api.CompressionGzip,
+			Body:        largeBody(),
+			Expected:    "gzip",
+		},
+		"zstd": {
+			Compression: api.CompressionZstd,
+			Body:        largeBody(),
+			Expected:    "zstd",
+		},
+		"small body": {
+			Compression: api.CompressionGzip,
+			Body:        `{"entity":"main.go"}`,
+		},
+		"none": {
+			Compression: api.CompressionNone,
+			Body:        largeBody(),
+		},
+		"auto without advertisement": {
+			Compression: api.CompressionAuto,
+			Body:        largeBody(),
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			url, router, tearDown := setupTestServer()
+			defer tearDown()
+
+			var numCalls int
+
+			router.HandleFunc("/", func(w http.ResponseWriter, req *http.Request) {
+				numCalls++
+
+				assert.Equal(t, test.Expected, req.Header.Get("Content-Encoding"))
+				assert.Equal(t, test.Body, decodeBody(t, req))
+
+				w.WriteHeader(http.StatusCreated)
+			})
+
+			c := api.NewClient("", api.WithCompression(context.Background(), test.Compression))
+
+			resp, err := c.Do(context.Background(), newPostRequest(t, url, test.Body))
+			require.NoError(t, err)
+
+			defer resp.Body.Close()
+
+			assert.Equal(t, http.StatusCreated, resp.StatusCode)
+			assert.Equal(t, 1, numCalls)
+		})
+	}
+}
+
+func TestOption_WithCompression_Auto(t *testing.T) {
+	url, router, tearDown := setupTestServer()
+	defer tearDown()
+
+	var encodings []string
+
+	router.HandleFunc("/", func(w http.ResponseWriter, req *http.Request) {
+		encodings = append(encodings, req.Header.Get("Content-Encoding"))
+
+		assert.Equal(t, largeBody(), decodeBody(t, req))
+
+		w.Header().Set("Accept-Encoding", "gzip, zstd")
+		w.WriteHeader(http.StatusCreated)
+	})
+
+	c := api.NewClient("", api.WithCompression(context.Background(), api.CompressionAuto))
+
+	for i := 0; i < 2; i++ {
+		resp, err := c.Do(context.Background(), newPostRequest(t, url, largeBody()))
+		require.NoError(t, err)
+
+		resp.Body.Close()
+	}
+
+	// first request is sent uncompressed, the next one with the advertised encoding
+	assert.Equal(t, []string{"", "zstd"}, encodings)
+}
+
+func TestOption_WithCompression_UnsupportedMediaType(t *testing.T) {
+	tests := map[string]struct {
+		Compression    api.Compression
+		AcceptEncoding string
+		Expected       []string
+	}{
+		"fallback to advertised encoding": {
+			Compression:    api.CompressionZstd,
+			AcceptEncoding: "gzip",
+			Expected:       []string{"zstd", "gzip", "gzip"},
+		},
+		"fallback to uncompressed": {
+			Compression: api.CompressionGzip,
+			Expected:    []string{"gzip", "", ""},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			url, router, tearDown := setupTestServer()
+			defer tearDown()
+
+			var (
+				encodings []string
+				mu        sync.Mutex
+			)
+
+			router.HandleFunc("/", func(w http.ResponseWriter, req *http.Request) {
+				mu.Lock()
+				defer mu.Unlock()
+
+				encoding := req.Header.Get("Content-Encoding")
+				encodings = append(encodings, encoding)
+
+				if encoding != "" && encoding != test.AcceptEncoding {
+					if test.AcceptEncoding != "" {
+						w.Header().Set("Accept-Encoding", test.AcceptEncoding)
+					}
+
+					w.WriteHeader(http.StatusUnsupportedMediaType)
+
+					return
+				}
+
+				assert.Equal(t, largeBody(), decodeBody(t, req))
+
+				w.WriteHeader(http.StatusCreated)
+			})
+
+			c := api.NewClient("", api.WithCompression(context.Background(), test.Compression))
+
+			for i := 0; i < 2; i++ {
+				resp, err := c.Do(context.Background(), newPostRequest(t, url, largeBody()))
+				require.NoError(t, err)
+
+				assert.Equal(t, http.StatusCreated, resp.StatusCode)
+
+				resp.Body.Close()
+			}
+
+			assert.Equal(t, test.Expected, encodings)
+		})
+	}
+}
+
+func TestParseCompression(t *testing.T) {
+	tests := map[string]api.Compression{
+		"":      "",
+		"auto":  api.CompressionAuto,
+		"GZIP":  api.CompressionGzip,
+		" zstd": api.CompressionZstd,
+		"none":  api.CompressionNone,
+	}
+
+	for value, expected := range tests {
+		t.Run(value, func(t *testing.T) {
+			compression, err := api.ParseCompression(value)
+			require.NoError(t, err)
+
+			assert.Equal(t, expected, compression)
+		})
+	}
+}
+
+func TestParseCompression_Invalid(t *testing.T) {
+	_, err := api.ParseCompression("brotli")
+
+	assert.EqualError(t, err, `invalid compression "brotli", expected one of "auto", "gzip", "zstd" or "none"`)
+}
+
+func largeBody() string {
+	return "[" + strings.Repeat(`{"entity":"/tmp/main.go","type":"file"},`, 100) + "{}]"
+}
+
+func newPostRequest(t *testing.T, url, body string) *http.Request {
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewBufferString(body))
+	require.NoError(t, err)
+
+	return req
+}
+
+func decodeBody(t *testing.T, req *http.Request) string {
+	var r io.Reader = req.Body
+
+	switch req.Header.Get("Content-Encoding") {
+	case "gzip":
+		gr, err := gzip.NewReader(req.Body)
+		require.NoError(t, err)
+
+		r = gr
+	case "zstd":
+		zr, err := zstd.NewReader(req.Body)
+		require.NoError(t, err)
+
+		defer zr.Close()
+
+		r = zr
+	}
+
+	data, err := io.ReadAll(r)
+	require.NoError(t, err)
+
+	return string(data)
+}
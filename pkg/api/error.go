@@ -1,9 +1,12 @@
 package api
 
 import (
+	"errors"
 	"fmt"
+	"time"
 
 	"github.com/wakatime/wakatime-cli/pkg/exitcode"
+	"github.com/wakatime/wakatime-cli/pkg/heartbeat"
 	"github.com/wakatime/wakatime-cli/pkg/wakaerror"
 
 	"go.uber.org/zap/zapcore"
@@ -141,3 +144,156 @@ func (ErrBackoff) SendDiagsOnErrors() bool {
 func (ErrBackoff) ShouldLogError() bool {
 	return false
 }
+
+// ErrRateLimited represents a 429 Too Many Requests response from the API.
+type ErrRateLimited struct {
+	Err error
+	// RetryAfter is the time after which sending can be retried, parsed from the
+	// Retry-After header, or from X-RateLimit-Reset if no requests are remaining.
+	// Zero if the api did not send any of these headers.
+	RetryAfter time.Time
+	// Limit is the X-RateLimit-Limit header value, -1 if missing.
+	Limit int
+	// Remaining is the X-RateLimit-Remaining header value, -1 if missing.
+	Remaining int
+	// Reset is the time parsed from the X-RateLimit-Reset header, zero if missing.
+	Reset time.Time
+}
+
+var _ wakaerror.Error = ErrRateLimited{}
+
+// Error method to implement error interface.
+func (e ErrRateLimited) Error() string {
+	return e.Err.Error()
+}
+
+// ExitCode method to implement wakaerror.Error interface.
+func (ErrRateLimited) ExitCode() int {
+	return exitcode.ErrBackoff
+}
+
+// Message method to implement wakaerror.Error interface.
+func (e ErrRateLimited) Message() string {
+	if e.RetryAfter.IsZero() {
+		return fmt.Sprintf("rate limited: %s", e.Err)
+	}
+
+	return fmt.Sprintf("rate limited until %s: %s", e.RetryAfter.Format(time.RFC3339), e.Err)
+}
+
+// SendDiagsOnErrors method to implement wakaerror.SendDiagsOnErrors interface.
+func (ErrRateLimited) SendDiagsOnErrors() bool {
+	return false
+}
+
+// ShouldLogError method to implement wakaerror.ShouldLogError interface.
+func (ErrRateLimited) ShouldLogError() bool {
+	return true
+}
+
+// ErrCertPin represents a tls certificate not matching any of the pins configured for a host.
+type ErrCertPin struct {
+	Err  error
+	Host string
+}
+
+var _ wakaerror.Error = ErrCertPin{}
+
+// Error method to implement error interface.
+func (e ErrCertPin) Error() string {
+	return e.Err.Error()
+}
+
+// ExitCode method to implement wakaerror.Error interface.
+func (ErrCertPin) ExitCode() int {
+	return exitcode.ErrCertPin
+}
+
+// Message method to implement wakaerror.Error interface.
+func (e ErrCertPin) Message() string {
+	return fmt.Sprintf("certificate pin mismatch for %s: %s", e.Host, e.Err)
+}
+
+// SendDiagsOnErrors method to implement wakaerror.SendDiagsOnErrors interface.
+func (ErrCertPin) SendDiagsOnErrors() bool {
+	return false
+}
+
+// ShouldLogError method to implement wakaerror.ShouldLogError interface.
+func (ErrCertPin) ShouldLogError() bool {
+	return true
+}
+
+// ErrPartial is returned when sending heartbeats failed for some, but not all api keys.
+type ErrPartial struct {
+	Groups []GroupResult
+}
+
+var _ wakaerror.Error = ErrPartial{}
+
+// Error method to implement error interface.
+func (e ErrPartial) Error() string {
+	var failed int
+
+	for _, g := range e.Groups {
+		if g.Err != nil {
+			failed++
+		}
+	}
+
+	return fmt.Sprintf("failed sending heartbeats for %d of %d api key(s): %s", failed, len(e.Groups), e.first())
+}
+
+// Unwrap returns the error of the first failed group.
+func (e ErrPartial) Unwrap() error {
+	return e.first()
+}
+
+// ExitCode method to implement wakaerror.Error interface. Returns the exit
+// code of the error of the first failed group.
+func (e ErrPartial) ExitCode() int {
+	var errwaka wakaerror.Error
+	if errors.As(e.first(), &errwaka) {
+		return errwaka.ExitCode()
+	}
+
+	return exitcode.ErrAPI
+}
+
+// Message method to implement wakaerror.Error interface.
+func (e ErrPartial) Message() string {
+	return fmt.Sprintf("api error: %s", e.Error())
+}
+
+// SendDiagsOnErrors method to implement wakaerror.SendDiagsOnErrors interface.
+func (ErrPartial) SendDiagsOnErrors() bool {
+	return false
+}
+
+// ShouldLogError method to implement wakaerror.ShouldLogError interface.
+func (ErrPartial) ShouldLogError() bool {
+	return true
+}
+
+// Failed returns the heartbeats of all failed groups.
+func (e ErrPartial) Failed() []heartbeat.Heartbeat {
+	var hh []heartbeat.Heartbeat
+
+	for _, g := range e.Groups {
+		if g.Err != nil {
+			hh = append(hh, g.Heartbeats...)
+		}
+	}
+
+	return hh
+}
+
+func (e ErrPartial) first() error {
+	for _, g := range e.Groups {
+		if g.Err != nil {
+			return g.Err
+		}
+	}
+
+	return nil
+}
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -50,6 +51,16 @@ func (c *Client) FileExperts(ctx context.Context, heartbeats []heartbeat.Heartbe
 
 	resp, err := c.Do(ctx, req)
 	if err != nil {
+		var errpin ErrCertPin
+		if errors.As(err, &errpin) {
+			return nil, errpin
+		}
+
+		var errauth ErrAuth
+		if errors.As(err, &errauth) {
+			return nil, errauth
+		}
+
 		return nil, Err{Err: fmt.Errorf("failed making request to %q: %s", url, err)}
 	}
 	defer resp.Body.Close() // nolint:errcheck,gosec
@@ -3,6 +3,7 @@ package api
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -27,6 +28,16 @@ func (c *Client) Goal(ctx context.Context, id string) (*goal.Goal, error) {
 
 	resp, err := c.Do(ctx, req)
 	if err != nil {
+		var errpin ErrCertPin
+		if errors.As(err, &errpin) {
+			return nil, errpin
+		}
+
+		var errauth ErrAuth
+		if errors.As(err, &errauth) {
+			return nil, errauth
+		}
+
 		return nil, Err{Err: fmt.Errorf("failed to make request to %q: %s", url, err)}
 	}
 	defer resp.Body.Close() // nolint:errcheck,gosec
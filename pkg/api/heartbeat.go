@@ -10,40 +10,109 @@ import (
 	"net/http"
 	"sort"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/wakatime/wakatime-cli/pkg/heartbeat"
 	"github.com/wakatime/wakatime-cli/pkg/log"
 )
 
+// GroupResult is the outcome of sending the heartbeats of a single api key.
+type GroupResult struct {
+	Heartbeats []heartbeat.Heartbeat
+	Results    []heartbeat.Result
+	Err        error
+}
+
 // SendHeartbeats sends a bulk of heartbeats to the wakatime api and returns the result.
 // The API does not guarantuee the setting of the Heartbeat property of the result.
 // On certain errors, like 429/too many heartbeats, this is omitted and not set.
 //
+// Heartbeats are sent in one request per api key. If sending fails for some,
+// but not all api keys, the results of the succeeded requests are returned
+// along with ErrPartial. If all requests fail, the first error is returned.
+//
 // ErrRequest is returned upon request failure with no received response from api.
 // ErrAuth is returned upon receiving a 401 Unauthorized api response.
 // Err is returned on any other api response related error.
 func (c *Client) SendHeartbeats(ctx context.Context, heartbeats []heartbeat.Heartbeat) ([]heartbeat.Result, error) {
+	groups := c.SendHeartbeatGroups(ctx, heartbeats)
+
+	var (
+		results []heartbeat.Result
+		failed  int
+		err     error
+	)
+
+	for _, g := range groups {
+		if g.Err != nil {
+			if err == nil {
+				err = g.Err
+			}
+
+			failed++
+
+			continue
+		}
+
+		results = append(results, g.Results...)
+	}
+
+	switch {
+	case failed == 0:
+		return results, nil
+	case failed == len(groups):
+		return nil, err
+	default:
+		return results, ErrPartial{Groups: groups}
+	}
+}
+
+// SendHeartbeatGroups sends heartbeats in one request per api key and returns
+// the outcome of each request, sorted by api key. Requests are sent
+// concurrently if the client was created using WithConcurrency.
+func (c *Client) SendHeartbeatGroups(ctx context.Context, heartbeats []heartbeat.Heartbeat) []GroupResult {
 	logger := log.Extract(ctx)
 
 	url := c.baseURL + "/users/current/heartbeats.bulk"
 
 	logger.Debugf("sending %d heartbeat(s) to api at %s", len(heartbeats), url)
 
-	var results []heartbeat.Result
-
 	grouped := groupByAPIKey(heartbeats)
 	keys := sortKeys(grouped)
 
-	for _, k := range keys {
-		res, err := c.sendHeartbeats(ctx, url, grouped[k])
-		if err != nil {
-			return nil, err
-		}
+	groups := make([]GroupResult, len(keys))
 
-		results = append(results, res...)
+	workers := c.workers
+	if workers < 1 {
+		workers = 1
 	}
 
-	return results, nil
+	var (
+		wg  sync.WaitGroup
+		sem = make(chan struct{}, workers)
+	)
+
+	for i, k := range keys {
+		groups[i].Heartbeats = grouped[k]
+
+		wg.Add(1)
+
+		sem <- struct{}{}
+
+		go func(g *GroupResult) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+
+			g.Results, g.Err = c.sendHeartbeats(ctx, url, g.Heartbeats)
+		}(&groups[i])
+	}
+
+	wg.Wait()
+
+	return groups
 }
 
 func (c *Client) sendHeartbeats(ctx context.Context, url string, hh []heartbeat.Heartbeat) ([]heartbeat.Result, error) {
@@ -68,6 +137,16 @@ func (c *Client) sendHeartbeats(ctx context.Context, url string, hh []heartbeat.
 
 	resp, err := c.Do(ctx, req)
 	if err != nil {
+		var errpin ErrCertPin
+		if errors.As(err, &errpin) {
+			return nil, errpin
+		}
+
+		var errauth ErrAuth
+		if errors.As(err, &errauth) {
+			return nil, errauth
+		}
+
 		return nil, Err{Err: fmt.Errorf("failed making request to %q: %s", url, err)}
 	}
 	defer resp.Body.Close() // nolint:errcheck,gosec,gosec
@@ -83,6 +162,12 @@ func (c *Client) sendHeartbeats(ctx context.Context, url string, hh []heartbeat.
 		return nil, ErrAuth{Err: fmt.Errorf("authentication failed at %q", url)}
 	case http.StatusBadRequest:
 		return nil, ErrBadRequest{Err: fmt.Errorf("bad request at %q", url)}
+	case http.StatusTooManyRequests:
+		return nil, ParseRateLimit(
+			fmt.Errorf("too many requests at %q. body: %q", url, string(body)),
+			resp.Header,
+			time.Now(),
+		)
 	default:
 		return nil, Err{Err: fmt.Errorf(
 			"invalid response status from %q. got: %d, want: %d/%d. body: %q",
@@ -244,7 +329,13 @@ func sortKeys[K string, V any](m map[K]V) []K {
 	return keys
 }
 
+// setAuthHeader sets the basic auth header of the api key. Without api key, like with
+// oauth, the request is authenticated by the client options instead.
 func setAuthHeader(req *http.Request, apiKey string) {
+	if apiKey == "" {
+		return
+	}
+
 	authHeaderValue, _ := BasicAuth{Secret: apiKey}.HeaderValue()
 
 	req.Header.Set("Authorization", authHeaderValue)
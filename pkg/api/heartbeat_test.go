@@ -3,13 +3,16 @@ package api_test
 import (
 	"context"
 	"errors"
+	"fmt"
 	"io"
 	"net/http"
 	"os"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/wakatime/wakatime-cli/pkg/api"
+	"github.com/wakatime/wakatime-cli/pkg/exitcode"
 	"github.com/wakatime/wakatime-cli/pkg/heartbeat"
 
 	"github.com/stretchr/testify/assert"
@@ -134,6 +137,131 @@ func TestClient_SendHeartbeats_MultipleApiKey(t *testing.T) {
 	assert.Eventually(t, func() bool { return numCalls == 2 }, time.Second, 50*time.Millisecond)
 }
 
+func TestClient_SendHeartbeats_PartialFailure(t *testing.T) {
+	url, router, close := setupTestServer()
+	defer close()
+
+	router.HandleFunc("/users/current/heartbeats.bulk", func(w http.ResponseWriter, req *http.Request) {
+		// fail for the second api key only
+		if req.Header.Get("Authorization") == "Basic MDAwMDAwMDAtMDAwMC00MDAwLTgwMDAtMDAwMDAwMDAwMDAx" {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		f, err := os.Open("testdata/api_heartbeats_response.json")
+		require.NoError(t, err)
+
+		w.WriteHeader(http.StatusCreated)
+		_, err = io.Copy(w, f)
+		require.NoError(t, err)
+	})
+
+	c := api.NewClient(url)
+
+	hh := testHeartbeats()
+	hh[1].APIKey = "00000000-0000-4000-8000-000000000001"
+
+	results, err := c.SendHeartbeats(context.Background(), hh)
+	require.Error(t, err)
+
+	assert.Len(t, results, 2)
+
+	var errpartial api.ErrPartial
+
+	require.ErrorAs(t, err, &errpartial)
+
+	assert.Equal(t, []heartbeat.Heartbeat{hh[1]}, errpartial.Failed())
+	assert.Equal(t, exitcode.ErrAPI, errpartial.ExitCode())
+	assert.Len(t, errpartial.Groups, 2)
+	assert.NoError(t, errpartial.Groups[0].Err)
+
+	var errapi api.Err
+
+	assert.ErrorAs(t, err, &errapi)
+}
+
+func TestClient_SendHeartbeats_AllGroupsFailed(t *testing.T) {
+	url, router, close := setupTestServer()
+	defer close()
+
+	router.HandleFunc("/users/current/heartbeats.bulk", func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	})
+
+	c := api.NewClient(url)
+
+	hh := testHeartbeats()
+	hh[1].APIKey = "00000000-0000-4000-8000-000000000001"
+
+	_, err := c.SendHeartbeats(context.Background(), hh)
+
+	var errauth api.ErrAuth
+
+	var errpartial api.ErrPartial
+
+	assert.ErrorAs(t, err, &errauth)
+	assert.False(t, errors.As(err, &errpartial))
+}
+
+func TestClient_SendHeartbeats_WithConcurrency(t *testing.T) {
+	url, router, close := setupTestServer()
+	defer close()
+
+	var (
+		numCalls    int32
+		inflight    int32
+		maxInflight int32
+	)
+
+	router.HandleFunc("/users/current/heartbeats.bulk", func(w http.ResponseWriter, _ *http.Request) {
+		atomic.AddInt32(&numCalls, 1)
+
+		current := atomic.AddInt32(&inflight, 1)
+		defer atomic.AddInt32(&inflight, -1)
+
+		for {
+			prev := atomic.LoadInt32(&maxInflight)
+			if current <= prev || atomic.CompareAndSwapInt32(&maxInflight, prev, current) {
+				break
+			}
+		}
+
+		time.Sleep(50 * time.Millisecond)
+
+		f, err := os.Open("testdata/api_heartbeats_response.json")
+		require.NoError(t, err)
+
+		w.WriteHeader(http.StatusCreated)
+		_, err = io.Copy(w, f)
+		require.NoError(t, err)
+	})
+
+	c := api.NewClient(url, api.WithConcurrency(2))
+
+	var hh []heartbeat.Heartbeat
+
+	for i := 0; i < 4; i++ {
+		h := testHeartbeats()[0]
+		h.APIKey = fmt.Sprintf("00000000-0000-4000-8000-00000000000%d", i)
+
+		hh = append(hh, h)
+	}
+
+	groups := c.SendHeartbeatGroups(context.Background(), hh)
+	require.Len(t, groups, 4)
+
+	for i, g := range groups {
+		require.NoError(t, g.Err)
+
+		// groups are sorted by api key
+		assert.Equal(t, []heartbeat.Heartbeat{hh[i]}, g.Heartbeats)
+		assert.Len(t, g.Results, 2)
+	}
+
+	assert.Equal(t, int32(4), atomic.LoadInt32(&numCalls))
+	assert.Equal(t, int32(2), atomic.LoadInt32(&maxInflight))
+}
+
 func TestClient_SendHeartbeats_Err(t *testing.T) {
 	url, router, close := setupTestServer()
 	defer close()
@@ -203,6 +331,37 @@ func TestClient_SendHeartbeats_ErrBadRequest(t *testing.T) {
 	assert.Eventually(t, func() bool { return numCalls == 1 }, time.Second, 50*time.Millisecond)
 }
 
+func TestClient_SendHeartbeats_ErrRateLimited(t *testing.T) {
+	url, router, close := setupTestServer()
+	defer close()
+
+	var numCalls int
+
+	router.HandleFunc("/users/current/heartbeats.bulk", func(w http.ResponseWriter, _ *http.Request) {
+		numCalls++
+
+		w.Header().Set("Retry-After", "120")
+		w.Header().Set("X-RateLimit-Limit", "100")
+		w.Header().Set("X-RateLimit-Remaining", "0")
+		w.WriteHeader(http.StatusTooManyRequests)
+	})
+
+	c := api.NewClient(url)
+
+	_, err := c.SendHeartbeats(context.Background(), testHeartbeats())
+
+	var errratelimited api.ErrRateLimited
+
+	require.ErrorAs(t, err, &errratelimited)
+
+	assert.Equal(t, exitcode.ErrBackoff, errratelimited.ExitCode())
+	assert.Equal(t, 100, errratelimited.Limit)
+	assert.Equal(t, 0, errratelimited.Remaining)
+	assert.WithinDuration(t, time.Now().Add(120*time.Second), errratelimited.RetryAfter, 5*time.Second)
+
+	assert.Eventually(t, func() bool { return numCalls == 1 }, time.Second, 50*time.Millisecond)
+}
+
 func TestClient_SendHeartbeats_InvalidUrl(t *testing.T) {
 	c := api.NewClient("invalid-url")
 
@@ -2,11 +2,14 @@ package api
 
 import (
 	"context"
+	"crypto/tls"
 	"crypto/x509"
+	"encoding/pem"
 	"fmt"
 	"net/http"
 	"net/url"
 	"os"
+	"path/filepath"
 	"strings"
 	"time"
 
@@ -14,6 +17,7 @@ import (
 	"github.com/wakatime/wakatime-cli/pkg/log"
 
 	"github.com/Azure/go-ntlmssp"
+	"golang.org/x/crypto/pkcs12" // nolint:staticcheck
 )
 
 // Option is a functional option for Client.
@@ -35,6 +39,22 @@ func WithAuth(auth BasicAuth) (Option, error) {
 	}, nil
 }
 
+// WithConcurrency sets the maximum number of requests sent concurrently
+// when sending heartbeats of multiple api keys. Defaults to sequential sending.
+// The transport's connection limit is raised accordingly.
+func WithConcurrency(workers int) Option {
+	return func(c *Client) {
+		c.workers = workers
+
+		transport := LazyCreateNewTransport(c)
+		transport.MaxConnsPerHost = workers
+		transport.MaxIdleConns = workers
+		transport.MaxIdleConnsPerHost = workers
+
+		c.client.Transport = transport
+	}
+}
+
 // WithDisableSSLVerify disables verification of insecure certificates.
 func WithDisableSSLVerify() Option {
 	return func(c *Client) {
@@ -112,11 +132,9 @@ func WithProxy(proxyURL string) (Option, error) {
 		return nil, fmt.Errorf("failed to parse proxy url %q: %s", proxyURL, err)
 	}
 
-	return func(c *Client) {
-		transport := LazyCreateNewTransport(c)
-		transport.Proxy = http.ProxyURL(u)
-		c.client.Transport = transport
-	}, nil
+	return WithProxyFunc(func(*url.URL) (*url.URL, error) {
+		return u, nil
+	}), nil
 }
 
 // WithSSLCertFile overrides the default CA certs file to trust specified cert file.
@@ -146,6 +164,75 @@ func WithSSLCertPool(caCertPool *x509.CertPool) Option {
 	}
 }
 
+// WithSSLClientCert authenticates with the passed in client certificate,
+// for api endpoints requiring mutual tls.
+func WithSSLClientCert(cert tls.Certificate) Option {
+	return func(c *Client) {
+		transport := LazyCreateNewTransport(c)
+
+		tlsConfig := transport.TLSClientConfig
+		tlsConfig.Certificates = []tls.Certificate{cert}
+
+		transport.TLSClientConfig = tlsConfig
+
+		c.client.Transport = transport
+	}
+}
+
+// WithSSLClientCertFile authenticates with a client certificate loaded from file.
+// PKCS#12 files, with .p12 or .pfx extension, are decrypted with the passed in
+// passphrase. Otherwise PEM encoded files are expected, and the private key is read
+// from the certificate file itself if keyFile is empty.
+func WithSSLClientCertFile(certFile, keyFile, passphrase string) (Option, error) {
+	var (
+		cert tls.Certificate
+		err  error
+	)
+
+	switch strings.ToLower(filepath.Ext(certFile)) {
+	case ".p12", ".pfx":
+		cert, err = loadPKCS12(certFile, passphrase)
+	default:
+		if keyFile == "" {
+			keyFile = certFile
+		}
+
+		cert, err = tls.LoadX509KeyPair(certFile, keyFile)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to load client certificate %q: %s", certFile, err)
+	}
+
+	return WithSSLClientCert(cert), nil
+}
+
+// loadPKCS12 decodes a PKCS#12 file into a certificate, including its chain.
+func loadPKCS12(filepath, passphrase string) (tls.Certificate, error) {
+	data, err := os.ReadFile(filepath) // nolint:gosec
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+
+	blocks, err := pkcs12.ToPEM(data, passphrase)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+
+	var certPEM, keyPEM []byte
+
+	for _, b := range blocks {
+		if b.Type == "CERTIFICATE" {
+			certPEM = append(certPEM, pem.EncodeToMemory(b)...)
+			continue
+		}
+
+		keyPEM = append(keyPEM, pem.EncodeToMemory(b)...)
+	}
+
+	return tls.X509KeyPair(certPEM, keyPEM)
+}
+
 // WithTimeout configures a timeout for all requests.
 func WithTimeout(timeout time.Duration) Option {
 	return func(c *Client) {
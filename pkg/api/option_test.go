@@ -2,9 +2,14 @@ package api_test
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"encoding/base64"
 	"fmt"
+	"io"
 	"net/http"
+	"net/http/httptest"
+	"os"
 	"runtime"
 	"strings"
 	"testing"
@@ -266,6 +271,91 @@ func TestOption_WithProxy(t *testing.T) {
 	assert.Eventually(t, func() bool { return numCalls == 1 }, time.Second, 50*time.Millisecond)
 }
 
+func TestOption_WithSSLClientCertFile(t *testing.T) {
+	tests := map[string]struct {
+		CertFile   string
+		KeyFile    string
+		Passphrase string
+	}{
+		"pem": {
+			CertFile: "testdata/client_cert.pem",
+			KeyFile:  "testdata/client_key.pem",
+		},
+		"pkcs12": {
+			CertFile:   "testdata/client.p12",
+			Passphrase: "secret",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			url, pool, tearDown := setupTestTLSServerWithClientAuth(t)
+			defer tearDown()
+
+			withClientCert, err := api.WithSSLClientCertFile(test.CertFile, test.KeyFile, test.Passphrase)
+			require.NoError(t, err)
+
+			c := api.NewClient("", api.WithSSLCertPool(pool), withClientCert)
+
+			req, err := http.NewRequest(http.MethodGet, url, nil)
+			require.NoError(t, err)
+
+			resp, err := c.Do(context.Background(), req)
+			require.NoError(t, err)
+
+			defer resp.Body.Close()
+
+			body, err := io.ReadAll(resp.Body)
+			require.NoError(t, err)
+
+			assert.Equal(t, http.StatusOK, resp.StatusCode)
+			assert.Equal(t, "wakatime-cli test client", string(body))
+		})
+	}
+}
+
+func TestOption_WithSSLClientCertFile_MissingCert(t *testing.T) {
+	url, pool, tearDown := setupTestTLSServerWithClientAuth(t)
+	defer tearDown()
+
+	c := api.NewClient("", api.WithSSLCertPool(pool))
+
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	require.NoError(t, err)
+
+	_, err = c.Do(context.Background(), req)
+	require.Error(t, err)
+}
+
+func TestOption_WithSSLClientCertFile_InvalidPassphrase(t *testing.T) {
+	_, err := api.WithSSLClientCertFile("testdata/client.p12", "", "invalid")
+
+	assert.ErrorContains(t, err, `failed to load client certificate "testdata/client.p12"`)
+}
+
+func setupTestTLSServerWithClientAuth(t *testing.T) (string, *x509.CertPool, func()) {
+	caCert, err := os.ReadFile("testdata/client_ca.pem")
+	require.NoError(t, err)
+
+	clientCAs := x509.NewCertPool()
+	require.True(t, clientCAs.AppendCertsFromPEM(caCert))
+
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		_, _ = w.Write([]byte(req.TLS.PeerCertificates[0].Subject.CommonName))
+	}))
+	srv.TLS = &tls.Config{
+		ClientAuth: tls.RequireAndVerifyClientCert,
+		ClientCAs:  clientCAs,
+		MinVersion: tls.VersionTLS12,
+	}
+	srv.StartTLS()
+
+	pool := x509.NewCertPool()
+	pool.AddCert(srv.Certificate())
+
+	return srv.URL, pool, srv.Close
+}
+
 func TestOption_WithUserAgent(t *testing.T) {
 	url, router, tearDown := setupTestServer()
 	defer tearDown()
@@ -0,0 +1,544 @@
+package api
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dop251/goja"
+)
+
+const (
+	// pacDNSTimeout is the timeout of dns lookups done by pac scripts.
+	pacDNSTimeout = 5 * time.Second
+	// pacDefaultTimeout is the default maximum time to run the pac script or
+	// its FindProxyForURL function.
+	pacDefaultTimeout = 10 * time.Second
+)
+
+// PAC evaluates a proxy auto-config script, to choose the proxy per request.
+type PAC struct {
+	// mu guards the javascript runtime, which is not safe for concurrent use.
+	mu       sync.Mutex
+	vm       *goja.Runtime
+	findFor  goja.Callable
+	resolver *Resolver
+	timeout  time.Duration
+}
+
+// PACOption is a functional option for LoadPAC and NewPAC.
+type PACOption func(*PAC)
+
+// WithPACResolver resolves hostnames of dnsResolve, isResolvable and isInNet with r,
+// and fetches pac scripts from urls with r, instead of the system resolver.
+func WithPACResolver(r *Resolver) PACOption {
+	return func(p *PAC) {
+		p.resolver = r
+	}
+}
+
+// WithPACTimeout sets the maximum time to run the pac script or its FindProxyForURL
+// function, after which it's interrupted. Defaults to 10 seconds.
+func WithPACTimeout(timeout time.Duration) PACOption {
+	return func(p *PAC) {
+		p.timeout = timeout
+	}
+}
+
+// LoadPAC loads a pac script from a http(s) url or a file path.
+func LoadPAC(ctx context.Context, location string, opts ...PACOption) (*PAC, error) {
+	var (
+		script []byte
+		err    error
+	)
+
+	if strings.HasPrefix(location, "http://") || strings.HasPrefix(location, "https://") {
+		p := newPAC(opts...)
+
+		script, err = fetchPAC(ctx, location, p.resolver)
+	} else {
+		script, err = os.ReadFile(location) // nolint:gosec
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to load pac script from %q: %s", location, err)
+	}
+
+	return NewPAC(string(script), opts...)
+}
+
+func fetchPAC(ctx context.Context, location string, resolver *Resolver) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, location, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	// the pac script itself is always fetched directly
+	transport := NewTransport()
+	if resolver != nil {
+		transport.DialContext = resolver.DialContext
+	}
+
+	client := &http.Client{Transport: transport}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close() // nolint:errcheck,gosec
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("invalid response status %d", resp.StatusCode)
+	}
+
+	return io.ReadAll(resp.Body)
+}
+
+// NewPAC compiles a pac script, which must define a FindProxyForURL(url, host) function.
+func NewPAC(script string, opts ...PACOption) (*PAC, error) {
+	p := newPAC(opts...)
+
+	for name, fn := range p.functions() {
+		if err := p.vm.Set(name, fn); err != nil {
+			return nil, fmt.Errorf("failed to define pac function %s: %s", name, err)
+		}
+	}
+
+	if _, err := p.run(func() (goja.Value, error) { return p.vm.RunString(script) }); err != nil {
+		return nil, fmt.Errorf("failed to run pac script: %s", err)
+	}
+
+	findFor, ok := goja.AssertFunction(p.vm.Get("FindProxyForURL"))
+	if !ok {
+		return nil, errors.New("pac script does not define a FindProxyForURL function")
+	}
+
+	p.findFor = findFor
+
+	return p, nil
+}
+
+func newPAC(opts ...PACOption) *PAC {
+	p := &PAC{
+		vm:      goja.New(),
+		timeout: pacDefaultTimeout,
+	}
+
+	for _, option := range opts {
+		option(p)
+	}
+
+	return p
+}
+
+// run runs fn, interrupting the javascript runtime when it takes longer than the timeout,
+// like for endless loops.
+func (p *PAC) run(fn func() (goja.Value, error)) (goja.Value, error) {
+	interrupted := make(chan struct{})
+
+	timer := time.AfterFunc(p.timeout, func() {
+		p.vm.Interrupt("timeout")
+		close(interrupted)
+	})
+
+	value, err := fn()
+
+	if !timer.Stop() {
+		// the interrupt may arrive after fn returned, and must not affect the next run
+		<-interrupted
+		p.vm.ClearInterrupt()
+	}
+
+	var interruptedErr *goja.InterruptedError
+	if errors.As(err, &interruptedErr) {
+		return nil, fmt.Errorf("timed out after %s", p.timeout)
+	}
+
+	return value, err
+}
+
+// FindProxyForURL returns the raw result of the pac script for an url, for ex:
+// "PROXY proxy.example.org:8080; DIRECT". Paths of https urls are not passed to
+// the script, as browsers do.
+func (p *PAC) FindProxyForURL(u *url.URL) (string, error) {
+	target := u.String()
+	if u.Scheme == "https" {
+		target = u.Scheme + "://" + u.Host + "/"
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	result, err := p.run(func() (goja.Value, error) {
+		return p.findFor(goja.Undefined(), p.vm.ToValue(target), p.vm.ToValue(u.Hostname()))
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to run FindProxyForURL: %s", err)
+	}
+
+	return result.String(), nil
+}
+
+// ProxyFunc returns a ProxyFunc using the first supported proxy returned by the pac script.
+func (p *PAC) ProxyFunc() ProxyFunc {
+	return func(u *url.URL) (*url.URL, error) {
+		result, err := p.FindProxyForURL(u)
+		if err != nil {
+			return nil, err
+		}
+
+		return ParsePACResult(result)
+	}
+}
+
+// ParsePACResult returns the first supported proxy of a pac result, or nil for DIRECT.
+// PROXY and HTTP entries are http proxies, HTTPS entries https proxies, and SOCKS and
+// SOCKS5 entries socks5 proxies. Other entries, like SOCKS4, are skipped.
+func ParsePACResult(result string) (*url.URL, error) {
+	for _, entry := range strings.Split(result, ";") {
+		fields := strings.Fields(entry)
+		if len(fields) == 0 {
+			continue
+		}
+
+		var scheme string
+
+		switch strings.ToUpper(fields[0]) {
+		case "DIRECT":
+			return nil, nil
+		case "PROXY", "HTTP":
+			scheme = "http"
+		case "HTTPS":
+			scheme = "https"
+		case "SOCKS", "SOCKS5":
+			scheme = "socks5"
+		default:
+			continue
+		}
+
+		if len(fields) < 2 {
+			continue
+		}
+
+		return &url.URL{Scheme: scheme, Host: fields[1]}, nil
+	}
+
+	return nil, fmt.Errorf("no supported proxy in pac result %q", result)
+}
+
+// functions returns the predefined functions available to pac scripts.
+func (p *PAC) functions() map[string]any {
+	return map[string]any{
+		"alert":               func(string) {},
+		"dnsDomainIs":         pacDNSDomainIs,
+		"dnsDomainLevels":     func(host string) int { return strings.Count(host, ".") },
+		"dnsResolve":          p.dnsResolve,
+		"isInNet":             p.isInNet,
+		"isPlainHostName":     func(host string) bool { return !strings.Contains(host, ".") },
+		"isResolvable":        func(host string) bool { return p.dnsResolve(host) != nil },
+		"localHostOrDomainIs": pacLocalHostOrDomainIs,
+		"myIpAddress":         pacMyIPAddress,
+		"shExpMatch":          pacShExpMatch,
+		"weekdayRange":        pacWeekdayRange,
+		"dateRange":           pacDateRange,
+		"timeRange":           pacTimeRange,
+		"convert_addr":        pacConvertAddr,
+	}
+}
+
+func pacDNSDomainIs(host, domain string) bool {
+	return strings.HasSuffix(strings.ToLower(host), strings.ToLower(domain))
+}
+
+func pacLocalHostOrDomainIs(host, hostdom string) bool {
+	host, hostdom = strings.ToLower(host), strings.ToLower(hostdom)
+
+	if host == hostdom {
+		return true
+	}
+
+	return !strings.Contains(host, ".") && strings.HasPrefix(hostdom, host+".")
+}
+
+// dnsResolve returns the first ipv4 address of a host, or nil (null) if it can't be resolved.
+func (p *PAC) dnsResolve(host string) any {
+	if ip := net.ParseIP(host); ip != nil {
+		return ip.String()
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), pacDNSTimeout)
+	defer cancel()
+
+	var (
+		ips []net.IP
+		err error
+	)
+
+	if p.resolver != nil {
+		ips, err = p.resolver.LookupIP(ctx, host, "")
+	} else {
+		ips, err = net.DefaultResolver.LookupIP(ctx, "ip", host)
+	}
+
+	if err != nil || len(ips) == 0 {
+		return nil
+	}
+
+	for _, ip := range ips {
+		if ip.To4() != nil {
+			return ip.String()
+		}
+	}
+
+	return ips[0].String()
+}
+
+func (p *PAC) isInNet(host, pattern, mask string) bool {
+	resolved, ok := p.dnsResolve(host).(string)
+	if !ok {
+		return false
+	}
+
+	ip, n, m := net.ParseIP(resolved).To4(), net.ParseIP(pattern).To4(), net.ParseIP(mask).To4()
+	if ip == nil || n == nil || m == nil {
+		return false
+	}
+
+	for i := range ip {
+		if ip[i]&m[i] != n[i]&m[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// pacMyIPAddress returns the first non loopback ipv4 address of this machine.
+func pacMyIPAddress() string {
+	addrs, err := net.InterfaceAddrs()
+	if err != nil {
+		return "127.0.0.1"
+	}
+
+	for _, addr := range addrs {
+		ipnet, ok := addr.(*net.IPNet)
+		if !ok || ipnet.IP.IsLoopback() || ipnet.IP.To4() == nil {
+			continue
+		}
+
+		return ipnet.IP.String()
+	}
+
+	return "127.0.0.1"
+}
+
+func pacShExpMatch(str, shexp string) bool {
+	pattern := regexp.QuoteMeta(shexp)
+	pattern = strings.ReplaceAll(pattern, `\*`, ".*")
+	pattern = strings.ReplaceAll(pattern, `\?`, ".")
+
+	matched, err := regexp.MatchString("^"+pattern+"$", str)
+
+	return err == nil && matched
+}
+
+func pacConvertAddr(ipchars string) int64 {
+	ip := net.ParseIP(ipchars).To4()
+	if ip == nil {
+		return 0
+	}
+
+	return int64(binary.BigEndian.Uint32(ip))
+}
+
+// pacNow returns the current time, in UTC if the last argument is "GMT", along with
+// the remaining arguments.
+func pacNow(args []goja.Value) (time.Time, []goja.Value) {
+	now := time.Now()
+
+	if len(args) > 0 && strings.EqualFold(args[len(args)-1].String(), "GMT") {
+		return now.UTC(), args[:len(args)-1]
+	}
+
+	return now, args
+}
+
+// pacInRange checks if value is within start and end, inclusive. Ranges wrap around
+// when start is greater than end, for ex: from friday to monday.
+func pacInRange(value, start, end int) bool {
+	if start <= end {
+		return value >= start && value <= end
+	}
+
+	return value >= start || value <= end
+}
+
+// nolint:gochecknoglobals
+var pacWeekdays = []string{"SUN", "MON", "TUE", "WED", "THU", "FRI", "SAT"}
+
+// nolint:gochecknoglobals
+var pacMonths = []string{"JAN", "FEB", "MAR", "APR", "MAY", "JUN", "JUL", "AUG", "SEP", "OCT", "NOV", "DEC"}
+
+func indexOf(list []string, s string) int {
+	for i, item := range list {
+		if item == strings.ToUpper(s) {
+			return i
+		}
+	}
+
+	return -1
+}
+
+func pacWeekdayRange(args ...goja.Value) bool {
+	now, args := pacNow(args)
+	if len(args) == 0 {
+		return false
+	}
+
+	start := indexOf(pacWeekdays, args[0].String())
+	end := start
+
+	if len(args) > 1 {
+		end = indexOf(pacWeekdays, args[1].String())
+	}
+
+	if start < 0 || end < 0 {
+		return false
+	}
+
+	return pacInRange(int(now.Weekday()), start, end)
+}
+
+func pacTimeRange(args ...goja.Value) bool {
+	now, args := pacNow(args)
+
+	values := make([]int, len(args))
+	for i, arg := range args {
+		values[i] = int(arg.ToInteger())
+	}
+
+	secs := now.Hour()*3600 + now.Minute()*60 + now.Second()
+
+	switch len(values) {
+	case 1:
+		return now.Hour() == values[0]
+	case 2:
+		// the end hour is exclusive, for ex: 9 to 17 ends at 17:00
+		return pacInRange(secs, values[0]*3600, values[1]*3600-1)
+	case 4:
+		return pacInRange(secs, values[0]*3600+values[1]*60, values[2]*3600+values[3]*60)
+	case 6:
+		return pacInRange(
+			secs,
+			values[0]*3600+values[1]*60+values[2],
+			values[3]*3600+values[4]*60+values[5],
+		)
+	default:
+		return false
+	}
+}
+
+// pacDate contains the components of a dateRange argument list. Missing components are -1.
+type pacDate struct {
+	Year, Month, Day int
+}
+
+func parsePACDate(args []goja.Value) (pacDate, bool) {
+	d := pacDate{Year: -1, Month: -1, Day: -1}
+
+	for _, arg := range args {
+		if month := indexOf(pacMonths, arg.String()); month >= 0 {
+			d.Month = month
+			continue
+		}
+
+		n, err := strconv.Atoi(arg.String())
+		if err != nil {
+			return pacDate{}, false
+		}
+
+		if n > 31 {
+			d.Year = n
+		} else {
+			d.Day = n
+		}
+	}
+
+	return d, true
+}
+
+// value returns the comparable value of t, using only the components set in d.
+func (d pacDate) value(t time.Time) int {
+	var v int
+
+	if d.Year >= 0 {
+		v = t.Year()
+	}
+
+	if d.Month >= 0 {
+		v = v*100 + int(t.Month()) - 1
+	}
+
+	if d.Day >= 0 {
+		v = v*100 + t.Day()
+	}
+
+	return v
+}
+
+func (d pacDate) own() int {
+	var v int
+
+	if d.Year >= 0 {
+		v = d.Year
+	}
+
+	if d.Month >= 0 {
+		v = v*100 + d.Month
+	}
+
+	if d.Day >= 0 {
+		v = v*100 + d.Day
+	}
+
+	return v
+}
+
+func pacDateRange(args ...goja.Value) bool {
+	now, args := pacNow(args)
+	if len(args) == 0 {
+		return false
+	}
+
+	if len(args)%2 == 1 {
+		d, ok := parsePACDate(args)
+		return ok && d.value(now) == d.own()
+	}
+
+	start, ok := parsePACDate(args[:len(args)/2])
+	if !ok {
+		return false
+	}
+
+	end, ok := parsePACDate(args[len(args)/2:])
+	if !ok || start.Year >= 0 != (end.Year >= 0) || start.Month >= 0 != (end.Month >= 0) ||
+		start.Day >= 0 != (end.Day >= 0) {
+		return false
+	}
+
+	return pacInRange(start.value(now), start.own(), end.own())
+}
@@ -0,0 +1,317 @@
+package api_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/wakatime/wakatime-cli/pkg/api"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testPACScript = `
+function FindProxyForURL(url, host) {
+	if (isPlainHostName(host) || dnsDomainIs(host, ".internal.example.org")) {
+		return "DIRECT";
+	}
+
+	if (isInNet(host, "10.0.0.0", "255.0.0.0")) {
+		return "SOCKS proxy.example.org:1080";
+	}
+
+	if (shExpMatch(url, "https://*.wakatime.com/*")) {
+		return "HTTPS secure.example.org:443; DIRECT";
+	}
+
+	if (localHostOrDomainIs(host, "www.example.org") && weekdayRange("SUN", "SAT")) {
+		return "PROXY www-proxy.example.org:3128";
+	}
+
+	return "PROXY proxy.example.org:8080; DIRECT";
+}
+`
+
+func TestPAC_FindProxyForURL(t *testing.T) {
+	tests := map[string]struct {
+		URL      string
+		Expected string
+	}{
+		"plain hostname": {
+			URL:      "http://intranet/page",
+			Expected: "DIRECT",
+		},
+		"domain": {
+			URL:      "https://git.internal.example.org/repo",
+			Expected: "DIRECT",
+		},
+		"ip in net": {
+			URL:      "http://10.1.2.3:8080",
+			Expected: "SOCKS proxy.example.org:1080",
+		},
+		"shell expression": {
+			URL:      "https://api.wakatime.com/api/v1/users/current",
+			Expected: "HTTPS secure.example.org:443; DIRECT",
+		},
+		"local host or domain": {
+			URL:      "http://www.example.org/index.html",
+			Expected: "PROXY www-proxy.example.org:3128",
+		},
+		"default": {
+			URL:      "https://example.com",
+			Expected: "PROXY proxy.example.org:8080; DIRECT",
+		},
+	}
+
+	pac, err := api.NewPAC(testPACScript)
+	require.NoError(t, err)
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			u, err := url.Parse(test.URL)
+			require.NoError(t, err)
+
+			result, err := pac.FindProxyForURL(u)
+			require.NoError(t, err)
+
+			assert.Equal(t, test.Expected, result)
+		})
+	}
+}
+
+func TestPAC_FindProxyForURL_HTTPSPathStripped(t *testing.T) {
+	pac, err := api.NewPAC(`function FindProxyForURL(url, host) { return "PROXY " + url; }`)
+	require.NoError(t, err)
+
+	u, err := url.Parse("https://api.wakatime.com/api/v1/users/current?api_key=secret")
+	require.NoError(t, err)
+
+	result, err := pac.FindProxyForURL(u)
+	require.NoError(t, err)
+
+	assert.Equal(t, "PROXY https://api.wakatime.com/", result)
+}
+
+func TestPAC_FindProxyForURL_TimeFunctions(t *testing.T) {
+	pac, err := api.NewPAC(`
+function FindProxyForURL(url, host) {
+	return [
+		weekdayRange("MON", "SUN"),
+		weekdayRange("SUN", "SAT", "GMT"),
+		timeRange(0, 24),
+		timeRange(0, 0, 0, 23, 59, 59, "GMT"),
+		dateRange("JAN", "DEC"),
+		dateRange(1, 31),
+		dateRange(1970, 2999),
+		weekdayRange("XYZ"),
+		dateRange(),
+	].join(",");
+}
+`)
+	require.NoError(t, err)
+
+	result, err := pac.FindProxyForURL(&url.URL{Scheme: "https", Host: "example.org"})
+	require.NoError(t, err)
+
+	assert.Equal(t, "true,true,true,true,true,true,true,false,false", result)
+}
+
+func TestPAC_FindProxyForURL_Resolver(t *testing.T) {
+	resolver, err := api.NewResolver(api.ResolverConfig{
+		Hosts: []string{"proxy.example.invalid:*:10.1.2.3"},
+	})
+	require.NoError(t, err)
+
+	pac, err := api.NewPAC(`
+function FindProxyForURL(url, host) {
+	return dnsResolve("proxy.example.invalid") + "," + isInNet("proxy.example.invalid", "10.1.0.0", "255.255.0.0");
+}
+`, api.WithPACResolver(resolver))
+	require.NoError(t, err)
+
+	result, err := pac.FindProxyForURL(&url.URL{Scheme: "https", Host: "example.org"})
+	require.NoError(t, err)
+
+	assert.Equal(t, "10.1.2.3,true", result)
+}
+
+func TestPAC_FindProxyForURL_Timeout(t *testing.T) {
+	pac, err := api.NewPAC(`
+function FindProxyForURL(url, host) {
+	if (host === "loop.example.org") {
+		while(true){}
+	}
+
+	return "DIRECT";
+}
+`, api.WithPACTimeout(100*time.Millisecond))
+	require.NoError(t, err)
+
+	_, err = pac.FindProxyForURL(&url.URL{Scheme: "https", Host: "loop.example.org"})
+	assert.EqualError(t, err, "failed to run FindProxyForURL: timed out after 100ms")
+
+	// the runtime can still be used after an interrupt
+	result, err := pac.FindProxyForURL(&url.URL{Scheme: "https", Host: "example.org"})
+	require.NoError(t, err)
+
+	assert.Equal(t, "DIRECT", result)
+}
+
+func TestNewPAC_Err(t *testing.T) {
+	tests := map[string]struct {
+		Script   string
+		Expected string
+	}{
+		"syntax error": {
+			Script:   "function FindProxyForURL(url, host) {",
+			Expected: "failed to run pac script",
+		},
+		"endless loop": {
+			Script:   "while(true){}",
+			Expected: "failed to run pac script: timed out after 100ms",
+		},
+		"missing function": {
+			Script:   "var proxy = 'DIRECT';",
+			Expected: "pac script does not define a FindProxyForURL function",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := api.NewPAC(test.Script, api.WithPACTimeout(100*time.Millisecond))
+			require.Error(t, err)
+
+			assert.Contains(t, err.Error(), test.Expected)
+		})
+	}
+}
+
+func TestLoadPAC(t *testing.T) {
+	testServerURL, router, tearDown := setupTestServer()
+	defer tearDown()
+
+	router.HandleFunc("/proxy.pac", func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/x-ns-proxy-autoconfig")
+		_, _ = w.Write([]byte(testPACScript))
+	})
+
+	fp := filepath.Join(t.TempDir(), "proxy.pac")
+
+	err := os.WriteFile(fp, []byte(testPACScript), 0600)
+	require.NoError(t, err)
+
+	for name, location := range map[string]string{"url": testServerURL + "/proxy.pac", "file": fp} {
+		t.Run(name, func(t *testing.T) {
+			pac, err := api.LoadPAC(context.Background(), location)
+			require.NoError(t, err)
+
+			proxyURL, err := pac.ProxyFunc()(&url.URL{Scheme: "https", Host: "example.com"})
+			require.NoError(t, err)
+
+			assert.Equal(t, "http://proxy.example.org:8080", proxyURL.String())
+		})
+	}
+}
+
+func TestLoadPAC_NotFound(t *testing.T) {
+	testServerURL, _, tearDown := setupTestServer()
+	defer tearDown()
+
+	_, err := api.LoadPAC(context.Background(), testServerURL+"/proxy.pac")
+
+	assert.EqualError(t, err, fmt.Sprintf("failed to load pac script from %q: invalid response status 404", testServerURL+"/proxy.pac"))
+}
+
+func TestParsePACResult(t *testing.T) {
+	tests := map[string]struct {
+		Result   string
+		Expected *url.URL
+	}{
+		"direct": {
+			Result: "DIRECT",
+		},
+		"proxy": {
+			Result:   "PROXY proxy.example.org:8080",
+			Expected: &url.URL{Scheme: "http", Host: "proxy.example.org:8080"},
+		},
+		"http": {
+			Result:   "HTTP proxy.example.org:8080",
+			Expected: &url.URL{Scheme: "http", Host: "proxy.example.org:8080"},
+		},
+		"https": {
+			Result:   "HTTPS proxy.example.org:443",
+			Expected: &url.URL{Scheme: "https", Host: "proxy.example.org:443"},
+		},
+		"socks": {
+			Result:   "SOCKS proxy.example.org:1080",
+			Expected: &url.URL{Scheme: "socks5", Host: "proxy.example.org:1080"},
+		},
+		"first entry wins": {
+			Result:   "PROXY first.example.org:8080; PROXY second.example.org:8080; DIRECT",
+			Expected: &url.URL{Scheme: "http", Host: "first.example.org:8080"},
+		},
+		"unsupported entries skipped": {
+			Result:   "SOCKS4 proxy.example.org:1080; SOCKS5 proxy.example.org:1081",
+			Expected: &url.URL{Scheme: "socks5", Host: "proxy.example.org:1081"},
+		},
+		"case insensitive": {
+			Result: " direct ",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			proxyURL, err := api.ParsePACResult(test.Result)
+			require.NoError(t, err)
+
+			assert.Equal(t, test.Expected, proxyURL)
+		})
+	}
+}
+
+func TestParsePACResult_Err(t *testing.T) {
+	_, err := api.ParsePACResult("SOCKS4 proxy.example.org:1080")
+
+	assert.EqualError(t, err, `no supported proxy in pac result "SOCKS4 proxy.example.org:1080"`)
+}
+
+func TestOption_WithProxyFunc_PAC(t *testing.T) {
+	testServerURL, router, tearDown := setupTestServer()
+	defer tearDown()
+
+	var numCalls int
+
+	router.HandleFunc("/", func(w http.ResponseWriter, req *http.Request) {
+		numCalls++
+
+		// requests to the proxy contain the absolute url
+		assert.Equal(t, "http://example.org/", req.RequestURI)
+
+		w.WriteHeader(http.StatusOK)
+	})
+
+	u, err := url.Parse(testServerURL)
+	require.NoError(t, err)
+
+	pac, err := api.NewPAC(fmt.Sprintf(`function FindProxyForURL(url, host) { return "PROXY %s"; }`, u.Host))
+	require.NoError(t, err)
+
+	c := api.NewClient("", api.WithProxyFunc(pac.ProxyFunc()))
+
+	req, err := http.NewRequest(http.MethodGet, "http://example.org/", nil)
+	require.NoError(t, err)
+
+	resp, err := c.Do(context.Background(), req)
+	require.NoError(t, err)
+
+	defer resp.Body.Close()
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, 1, numCalls)
+}
@@ -0,0 +1,128 @@
+package api
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/wakatime/wakatime-cli/pkg/log"
+)
+
+// sslPinPrefix is the optional prefix of pins, as used by HPKP.
+const sslPinPrefix = "sha256/"
+
+// SPKIPin returns the base64 encoded SHA-256 hash of the certificate's subject public key info.
+func SPKIPin(cert *x509.Certificate) string {
+	sum := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// ParseSSLPin validates a base64 encoded SHA-256 SPKI pin, optionally prefixed
+// with "sha256/", and returns it without prefix.
+func ParseSSLPin(pin string) (string, error) {
+	pin = strings.TrimPrefix(strings.TrimSpace(pin), sslPinPrefix)
+
+	decoded, err := base64.StdEncoding.DecodeString(pin)
+	if err != nil {
+		return "", fmt.Errorf("invalid ssl pin %q: %s", pin, err)
+	}
+
+	if len(decoded) != sha256.Size {
+		return "", fmt.Errorf("invalid ssl pin %q: expected %d bytes sha256 hash but got %d bytes", pin, sha256.Size, len(decoded))
+	}
+
+	return pin, nil
+}
+
+// WithSSLPins pins the tls certificates of the passed in hosts to SHA-256 SPKI hashes.
+// A connection is accepted when any certificate of the chain matches any pin of the host.
+// Hosts without pins are not affected. Hosts are matched by tls server name, so ip
+// addresses can't be pinned. In report only mode, mismatches are logged and
+// connections are accepted. Otherwise the client doesn't retry with the api ip
+// address on dns errors.
+//
+// Pins are checked by VerifyConnection instead of VerifyPeerCertificate, which knows
+// the server name and also runs on resumed sessions.
+func WithSSLPins(ctx context.Context, pins map[string][]string, reportOnly bool) (Option, error) {
+	parsed := make(map[string]map[string]struct{}, len(pins))
+
+	for host, hostPins := range pins {
+		host = strings.ToLower(strings.TrimSpace(host))
+
+		if _, ok := parsed[host]; !ok {
+			parsed[host] = map[string]struct{}{}
+		}
+
+		for _, pin := range hostPins {
+			p, err := ParseSSLPin(pin)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse pins of %q: %s", host, err)
+			}
+
+			parsed[host][p] = struct{}{}
+		}
+	}
+
+	return func(c *Client) {
+		logger := log.Extract(ctx)
+
+		if !reportOnly {
+			for _, hostPins := range parsed {
+				if len(hostPins) > 0 {
+					c.pinned = true
+				}
+			}
+		}
+
+		transport := LazyCreateNewTransport(c)
+
+		tlsConfig := transport.TLSClientConfig
+		tlsConfig.VerifyConnection = func(cs tls.ConnectionState) error {
+			host := strings.ToLower(cs.ServerName)
+
+			hostPins, ok := parsed[host]
+			if !ok || len(hostPins) == 0 {
+				return nil
+			}
+
+			// verified chains are empty when ssl verification is disabled
+			chains := cs.VerifiedChains
+			if len(chains) == 0 {
+				chains = [][]*x509.Certificate{cs.PeerCertificates}
+			}
+
+			var got []string
+
+			for _, chain := range chains {
+				for _, cert := range chain {
+					pin := SPKIPin(cert)
+					if _, ok := hostPins[pin]; ok {
+						return nil
+					}
+
+					if !slices.Contains(got, sslPinPrefix+pin) {
+						got = append(got, sslPinPrefix+pin)
+					}
+				}
+			}
+
+			err := fmt.Errorf("none of the certificate pins %s matched", strings.Join(got, ", "))
+
+			if reportOnly {
+				logger.Warnf("certificate pin mismatch for %s, report only: %s", host, err)
+				return nil
+			}
+
+			return ErrCertPin{Err: err, Host: host}
+		}
+
+		transport.TLSClientConfig = tlsConfig
+
+		c.client.Transport = transport
+	}, nil
+}
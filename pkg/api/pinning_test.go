@@ -0,0 +1,132 @@
+package api_test
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/wakatime/wakatime-cli/pkg/api"
+	"github.com/wakatime/wakatime-cli/pkg/exitcode"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOption_WithSSLPins(t *testing.T) {
+	url, pool, pin, tearDown := setupTestPinnedServer(t, http.StatusOK)
+	defer tearDown()
+
+	tests := map[string]struct {
+		Pins       map[string][]string
+		ReportOnly bool
+		Err        bool
+	}{
+		"matching pin": {
+			Pins: map[string][]string{"localhost": {"sha256/" + pin}},
+		},
+		"matching pin without prefix": {
+			Pins: map[string][]string{"localhost": {"47DEQpj8HBSa+/TImW+5JCeuQeRkm5NMpJWZG3hSuFU=", pin}},
+		},
+		"mismatching pin": {
+			Pins: map[string][]string{"localhost": {"sha256/47DEQpj8HBSa+/TImW+5JCeuQeRkm5NMpJWZG3hSuFU="}},
+			Err:  true,
+		},
+		"mismatching pin report only": {
+			Pins:       map[string][]string{"localhost": {"sha256/47DEQpj8HBSa+/TImW+5JCeuQeRkm5NMpJWZG3hSuFU="}},
+			ReportOnly: true,
+		},
+		"other host": {
+			Pins: map[string][]string{"api.wakatime.com": {"sha256/47DEQpj8HBSa+/TImW+5JCeuQeRkm5NMpJWZG3hSuFU="}},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			withSSLPins, err := api.WithSSLPins(context.Background(), test.Pins, test.ReportOnly)
+			require.NoError(t, err)
+
+			c := api.NewClient("", api.WithSSLCertPool(pool), withSSLPins)
+
+			req, err := http.NewRequest(http.MethodGet, url, nil)
+			require.NoError(t, err)
+
+			resp, err := c.Do(context.Background(), req)
+
+			if test.Err {
+				var errpin api.ErrCertPin
+
+				require.True(t, errors.As(err, &errpin))
+
+				assert.Equal(t, "localhost", errpin.Host)
+				assert.Equal(t, exitcode.ErrCertPin, errpin.ExitCode())
+				assert.Contains(t, errpin.Error(), "sha256/"+pin)
+
+				return
+			}
+
+			require.NoError(t, err)
+
+			defer resp.Body.Close()
+
+			assert.Equal(t, http.StatusOK, resp.StatusCode)
+		})
+	}
+}
+
+func TestOption_WithSSLPins_InvalidPin(t *testing.T) {
+	_, err := api.WithSSLPins(context.Background(), map[string][]string{"api.wakatime.com": {"sha256/invalid"}}, false)
+
+	assert.ErrorContains(t, err, `failed to parse pins of "api.wakatime.com": invalid ssl pin "invalid"`)
+}
+
+func TestClient_SendHeartbeats_ErrCertPin(t *testing.T) {
+	url, pool, _, tearDown := setupTestPinnedServer(t, http.StatusCreated)
+	defer tearDown()
+
+	withSSLPins, err := api.WithSSLPins(
+		context.Background(),
+		map[string][]string{"localhost": {"sha256/47DEQpj8HBSa+/TImW+5JCeuQeRkm5NMpJWZG3hSuFU="}},
+		false,
+	)
+	require.NoError(t, err)
+
+	c := api.NewClient(url, api.WithSSLCertPool(pool), withSSLPins)
+
+	_, err = c.SendHeartbeats(context.Background(), testHeartbeats())
+
+	var errpin api.ErrCertPin
+
+	assert.ErrorAs(t, err, &errpin)
+}
+
+// setupTestPinnedServer starts a tls server for localhost and returns its url,
+// a cert pool trusting it and the pin of its certificate.
+func setupTestPinnedServer(t *testing.T, status int) (string, *x509.CertPool, string, func()) {
+	cert, err := tls.LoadX509KeyPair("testdata/server_cert.pem", "testdata/server_key.pem")
+	require.NoError(t, err)
+
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(status)
+	}))
+	srv.TLS = &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+	}
+	srv.StartTLS()
+
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	require.NoError(t, err)
+
+	pool := x509.NewCertPool()
+	pool.AddCert(leaf)
+
+	_, port, err := net.SplitHostPort(srv.Listener.Addr().String())
+	require.NoError(t, err)
+
+	return "https://localhost:" + port, pool, api.SPKIPin(leaf), srv.Close
+}
@@ -0,0 +1,260 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"golang.org/x/net/proxy"
+)
+
+// ProxyFunc returns the proxy url to use for a request url, or nil to connect directly.
+// Supported proxy url schemes are http, https, socks5 and socks5h.
+type ProxyFunc func(*url.URL) (*url.URL, error)
+
+// proxyDecisionKey is the context key of the proxy chosen for a request.
+type proxyDecisionKey struct{}
+
+type proxyDecision struct {
+	url *url.URL
+	err error
+}
+
+// WithProxyFunc configures the client to proxy outgoing requests to the proxy returned by fn.
+// Http and https proxies are handled by the transport. For socks5 proxies, connections are
+// dialed through the proxy, resolving hostnames locally for socks5 and by the proxy for socks5h.
+func WithProxyFunc(fn ProxyFunc) Option {
+	return func(c *Client) {
+		dialer := &net.Dialer{
+			Timeout:   30 * time.Second,
+			KeepAlive: 30 * time.Second,
+		}
+
+		// use the custom resolver for direct connections and to connect to proxies
+		resolver := c.resolver
+
+		dial := dialer.DialContext
+		if resolver != nil {
+			dial = resolver.DialContext
+		}
+
+		transport := LazyCreateNewTransport(c)
+		transport.Proxy = func(req *http.Request) (*url.URL, error) {
+			u, err := decideProxy(req.Context(), fn, req.URL)
+			if err != nil || u == nil || isSOCKSProxy(u) {
+				return nil, err
+			}
+
+			return u, nil
+		}
+		transport.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+			decision, ok := ctx.Value(proxyDecisionKey{}).(proxyDecision)
+			if !ok || decision.err != nil || decision.url == nil || !isSOCKSProxy(decision.url) {
+				// direct connection, or to a http proxy
+				return dial(ctx, network, addr)
+			}
+
+			return dialSOCKS(ctx, resolver, decision.url, network, addr)
+		}
+
+		c.client.Transport = transport
+
+		// choose the proxy once per request, so transport and dialer agree on it
+		next := c.doFunc
+		c.doFunc = func(c *Client, req *http.Request) (*http.Response, error) {
+			u, err := fn(req.URL)
+
+			ctx := context.WithValue(req.Context(), proxyDecisionKey{}, proxyDecision{url: u, err: err})
+
+			return next(c, req.WithContext(ctx))
+		}
+	}
+}
+
+// decideProxy returns the proxy chosen for the request, or calls fn if none was chosen yet.
+func decideProxy(ctx context.Context, fn ProxyFunc, u *url.URL) (*url.URL, error) {
+	if decision, ok := ctx.Value(proxyDecisionKey{}).(proxyDecision); ok {
+		return decision.url, decision.err
+	}
+
+	return fn(u)
+}
+
+func isSOCKSProxy(u *url.URL) bool {
+	return u.Scheme == "socks5" || u.Scheme == "socks5h"
+}
+
+// dialFunc adapts a dial function to the proxy.Dialer and proxy.ContextDialer interfaces.
+type dialFunc func(ctx context.Context, network, addr string) (net.Conn, error)
+
+func (f dialFunc) Dial(network, addr string) (net.Conn, error) {
+	return f(context.Background(), network, addr)
+}
+
+func (f dialFunc) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	return f(ctx, network, addr)
+}
+
+// dialSOCKS connects to addr through a socks5 proxy, authenticating with the
+// user info of the proxy url, if any. Hostnames are resolved with resolver,
+// or the system resolver if nil.
+func dialSOCKS(ctx context.Context, resolver *Resolver, proxyURL *url.URL, network, addr string) (net.Conn, error) {
+	var auth *proxy.Auth
+
+	if proxyURL.User != nil {
+		password, _ := proxyURL.User.Password()
+
+		auth = &proxy.Auth{
+			User:     proxyURL.User.Username(),
+			Password: password,
+		}
+	}
+
+	proxyAddr := proxyURL.Host
+	if proxyURL.Port() == "" {
+		proxyAddr = net.JoinHostPort(proxyURL.Hostname(), "1080")
+	}
+
+	var forward proxy.Dialer = &net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+	}
+
+	if resolver != nil {
+		forward = dialFunc(resolver.DialContext)
+	}
+
+	d, err := proxy.SOCKS5("tcp", proxyAddr, auth, forward)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create socks5 dialer for %q: %s", proxyAddr, err)
+	}
+
+	if proxyURL.Scheme == "socks5" {
+		resolved, err := resolveAddr(ctx, resolver, addr)
+		if err != nil {
+			return nil, err
+		}
+
+		addr = resolved
+	}
+
+	return d.(proxy.ContextDialer).DialContext(ctx, network, addr)
+}
+
+// resolveAddr replaces the hostname of addr by its first ip address. Resolves with
+// resolver, or the system resolver if nil.
+func resolveAddr(ctx context.Context, resolver *Resolver, addr string) (string, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return "", err
+	}
+
+	if net.ParseIP(host) != nil {
+		return addr, nil
+	}
+
+	if resolver != nil {
+		ips, err := resolver.LookupIP(ctx, host, port)
+		if err != nil {
+			return "", fmt.Errorf("failed to resolve %q: %w", host, err)
+		}
+
+		return net.JoinHostPort(ips[0].String(), port), nil
+	}
+
+	ips, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve %q: %w", host, err)
+	}
+
+	if len(ips) == 0 {
+		return "", fmt.Errorf("failed to resolve %q: no ip addresses found", host)
+	}
+
+	return net.JoinHostPort(ips[0].IP.String(), port), nil
+}
+
+// ProxyBypass wraps fn to connect directly to hosts matching the NO_PROXY style bypass list.
+// Entries are hostnames, matching the host and its subdomains, domains with a leading dot
+// or "*.", matching only subdomains, ip addresses, cidr ranges or "*" to match all hosts.
+// Each entry can be followed by a port, to only match that port.
+func ProxyBypass(fn ProxyFunc, noProxy []string) ProxyFunc {
+	return func(u *url.URL) (*url.URL, error) {
+		if matchNoProxy(u, noProxy) {
+			return nil, nil
+		}
+
+		return fn(u)
+	}
+}
+
+func matchNoProxy(u *url.URL, noProxy []string) bool {
+	host := strings.ToLower(u.Hostname())
+
+	port := u.Port()
+	if port == "" {
+		switch u.Scheme {
+		case "https":
+			port = "443"
+		case "http":
+			port = "80"
+		}
+	}
+
+	for _, entry := range noProxy {
+		entry = strings.ToLower(strings.TrimSpace(entry))
+		if entry == "" {
+			continue
+		}
+
+		if entry == "*" {
+			return true
+		}
+
+		if _, ipnet, err := net.ParseCIDR(entry); err == nil {
+			if ip := net.ParseIP(host); ip != nil && ipnet.Contains(ip) {
+				return true
+			}
+
+			continue
+		}
+
+		entryHost, entryPort := entry, ""
+		if h, p, err := net.SplitHostPort(entry); err == nil {
+			entryHost, entryPort = h, p
+		}
+
+		if entryPort != "" && entryPort != port {
+			continue
+		}
+
+		if ip := net.ParseIP(entryHost); ip != nil {
+			if ip.Equal(net.ParseIP(host)) {
+				return true
+			}
+
+			continue
+		}
+
+		switch {
+		case strings.HasPrefix(entryHost, "*."):
+			if strings.HasSuffix(host, entryHost[1:]) {
+				return true
+			}
+		case strings.HasPrefix(entryHost, "."):
+			if strings.HasSuffix(host, entryHost) {
+				return true
+			}
+		default:
+			if host == entryHost || strings.HasSuffix(host, "."+entryHost) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
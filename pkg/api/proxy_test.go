@@ -0,0 +1,295 @@
+package api_test
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/wakatime/wakatime-cli/pkg/api"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOption_WithProxyFunc_SOCKS5(t *testing.T) {
+	tests := map[string]struct {
+		Scheme   string
+		Expected string
+	}{
+		"socks5 resolves locally": {
+			Scheme:   "socks5",
+			Expected: "ip",
+		},
+		"socks5h resolves by proxy": {
+			Scheme:   "socks5h",
+			Expected: "domain",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			testServerURL, router, tearDown := setupTestServer()
+			defer tearDown()
+
+			var numCalls int
+
+			router.HandleFunc("/", func(w http.ResponseWriter, _ *http.Request) {
+				numCalls++
+
+				w.WriteHeader(http.StatusOK)
+			})
+
+			target, err := url.Parse(testServerURL)
+			require.NoError(t, err)
+
+			proxyAddr, addrTypes := setupTestSOCKS5Server(t, target.Host)
+
+			proxyURL := &url.URL{Scheme: test.Scheme, Host: proxyAddr}
+
+			c := api.NewClient("", api.WithProxyFunc(func(*url.URL) (*url.URL, error) {
+				return proxyURL, nil
+			}))
+
+			req, err := http.NewRequest(http.MethodGet, "http://localhost:"+target.Port(), nil)
+			require.NoError(t, err)
+
+			resp, err := c.Do(context.Background(), req)
+			require.NoError(t, err)
+
+			defer resp.Body.Close()
+
+			assert.Equal(t, http.StatusOK, resp.StatusCode)
+			assert.Equal(t, 1, numCalls)
+			assert.Equal(t, test.Expected, <-addrTypes)
+		})
+	}
+}
+
+func TestOption_WithProxyFunc_Direct(t *testing.T) {
+	testServerURL, router, tearDown := setupTestServer()
+	defer tearDown()
+
+	var numCalls int
+
+	router.HandleFunc("/", func(w http.ResponseWriter, _ *http.Request) {
+		numCalls++
+
+		w.WriteHeader(http.StatusOK)
+	})
+
+	// unreachable proxy, which must be bypassed
+	proxyFunc := api.ProxyBypass(func(*url.URL) (*url.URL, error) {
+		return &url.URL{Scheme: "http", Host: "localhost:1"}, nil
+	}, []string{"127.0.0.1"})
+
+	c := api.NewClient("", api.WithProxyFunc(proxyFunc))
+
+	req, err := http.NewRequest(http.MethodGet, testServerURL, nil)
+	require.NoError(t, err)
+
+	resp, err := c.Do(context.Background(), req)
+	require.NoError(t, err)
+
+	defer resp.Body.Close()
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, 1, numCalls)
+}
+
+func TestProxyBypass(t *testing.T) {
+	tests := map[string]struct {
+		URL      string
+		NoProxy  []string
+		Bypassed bool
+	}{
+		"wildcard": {
+			URL:      "https://api.wakatime.com/api/v1",
+			NoProxy:  []string{"*"},
+			Bypassed: true,
+		},
+		"hostname": {
+			URL:      "https://example.org",
+			NoProxy:  []string{"example.org"},
+			Bypassed: true,
+		},
+		"hostname matches subdomain": {
+			URL:      "https://api.example.org",
+			NoProxy:  []string{"example.org"},
+			Bypassed: true,
+		},
+		"hostname does not match suffix": {
+			URL:     "https://badexample.org",
+			NoProxy: []string{"example.org"},
+		},
+		"leading dot matches subdomain": {
+			URL:      "https://api.example.org",
+			NoProxy:  []string{".example.org"},
+			Bypassed: true,
+		},
+		"leading dot does not match domain": {
+			URL:     "https://example.org",
+			NoProxy: []string{".example.org"},
+		},
+		"leading wildcard matches subdomain": {
+			URL:      "https://api.example.org",
+			NoProxy:  []string{"*.example.org"},
+			Bypassed: true,
+		},
+		"case insensitive": {
+			URL:      "https://API.Example.org",
+			NoProxy:  []string{"example.ORG"},
+			Bypassed: true,
+		},
+		"ip address": {
+			URL:      "http://10.1.2.3:8080",
+			NoProxy:  []string{"10.1.2.3"},
+			Bypassed: true,
+		},
+		"cidr": {
+			URL:      "http://10.1.2.3:8080",
+			NoProxy:  []string{"10.0.0.0/8"},
+			Bypassed: true,
+		},
+		"cidr does not match hostname": {
+			URL:     "http://example.org",
+			NoProxy: []string{"10.0.0.0/8"},
+		},
+		"ipv6 cidr": {
+			URL:      "http://[fd00::1]:8080",
+			NoProxy:  []string{"fd00::/8"},
+			Bypassed: true,
+		},
+		"port": {
+			URL:      "https://example.org",
+			NoProxy:  []string{"example.org:443"},
+			Bypassed: true,
+		},
+		"port mismatch": {
+			URL:     "https://example.org",
+			NoProxy: []string{"example.org:8443"},
+		},
+		"no match": {
+			URL:     "https://api.wakatime.com",
+			NoProxy: []string{"localhost", "", ".internal"},
+		},
+	}
+
+	proxyURL := &url.URL{Scheme: "http", Host: "proxy.example.org:3128"}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			fn := api.ProxyBypass(func(*url.URL) (*url.URL, error) {
+				return proxyURL, nil
+			}, test.NoProxy)
+
+			u, err := url.Parse(test.URL)
+			require.NoError(t, err)
+
+			result, err := fn(u)
+			require.NoError(t, err)
+
+			if test.Bypassed {
+				assert.Nil(t, result)
+				return
+			}
+
+			assert.Equal(t, proxyURL, result)
+		})
+	}
+}
+
+// setupTestSOCKS5Server starts a minimal socks5 server without authentication, which
+// forwards all connect requests to target. Returns its address and a channel receiving
+// the address type of each connect request, "ip" or "domain".
+func setupTestSOCKS5Server(t *testing.T, target string) (string, <-chan string) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	t.Cleanup(func() { ln.Close() })
+
+	addrTypes := make(chan string, 10)
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+
+			go handleTestSOCKS5Conn(conn, target, addrTypes)
+		}
+	}()
+
+	return ln.Addr().String(), addrTypes
+}
+
+func handleTestSOCKS5Conn(conn net.Conn, target string, addrTypes chan<- string) {
+	defer conn.Close()
+
+	// greeting: version, number of methods, methods
+	header := make([]byte, 2)
+	if _, err := io.ReadFull(conn, header); err != nil {
+		return
+	}
+
+	if _, err := io.ReadFull(conn, make([]byte, header[1])); err != nil {
+		return
+	}
+
+	if _, err := conn.Write([]byte{0x05, 0x00}); err != nil {
+		return
+	}
+
+	// request: version, command, reserved, address type
+	request := make([]byte, 4)
+	if _, err := io.ReadFull(conn, request); err != nil {
+		return
+	}
+
+	var addrLen int
+
+	switch request[3] {
+	case 0x01:
+		addrLen = net.IPv4len
+		addrTypes <- "ip"
+	case 0x04:
+		addrLen = net.IPv6len
+		addrTypes <- "ip"
+	case 0x03:
+		size := make([]byte, 1)
+		if _, err := io.ReadFull(conn, size); err != nil {
+			return
+		}
+
+		addrLen = int(size[0])
+		addrTypes <- "domain"
+	default:
+		return
+	}
+
+	// address and port are ignored, all connections go to target
+	if _, err := io.ReadFull(conn, make([]byte, addrLen+2)); err != nil {
+		return
+	}
+
+	upstream, err := net.Dial("tcp", target)
+	if err != nil {
+		_, _ = conn.Write([]byte{0x05, 0x01, 0x00, 0x01, 0, 0, 0, 0, 0, 0})
+		return
+	}
+
+	defer upstream.Close()
+
+	if _, err := conn.Write([]byte{0x05, 0x00, 0x00, 0x01, 127, 0, 0, 1, 0, 0}); err != nil {
+		return
+	}
+
+	go func() {
+		_, _ = io.Copy(upstream, conn)
+	}()
+
+	_, _ = io.Copy(conn, upstream)
+}
@@ -0,0 +1,69 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// resetEpochThreshold separates X-RateLimit-Reset values sent as unix timestamps
+// from values sent as seconds until reset. It's roughly one year in seconds.
+const resetEpochThreshold = 365 * 24 * 60 * 60
+
+// ParseRateLimit creates an ErrRateLimited from the rate limit headers of a 429 response.
+// Retry-After can be in seconds or an http date. X-RateLimit-Reset can be a unix
+// timestamp or seconds until reset.
+func ParseRateLimit(err error, header http.Header, now time.Time) ErrRateLimited {
+	e := ErrRateLimited{
+		Err:        err,
+		RetryAfter: parseRetryAfter(header.Get("Retry-After"), now),
+		Limit:      parseHeaderInt(header.Get("X-RateLimit-Limit")),
+		Remaining:  parseHeaderInt(header.Get("X-RateLimit-Remaining")),
+	}
+
+	if reset := parseHeaderInt(header.Get("X-RateLimit-Reset")); reset >= 0 {
+		if reset > resetEpochThreshold {
+			e.Reset = time.Unix(int64(reset), 0)
+		} else {
+			e.Reset = now.Add(time.Duration(reset) * time.Second)
+		}
+	}
+
+	if e.RetryAfter.IsZero() && e.Remaining == 0 && e.Reset.After(now) {
+		e.RetryAfter = e.Reset
+	}
+
+	return e
+}
+
+func parseRetryAfter(value string, now time.Time) time.Time {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return time.Time{}
+	}
+
+	if secs, err := strconv.Atoi(value); err == nil {
+		if secs < 0 {
+			return time.Time{}
+		}
+
+		return now.Add(time.Duration(secs) * time.Second)
+	}
+
+	if t, err := http.ParseTime(value); err == nil {
+		return t
+	}
+
+	return time.Time{}
+}
+
+// parseHeaderInt parses a non-negative integer header value, or returns -1.
+func parseHeaderInt(value string) int {
+	i, err := strconv.Atoi(strings.TrimSpace(value))
+	if err != nil || i < 0 {
+		return -1
+	}
+
+	return i
+}
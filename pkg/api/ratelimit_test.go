@@ -0,0 +1,115 @@
+package api_test
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/wakatime/wakatime-cli/pkg/api"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseRateLimit(t *testing.T) {
+	now := time.Date(2024, 3, 10, 12, 0, 0, 0, time.UTC)
+
+	tests := map[string]struct {
+		Header   http.Header
+		Expected api.ErrRateLimited
+	}{
+		"retry after seconds": {
+			Header: http.Header{
+				"Retry-After": []string{"30"},
+			},
+			Expected: api.ErrRateLimited{
+				RetryAfter: now.Add(30 * time.Second),
+				Limit:      -1,
+				Remaining:  -1,
+			},
+		},
+		"retry after http date": {
+			Header: http.Header{
+				"Retry-After": []string{"Sun, 10 Mar 2024 12:05:00 GMT"},
+			},
+			Expected: api.ErrRateLimited{
+				RetryAfter: time.Date(2024, 3, 10, 12, 5, 0, 0, time.UTC),
+				Limit:      -1,
+				Remaining:  -1,
+			},
+		},
+		"reset as unix timestamp": {
+			Header: http.Header{
+				"X-Ratelimit-Limit":     []string{"100"},
+				"X-Ratelimit-Remaining": []string{"0"},
+				"X-Ratelimit-Reset":     []string{"1710072300"},
+			},
+			Expected: api.ErrRateLimited{
+				RetryAfter: time.Unix(1710072300, 0),
+				Limit:      100,
+				Remaining:  0,
+				Reset:      time.Unix(1710072300, 0),
+			},
+		},
+		"reset as seconds": {
+			Header: http.Header{
+				"X-Ratelimit-Remaining": []string{"0"},
+				"X-Ratelimit-Reset":     []string{"60"},
+			},
+			Expected: api.ErrRateLimited{
+				RetryAfter: now.Add(60 * time.Second),
+				Limit:      -1,
+				Remaining:  0,
+				Reset:      now.Add(60 * time.Second),
+			},
+		},
+		"retry after takes precedence over reset": {
+			Header: http.Header{
+				"Retry-After":           []string{"10"},
+				"X-Ratelimit-Remaining": []string{"0"},
+				"X-Ratelimit-Reset":     []string{"60"},
+			},
+			Expected: api.ErrRateLimited{
+				RetryAfter: now.Add(10 * time.Second),
+				Limit:      -1,
+				Remaining:  0,
+				Reset:      now.Add(60 * time.Second),
+			},
+		},
+		"requests remaining": {
+			Header: http.Header{
+				"X-Ratelimit-Remaining": []string{"5"},
+				"X-Ratelimit-Reset":     []string{"60"},
+			},
+			Expected: api.ErrRateLimited{
+				Limit:     -1,
+				Remaining: 5,
+				Reset:     now.Add(60 * time.Second),
+			},
+		},
+		"invalid headers": {
+			Header: http.Header{
+				"Retry-After":       []string{"soon"},
+				"X-Ratelimit-Limit": []string{"-3"},
+			},
+			Expected: api.ErrRateLimited{
+				Limit:     -1,
+				Remaining: -1,
+			},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			err := errors.New("too many requests")
+
+			result := api.ParseRateLimit(err, test.Header, now)
+
+			assert.True(t, test.Expected.RetryAfter.Equal(result.RetryAfter), "retry after %s", result.RetryAfter)
+			assert.True(t, test.Expected.Reset.Equal(result.Reset), "reset %s", result.Reset)
+			assert.Equal(t, test.Expected.Limit, result.Limit)
+			assert.Equal(t, test.Expected.Remaining, result.Remaining)
+			assert.Equal(t, err, result.Err)
+		})
+	}
+}
@@ -0,0 +1,495 @@
+package api
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/wakatime/wakatime-cli/pkg/log"
+	"github.com/wakatime/wakatime-cli/pkg/version"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// redacted replaces secrets in recorded requests and responses.
+const redacted = "REDACTED"
+
+// nolint:gochecknoglobals
+var (
+	// recordSeq makes file names unique, when requests are recorded concurrently.
+	recordSeq atomic.Uint64
+	// unsafeFileNameChars are replaced in recorded file names.
+	unsafeFileNameChars = regexp.MustCompile(`[^a-zA-Z0-9.-]+`)
+	// secretHeaders are redacted in recorded requests and responses.
+	secretHeaders = []string{"Authorization", "Cookie", "Proxy-Authorization", "Set-Cookie"}
+	// secretQueryParams are redacted in recorded urls.
+	secretQueryParams = []string{"api_key", "access_token"}
+	// secretBodyFields are redacted in recorded form and json bodies, like the
+	// tokens of oauth token requests and responses.
+	secretBodyFields = []string{"access_token", "api_key", "client_secret", "device_code", "refresh_token"}
+)
+
+// WithRecordHTTP writes each request and its response to a HAR (HTTP Archive) file
+// in dir, to debug rejected requests. Auth headers, cookies, the api key or token
+// of the request and the tokens of form and json bodies are redacted everywhere,
+// and compressed request bodies are decoded.
+// Recording failures are logged and don't fail requests.
+//
+// Should be the first option, to record requests as sent by the transport.
+func WithRecordHTTP(ctx context.Context, dir string) Option {
+	return func(c *Client) {
+		logger := log.Extract(ctx)
+
+		next := c.doFunc
+		c.doFunc = func(c *Client, req *http.Request) (*http.Response, error) {
+			reqBody, err := copyRequestBody(req)
+			if err != nil {
+				return nil, err
+			}
+
+			started := time.Now()
+
+			resp, err := next(c, req)
+			if err != nil {
+				return nil, err
+			}
+
+			respBody, err := io.ReadAll(resp.Body)
+			_ = resp.Body.Close()
+
+			resp.Body = io.NopCloser(bytes.NewReader(respBody))
+
+			if err != nil {
+				return nil, fmt.Errorf("failed to read response body: %s", err)
+			}
+
+			entry := newHAREntry(req, reqBody, resp, respBody, started, time.Since(started))
+
+			fp, err := writeHAR(dir, entry)
+			if err != nil {
+				logger.Warnf("failed to record http request: %s", err)
+				return resp, nil
+			}
+
+			logger.Debugf("recorded http request to %s", fp)
+
+			return resp, nil
+		}
+	}
+}
+
+// copyRequestBody returns a copy of the request body, restoring the body if consumed.
+// Returns nil if there is no body.
+func copyRequestBody(req *http.Request) ([]byte, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil, nil
+	}
+
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get request body: %s", err)
+		}
+
+		defer body.Close() // nolint:errcheck
+
+		return io.ReadAll(body)
+	}
+
+	data, err := io.ReadAll(req.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read request body: %s", err)
+	}
+
+	_ = req.Body.Close()
+
+	req.Body = io.NopCloser(bytes.NewReader(data))
+
+	return data, nil
+}
+
+// har is the root of a HTTP Archive file, see http://www.softwareishard.com/blog/har-12-spec.
+type har struct {
+	Log harLog `json:"log"`
+}
+
+type harLog struct {
+	Version string     `json:"version"`
+	Creator harCreator `json:"creator"`
+	Entries []harEntry `json:"entries"`
+}
+
+type harCreator struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+type harEntry struct {
+	StartedDateTime time.Time   `json:"startedDateTime"`
+	Time            float64     `json:"time"`
+	Request         harRequest  `json:"request"`
+	Response        harResponse `json:"response"`
+	Cache           struct{}    `json:"cache"`
+	Timings         harTimings  `json:"timings"`
+}
+
+type harRequest struct {
+	Method      string         `json:"method"`
+	URL         string         `json:"url"`
+	HTTPVersion string         `json:"httpVersion"`
+	Cookies     []harNameValue `json:"cookies"`
+	Headers     []harNameValue `json:"headers"`
+	QueryString []harNameValue `json:"queryString"`
+	PostData    *harPostData   `json:"postData,omitempty"`
+	HeadersSize int            `json:"headersSize"`
+	BodySize    int            `json:"bodySize"`
+}
+
+type harResponse struct {
+	Status      int            `json:"status"`
+	StatusText  string         `json:"statusText"`
+	HTTPVersion string         `json:"httpVersion"`
+	Cookies     []harNameValue `json:"cookies"`
+	Headers     []harNameValue `json:"headers"`
+	Content     harContent     `json:"content"`
+	RedirectURL string         `json:"redirectURL"`
+	HeadersSize int            `json:"headersSize"`
+	BodySize    int            `json:"bodySize"`
+}
+
+type harNameValue struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type harPostData struct {
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text"`
+}
+
+type harContent struct {
+	Size     int    `json:"size"`
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text"`
+}
+
+type harTimings struct {
+	Send    float64 `json:"send"`
+	Wait    float64 `json:"wait"`
+	Receive float64 `json:"receive"`
+}
+
+func newHAREntry(
+	req *http.Request,
+	reqBody []byte,
+	resp *http.Response,
+	respBody []byte,
+	started time.Time,
+	duration time.Duration,
+) harEntry {
+	var reqText []byte
+
+	if reqBody != nil {
+		text, err := decompress(reqBody, req.Header.Get("Content-Encoding"))
+		if err != nil {
+			text = []byte(base64.StdEncoding.EncodeToString(reqBody))
+		}
+
+		reqText = text
+	}
+
+	r := newRedactor(req)
+	r.addBodySecrets(req.Header.Get("Content-Type"), reqText)
+	r.addBodySecrets(resp.Header.Get("Content-Type"), respBody)
+
+	u := r.url(req.URL)
+
+	var query []harNameValue
+
+	for _, name := range sortedKeys(u.Query()) {
+		for _, value := range u.Query()[name] {
+			query = append(query, harNameValue{Name: name, Value: value})
+		}
+	}
+
+	entry := harEntry{
+		StartedDateTime: started,
+		Time:            float64(duration.Microseconds()) / 1000,
+		Request: harRequest{
+			Method:      req.Method,
+			URL:         u.String(),
+			HTTPVersion: req.Proto,
+			Cookies:     []harNameValue{},
+			Headers:     r.headers(req.Header),
+			QueryString: query,
+			HeadersSize: -1,
+			BodySize:    len(reqBody),
+		},
+		Response: harResponse{
+			Status:      resp.StatusCode,
+			StatusText:  http.StatusText(resp.StatusCode),
+			HTTPVersion: resp.Proto,
+			Cookies:     []harNameValue{},
+			Headers:     r.headers(resp.Header),
+			Content: harContent{
+				Size:     len(respBody),
+				MimeType: resp.Header.Get("Content-Type"),
+				Text:     r.text(respBody),
+			},
+			RedirectURL: resp.Header.Get("Location"),
+			HeadersSize: -1,
+			BodySize:    len(respBody),
+		},
+		Timings: harTimings{
+			Wait: float64(duration.Microseconds()) / 1000,
+		},
+	}
+
+	if entry.Request.QueryString == nil {
+		entry.Request.QueryString = []harNameValue{}
+	}
+
+	if reqBody != nil {
+		entry.Request.PostData = &harPostData{
+			MimeType: req.Header.Get("Content-Type"),
+			Text:     r.text(reqText),
+		}
+	}
+
+	return entry
+}
+
+// writeHAR writes the entry to a new HAR file in dir and returns its path.
+func writeHAR(dir string, entry harEntry) (string, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", fmt.Errorf("failed to create record directory: %s", err)
+	}
+
+	data, err := json.MarshalIndent(har{
+		Log: harLog{
+			Version: "1.2",
+			Creator: harCreator{Name: "wakatime-cli", Version: version.Version},
+			Entries: []harEntry{entry},
+		},
+	}, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to json encode har: %s", err)
+	}
+
+	u, _ := url.Parse(entry.Request.URL)
+
+	name := fmt.Sprintf(
+		"%s-%04d-%s%s.har",
+		entry.StartedDateTime.UTC().Format("20060102T150405.000000Z"),
+		recordSeq.Add(1),
+		entry.Request.Method,
+		unsafeFileNameChars.ReplaceAllString(u.Path, "_"),
+	)
+
+	fp := filepath.Join(dir, name)
+
+	if err := os.WriteFile(fp, data, 0600); err != nil {
+		return "", fmt.Errorf("failed to write har file: %s", err)
+	}
+
+	return fp, nil
+}
+
+// redactor replaces the secrets of a request, like the api key of basic auth.
+type redactor struct {
+	secrets []string
+}
+
+func newRedactor(req *http.Request) redactor {
+	var r redactor
+
+	for _, name := range []string{"Authorization", "Proxy-Authorization"} {
+		scheme, credentials, ok := strings.Cut(req.Header.Get(name), " ")
+		if !ok || credentials == "" {
+			continue
+		}
+
+		r.secrets = append(r.secrets, credentials)
+
+		if !strings.EqualFold(scheme, "basic") {
+			continue
+		}
+
+		decoded, err := base64.StdEncoding.DecodeString(credentials)
+		if err != nil {
+			continue
+		}
+
+		user, password, _ := strings.Cut(string(decoded), ":")
+
+		for _, s := range []string{user, password} {
+			if len(s) >= 8 {
+				r.secrets = append(r.secrets, s)
+			}
+		}
+	}
+
+	for _, name := range secretQueryParams {
+		if value := req.URL.Query().Get(name); value != "" {
+			r.secrets = append(r.secrets, value)
+		}
+	}
+
+	return r
+}
+
+// addBodySecrets adds the values of the secret fields of a form or json body.
+// Bodies of other content types or which can't be parsed are skipped.
+func (r *redactor) addBodySecrets(contentType string, body []byte) {
+	if len(body) == 0 {
+		return
+	}
+
+	mediaType, _, _ := strings.Cut(contentType, ";")
+
+	values := map[string]string{}
+
+	switch strings.ToLower(strings.TrimSpace(mediaType)) {
+	case "application/x-www-form-urlencoded":
+		form, err := url.ParseQuery(string(body))
+		if err != nil {
+			return
+		}
+
+		for name := range form {
+			values[name] = form.Get(name)
+		}
+	case "application/json":
+		var fields map[string]any
+		if err := json.Unmarshal(body, &fields); err != nil {
+			return
+		}
+
+		for name, value := range fields {
+			if s, ok := value.(string); ok {
+				values[name] = s
+			}
+		}
+	default:
+		return
+	}
+
+	for _, name := range secretBodyFields {
+		if value := values[name]; value != "" {
+			r.secrets = append(r.secrets, value)
+		}
+	}
+}
+
+func (r redactor) text(data []byte) string {
+	s := string(data)
+
+	for _, secret := range r.secrets {
+		s = strings.ReplaceAll(s, secret, redacted)
+	}
+
+	return s
+}
+
+func (r redactor) url(u *url.URL) *url.URL {
+	redactedURL := *u
+	redactedURL.User = nil
+
+	query := u.Query()
+
+	for _, name := range secretQueryParams {
+		if query.Has(name) {
+			query.Set(name, redacted)
+		}
+	}
+
+	if u.RawQuery != "" {
+		redactedURL.RawQuery = query.Encode()
+	}
+
+	redactedURL.Path = r.text([]byte(u.Path))
+	redactedURL.RawPath = ""
+
+	return &redactedURL
+}
+
+func (r redactor) headers(header http.Header) []harNameValue {
+	headers := []harNameValue{}
+
+	for _, name := range sortedKeys(header) {
+		for _, value := range header[name] {
+			if isSecretHeader(name) {
+				scheme, _, ok := strings.Cut(value, " ")
+				if ok && (name == "Authorization" || name == "Proxy-Authorization") {
+					value = scheme + " " + redacted
+				} else {
+					value = redacted
+				}
+			}
+
+			headers = append(headers, harNameValue{Name: name, Value: r.text([]byte(value))})
+		}
+	}
+
+	return headers
+}
+
+func isSecretHeader(name string) bool {
+	for _, secret := range secretHeaders {
+		if strings.EqualFold(name, secret) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+
+	return keys
+}
+
+// decompress decodes data compressed with the given content encoding.
+func decompress(data []byte, encoding string) ([]byte, error) {
+	switch Compression(strings.ToLower(encoding)) {
+	case "":
+		return data, nil
+	case CompressionGzip:
+		r, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+
+		return io.ReadAll(r)
+	case CompressionZstd:
+		r, err := zstd.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+
+		defer r.Close()
+
+		return io.ReadAll(r)
+	default:
+		return nil, fmt.Errorf("unsupported encoding %q", encoding)
+	}
+}
@@ -0,0 +1,270 @@
+package api_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/wakatime/wakatime-cli/pkg/api"
+	"github.com/wakatime/wakatime-cli/pkg/oauth"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOption_WithRecordHTTP(t *testing.T) {
+	url, router, tearDown := setupTestServer()
+	defer tearDown()
+
+	router.HandleFunc("/users/current/heartbeats.bulk", func(w http.ResponseWriter, _ *http.Request) {
+		f, err := os.Open("testdata/api_heartbeats_response.json")
+		require.NoError(t, err)
+
+		defer f.Close()
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Set-Cookie", "session=secret")
+		w.WriteHeader(http.StatusCreated)
+
+		_, err = io.Copy(w, f)
+		require.NoError(t, err)
+	})
+
+	dir := filepath.Join(t.TempDir(), "records")
+
+	c := api.NewClient(url, api.WithRecordHTTP(context.Background(), dir))
+
+	results, err := c.SendHeartbeats(context.Background(), testHeartbeats())
+	require.NoError(t, err)
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.har"))
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+
+	assert.Regexp(t, `^\d{8}T\d{6}\.\d{6}Z-\d{4}-POST_users_current_heartbeats.bulk\.har$`, filepath.Base(files[0]))
+
+	data, err := os.ReadFile(files[0])
+	require.NoError(t, err)
+
+	// api key is redacted everywhere
+	assert.NotContains(t, string(data), "00000000-0000-4000-8000-000000000000")
+	assert.NotContains(t, string(data), "MDAwMDAwMDAtMDAwMC00MDAwLTgwMDAtMDAwMDAwMDAwMDAw")
+	assert.NotContains(t, string(data), "session=secret")
+
+	var archive struct {
+		Log struct {
+			Entries []struct {
+				Request struct {
+					Method   string `json:"method"`
+					URL      string `json:"url"`
+					Headers  []struct{ Name, Value string }
+					PostData struct {
+						Text string `json:"text"`
+					} `json:"postData"`
+				} `json:"request"`
+				Response struct {
+					Status  int `json:"status"`
+					Content struct {
+						Text string `json:"text"`
+					} `json:"content"`
+				} `json:"response"`
+			} `json:"entries"`
+		} `json:"log"`
+	}
+
+	err = json.Unmarshal(data, &archive)
+	require.NoError(t, err)
+	require.Len(t, archive.Log.Entries, 1)
+
+	entry := archive.Log.Entries[0]
+
+	assert.Equal(t, http.MethodPost, entry.Request.Method)
+	assert.Equal(t, url+"/users/current/heartbeats.bulk", entry.Request.URL)
+	assert.Contains(t, entry.Request.Headers, struct{ Name, Value string }{"Authorization", "Basic REDACTED"})
+
+	expectedBody, err := os.ReadFile("testdata/api_heartbeats_request.json")
+	require.NoError(t, err)
+
+	assert.JSONEq(t, string(expectedBody), entry.Request.PostData.Text)
+
+	assert.Equal(t, http.StatusCreated, entry.Response.Status)
+	assert.NotEmpty(t, entry.Response.Content.Text)
+
+	// replay the recorded response
+	replay, err := api.WithReplayHTTP(dir)
+	require.NoError(t, err)
+
+	c = api.NewClient("http://localhost:1", replay)
+
+	replayed, err := c.SendHeartbeats(context.Background(), testHeartbeats())
+	require.NoError(t, err)
+
+	assert.Equal(t, results, replayed)
+}
+
+func TestOption_WithRecordHTTP_OAuthTokens(t *testing.T) {
+	url, router, tearDown := setupTestServer()
+	defer tearDown()
+
+	router.HandleFunc("/oauth/token", func(w http.ResponseWriter, req *http.Request) {
+		assert.Equal(t, "old-refresh-token", req.PostFormValue("refresh_token"))
+
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"access_token":  "new-access-token",
+			"token_type":    "bearer",
+			"refresh_token": "new-refresh-token",
+		})
+	})
+
+	dir := filepath.Join(t.TempDir(), "records")
+
+	c := api.NewClient(url, api.WithRecordHTTP(context.Background(), dir))
+
+	token, err := oauth.RefreshToken(context.Background(), c, oauth.Config{
+		ClientID: "wakatime-cli",
+		TokenURL: url + "/oauth/token",
+	}, "old-refresh-token")
+	require.NoError(t, err)
+
+	assert.Equal(t, "new-access-token", token.AccessToken)
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.har"))
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+
+	data, err := os.ReadFile(files[0])
+	require.NoError(t, err)
+
+	assert.NotContains(t, string(data), "old-refresh-token")
+	assert.NotContains(t, string(data), "new-access-token")
+	assert.NotContains(t, string(data), "new-refresh-token")
+	assert.Contains(t, string(data), "refresh_token=REDACTED")
+	assert.Contains(t, string(data), `\"access_token\":\"REDACTED\"`)
+}
+
+func TestOption_WithRecordHTTP_Compressed(t *testing.T) {
+	url, router, tearDown := setupTestServer()
+	defer tearDown()
+
+	router.HandleFunc("/users/current/heartbeats.bulk", func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+	})
+
+	dir := t.TempDir()
+
+	c := api.NewClient(url, api.WithRecordHTTP(context.Background(), dir))
+
+	body := strings.Repeat(`{"entity":"/tmp/main.go"}`, 100)
+
+	var buf bytes.Buffer
+
+	w := gzip.NewWriter(&buf)
+	_, err := w.Write([]byte(body))
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+
+	req, err := http.NewRequest(http.MethodPost, url+"/users/current/heartbeats.bulk", &buf)
+	require.NoError(t, err)
+
+	req.Header.Set("Content-Encoding", "gzip")
+
+	resp, err := c.Do(context.Background(), req)
+	require.NoError(t, err)
+
+	defer resp.Body.Close()
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.har"))
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+
+	data, err := os.ReadFile(files[0])
+	require.NoError(t, err)
+
+	var archive struct {
+		Log struct {
+			Entries []struct {
+				Request struct {
+					PostData struct {
+						Text string `json:"text"`
+					} `json:"postData"`
+				} `json:"request"`
+			} `json:"entries"`
+		} `json:"log"`
+	}
+
+	err = json.Unmarshal(data, &archive)
+	require.NoError(t, err)
+	require.Len(t, archive.Log.Entries, 1)
+
+	// compressed request body is recorded decoded
+	assert.Equal(t, body, archive.Log.Entries[0].Request.PostData.Text)
+}
+
+func TestReplayTransport(t *testing.T) {
+	dir := t.TempDir()
+
+	for i, status := range []int{http.StatusInternalServerError, http.StatusCreated} {
+		url, router, tearDown := setupTestServer()
+
+		router.HandleFunc("/users/current/heartbeats.bulk", func(w http.ResponseWriter, _ *http.Request) {
+			w.WriteHeader(status)
+			_, _ = w.Write([]byte(`{"responses": []}`))
+		})
+
+		c := api.NewClient(url, api.WithRecordHTTP(context.Background(), dir))
+
+		req, err := http.NewRequest(http.MethodPost, url+"/users/current/heartbeats.bulk?api_key=secret", nil)
+		require.NoError(t, err)
+
+		resp, err := c.Do(context.Background(), req)
+		require.NoError(t, err)
+
+		resp.Body.Close()
+		tearDown()
+
+		files, err := filepath.Glob(filepath.Join(dir, "*.har"))
+		require.NoError(t, err)
+		require.Len(t, files, i+1)
+	}
+
+	transport, err := api.NewReplayTransport(dir)
+	require.NoError(t, err)
+
+	// served in recording order, repeating the last response
+	for _, expected := range []int{http.StatusInternalServerError, http.StatusCreated, http.StatusCreated} {
+		req, err := http.NewRequest(http.MethodPost, "https://api.example.org/users/current/heartbeats.bulk?api_key=other", nil)
+		require.NoError(t, err)
+
+		resp, err := transport.RoundTrip(req)
+		require.NoError(t, err)
+
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+
+		assert.Equal(t, expected, resp.StatusCode)
+		assert.Equal(t, `{"responses": []}`, string(body))
+	}
+
+	req, err := http.NewRequest(http.MethodGet, "https://api.example.org/users/current/statusbar/today", nil)
+	require.NoError(t, err)
+
+	_, err = transport.RoundTrip(req)
+
+	assert.EqualError(t, err, "no recorded response for GET /users/current/statusbar/today")
+}
+
+func TestNewReplayTransport_NoFiles(t *testing.T) {
+	dir := t.TempDir()
+
+	_, err := api.NewReplayTransport(dir)
+
+	assert.EqualError(t, err, "no har files found in \""+dir+"\"")
+}
@@ -0,0 +1,152 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ReplayTransport serves responses recorded by WithRecordHTTP, instead of sending requests.
+// Requests are matched by method, path and query, ignoring the host. Responses recorded
+// for the same request are served in recording order, repeating the last one.
+type ReplayTransport struct {
+	mu      sync.Mutex
+	entries map[string][]harEntry
+	served  map[string]int
+}
+
+var _ http.RoundTripper = (*ReplayTransport)(nil)
+
+// NewReplayTransport loads the HAR files in dir.
+func NewReplayTransport(dir string) (*ReplayTransport, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.har"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list har files: %s", err)
+	}
+
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no har files found in %q", dir)
+	}
+
+	var entries []harEntry
+
+	for _, fp := range files {
+		data, err := os.ReadFile(fp) // nolint:gosec
+		if err != nil {
+			return nil, fmt.Errorf("failed to read har file: %s", err)
+		}
+
+		var archive har
+		if err := json.Unmarshal(data, &archive); err != nil {
+			return nil, fmt.Errorf("failed to parse har file %q: %s", fp, err)
+		}
+
+		entries = append(entries, archive.Log.Entries...)
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].StartedDateTime.Before(entries[j].StartedDateTime)
+	})
+
+	t := &ReplayTransport{
+		entries: map[string][]harEntry{},
+		served:  map[string]int{},
+	}
+
+	for _, entry := range entries {
+		u, err := url.Parse(entry.Request.URL)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse recorded url %q: %s", entry.Request.URL, err)
+		}
+
+		key := replayKey(entry.Request.Method, u)
+		t.entries[key] = append(t.entries[key], entry)
+	}
+
+	return t, nil
+}
+
+// RoundTrip implements http.RoundTripper interface.
+func (t *ReplayTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Body != nil {
+		_ = req.Body.Close()
+	}
+
+	key := replayKey(req.Method, req.URL)
+
+	t.mu.Lock()
+
+	entries := t.entries[key]
+	if len(entries) == 0 {
+		t.mu.Unlock()
+		return nil, fmt.Errorf("no recorded response for %s", key)
+	}
+
+	i := t.served[key]
+	if i >= len(entries) {
+		i = len(entries) - 1
+	}
+
+	t.served[key]++
+
+	t.mu.Unlock()
+
+	recorded := entries[i].Response
+
+	header := http.Header{}
+	for _, h := range recorded.Headers {
+		header.Add(h.Name, h.Value)
+	}
+
+	// the body is recorded decoded
+	header.Del("Content-Encoding")
+	header.Set("Content-Length", strconv.Itoa(len(recorded.Content.Text)))
+
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", recorded.Status, http.StatusText(recorded.Status)),
+		StatusCode:    recorded.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(strings.NewReader(recorded.Content.Text)),
+		ContentLength: int64(len(recorded.Content.Text)),
+		Request:       req,
+	}, nil
+}
+
+// WithReplayHTTP serves responses recorded by WithRecordHTTP in dir, instead of sending
+// requests. Should be the first option.
+func WithReplayHTTP(dir string) (Option, error) {
+	transport, err := NewReplayTransport(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(c *Client) {
+		c.doFunc = func(_ *Client, req *http.Request) (*http.Response, error) {
+			return transport.RoundTrip(req)
+		}
+	}, nil
+}
+
+// replayKey returns the key matching requests to recorded responses. Secret query
+// params are redacted as in recordings.
+func replayKey(method string, u *url.URL) string {
+	redactedURL := redactor{}.url(u)
+
+	key := method + " " + redactedURL.Path
+	if redactedURL.RawQuery != "" {
+		key += "?" + redactedURL.RawQuery
+	}
+
+	return key
+}
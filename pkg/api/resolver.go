@@ -0,0 +1,593 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// IPPreference controls which ip address family is connected to first.
+type IPPreference string
+
+const (
+	// IPPreferenceAuto keeps the order of resolved addresses, racing the other
+	// address family after the happy eyeballs delay.
+	IPPreferenceAuto IPPreference = "auto"
+	// IPPreferenceIPv4 connects to ipv4 addresses first, falling back to ipv6.
+	IPPreferenceIPv4 IPPreference = "ipv4"
+	// IPPreferenceIPv6 connects to ipv6 addresses first, falling back to ipv4.
+	IPPreferenceIPv6 IPPreference = "ipv6"
+	// IPPreferenceIPv4Only only connects to ipv4 addresses.
+	IPPreferenceIPv4Only IPPreference = "ipv4_only"
+	// IPPreferenceIPv6Only only connects to ipv6 addresses.
+	IPPreferenceIPv6Only IPPreference = "ipv6_only"
+)
+
+const (
+	// defaultFallbackDelay is the happy eyeballs delay before racing the other
+	// address family, as recommended by RFC 6555.
+	defaultFallbackDelay = 300 * time.Millisecond
+	// dohTimeout is the timeout of dns over https queries.
+	dohTimeout = 10 * time.Second
+)
+
+// ParseIPPreference parses an ip preference. Empty string is returned as is,
+// and defaults to auto.
+func ParseIPPreference(s string) (IPPreference, error) {
+	switch p := IPPreference(strings.ToLower(strings.TrimSpace(s))); p {
+	case "", IPPreferenceAuto, IPPreferenceIPv4, IPPreferenceIPv6, IPPreferenceIPv4Only, IPPreferenceIPv6Only:
+		return p, nil
+	default:
+		return "", fmt.Errorf(
+			"invalid ip preference %q, expected one of %q, %q, %q, %q or %q",
+			s, IPPreferenceAuto, IPPreferenceIPv4, IPPreferenceIPv6, IPPreferenceIPv4Only, IPPreferenceIPv6Only,
+		)
+	}
+}
+
+// ResolverConfig contains the settings of a Resolver.
+type ResolverConfig struct {
+	// DNSServers are the dns servers queried instead of the system's, as ip with optional port.
+	DNSServers []string
+	// DoHURL is the url of a dns over https (RFC 8484) endpoint, taking precedence over DNSServers.
+	DoHURL string
+	// FallbackDelay is the happy eyeballs delay. Zero defaults to 300ms, and a negative
+	// delay connects to all addresses one after another.
+	FallbackDelay time.Duration
+	// Hosts are static host mappings in the format of curl's --resolve, "host:port:addr[,addr]...".
+	// Port can be "*" to match all ports.
+	Hosts []string
+	// IPPreference controls which ip address family is connected to first.
+	IPPreference IPPreference
+}
+
+// Enabled returns true if any setting differs from the system's default resolving.
+func (c ResolverConfig) Enabled() bool {
+	return len(c.DNSServers) > 0 ||
+		c.DoHURL != "" ||
+		c.FallbackDelay != 0 ||
+		len(c.Hosts) > 0 ||
+		(c.IPPreference != "" && c.IPPreference != IPPreferenceAuto)
+}
+
+// Resolver resolves hostnames with static host mappings, custom dns servers or
+// dns over https, and dials connections racing ipv4 and ipv6 addresses (happy eyeballs).
+type Resolver struct {
+	dialer        *net.Dialer
+	fallbackDelay time.Duration
+	hosts         map[string][]net.IP
+	lookup        func(ctx context.Context, host string) ([]net.IP, error)
+	preference    IPPreference
+}
+
+// NewResolver creates a new Resolver.
+func NewResolver(config ResolverConfig) (*Resolver, error) {
+	preference, err := ParseIPPreference(string(config.IPPreference))
+	if err != nil {
+		return nil, err
+	}
+
+	hosts, err := parseResolveHosts(config.Hosts)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &Resolver{
+		dialer: &net.Dialer{
+			Timeout:   30 * time.Second,
+			KeepAlive: 30 * time.Second,
+		},
+		fallbackDelay: config.FallbackDelay,
+		hosts:         hosts,
+		lookup:        systemLookup(net.DefaultResolver),
+		preference:    preference,
+	}
+
+	if len(config.DNSServers) > 0 {
+		servers, err := parseDNSServers(config.DNSServers)
+		if err != nil {
+			return nil, err
+		}
+
+		r.lookup = systemLookup(dnsServersResolver(r.dialer, servers))
+	}
+
+	if config.DoHURL != "" {
+		if !strings.HasPrefix(config.DoHURL, "https://") {
+			return nil, fmt.Errorf("invalid dns over https url %q, must start with https://", config.DoHURL)
+		}
+
+		// the doh endpoint itself is resolved with static hosts and dns servers
+		bootstrap := *r
+
+		r.lookup = dohLookup(config.DoHURL, &http.Client{
+			Timeout: dohTimeout,
+			Transport: &http.Transport{
+				DialContext:         bootstrap.DialContext,
+				ForceAttemptHTTP2:   true,
+				TLSHandshakeTimeout: dohTimeout,
+			},
+		})
+	}
+
+	return r, nil
+}
+
+// WithResolver configures the client to resolve hostnames and dial connections with r.
+// Should be set before WithProxyFunc, which uses r for direct connections.
+func WithResolver(r *Resolver) Option {
+	return func(c *Client) {
+		c.resolver = r
+
+		transport := LazyCreateNewTransport(c)
+		transport.DialContext = r.DialContext
+		c.client.Transport = transport
+	}
+}
+
+// LookupIP returns the ip addresses of host, ordered and filtered by the ip preference.
+// Static host mappings matching host and port take precedence.
+func (r *Resolver) LookupIP(ctx context.Context, host, port string) ([]net.IP, error) {
+	if ip := net.ParseIP(strings.Trim(host, "[]")); ip != nil {
+		return []net.IP{ip}, nil
+	}
+
+	ips, ok := r.staticHost(host, port)
+	if !ok {
+		var err error
+
+		ips, err = r.lookup(ctx, host)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	ips = sortIPs(ips, r.preference)
+	if len(ips) == 0 {
+		return nil, &net.DNSError{
+			Err:        fmt.Sprintf("no ip address found matching preference %q", r.preference),
+			Name:       host,
+			IsNotFound: true,
+		}
+	}
+
+	return ips, nil
+}
+
+// DialContext connects to addr. Hostnames are resolved with LookupIP, and the first
+// address of the other family is raced after the happy eyeballs delay.
+func (r *Resolver) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+
+	ips, err := r.LookupIP(ctx, host, port)
+	if err != nil {
+		return nil, err
+	}
+
+	primaries, fallbacks := partitionIPs(ips)
+
+	if len(fallbacks) == 0 || r.fallbackDelay < 0 {
+		return r.dialSerial(ctx, network, port, ips)
+	}
+
+	delay := r.fallbackDelay
+	if delay == 0 {
+		delay = defaultFallbackDelay
+	}
+
+	return r.dialParallel(ctx, network, port, primaries, fallbacks, delay)
+}
+
+// staticHost returns the ip addresses of a static host mapping, matching the
+// port or all ports.
+func (r *Resolver) staticHost(host, port string) ([]net.IP, bool) {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+
+	if ips, ok := r.hosts[host+":"+port]; ok {
+		return ips, true
+	}
+
+	ips, ok := r.hosts[host+":*"]
+
+	return ips, ok
+}
+
+// dialSerial connects to the ip addresses one after another, returning the first
+// established connection or the first error.
+func (r *Resolver) dialSerial(ctx context.Context, network, port string, ips []net.IP) (net.Conn, error) {
+	var firstErr error
+
+	for _, ip := range ips {
+		conn, err := r.dialer.DialContext(ctx, network, net.JoinHostPort(ip.String(), port))
+		if err == nil {
+			return conn, nil
+		}
+
+		if firstErr == nil {
+			firstErr = err
+		}
+
+		if ctx.Err() != nil {
+			break
+		}
+	}
+
+	return nil, firstErr
+}
+
+// dialParallel connects to the primary addresses, and starts connecting to the
+// fallback addresses after delay or as soon as all primaries failed.
+func (r *Resolver) dialParallel(
+	ctx context.Context,
+	network, port string,
+	primaries, fallbacks []net.IP,
+	delay time.Duration,
+) (net.Conn, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	type result struct {
+		conn    net.Conn
+		err     error
+		primary bool
+	}
+
+	results := make(chan result, 2)
+
+	dial := func(primary bool, ips []net.IP) {
+		conn, err := r.dialSerial(ctx, network, port, ips)
+		results <- result{conn: conn, err: err, primary: primary}
+	}
+
+	go dial(true, primaries)
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	var (
+		primaryErr      error
+		fallbackErr     error
+		fallbackStarted bool
+		pending         = 1
+	)
+
+	startFallback := func() {
+		fallbackStarted = true
+		pending++
+
+		go dial(false, fallbacks)
+	}
+
+	for {
+		select {
+		case <-timer.C:
+			if !fallbackStarted {
+				startFallback()
+			}
+		case res := <-results:
+			pending--
+
+			if res.err == nil {
+				// close connections established after the winner
+				go func(n int) {
+					for range n {
+						if late := <-results; late.conn != nil {
+							_ = late.conn.Close()
+						}
+					}
+				}(pending)
+
+				return res.conn, nil
+			}
+
+			if res.primary {
+				primaryErr = res.err
+			} else {
+				fallbackErr = res.err
+			}
+
+			if !fallbackStarted {
+				startFallback()
+			}
+
+			if pending == 0 {
+				if primaryErr != nil {
+					return nil, primaryErr
+				}
+
+				return nil, fallbackErr
+			}
+		}
+	}
+}
+
+// preferIPv6 returns true if the hardcoded api ip address fallback should use ipv6.
+func (r *Resolver) preferIPv6(ctx context.Context) bool {
+	if r != nil {
+		switch r.preference {
+		case IPPreferenceIPv4, IPPreferenceIPv4Only:
+			return false
+		case IPPreferenceIPv6, IPPreferenceIPv6Only:
+			return true
+		}
+	}
+
+	return isLocalIPv6(ctx)
+}
+
+// parseResolveHosts parses static host mappings in the format "host:port:addr[,addr]...".
+func parseResolveHosts(entries []string) (map[string][]net.IP, error) {
+	hosts := map[string][]net.IP{}
+
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		host, rest, ok := strings.Cut(entry, ":")
+		if !ok || host == "" {
+			return nil, fmt.Errorf("invalid resolve entry %q, expected host:port:addr", entry)
+		}
+
+		port, addrs, ok := strings.Cut(rest, ":")
+		if !ok || port == "" || addrs == "" {
+			return nil, fmt.Errorf("invalid resolve entry %q, expected host:port:addr", entry)
+		}
+
+		var ips []net.IP
+
+		for _, addr := range strings.Split(addrs, ",") {
+			ip := net.ParseIP(strings.Trim(strings.TrimSpace(addr), "[]"))
+			if ip == nil {
+				return nil, fmt.Errorf("invalid ip address %q in resolve entry %q", addr, entry)
+			}
+
+			ips = append(ips, ip)
+		}
+
+		key := strings.ToLower(strings.TrimSuffix(host, ".")) + ":" + port
+		hosts[key] = append(hosts[key], ips...)
+	}
+
+	return hosts, nil
+}
+
+// parseDNSServers returns the dns server addresses, defaulting to port 53.
+func parseDNSServers(servers []string) ([]string, error) {
+	var parsed []string
+
+	for _, server := range servers {
+		server = strings.TrimSpace(server)
+		if server == "" {
+			continue
+		}
+
+		if ip := net.ParseIP(strings.Trim(server, "[]")); ip != nil {
+			parsed = append(parsed, net.JoinHostPort(ip.String(), "53"))
+			continue
+		}
+
+		host, _, err := net.SplitHostPort(server)
+		if err != nil || net.ParseIP(host) == nil {
+			return nil, fmt.Errorf("invalid dns server %q, expected ip address with optional port", server)
+		}
+
+		parsed = append(parsed, server)
+	}
+
+	return parsed, nil
+}
+
+// dnsServersResolver returns a resolver querying the dns servers in turn.
+func dnsServersResolver(dialer *net.Dialer, servers []string) *net.Resolver {
+	var next atomic.Uint64
+
+	return &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			server := servers[(next.Add(1)-1)%uint64(len(servers))]
+			return dialer.DialContext(ctx, network, server)
+		},
+	}
+}
+
+func systemLookup(resolver *net.Resolver) func(ctx context.Context, host string) ([]net.IP, error) {
+	return func(ctx context.Context, host string) ([]net.IP, error) {
+		addrs, err := resolver.LookupIPAddr(ctx, host)
+		if err != nil {
+			return nil, err
+		}
+
+		ips := make([]net.IP, 0, len(addrs))
+		for _, addr := range addrs {
+			ips = append(ips, addr.IP)
+		}
+
+		return ips, nil
+	}
+}
+
+// dohLookup returns a lookup function sending A and AAAA queries to a dns over https
+// endpoint, in the wire format of RFC 8484.
+func dohLookup(endpoint string, client *http.Client) func(ctx context.Context, host string) ([]net.IP, error) {
+	return func(ctx context.Context, host string) ([]net.IP, error) {
+		var (
+			ips     []net.IP
+			lastErr error
+		)
+
+		for _, qtype := range []dnsmessage.Type{dnsmessage.TypeAAAA, dnsmessage.TypeA} {
+			found, err := queryDoH(ctx, client, endpoint, host, qtype)
+			if err != nil {
+				lastErr = err
+				continue
+			}
+
+			ips = append(ips, found...)
+		}
+
+		if len(ips) == 0 {
+			if lastErr != nil {
+				return nil, lastErr
+			}
+
+			return nil, &net.DNSError{Err: "no such host", Name: host, Server: endpoint, IsNotFound: true}
+		}
+
+		return ips, nil
+	}
+}
+
+func queryDoH(ctx context.Context, client *http.Client, endpoint, host string, qtype dnsmessage.Type) ([]net.IP, error) {
+	name, err := dnsmessage.NewName(strings.TrimSuffix(host, ".") + ".")
+	if err != nil {
+		return nil, &net.DNSError{Err: err.Error(), Name: host, Server: endpoint}
+	}
+
+	query, err := (&dnsmessage.Message{
+		Header: dnsmessage.Header{RecursionDesired: true},
+		Questions: []dnsmessage.Question{
+			{Name: name, Type: qtype, Class: dnsmessage.ClassINET},
+		},
+	}).Pack()
+	if err != nil {
+		return nil, fmt.Errorf("failed to pack dns query: %s", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(query))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create dns over https request: %s", err)
+	}
+
+	req.Header.Set("Accept", "application/dns-message")
+	req.Header.Set("Content-Type", "application/dns-message")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, &net.DNSError{Err: err.Error(), Name: host, Server: endpoint, IsTemporary: true}
+	}
+
+	defer resp.Body.Close() // nolint:errcheck
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	if err != nil {
+		return nil, &net.DNSError{Err: err.Error(), Name: host, Server: endpoint, IsTemporary: true}
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, &net.DNSError{
+			Err:         fmt.Sprintf("invalid response status %d", resp.StatusCode),
+			Name:        host,
+			Server:      endpoint,
+			IsTemporary: true,
+		}
+	}
+
+	var msg dnsmessage.Message
+	if err := msg.Unpack(body); err != nil {
+		return nil, &net.DNSError{Err: fmt.Sprintf("invalid dns response: %s", err), Name: host, Server: endpoint}
+	}
+
+	switch msg.RCode {
+	case dnsmessage.RCodeSuccess:
+	case dnsmessage.RCodeNameError:
+		return nil, &net.DNSError{Err: "no such host", Name: host, Server: endpoint, IsNotFound: true}
+	default:
+		return nil, &net.DNSError{Err: "server misbehaving: " + msg.RCode.String(), Name: host, Server: endpoint}
+	}
+
+	var ips []net.IP
+
+	for _, answer := range msg.Answers {
+		switch body := answer.Body.(type) {
+		case *dnsmessage.AResource:
+			ips = append(ips, net.IP(body.A[:]))
+		case *dnsmessage.AAAAResource:
+			ips = append(ips, net.IP(body.AAAA[:]))
+		}
+	}
+
+	return ips, nil
+}
+
+// sortIPs orders the ip addresses by the preferred family, keeping the resolved order
+// within each family, and drops addresses of the other family for only preferences.
+func sortIPs(ips []net.IP, preference IPPreference) []net.IP {
+	sorted := make([]net.IP, 0, len(ips))
+
+	for _, ip := range ips {
+		switch {
+		case preference == IPPreferenceIPv4Only && ip.To4() == nil,
+			preference == IPPreferenceIPv6Only && ip.To4() != nil:
+			continue
+		}
+
+		sorted = append(sorted, ip)
+	}
+
+	switch preference {
+	case IPPreferenceIPv4:
+		sort.SliceStable(sorted, func(i, j int) bool {
+			return sorted[i].To4() != nil && sorted[j].To4() == nil
+		})
+	case IPPreferenceIPv6:
+		sort.SliceStable(sorted, func(i, j int) bool {
+			return sorted[i].To4() == nil && sorted[j].To4() != nil
+		})
+	}
+
+	return sorted
+}
+
+// partitionIPs splits the ip addresses into those of the first address's family,
+// and those of the other family.
+func partitionIPs(ips []net.IP) (primaries, fallbacks []net.IP) {
+	if len(ips) == 0 {
+		return nil, nil
+	}
+
+	isIPv4 := ips[0].To4() != nil
+
+	for _, ip := range ips {
+		if (ip.To4() != nil) == isIPv4 {
+			primaries = append(primaries, ip)
+		} else {
+			fallbacks = append(fallbacks, ip)
+		}
+	}
+
+	return primaries, fallbacks
+}
@@ -0,0 +1,145 @@
+package api
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+func TestDoHLookup(t *testing.T) {
+	var qtypes []dnsmessage.Type
+
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		assert.Equal(t, http.MethodPost, req.Method)
+		assert.Equal(t, "application/dns-message", req.Header.Get("Content-Type"))
+
+		query, err := io.ReadAll(req.Body)
+		require.NoError(t, err)
+
+		response, qtype := answerDNSQuery(t, query, map[string][]net.IP{
+			"api.example.org.": {net.ParseIP("10.0.0.1"), net.ParseIP("2001:db8::1")},
+		})
+
+		qtypes = append(qtypes, qtype)
+
+		w.Header().Set("Content-Type", "application/dns-message")
+		_, _ = w.Write(response)
+	}))
+	defer srv.Close()
+
+	lookup := dohLookup(srv.URL+"/dns-query", srv.Client())
+
+	ips, err := lookup(context.Background(), "api.example.org")
+	require.NoError(t, err)
+
+	assert.Equal(t, []net.IP{net.ParseIP("2001:db8::1"), net.ParseIP("10.0.0.1").To4()}, ips)
+	assert.Equal(t, []dnsmessage.Type{dnsmessage.TypeAAAA, dnsmessage.TypeA}, qtypes)
+}
+
+func TestDoHLookup_NotFound(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		query, err := io.ReadAll(req.Body)
+		require.NoError(t, err)
+
+		response, _ := answerDNSQuery(t, query, nil)
+
+		_, _ = w.Write(response)
+	}))
+	defer srv.Close()
+
+	lookup := dohLookup(srv.URL+"/dns-query", srv.Client())
+
+	_, err := lookup(context.Background(), "unknown.example.org")
+
+	var dnsErr *net.DNSError
+
+	require.ErrorAs(t, err, &dnsErr)
+	assert.True(t, dnsErr.IsNotFound)
+}
+
+func TestNewResolver_DNSServers(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	defer conn.Close()
+
+	go func() {
+		buf := make([]byte, 512)
+
+		for {
+			n, addr, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+
+			response, _ := answerDNSQuery(t, buf[:n], map[string][]net.IP{
+				"api.example.org.": {net.ParseIP("10.0.0.1")},
+			})
+
+			_, _ = conn.WriteTo(response, addr)
+		}
+	}()
+
+	r, err := NewResolver(ResolverConfig{
+		DNSServers:   []string{conn.LocalAddr().String()},
+		IPPreference: IPPreferenceIPv4Only,
+	})
+	require.NoError(t, err)
+
+	ips, err := r.LookupIP(context.Background(), "api.example.org", "443")
+	require.NoError(t, err)
+
+	require.Len(t, ips, 1)
+	assert.Equal(t, "10.0.0.1", ips[0].String())
+}
+
+// answerDNSQuery returns the response to a dns query, answering with the records
+// matching the queried name and type, or NXDOMAIN for unknown names.
+func answerDNSQuery(t *testing.T, query []byte, records map[string][]net.IP) ([]byte, dnsmessage.Type) {
+	var msg dnsmessage.Message
+
+	err := msg.Unpack(query)
+	require.NoError(t, err)
+	require.Len(t, msg.Questions, 1)
+
+	question := msg.Questions[0]
+
+	msg.Header.Response = true
+	msg.Header.RecursionAvailable = true
+
+	ips, ok := records[question.Name.String()]
+	if !ok {
+		msg.Header.RCode = dnsmessage.RCodeNameError
+	}
+
+	for _, ip := range ips {
+		header := dnsmessage.ResourceHeader{Name: question.Name, Class: dnsmessage.ClassINET, TTL: 60}
+
+		switch {
+		case question.Type == dnsmessage.TypeA && ip.To4() != nil:
+			header.Type = dnsmessage.TypeA
+			msg.Answers = append(msg.Answers, dnsmessage.Resource{
+				Header: header,
+				Body:   &dnsmessage.AResource{A: [4]byte(ip.To4())},
+			})
+		case question.Type == dnsmessage.TypeAAAA && ip.To4() == nil:
+			header.Type = dnsmessage.TypeAAAA
+			msg.Answers = append(msg.Answers, dnsmessage.Resource{
+				Header: header,
+				Body:   &dnsmessage.AAAAResource{AAAA: [16]byte(ip.To16())},
+			})
+		}
+	}
+
+	response, err := msg.Pack()
+	require.NoError(t, err)
+
+	return response, question.Type
+}
@@ -0,0 +1,218 @@
+package api_test
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/wakatime/wakatime-cli/pkg/api"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseIPPreference(t *testing.T) {
+	tests := map[string]api.IPPreference{
+		"":          "",
+		"auto":      api.IPPreferenceAuto,
+		"IPv4":      api.IPPreferenceIPv4,
+		" ipv6 ":    api.IPPreferenceIPv6,
+		"ipv4_only": api.IPPreferenceIPv4Only,
+		"ipv6_only": api.IPPreferenceIPv6Only,
+	}
+
+	for value, expected := range tests {
+		t.Run(value, func(t *testing.T) {
+			preference, err := api.ParseIPPreference(value)
+			require.NoError(t, err)
+
+			assert.Equal(t, expected, preference)
+		})
+	}
+}
+
+func TestParseIPPreference_Err(t *testing.T) {
+	_, err := api.ParseIPPreference("ipv5")
+
+	assert.EqualError(
+		t,
+		err,
+		`invalid ip preference "ipv5", expected one of "auto", "ipv4", "ipv6", "ipv4_only" or "ipv6_only"`,
+	)
+}
+
+func TestResolver_LookupIP(t *testing.T) {
+	tests := map[string]struct {
+		Preference api.IPPreference
+		Port       string
+		Expected   []string
+	}{
+		"auto": {
+			Port:     "443",
+			Expected: []string{"2001:db8::1", "10.0.0.1", "10.0.0.2"},
+		},
+		"ipv4 first": {
+			Preference: api.IPPreferenceIPv4,
+			Port:       "443",
+			Expected:   []string{"10.0.0.1", "10.0.0.2", "2001:db8::1"},
+		},
+		"ipv6 first": {
+			Preference: api.IPPreferenceIPv6,
+			Port:       "443",
+			Expected:   []string{"2001:db8::1", "10.0.0.1", "10.0.0.2"},
+		},
+		"ipv4 only": {
+			Preference: api.IPPreferenceIPv4Only,
+			Port:       "443",
+			Expected:   []string{"10.0.0.1", "10.0.0.2"},
+		},
+		"ipv6 only": {
+			Preference: api.IPPreferenceIPv6Only,
+			Port:       "443",
+			Expected:   []string{"2001:db8::1"},
+		},
+		"all ports": {
+			Port:     "80",
+			Expected: []string{"10.0.0.3"},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			r, err := api.NewResolver(api.ResolverConfig{
+				Hosts: []string{
+					"api.example.org:443:[2001:db8::1],10.0.0.1",
+					"API.example.org:443:10.0.0.2",
+					"api.example.org:*:10.0.0.3",
+				},
+				IPPreference: test.Preference,
+			})
+			require.NoError(t, err)
+
+			ips, err := r.LookupIP(context.Background(), "api.example.org", test.Port)
+			require.NoError(t, err)
+
+			var actual []string
+			for _, ip := range ips {
+				actual = append(actual, ip.String())
+			}
+
+			assert.Equal(t, test.Expected, actual)
+		})
+	}
+}
+
+func TestResolver_LookupIP_NoMatchingFamily(t *testing.T) {
+	r, err := api.NewResolver(api.ResolverConfig{
+		Hosts:        []string{"api.example.org:443:10.0.0.1"},
+		IPPreference: api.IPPreferenceIPv6Only,
+	})
+	require.NoError(t, err)
+
+	_, err = r.LookupIP(context.Background(), "api.example.org", "443")
+
+	var dnsErr *net.DNSError
+
+	require.ErrorAs(t, err, &dnsErr)
+	assert.True(t, dnsErr.IsNotFound)
+}
+
+func TestNewResolver_Err(t *testing.T) {
+	tests := map[string]struct {
+		Config   api.ResolverConfig
+		Expected string
+	}{
+		"resolve entry without address": {
+			Config:   api.ResolverConfig{Hosts: []string{"api.example.org:443"}},
+			Expected: `invalid resolve entry "api.example.org:443", expected host:port:addr`,
+		},
+		"resolve entry with invalid address": {
+			Config:   api.ResolverConfig{Hosts: []string{"api.example.org:443:localhost"}},
+			Expected: `invalid ip address "localhost" in resolve entry "api.example.org:443:localhost"`,
+		},
+		"dns server hostname": {
+			Config:   api.ResolverConfig{DNSServers: []string{"dns.example.org"}},
+			Expected: `invalid dns server "dns.example.org", expected ip address with optional port`,
+		},
+		"dns over http": {
+			Config:   api.ResolverConfig{DoHURL: "http://dns.example.org/dns-query"},
+			Expected: `invalid dns over https url "http://dns.example.org/dns-query", must start with https://`,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := api.NewResolver(test.Config)
+
+			assert.EqualError(t, err, test.Expected)
+		})
+	}
+}
+
+func TestResolver_DialContext_HappyEyeballsFallback(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	defer ln.Close()
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+
+			_ = conn.Close()
+		}
+	}()
+
+	_, port, err := net.SplitHostPort(ln.Addr().String())
+	require.NoError(t, err)
+
+	// nothing listens on the preferred ipv6 address, so the ipv4 fallback wins
+	r, err := api.NewResolver(api.ResolverConfig{
+		Hosts:        []string{"api.example.org:" + port + ":127.0.0.1,[::1]"},
+		IPPreference: api.IPPreferenceIPv6,
+	})
+	require.NoError(t, err)
+
+	conn, err := r.DialContext(context.Background(), "tcp", net.JoinHostPort("api.example.org", port))
+	require.NoError(t, err)
+
+	defer conn.Close()
+
+	assert.Equal(t, ln.Addr().String(), conn.RemoteAddr().String())
+}
+
+func TestOption_WithResolver(t *testing.T) {
+	testServerURL, router, tearDown := setupTestServer()
+	defer tearDown()
+
+	u, err := url.Parse(testServerURL)
+	require.NoError(t, err)
+
+	var numCalls int
+
+	router.HandleFunc("/users/current/heartbeats.bulk", func(w http.ResponseWriter, req *http.Request) {
+		numCalls++
+
+		assert.Equal(t, "api.example.org:"+u.Port(), req.Host)
+
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte(`{"responses": []}`))
+	})
+
+	r, err := api.NewResolver(api.ResolverConfig{
+		Hosts: []string{"api.example.org:" + u.Port() + ":" + u.Hostname()},
+	})
+	require.NoError(t, err)
+
+	c := api.NewClient("http://api.example.org:"+u.Port(), api.WithResolver(r))
+
+	_, err = c.SendHeartbeats(context.Background(), testHeartbeats())
+	require.NoError(t, err)
+
+	assert.Equal(t, 1, numCalls)
+}
@@ -8,6 +8,8 @@ import (
 	"time"
 
 	"github.com/wakatime/wakatime-cli/pkg/log"
+
+	"github.com/Azure/go-ntlmssp"
 )
 
 const (
@@ -99,17 +101,40 @@ func NewTransport() *http.Transport {
 	}
 }
 
-// NewTransportWithHostVerificationDisabled initializes a new http.Transport with disabled host verification.
-func NewTransportWithHostVerificationDisabled(ctx context.Context) *http.Transport {
-	t := NewTransport()
+// fallbackTransport returns a copy of the configured transport, which verifies the
+// api certificate against its host name when connecting to the api ip address. The
+// client certificate, proxy, dialer and custom tls settings are kept. Returns false
+// if the transport can't be copied.
+func fallbackTransport(ctx context.Context, rt http.RoundTripper) (http.RoundTripper, bool) {
+	switch t := rt.(type) {
+	case nil:
+		return fallbackTransport(ctx, NewTransport())
+	case *http.Transport:
+		clone := t.Clone()
 
-	t.TLSClientConfig = &tls.Config{
-		MinVersion: tls.VersionTLS12,
-		RootCAs:    CACerts(ctx),
-		ServerName: serverName,
-	}
+		if clone.TLSClientConfig == nil {
+			clone.TLSClientConfig = &tls.Config{
+				MinVersion: tls.VersionTLS12,
+			}
+		}
+
+		if clone.TLSClientConfig.RootCAs == nil {
+			clone.TLSClientConfig.RootCAs = CACerts(ctx)
+		}
 
-	return t
+		clone.TLSClientConfig.ServerName = serverName
+
+		return clone, true
+	case ntlmssp.Negotiator:
+		inner, ok := fallbackTransport(ctx, t.RoundTripper)
+		if !ok {
+			return nil, false
+		}
+
+		return ntlmssp.Negotiator{RoundTripper: inner}, true
+	default:
+		return nil, false
+	}
 }
 
 // LazyCreateNewTransport uses the client's Transport if exists, or creates a new one.
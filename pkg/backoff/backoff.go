@@ -33,6 +33,8 @@ type Config struct {
 	V *viper.Viper
 	// HasProxy is true when using a proxy
 	HasProxy bool
+	// Until is the time the api asked to retry after, when rate limited.
+	Until time.Time
 }
 
 // WithBackoff initializes and returns a heartbeat handle option, which
@@ -44,6 +46,13 @@ func WithBackoff(config Config) heartbeat.HandleOption {
 			logger := log.Extract(ctx)
 			logger.Debugln("execute heartbeat backoff algorithm")
 
+			if rateLimited(ctx, config.Until) {
+				return nil, api.ErrBackoff{Err: fmt.Errorf(
+					"won't send heartbeat due to api rate limit until %s",
+					config.Until.Format(ini.DateFormat),
+				)}
+			}
+
 			if shouldBackoff(ctx, config.Retries, config.At) {
 				if config.HasProxy {
 					return nil, api.ErrBackoff{Err: errors.New("won't send heartbeat due to backoff with proxy")}
@@ -54,16 +63,34 @@ func WithBackoff(config Config) heartbeat.HandleOption {
 
 			results, err := next(ctx, hh)
 			if err != nil {
-				// error response, increment backoff
-				if updateErr := updateBackoffSettings(ctx, config.V, config.Retries+1, time.Now()); updateErr != nil {
+				var errratelimited api.ErrRateLimited
+
+				if errors.As(err, &errratelimited) && !errratelimited.RetryAfter.IsZero() {
+					// rate limited, respect the api's deadline instead of exponential backoff
+					until := errratelimited.RetryAfter
+					if limit := time.Now().Add(maxBackoffSecs * time.Second); until.After(limit) {
+						until = limit
+					}
+
+					if updateErr := updateRateLimitSettings(ctx, config.V, until); updateErr != nil {
+						logger.Warnf("failed to update rate limit settings: %s", updateErr)
+					}
+				} else if updateErr := updateBackoffSettings(ctx, config.V, config.Retries+1, time.Now()); updateErr != nil {
+					// error response, increment backoff
 					logger.Warnf("failed to update backoff settings: %s", updateErr)
 				}
 
+				// keep results of succeeded api keys, so they are not queued again
+				var errpartial api.ErrPartial
+				if errors.As(err, &errpartial) {
+					return results, err
+				}
+
 				return nil, err
 			}
 
 			// success response, reset backoff
-			if config.Retries > 0 || !config.At.IsZero() {
+			if config.Retries > 0 || !config.At.IsZero() || !config.Until.IsZero() {
 				if resetErr := updateBackoffSettings(ctx, config.V, 0, time.Time{}); resetErr != nil {
 					logger.Warnf("failed to reset backoff settings: %s", resetErr)
 				}
@@ -84,11 +111,8 @@ func shouldBackoff(ctx context.Context, retries int, at time.Time) bool {
 
 	logger := log.Extract(ctx)
 
-	backoffSeconds := float64(factor) * math.Pow(2, float64(retries))
-
-	duration := time.Duration(backoffSeconds) * time.Second
-
-	if backoffSeconds > maxBackoffSecs {
+	duration, ok := backoffDuration(retries)
+	if !ok {
 		logger.Debugf(
 			"exponential backoff tried %d times since %s, will reset because reached %s max backoff",
 			retries,
@@ -113,6 +137,44 @@ func shouldBackoff(ctx context.Context, retries int, at time.Time) bool {
 	return true
 }
 
+// rateLimited returns true if the api asked to not send heartbeats until a time in the future.
+func rateLimited(ctx context.Context, until time.Time) bool {
+	if until.IsZero() || !until.After(time.Now()) {
+		return false
+	}
+
+	log.Extract(ctx).Debugf("rate limited by api, will retry again after %s", until.Format(ini.DateFormat))
+
+	return true
+}
+
+// backoffDuration returns the exponential backoff duration for the given number of
+// retries. Returns false when the maximum backoff was reached, and backoff resets.
+func backoffDuration(retries int) (time.Duration, bool) {
+	backoffSeconds := float64(factor) * math.Pow(2, float64(retries))
+
+	return time.Duration(backoffSeconds) * time.Second, backoffSeconds <= maxBackoffSecs
+}
+
+// Deadline returns the time until which heartbeats won't be sent, either because
+// the api rate limited us or due to exponential backoff. Returns zero time if
+// not backing off at all. The returned time may be in the past.
+func Deadline(retries int, at, until time.Time) time.Time {
+	var deadline time.Time
+
+	if retries >= 1 && !at.IsZero() {
+		if duration, ok := backoffDuration(retries); ok {
+			deadline = at.Add(duration)
+		}
+	}
+
+	if until.After(deadline) {
+		deadline = until
+	}
+
+	return deadline
+}
+
 func updateBackoffSettings(ctx context.Context, v *viper.Viper, retries int, at time.Time) error {
 	w, err := ini.NewWriter(ctx, v, ini.InternalFilePath)
 	if err != nil {
@@ -122,6 +184,7 @@ func updateBackoffSettings(ctx context.Context, v *viper.Viper, retries int, at
 	keyValue := map[string]string{
 		"backoff_retries": strconv.Itoa(retries),
 		"backoff_at":      "",
+		"backoff_until":   "",
 	}
 
 	if !at.IsZero() {
@@ -136,3 +199,20 @@ func updateBackoffSettings(ctx context.Context, v *viper.Viper, retries int, at
 
 	return nil
 }
+
+func updateRateLimitSettings(ctx context.Context, v *viper.Viper, until time.Time) error {
+	w, err := ini.NewWriter(ctx, v, ini.InternalFilePath)
+	if err != nil {
+		return fmt.Errorf("failed to parse config file: %s", err)
+	}
+
+	keyValue := map[string]string{
+		"backoff_until": until.Format(ini.DateFormat),
+	}
+
+	if err := w.Write(ctx, "internal", keyValue); err != nil {
+		return fmt.Errorf("failed to write to internal config file: %s", err)
+	}
+
+	return nil
+}
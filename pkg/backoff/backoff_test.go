@@ -49,6 +49,40 @@ func TestWithBackoff(t *testing.T) {
 	assert.Empty(t, v.GetString("internal.backoff_retries"))
 }
 
+func TestWithBackoff_PartialFailure(t *testing.T) {
+	tmpFile, err := os.CreateTemp(t.TempDir(), "wakatime")
+	require.NoError(t, err)
+
+	defer tmpFile.Close()
+
+	v := viper.New()
+	v.Set("internal-config", tmpFile.Name())
+
+	opt := backoff.WithBackoff(backoff.Config{
+		V: v,
+	})
+
+	handle := opt(func(_ context.Context, _ []heartbeat.Heartbeat) ([]heartbeat.Result, error) {
+		return []heartbeat.Result{{Status: 201}}, api.ErrPartial{
+			Groups: []api.GroupResult{
+				{Results: []heartbeat.Result{{Status: 201}}},
+				{Err: api.Err{Err: errors.New("error")}},
+			},
+		}
+	})
+
+	results, err := handle(context.Background(), []heartbeat.Heartbeat{})
+	require.Error(t, err)
+
+	// results of succeeded api keys are kept
+	assert.Equal(t, []heartbeat.Result{{Status: 201}}, results)
+
+	err = ini.ReadInConfig(v, tmpFile.Name())
+	require.NoError(t, err)
+
+	assert.Equal(t, "1", v.GetString("internal.backoff_retries"))
+}
+
 func TestWithBackoff_BeforeNextBackoff(t *testing.T) {
 	tmpFile, err := os.CreateTemp(t.TempDir(), "wakatime")
 	require.NoError(t, err)
@@ -375,3 +409,169 @@ func TestWithBackoff_ShouldRetry(t *testing.T) {
 	assert.Empty(t, v.GetString("internal.backoff_at"))
 	assert.Equal(t, "0", v.GetString("internal.backoff_retries"))
 }
+
+func TestWithBackoff_RateLimited(t *testing.T) {
+	tmpFile, err := os.CreateTemp(t.TempDir(), "wakatime")
+	require.NoError(t, err)
+
+	defer tmpFile.Close()
+
+	v := viper.New()
+	v.Set("internal-config", tmpFile.Name())
+
+	opt := backoff.WithBackoff(backoff.Config{
+		V: v,
+	})
+
+	retryAfter := time.Now().Add(2 * time.Minute)
+
+	handle := opt(func(_ context.Context, _ []heartbeat.Heartbeat) ([]heartbeat.Result, error) {
+		return nil, api.ErrRateLimited{
+			Err:        errors.New("too many requests"),
+			RetryAfter: retryAfter,
+		}
+	})
+
+	_, err = handle(context.Background(), []heartbeat.Heartbeat{})
+	require.Error(t, err)
+
+	err = ini.ReadInConfig(v, tmpFile.Name())
+	require.NoError(t, err)
+
+	// make sure the api's deadline was written instead of exponential backoff
+	assert.Equal(t, retryAfter.Format(ini.DateFormat), v.GetString("internal.backoff_until"))
+	assert.Empty(t, v.GetString("internal.backoff_at"))
+	assert.Empty(t, v.GetString("internal.backoff_retries"))
+}
+
+func TestWithBackoff_RateLimitedCapped(t *testing.T) {
+	tmpFile, err := os.CreateTemp(t.TempDir(), "wakatime")
+	require.NoError(t, err)
+
+	defer tmpFile.Close()
+
+	v := viper.New()
+	v.Set("internal-config", tmpFile.Name())
+
+	opt := backoff.WithBackoff(backoff.Config{
+		V: v,
+	})
+
+	handle := opt(func(_ context.Context, _ []heartbeat.Heartbeat) ([]heartbeat.Result, error) {
+		return nil, api.ErrRateLimited{
+			Err:        errors.New("too many requests"),
+			RetryAfter: time.Now().Add(48 * time.Hour),
+		}
+	})
+
+	_, err = handle(context.Background(), []heartbeat.Heartbeat{})
+	require.Error(t, err)
+
+	err = ini.ReadInConfig(v, tmpFile.Name())
+	require.NoError(t, err)
+
+	until, err := time.Parse(ini.DateFormat, v.GetString("internal.backoff_until"))
+	require.NoError(t, err)
+
+	assert.WithinDuration(t, time.Now().Add(time.Hour), until, 5*time.Second)
+}
+
+func TestWithBackoff_BeforeRateLimitDeadline(t *testing.T) {
+	opt := backoff.WithBackoff(backoff.Config{
+		Until: time.Now().Add(time.Minute),
+	})
+
+	var called bool
+
+	handle := opt(func(_ context.Context, _ []heartbeat.Heartbeat) ([]heartbeat.Result, error) {
+		called = true
+
+		return []heartbeat.Result{{Status: 201}}, nil
+	})
+
+	_, err := handle(context.Background(), []heartbeat.Heartbeat{})
+	require.Error(t, err)
+
+	var errbackoff api.ErrBackoff
+
+	assert.ErrorAs(t, err, &errbackoff)
+	assert.Contains(t, err.Error(), "won't send heartbeat due to api rate limit until")
+	assert.False(t, called)
+}
+
+func TestWithBackoff_AfterRateLimitDeadline(t *testing.T) {
+	tmpFile, err := os.CreateTemp(t.TempDir(), "wakatime")
+	require.NoError(t, err)
+
+	defer tmpFile.Close()
+
+	_, err = tmpFile.WriteString("[internal]\nbackoff_until = 2024-03-10T12:00:00Z\n")
+	require.NoError(t, err)
+
+	v := viper.New()
+	v.Set("internal-config", tmpFile.Name())
+
+	opt := backoff.WithBackoff(backoff.Config{
+		V:     v,
+		Until: time.Now().Add(-time.Second),
+	})
+
+	handle := opt(func(_ context.Context, _ []heartbeat.Heartbeat) ([]heartbeat.Result, error) {
+		return []heartbeat.Result{{Status: 201}}, nil
+	})
+
+	_, err = handle(context.Background(), []heartbeat.Heartbeat{})
+	require.NoError(t, err)
+
+	err = ini.ReadInConfig(v, tmpFile.Name())
+	require.NoError(t, err)
+
+	// make sure the deadline was reset after sending successfully
+	assert.Empty(t, v.GetString("internal.backoff_until"))
+}
+
+func TestDeadline(t *testing.T) {
+	at := time.Date(2024, 3, 10, 12, 0, 0, 0, time.UTC)
+
+	tests := map[string]struct {
+		Retries  int
+		At       time.Time
+		Until    time.Time
+		Expected time.Time
+	}{
+		"not backing off": {},
+		"exponential backoff": {
+			Retries:  2,
+			At:       at,
+			Expected: at.Add(60 * time.Second),
+		},
+		"max backoff reached": {
+			Retries: 8,
+			At:      at,
+		},
+		"rate limited": {
+			Until:    at.Add(time.Minute),
+			Expected: at.Add(time.Minute),
+		},
+		"rate limited after backoff": {
+			Retries:  1,
+			At:       at,
+			Until:    at.Add(10 * time.Minute),
+			Expected: at.Add(10 * time.Minute),
+		},
+		"backoff after rate limit": {
+			Retries:  3,
+			At:       at,
+			Until:    at.Add(time.Minute),
+			Expected: at.Add(120 * time.Second),
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			deadline := backoff.Deadline(test.Retries, test.At, test.Until)
+
+			assert.True(t, test.Expected.Equal(deadline), "got %s", deadline)
+		})
+	}
+}
@@ -19,6 +19,8 @@ const (
 	ErrConfigFileWrite = 111
 	// ErrBackoff is used when sending heartbeats postponed because we're currently rate limited.
 	ErrBackoff = 112
+	// ErrCertPin is used when the api's tls certificate doesn't match any of the configured pins.
+	ErrCertPin = 113
 )
 
 // Err represents a type response for exit code errors. A Success response is also wrapped in this type.
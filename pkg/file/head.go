@@ -0,0 +1,52 @@
+package file
+
+import (
+	"fmt"
+	"io"
+	"sync"
+)
+
+// MaxHeadSize is the maximum number of bytes read from the start of a file
+// to inspect its content. Default is 512Kb.
+const MaxHeadSize = 512000
+
+// Heads reads the first bytes of files and caches them by file path, so
+// multiple heartbeat processing steps can inspect a file's content while
+// reading it only once.
+type Heads struct {
+	data map[string][]byte
+	mu   sync.Mutex
+}
+
+// NewHeads creates a new empty cache of file heads.
+func NewHeads() *Heads {
+	return &Heads{
+		data: make(map[string][]byte),
+	}
+}
+
+// Read returns the first `MaxHeadSize` bytes of the file's content.
+func (h *Heads) Read(fp string) ([]byte, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if data, ok := h.data[fp]; ok {
+		return data, nil
+	}
+
+	f, err := OpenNoLock(fp)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file: %s", err)
+	}
+
+	defer f.Close() // nolint:errcheck
+
+	data, err := io.ReadAll(io.LimitReader(f, MaxHeadSize))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read bytes from file: %s", err)
+	}
+
+	h.data[fp] = data
+
+	return data, nil
+}
@@ -0,0 +1,55 @@
+package file_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/wakatime/wakatime-cli/pkg/file"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHeads_Read(t *testing.T) {
+	fp := filepath.Join(t.TempDir(), "main.go")
+
+	err := os.WriteFile(fp, []byte("package main\n"), 0600)
+	require.NoError(t, err)
+
+	heads := file.NewHeads()
+
+	head, err := heads.Read(fp)
+	require.NoError(t, err)
+
+	assert.Equal(t, "package main\n", string(head))
+
+	// cached content is returned after the file changed
+	err = os.WriteFile(fp, []byte("package other\n"), 0600)
+	require.NoError(t, err)
+
+	head, err = heads.Read(fp)
+	require.NoError(t, err)
+
+	assert.Equal(t, "package main\n", string(head))
+}
+
+func TestHeads_Read_MaxHeadSize(t *testing.T) {
+	fp := filepath.Join(t.TempDir(), "large.txt")
+
+	err := os.WriteFile(fp, []byte(strings.Repeat("a", file.MaxHeadSize+10)), 0600)
+	require.NoError(t, err)
+
+	head, err := file.NewHeads().Read(fp)
+	require.NoError(t, err)
+
+	assert.Len(t, head, file.MaxHeadSize)
+}
+
+func TestHeads_Read_NotExist(t *testing.T) {
+	_, err := file.NewHeads().Read(filepath.Join(t.TempDir(), "missing.txt"))
+	require.Error(t, err)
+
+	assert.Contains(t, err.Error(), "failed to open file")
+}
@@ -6,6 +6,7 @@ import (
 	"os"
 
 	"github.com/wakatime/wakatime-cli/pkg/heartbeat"
+	"github.com/wakatime/wakatime-cli/pkg/ini"
 	"github.com/wakatime/wakatime-cli/pkg/log"
 	"github.com/wakatime/wakatime-cli/pkg/project"
 	"github.com/wakatime/wakatime-cli/pkg/regex"
@@ -14,7 +15,10 @@ import (
 // Config contains filtering configurations.
 type Config struct {
 	Exclude                    []regex.Regex
+	ExcludeGlobs               GlobRules
+	HonorIgnoreFiles           bool
 	Include                    []regex.Regex
+	IncludeGlobs               GlobRules
 	IncludeOnlyWithProjectFile bool
 }
 
@@ -29,11 +33,18 @@ func WithFiltering(config Config) heartbeat.HandleOption {
 
 			var filtered []heartbeat.Heartbeat
 
+			ignored := newIgnoreFiles()
+
 			for _, h := range hh {
-				err := Filter(ctx, h, config)
+				err := filter(ctx, h, config, ignored)
 				if err != nil {
 					logger.Debugln(err.Error())
 
+					heartbeat.RecordOutcome(ctx, h, heartbeat.Outcome{
+						Status: heartbeat.OutcomeFiltered,
+						Reason: err.Error(),
+					})
+
 					continue
 				}
 
@@ -45,6 +56,52 @@ func WithFiltering(config Config) heartbeat.HandleOption {
 	}
 }
 
+// WithLocalScope initializes and returns a heartbeat handle option, which skips
+// file heartbeats whose per-directory config files differ from the ones applied
+// for the main entity, as their settings would otherwise be applied to other
+// directories. It must run before formatting, so entities are compared as passed in.
+// A nil scope disables the check.
+func WithLocalScope(scope *ini.LocalScope) heartbeat.HandleOption {
+	return func(next heartbeat.Handle) heartbeat.Handle {
+		return func(ctx context.Context, hh []heartbeat.Heartbeat) ([]heartbeat.Result, error) {
+			if scope == nil {
+				return next(ctx, hh)
+			}
+
+			logger := log.Extract(ctx)
+			logger.Debugln("execute local config scope filtering")
+
+			var filtered []heartbeat.Heartbeat
+
+			for _, h := range hh {
+				if h.EntityType == heartbeat.FileType {
+					contained, err := scope.Contains(h.Entity)
+					if err != nil {
+						logger.Warnf("failed to find local config files of %q: %s", h.Entity, err)
+					}
+
+					if err != nil || !contained {
+						reason := "skipping because per-directory config files differ from the ones of the main entity"
+
+						logger.Debugln(reason)
+
+						heartbeat.RecordOutcome(ctx, h, heartbeat.Outcome{
+							Status: heartbeat.OutcomeFiltered,
+							Reason: reason,
+						})
+
+						continue
+					}
+				}
+
+				filtered = append(filtered, h)
+			}
+
+			return next(ctx, filtered)
+		}
+	}
+}
+
 // WithLengthValidator initializes and returns a heartbeat handle option, which
 // can be used to abort execution if all heartbeats were filtered and the list is empty.
 func WithLengthValidator() heartbeat.HandleOption {
@@ -65,12 +122,20 @@ func WithLengthValidator() heartbeat.HandleOption {
 // Filter determines, following the passed in configurations, if a heartbeat
 // should be skipped.
 func Filter(ctx context.Context, h heartbeat.Heartbeat, config Config) error {
+	return filter(ctx, h, config, newIgnoreFiles())
+}
+
+func filter(ctx context.Context, h heartbeat.Heartbeat, config Config, ignored *ignoreFiles) error {
+	included := isIncluded(ctx, h.Entity, config)
+
 	// filter by pattern
-	if err := filterByPattern(ctx, h.Entity, config.Include, config.Exclude); err != nil {
-		return fmt.Errorf("filter by pattern: %s", err)
+	if !included {
+		if err := filterByPattern(ctx, h.Entity, config.Exclude, config.ExcludeGlobs); err != nil {
+			return fmt.Errorf("filter by pattern: %s", err)
+		}
 	}
 
-	err := filterFileEntity(ctx, h, config)
+	err := filterFileEntity(ctx, h, config, included, ignored)
 	if err != nil {
 		return fmt.Errorf("filter file: %s", err)
 	}
@@ -78,36 +143,57 @@ func Filter(ctx context.Context, h heartbeat.Heartbeat, config Config) error {
 	return nil
 }
 
-// filterByPattern determines if a heartbeat should be skipped by checking an
-// entity against include and exclude patterns. Include will override exclude.
-// Returns Err to signal to the caller to skip the heartbeat.
-func filterByPattern(ctx context.Context, entity string, include, exclude []regex.Regex) error {
+// isIncluded determines if an entity matches include patterns, which override
+// exclude patterns and ignore files.
+func isIncluded(ctx context.Context, entity string, config Config) bool {
 	if entity == "" {
-		return nil
+		return false
 	}
 
-	// filter by include pattern
-	for _, pattern := range include {
+	for _, pattern := range config.Include {
 		if pattern.MatchString(ctx, entity) {
-			return nil
+			return true
 		}
 	}
 
-	// filter by  exclude pattern
+	return config.IncludeGlobs.Match(entity)
+}
+
+// filterByPattern determines if a heartbeat should be skipped by checking an
+// entity against exclude patterns.
+// Returns Err to signal to the caller to skip the heartbeat.
+func filterByPattern(ctx context.Context, entity string, exclude []regex.Regex, excludeGlobs GlobRules) error {
+	if entity == "" {
+		return nil
+	}
+
+	// filter by exclude pattern
 	for _, pattern := range exclude {
 		if pattern.MatchString(ctx, entity) {
 			return fmt.Errorf("skipping because matches exclude pattern %q", pattern.String())
 		}
 	}
 
+	// filter by exclude glob
+	if excludeGlobs.Match(entity) {
+		return fmt.Errorf("skipping because matches exclude glob patterns %q", excludeGlobs.String())
+	}
+
 	return nil
 }
 
 // filterFileEntity determines if a heartbeat of type file should be skipped, by verifying
 // the existence of the passed in filepath, and optionally by checking if a
-// wakatime project file can be detected in the filepath directory tree.
+// wakatime project file can be detected in the filepath directory tree, or if
+// the file is ignored by the project's ignore files.
 // Returns an error to signal to the caller to skip the heartbeat.
-func filterFileEntity(ctx context.Context, h heartbeat.Heartbeat, config Config) error {
+func filterFileEntity(
+	ctx context.Context,
+	h heartbeat.Heartbeat,
+	config Config,
+	included bool,
+	ignored *ignoreFiles,
+) error {
 	if h.EntityType != heartbeat.FileType {
 		return nil
 	}
@@ -138,5 +224,10 @@ func filterFileEntity(ctx context.Context, h heartbeat.Heartbeat, config Config)
 		}
 	}
 
+	// when honoring ignore files, skip files ignored by .gitignore or .wakatimeignore files
+	if config.HonorIgnoreFiles && !included && ignored.Match(ctx, entity) {
+		return fmt.Errorf("skipping because ignored by .gitignore or %s file", WakaTimeIgnoreFile)
+	}
+
 	return nil
 }
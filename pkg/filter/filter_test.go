@@ -9,8 +9,10 @@ import (
 
 	"github.com/wakatime/wakatime-cli/pkg/filter"
 	"github.com/wakatime/wakatime-cli/pkg/heartbeat"
+	"github.com/wakatime/wakatime-cli/pkg/ini"
 	"github.com/wakatime/wakatime-cli/pkg/regex"
 
+	"github.com/spf13/viper"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -64,6 +66,47 @@ func TestWithFiltering(t *testing.T) {
 	}, result)
 }
 
+func TestWithLocalScope(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	for _, dir := range []string{"foo", "bar"} {
+		err := os.MkdirAll(filepath.Join(tmpDir, dir), 0750)
+		require.NoError(t, err)
+
+		err = os.WriteFile(filepath.Join(tmpDir, dir, "main.go"), []byte("package main\n"), 0600)
+		require.NoError(t, err)
+	}
+
+	err := os.WriteFile(filepath.Join(tmpDir, "foo", ".wakatime.cfg"), []byte("[settings]\nexclude = ^/tmp/\n"), 0600)
+	require.NoError(t, err)
+
+	v := viper.New()
+	v.Set("config", filepath.Join(tmpDir, "global.cfg"))
+
+	scope, err := ini.NewLocalScope(context.Background(), v, filepath.Join(tmpDir, "foo", "main.go"))
+	require.NoError(t, err)
+
+	main := testHeartbeat()
+	main.Entity = filepath.Join(tmpDir, "foo", "main.go")
+
+	other := testHeartbeat()
+	other.Entity = filepath.Join(tmpDir, "bar", "main.go")
+
+	app := testHeartbeat()
+	app.Entity = "Slack"
+	app.EntityType = heartbeat.AppType
+
+	opt := filter.WithLocalScope(&scope)
+	h := opt(func(_ context.Context, hh []heartbeat.Heartbeat) ([]heartbeat.Result, error) {
+		assert.Equal(t, []heartbeat.Heartbeat{main, app}, hh)
+
+		return []heartbeat.Result{}, nil
+	})
+
+	_, err = h(context.Background(), []heartbeat.Heartbeat{main, other, app})
+	require.NoError(t, err)
+}
+
 func TestWithLengthValidator(t *testing.T) {
 	opt := filter.WithLengthValidator()
 	h := opt(func(_ context.Context, _ []heartbeat.Heartbeat) ([]heartbeat.Result, error) {
@@ -201,6 +244,142 @@ func TestFilter_ErrNonExistingProjectFile(t *testing.T) {
 	assert.EqualError(t, err, "filter file: skipping because missing .wakatime-project file in parent path")
 }
 
+func TestFilter_ErrMatchesExcludeGlob(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	err := os.MkdirAll(filepath.Join(tmpDir, "node_modules", "lib"), os.FileMode(int(0700)))
+	require.NoError(t, err)
+
+	tmpFile, err := os.Create(filepath.Join(tmpDir, "node_modules", "lib", "index.js"))
+	require.NoError(t, err)
+
+	defer tmpFile.Close()
+
+	globs, err := filter.ParseGlobRules([]string{"**/node_modules/**"}, "")
+	require.NoError(t, err)
+
+	h := testHeartbeat()
+	h.Entity = tmpFile.Name()
+
+	err = filter.Filter(context.Background(), h, filter.Config{
+		ExcludeGlobs: globs,
+	})
+
+	assert.EqualError(t, err, "filter by pattern: skipping because matches exclude glob patterns \"**/node_modules/**\"")
+}
+
+func TestFilter_IncludeGlobOverwritesExcludeMatch(t *testing.T) {
+	tmpFile, err := os.CreateTemp(t.TempDir(), "*.go")
+	require.NoError(t, err)
+
+	defer tmpFile.Close()
+
+	globs, err := filter.ParseGlobRules([]string{"*.go"}, "")
+	require.NoError(t, err)
+
+	h := testHeartbeat()
+	h.Entity = tmpFile.Name()
+
+	err = filter.Filter(context.Background(), h, filter.Config{
+		Exclude: []regex.Regex{
+			regex.MustCompile(".*"),
+		},
+		IncludeGlobs: globs,
+	})
+	require.NoError(t, err)
+}
+
+func TestFilter_HonorIgnoreFiles(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	err := os.MkdirAll(filepath.Join(tmpDir, ".git"), os.FileMode(int(0700)))
+	require.NoError(t, err)
+
+	err = os.MkdirAll(filepath.Join(tmpDir, "src", "gen"), os.FileMode(int(0700)))
+	require.NoError(t, err)
+
+	err = os.WriteFile(filepath.Join(tmpDir, ".gitignore"), []byte("# generated\n/src/gen/\n*.log\n"), 0600)
+	require.NoError(t, err)
+
+	err = os.WriteFile(filepath.Join(tmpDir, "src", ".wakatimeignore"), []byte("*.json\n!debug.log\n"), 0600)
+	require.NoError(t, err)
+
+	tests := map[string]struct {
+		Filepath string
+		Expected string
+	}{
+		"not ignored": {
+			Filepath: filepath.Join("src", "main.go"),
+		},
+		"ignored directory": {
+			Filepath: filepath.Join("src", "gen", "types.go"),
+			Expected: "filter file: skipping because ignored by .gitignore or .wakatimeignore file",
+		},
+		"ignored by gitignore": {
+			Filepath: "error.log",
+			Expected: "filter file: skipping because ignored by .gitignore or .wakatimeignore file",
+		},
+		"ignored by wakatimeignore": {
+			Filepath: filepath.Join("src", "package.json"),
+			Expected: "filter file: skipping because ignored by .gitignore or .wakatimeignore file",
+		},
+		"negated by wakatimeignore": {
+			Filepath: filepath.Join("src", "debug.log"),
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			fp := filepath.Join(tmpDir, test.Filepath)
+
+			err := os.WriteFile(fp, []byte{}, 0600)
+			require.NoError(t, err)
+
+			h := testHeartbeat()
+			h.Entity = fp
+
+			err = filter.Filter(context.Background(), h, filter.Config{
+				HonorIgnoreFiles: true,
+			})
+
+			if test.Expected == "" {
+				require.NoError(t, err)
+				return
+			}
+
+			assert.EqualError(t, err, test.Expected)
+
+			// include patterns override ignore files
+			err = filter.Filter(context.Background(), h, filter.Config{
+				HonorIgnoreFiles: true,
+				Include:          []regex.Regex{regex.MustCompile(".*")},
+			})
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestFilter_HonorIgnoreFiles_Disabled(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	err := os.WriteFile(filepath.Join(tmpDir, ".wakatimeignore"), []byte("*.log\n"), 0600)
+	require.NoError(t, err)
+
+	tmpFile, err := os.Create(filepath.Join(tmpDir, "error.log"))
+	require.NoError(t, err)
+
+	defer tmpFile.Close()
+
+	h := testHeartbeat()
+	h.Entity = tmpFile.Name()
+
+	err = filter.Filter(context.Background(), h, filter.Config{})
+	require.NoError(t, err)
+
+	err = filter.Filter(context.Background(), h, filter.Config{HonorIgnoreFiles: true})
+	assert.EqualError(t, err, "filter file: skipping because ignored by .gitignore or .wakatimeignore file")
+}
+
 func testHeartbeat() heartbeat.Heartbeat {
 	return heartbeat.Heartbeat{
 		Branch:          heartbeat.PointerTo("heartbeat"),
@@ -0,0 +1,310 @@
+package filter
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// GlobRules is an ordered list of gitignore-style glob patterns. Like in a
+// .gitignore file, the last matching pattern decides whether a path matches,
+// and patterns prefixed with "!" negate the match of previous patterns.
+type GlobRules []GlobPattern
+
+// GlobPattern is a single gitignore-style glob pattern.
+type GlobPattern struct {
+	// base is the slash separated directory anchored patterns are relative to.
+	// When empty, patterns are matched against absolute paths.
+	base    string
+	dirOnly bool
+	negate  bool
+	raw     string
+	rgx     *regexp.Regexp
+}
+
+// ParseGlobRules parses gitignore-style glob patterns relative to the base
+// directory. Blank lines and lines starting with "#" are skipped. When base
+// is empty, patterns containing a slash are anchored at the filesystem root.
+//
+// Supported syntax follows gitignore:
+//   - "*" and "?" match within a path segment, "[a-z]" matches a character class
+//   - "**/" matches any leading directories, "/**" everything inside a directory
+//     and "/**/" zero or more directories
+//   - a leading "!" negates the pattern, a trailing "/" only matches directories
+//   - patterns containing a slash are anchored at the base directory, others
+//     match a file or directory name at any depth
+//   - a backslash escapes the next character, like "\#" or "\!"
+func ParseGlobRules(patterns []string, base string) (GlobRules, error) {
+	var rules GlobRules
+
+	for _, p := range patterns {
+		pattern, ok, err := parseGlobPattern(p, base)
+		if err != nil {
+			return nil, err
+		}
+
+		if ok {
+			rules = append(rules, pattern)
+		}
+	}
+
+	return rules, nil
+}
+
+// parseGlobPattern parses a single gitignore-style glob pattern relative to the
+// base directory. Returns false for blank lines and comments.
+func parseGlobPattern(line, base string) (GlobPattern, bool, error) {
+	line = strings.TrimLeft(strings.TrimRight(line, "\r\n"), " \t")
+
+	// trailing spaces are ignored unless escaped with a backslash
+	trimmed := strings.TrimRight(line, " \t")
+	if strings.HasSuffix(trimmed, `\`) && len(trimmed) < len(line) {
+		trimmed += " "
+	}
+
+	if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+		return GlobPattern{}, false, nil
+	}
+
+	pattern := GlobPattern{
+		base: strings.TrimSuffix(filepath.ToSlash(base), "/"),
+		raw:  trimmed,
+	}
+
+	if strings.HasPrefix(trimmed, "!") {
+		pattern.negate = true
+		trimmed = trimmed[1:]
+	}
+
+	if strings.HasSuffix(trimmed, "/") {
+		pattern.dirOnly = true
+		trimmed = strings.TrimRight(trimmed, "/")
+	}
+
+	if trimmed == "" {
+		return GlobPattern{}, false, nil
+	}
+
+	anchored := strings.Contains(trimmed, "/")
+
+	expr, err := globToRegex(strings.TrimPrefix(trimmed, "/"))
+	if err != nil {
+		return GlobPattern{}, false, fmt.Errorf("invalid glob pattern %q: %s", pattern.raw, err)
+	}
+
+	if !anchored {
+		expr = "(?:.*/)?" + expr
+	}
+
+	rgx, err := regexp.Compile("^" + expr + "$")
+	if err != nil {
+		return GlobPattern{}, false, fmt.Errorf("invalid glob pattern %q: %s", pattern.raw, err)
+	}
+
+	pattern.rgx = rgx
+
+	return pattern, true, nil
+}
+
+// String returns the pattern as written.
+func (p GlobPattern) String() string {
+	return p.raw
+}
+
+// Match reports whether the path is matched by the rules. As in git, a path
+// inside a matched directory is matched as well, and can't be negated by a
+// later pattern.
+func (r GlobRules) Match(fp string) bool {
+	if len(r) == 0 {
+		return false
+	}
+
+	fp = filepath.ToSlash(fp)
+
+	for i := strings.Index(fp, "/"); i >= 0; {
+		if i > 0 {
+			if matched, _ := r.match(fp[:i], true); matched {
+				return true
+			}
+		}
+
+		next := strings.Index(fp[i+1:], "/")
+		if next < 0 {
+			break
+		}
+
+		i += next + 1
+	}
+
+	matched, _ := r.match(fp, false)
+
+	return matched
+}
+
+// match returns the result of the last pattern matching the path, and whether
+// any pattern matched at all.
+func (r GlobRules) match(fp string, isDir bool) (bool, bool) {
+	var matched, ok bool
+
+	for _, p := range r {
+		if p.dirOnly && !isDir {
+			continue
+		}
+
+		rel, inBase := relativePath(fp, p.base)
+		if !inBase {
+			continue
+		}
+
+		if p.rgx.MatchString(rel) {
+			matched = !p.negate
+			ok = true
+		}
+	}
+
+	return matched, ok
+}
+
+// String returns the patterns as written, separated by comma.
+func (r GlobRules) String() string {
+	patterns := make([]string, len(r))
+	for i, p := range r {
+		patterns[i] = p.raw
+	}
+
+	return strings.Join(patterns, ", ")
+}
+
+// relativePath returns the slash separated path relative to base, and false
+// if the path is not inside base.
+func relativePath(fp, base string) (string, bool) {
+	if base == "" {
+		return strings.TrimPrefix(fp, "/"), true
+	}
+
+	if !strings.HasPrefix(fp, base+"/") {
+		return "", false
+	}
+
+	return fp[len(base)+1:], true
+}
+
+// globToRegex translates a glob pattern to a regular expression without anchors.
+func globToRegex(glob string) (string, error) {
+	var b strings.Builder
+
+	for i := 0; i < len(glob); i++ {
+		c := glob[i]
+
+		switch c {
+		case '*':
+			if strings.HasPrefix(glob[i:], "**") && (i == 0 || glob[i-1] == '/') {
+				rest := glob[i+2:]
+
+				switch {
+				case rest == "":
+					b.WriteString(".*")
+					i++
+
+					continue
+				case rest[0] == '/':
+					b.WriteString("(?:.*/)?")
+					i += 2
+
+					continue
+				}
+			}
+
+			b.WriteString("[^/]*")
+
+			for i+1 < len(glob) && glob[i+1] == '*' {
+				i++
+			}
+		case '?':
+			b.WriteString("[^/]")
+		case '[':
+			end := classEnd(glob, i)
+			if end < 0 {
+				b.WriteString(`\[`)
+				continue
+			}
+
+			b.WriteString(translateClass(glob[i+1 : end]))
+
+			i = end
+		case '\\':
+			if i+1 == len(glob) {
+				return "", errors.New("trailing backslash")
+			}
+
+			i++
+
+			b.WriteString(regexp.QuoteMeta(string(glob[i])))
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+
+	return b.String(), nil
+}
+
+// classEnd returns the index of the bracket closing the character class
+// starting at index start, or -1 if the class is not closed.
+func classEnd(glob string, start int) int {
+	i := start + 1
+
+	if i < len(glob) && (glob[i] == '!' || glob[i] == '^') {
+		i++
+	}
+
+	// a closing bracket directly after the opening one is part of the class
+	if i < len(glob) && glob[i] == ']' {
+		i++
+	}
+
+	for ; i < len(glob); i++ {
+		switch glob[i] {
+		case '\\':
+			i++
+		case ']':
+			return i
+		}
+	}
+
+	return -1
+}
+
+// translateClass translates the content of a glob character class to a
+// regular expression character class. Negated classes don't match a slash.
+func translateClass(class string) string {
+	var b strings.Builder
+
+	b.WriteString("[")
+
+	if strings.HasPrefix(class, "!") || strings.HasPrefix(class, "^") {
+		b.WriteString("^/")
+
+		class = class[1:]
+	}
+
+	for i := 0; i < len(class); i++ {
+		c := class[i]
+
+		switch {
+		case c == '\\' && i+1 < len(class):
+			i++
+
+			b.WriteString(regexp.QuoteMeta(string(class[i])))
+		case c == '-':
+			b.WriteByte(c)
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+
+	b.WriteString("]")
+
+	return b.String()
+}
@@ -0,0 +1,138 @@
+package filter_test
+
+import (
+	"testing"
+
+	"github.com/wakatime/wakatime-cli/pkg/filter"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGlobRules_Match(t *testing.T) {
+	tests := map[string]struct {
+		Patterns []string
+		Base     string
+		Path     string
+		Expected bool
+	}{
+		"name at any depth": {
+			Patterns: []string{"*.log"},
+			Path:     "/home/user/project/logs/debug.log",
+			Expected: true,
+		},
+		"name no match": {
+			Patterns: []string{"*.log"},
+			Path:     "/home/user/project/main.go",
+		},
+		"double star directory": {
+			Patterns: []string{"**/node_modules/**"},
+			Path:     "/home/user/project/node_modules/lib/index.js",
+			Expected: true,
+		},
+		"directory name": {
+			Patterns: []string{"node_modules/"},
+			Path:     "/home/user/project/web/node_modules/lib/index.js",
+			Expected: true,
+		},
+		"directory only pattern doesn't match file": {
+			Patterns: []string{"build/"},
+			Path:     "/home/user/project/build",
+		},
+		"anchored at root": {
+			Patterns: []string{"/home/user/secret/**"},
+			Path:     "/home/user/secret/notes.txt",
+			Expected: true,
+		},
+		"anchored at root no match": {
+			Patterns: []string{"/user/secret/**"},
+			Path:     "/home/user/secret/notes.txt",
+		},
+		"anchored at base": {
+			Patterns: []string{"/dist"},
+			Base:     "/home/user/project",
+			Path:     "/home/user/project/dist/app.js",
+			Expected: true,
+		},
+		"anchored at base not nested": {
+			Patterns: []string{"/dist"},
+			Base:     "/home/user/project",
+			Path:     "/home/user/project/web/dist/app.js",
+		},
+		"outside base": {
+			Patterns: []string{"*.go"},
+			Base:     "/home/user/project",
+			Path:     "/home/user/other/main.go",
+		},
+		"middle double star": {
+			Patterns: []string{"src/**/gen/*.go"},
+			Base:     "/home/user/project",
+			Path:     "/home/user/project/src/gen/types.go",
+			Expected: true,
+		},
+		"negation": {
+			Patterns: []string{"*.env", "!example.env"},
+			Path:     "/home/user/project/example.env",
+		},
+		"negation overridden by later pattern": {
+			Patterns: []string{"*.env", "!example.env", "/home/user/project/example.env"},
+			Path:     "/home/user/project/example.env",
+			Expected: true,
+		},
+		"negation can't include file of excluded directory": {
+			Patterns: []string{"vendor/", "!vendor/keep.go"},
+			Base:     "/home/user/project",
+			Path:     "/home/user/project/vendor/keep.go",
+			Expected: true,
+		},
+		"question mark and class": {
+			Patterns: []string{"file?.[ch]"},
+			Path:     "/home/user/project/file1.c",
+			Expected: true,
+		},
+		"negated class": {
+			Patterns: []string{"file[!0-9].c"},
+			Path:     "/home/user/project/file1.c",
+		},
+		"escaped": {
+			Patterns: []string{`\#notes`, `\!important`},
+			Path:     "/home/user/project/!important",
+			Expected: true,
+		},
+		"single star doesn't cross directories": {
+			Patterns: []string{"/home/*/main.go"},
+			Path:     "/home/user/project/main.go",
+		},
+		"comment and blank lines": {
+			Patterns: []string{"# *.go", "", "   "},
+			Path:     "/home/user/project/main.go",
+		},
+		"windows path": {
+			Patterns: []string{"C:/Users/**/secret/"},
+			Path:     "C:/Users/user/secret/main.go",
+			Expected: true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			rules, err := filter.ParseGlobRules(test.Patterns, test.Base)
+			require.NoError(t, err)
+
+			assert.Equal(t, test.Expected, rules.Match(test.Path))
+		})
+	}
+}
+
+func TestParseGlobRules_Err(t *testing.T) {
+	_, err := filter.ParseGlobRules([]string{"*.go", `main\`}, "")
+
+	assert.EqualError(t, err, `invalid glob pattern "main\\": trailing backslash`)
+}
+
+func TestGlobRules_String(t *testing.T) {
+	rules, err := filter.ParseGlobRules([]string{"**/node_modules/**", "!*.md  "}, "")
+	require.NoError(t, err)
+
+	assert.Equal(t, "**/node_modules/**, !*.md", rules.String())
+}
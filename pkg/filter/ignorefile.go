@@ -0,0 +1,110 @@
+package filter
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/wakatime/wakatime-cli/pkg/log"
+	"github.com/wakatime/wakatime-cli/pkg/project"
+)
+
+const (
+	// gitIgnoreFile is the file name of git's ignore files.
+	gitIgnoreFile = ".gitignore"
+	// WakaTimeIgnoreFile is the file name of wakatime specific ignore files,
+	// using the same syntax as .gitignore files.
+	WakaTimeIgnoreFile = ".wakatimeignore"
+)
+
+// ignoreFiles reads the glob rules of ignore files, caching them by file path.
+type ignoreFiles struct {
+	rules map[string]GlobRules
+}
+
+func newIgnoreFiles() *ignoreFiles {
+	return &ignoreFiles{
+		rules: make(map[string]GlobRules),
+	}
+}
+
+// Match reports whether the file is ignored by .gitignore or .wakatimeignore
+// files in the directories from the project root down to the file's folder.
+// Like in git, ignore files in deeper directories take precedence, and
+// .wakatimeignore rules take precedence over .gitignore rules of the same folder.
+func (f *ignoreFiles) Match(ctx context.Context, fp string) bool {
+	fp = filepath.Clean(fp)
+	dir := filepath.Dir(fp)
+
+	root, ok := ignoreRoot(ctx, dir)
+	if !ok {
+		return false
+	}
+
+	var dirs []string
+
+	for d := dir; ; d = filepath.Dir(d) {
+		dirs = append(dirs, d)
+
+		if d == root || d == filepath.Dir(d) {
+			break
+		}
+	}
+
+	var rules GlobRules
+
+	for i := len(dirs) - 1; i >= 0; i-- {
+		for _, name := range []string{gitIgnoreFile, WakaTimeIgnoreFile} {
+			rules = append(rules, f.load(ctx, filepath.Join(dirs[i], name))...)
+		}
+	}
+
+	return rules.Match(fp)
+}
+
+// load returns the glob rules of the ignore file. Invalid patterns are skipped.
+func (f *ignoreFiles) load(ctx context.Context, fp string) GlobRules {
+	if rules, ok := f.rules[fp]; ok {
+		return rules
+	}
+
+	logger := log.Extract(ctx)
+
+	data, err := os.ReadFile(fp) // nolint:gosec
+	if err != nil && !os.IsNotExist(err) {
+		logger.Warnf("failed to read ignore file %q: %s", fp, err)
+	}
+
+	var rules GlobRules
+
+	for _, line := range strings.Split(string(data), "\n") {
+		pattern, ok, err := parseGlobPattern(line, filepath.Dir(fp))
+		if err != nil {
+			logger.Warnf("%s in ignore file %q, it will be ignored", err, fp)
+			continue
+		}
+
+		if ok {
+			rules = append(rules, pattern)
+		}
+	}
+
+	f.rules[fp] = rules
+
+	return rules
+}
+
+// ignoreRoot returns the project root, which is the folder containing the .git
+// folder, or else the folder of the closest .wakatimeignore file.
+func ignoreRoot(ctx context.Context, dir string) (string, bool) {
+	if fp, ok := project.FindFileOrDirectory(ctx, dir, ".git"); ok {
+		return filepath.Dir(fp), true
+	}
+
+	if fp, ok := project.FindFileOrDirectory(ctx, dir, WakaTimeIgnoreFile); ok {
+		return filepath.Dir(fp), true
+	}
+
+	return "", false
+}
@@ -24,6 +24,7 @@ type Heartbeat struct {
 	Branch                *string    `json:"branch,omitempty"`
 	BranchAlternate       string     `json:"-"`
 	Category              Category   `json:"category"`
+	CategoryUnset         bool       `json:"-"`
 	CursorPosition        *int       `json:"cursorpos,omitempty"`
 	Dependencies          []string   `json:"dependencies,omitempty"`
 	Entity                string     `json:"entity"`
@@ -45,6 +46,7 @@ type Heartbeat struct {
 	ProjectPath           string     `json:"-"`
 	ProjectPathOverride   string     `json:"-"`
 	ProjectRootCount      *int       `json:"project_root_count,omitempty"`
+	Ref                   int        `json:"-"`
 	Time                  float64    `json:"time"`
 	UserAgent             string     `json:"user_agent"`
 }
@@ -54,6 +56,7 @@ type Heartbeat struct {
 func New(
 	branchAlternate string,
 	category Category,
+	categoryUnset bool,
 	cursorPosition *int,
 	entity string,
 	entityType EntityType,
@@ -76,6 +79,7 @@ func New(
 	return Heartbeat{
 		BranchAlternate:      branchAlternate,
 		Category:             category,
+		CategoryUnset:        categoryUnset,
 		CursorPosition:       cursorPosition,
 		Entity:               entity,
 		EntityType:           entityType,
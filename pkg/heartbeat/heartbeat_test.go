@@ -22,6 +22,7 @@ func TestNew(t *testing.T) {
 	h := heartbeat.New(
 		"feature/branch",
 		heartbeat.CodingCategory,
+		false,
 		heartbeat.PointerTo(12),
 		"testdata/main.go",
 		heartbeat.FileType,
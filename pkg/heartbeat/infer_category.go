@@ -0,0 +1,148 @@
+package heartbeat
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/wakatime/wakatime-cli/pkg/log"
+	"github.com/wakatime/wakatime-cli/pkg/regex"
+)
+
+// nolint:gochecknoglobals
+var (
+	// testFileRegex matches test files by the conventions of common languages,
+	// like main_test.go, test_main.py, main.spec.ts or MainTest.java.
+	testFileRegex = regexp.MustCompile(
+		`(^|/)__tests__/` +
+			`|(^|/)test_[^/]+\.py$` +
+			`|_test\.(go|py|rb|exs|dart)$` +
+			`|_spec\.rb$` +
+			`|\.(spec|test)\.(js|jsx|mjs|cjs|ts|tsx|mts|cts)$` +
+			`|[a-z0-9](Test|Tests|Spec)\.(java|kt|scala|groovy|cs|swift|php)$`,
+	)
+	// docsFileRegex matches Markdown, reStructuredText and AsciiDoc files under a docs folder.
+	docsFileRegex = regexp.MustCompile(`(?i)(^|/)docs?/.*\.(md|markdown|rst|adoc|asciidoc)$`)
+	// buildFileRegex matches the file names of common build tools.
+	buildFileRegex = regexp.MustCompile(
+		`^(Makefile|makefile|GNUmakefile|[^/]+\.mk` +
+			`|CMakeLists\.txt|[^/]+\.cmake` +
+			`|meson\.build|SConstruct` +
+			`|Dockerfile|Dockerfile\.[^/]+|[^/]+\.dockerfile|Containerfile` +
+			`|build\.gradle|build\.gradle\.kts|settings\.gradle|settings\.gradle\.kts` +
+			`|pom\.xml|build\.xml|build\.sbt` +
+			`|BUILD|BUILD\.bazel|WORKSPACE|WORKSPACE\.bazel|[^/]+\.bzl` +
+			`|Jenkinsfile|justfile|Justfile|Taskfile\.ya?ml)$`,
+	)
+)
+
+// CategoryConfig defines how the category of heartbeats is inferred.
+type CategoryConfig struct {
+	// Enabled turns on inferring the category of file heartbeats without category.
+	Enabled bool
+	// Rules are checked in order before the built-in conventions.
+	Rules []CategoryRule
+}
+
+// CategoryRule sets the category of heartbeats whose entity matches a regex pattern.
+type CategoryRule struct {
+	Pattern  regex.Regex
+	Category Category
+}
+
+// ParseCategoryRule parses a rule from a regex pattern and a category,
+// separated by "=>". For ex:
+//
+//	^/work/acme/e2e/ => writing tests
+//	\.proto$ => building
+//	(?i)/notes/ => writing docs
+//
+// Whitespace around the pattern and the category is ignored.
+func ParseCategoryRule(s string) (CategoryRule, error) {
+	pattern, categoryStr, err := parseRule("category", "category", s)
+	if err != nil {
+		return CategoryRule{}, err
+	}
+
+	category, err := ParseCategory(categoryStr)
+	if err != nil {
+		return CategoryRule{}, fmt.Errorf("invalid category rule %q: %s", s, err)
+	}
+
+	return CategoryRule{
+		Pattern:  pattern,
+		Category: category,
+	}, nil
+}
+
+// String implements fmt.Stringer interface.
+func (r CategoryRule) String() string {
+	return fmt.Sprintf("%s => %s", r.Pattern, r.Category)
+}
+
+// WithCategoryInference initializes and returns a heartbeat handle option, which
+// can be used in a heartbeat processing pipeline to infer the category of file
+// heartbeats, which were sent without category.
+func WithCategoryInference(config CategoryConfig) HandleOption {
+	return func(next Handle) Handle {
+		return func(ctx context.Context, hh []Heartbeat) ([]Result, error) {
+			if !config.Enabled {
+				return next(ctx, hh)
+			}
+
+			logger := log.Extract(ctx)
+			logger.Debugln("execute category inference")
+
+			for n, h := range hh {
+				if !h.CategoryUnset || h.EntityType != FileType {
+					continue
+				}
+
+				category, ok := inferCategory(ctx, h.Entity, config.Rules)
+				if !ok {
+					continue
+				}
+
+				hh[n].Category = category
+			}
+
+			return next(ctx, hh)
+		}
+	}
+}
+
+// inferCategory returns the category of the file entity from the first matching
+// rule, or else from the conventions for test, docs and build files. Returns
+// false if the entity matches neither.
+func inferCategory(ctx context.Context, entity string, rules []CategoryRule) (Category, bool) {
+	logger := log.Extract(ctx)
+
+	for _, rule := range rules {
+		if rule.Pattern.MatchString(ctx, entity) {
+			logger.Debugf("setting category %q because matches category rule %q", rule.Category, rule.String())
+
+			return rule.Category, true
+		}
+	}
+
+	entity = strings.ReplaceAll(entity, `\`, "/")
+
+	switch {
+	case testFileRegex.MatchString(entity):
+		logger.Debugf("setting category %q because entity is a test file", WritingTestsCategory)
+
+		return WritingTestsCategory, true
+	case docsFileRegex.MatchString(entity):
+		logger.Debugf("setting category %q because entity is a docs file", WritingDocsCategory)
+
+		return WritingDocsCategory, true
+	case buildFileRegex.MatchString(path.Base(entity)):
+		logger.Debugf("setting category %q because entity is a build file", BuildingCategory)
+
+		return BuildingCategory, true
+	}
+
+	return CodingCategory, false
+}
@@ -0,0 +1,197 @@
+package heartbeat_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/wakatime/wakatime-cli/pkg/heartbeat"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithCategoryInference(t *testing.T) {
+	tests := map[string]struct {
+		Entity   string
+		Expected heartbeat.Category
+	}{
+		"go test":                {Entity: "/work/wakatime-cli/pkg/api/api_test.go", Expected: heartbeat.WritingTestsCategory},
+		"python test prefix":     {Entity: "/work/app/tests/test_views.py", Expected: heartbeat.WritingTestsCategory},
+		"python test suffix":     {Entity: "/work/app/views_test.py", Expected: heartbeat.WritingTestsCategory},
+		"typescript spec":        {Entity: "/work/app/src/user.spec.ts", Expected: heartbeat.WritingTestsCategory},
+		"javascript test":        {Entity: "/work/app/src/user.test.jsx", Expected: heartbeat.WritingTestsCategory},
+		"jest tests folder":      {Entity: "/work/app/src/__tests__/user.js", Expected: heartbeat.WritingTestsCategory},
+		"ruby spec":              {Entity: "/work/app/spec/user_spec.rb", Expected: heartbeat.WritingTestsCategory},
+		"java test":              {Entity: "/work/app/src/UserServiceTest.java", Expected: heartbeat.WritingTestsCategory},
+		"windows path":           {Entity: `C:\work\app\api_test.go`, Expected: heartbeat.WritingTestsCategory},
+		"markdown under docs":    {Entity: "/work/app/docs/guide/install.md", Expected: heartbeat.WritingDocsCategory},
+		"rst under doc":          {Entity: "/work/app/doc/index.rst", Expected: heartbeat.WritingDocsCategory},
+		"asciidoc under docs":    {Entity: "/work/app/Docs/manual.adoc", Expected: heartbeat.WritingDocsCategory},
+		"makefile":               {Entity: "/work/app/Makefile", Expected: heartbeat.BuildingCategory},
+		"cmake":                  {Entity: "/work/app/CMakeLists.txt", Expected: heartbeat.BuildingCategory},
+		"dockerfile":             {Entity: "/work/app/Dockerfile.dev", Expected: heartbeat.BuildingCategory},
+		"gradle":                 {Entity: "/work/app/build.gradle.kts", Expected: heartbeat.BuildingCategory},
+		"bazel":                  {Entity: "/work/app/BUILD.bazel", Expected: heartbeat.BuildingCategory},
+		"source file":            {Entity: "/work/app/main.go", Expected: heartbeat.CodingCategory},
+		"markdown outside docs":  {Entity: "/work/app/README.md", Expected: heartbeat.CodingCategory},
+		"python file named test": {Entity: "/work/app/latest.py", Expected: heartbeat.CodingCategory},
+		"java file ending test":  {Entity: "/work/app/Contest.java", Expected: heartbeat.CodingCategory},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			opt := heartbeat.WithCategoryInference(heartbeat.CategoryConfig{Enabled: true})
+
+			h := opt(func(_ context.Context, hh []heartbeat.Heartbeat) ([]heartbeat.Result, error) {
+				require.Len(t, hh, 1)
+
+				assert.Equal(t, test.Expected, hh[0].Category)
+
+				return []heartbeat.Result{}, nil
+			})
+
+			_, err := h(context.Background(), []heartbeat.Heartbeat{{
+				CategoryUnset: true,
+				Entity:        test.Entity,
+				EntityType:    heartbeat.FileType,
+			}})
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestWithCategoryInference_Rules(t *testing.T) {
+	opt := heartbeat.WithCategoryInference(heartbeat.CategoryConfig{
+		Enabled: true,
+		Rules: []heartbeat.CategoryRule{
+			mustParseCategoryRule(t, `^/work/app/e2e/ => writing tests`),
+			mustParseCategoryRule(t, `\.proto$ => building`),
+			// rules take precedence over the built-in conventions
+			mustParseCategoryRule(t, `_test\.go$ => coding`),
+		},
+	})
+
+	h := opt(func(_ context.Context, hh []heartbeat.Heartbeat) ([]heartbeat.Result, error) {
+		assert.Equal(t, heartbeat.WritingTestsCategory, hh[0].Category)
+		assert.Equal(t, heartbeat.BuildingCategory, hh[1].Category)
+		assert.Equal(t, heartbeat.CodingCategory, hh[2].Category)
+		assert.Equal(t, heartbeat.BuildingCategory, hh[3].Category)
+
+		return []heartbeat.Result{}, nil
+	})
+
+	_, err := h(context.Background(), []heartbeat.Heartbeat{
+		{CategoryUnset: true, Entity: "/work/app/e2e/login.js", EntityType: heartbeat.FileType},
+		{CategoryUnset: true, Entity: "/work/app/api/user.proto", EntityType: heartbeat.FileType},
+		{CategoryUnset: true, Entity: "/work/app/api/user_test.go", EntityType: heartbeat.FileType},
+		{CategoryUnset: true, Entity: "/work/app/Makefile", EntityType: heartbeat.FileType},
+	})
+	require.NoError(t, err)
+}
+
+func TestWithCategoryInference_Skipped(t *testing.T) {
+	tests := map[string]struct {
+		Config    heartbeat.CategoryConfig
+		Heartbeat heartbeat.Heartbeat
+		Expected  heartbeat.Category
+	}{
+		"disabled": {
+			Heartbeat: heartbeat.Heartbeat{
+				CategoryUnset: true,
+				Entity:        "/work/app/api_test.go",
+				EntityType:    heartbeat.FileType,
+			},
+			Expected: heartbeat.CodingCategory,
+		},
+		"category passed in": {
+			Config: heartbeat.CategoryConfig{Enabled: true},
+			Heartbeat: heartbeat.Heartbeat{
+				Category:   heartbeat.DebuggingCategory,
+				Entity:     "/work/app/api_test.go",
+				EntityType: heartbeat.FileType,
+			},
+			Expected: heartbeat.DebuggingCategory,
+		},
+		"coding category passed in": {
+			Config: heartbeat.CategoryConfig{Enabled: true},
+			Heartbeat: heartbeat.Heartbeat{
+				Category:   heartbeat.CodingCategory,
+				Entity:     "/work/app/api_test.go",
+				EntityType: heartbeat.FileType,
+			},
+			Expected: heartbeat.CodingCategory,
+		},
+		"app entity": {
+			Config: heartbeat.CategoryConfig{Enabled: true},
+			Heartbeat: heartbeat.Heartbeat{
+				CategoryUnset: true,
+				Entity:        "Makefile",
+				EntityType:    heartbeat.AppType,
+			},
+			Expected: heartbeat.CodingCategory,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			opt := heartbeat.WithCategoryInference(test.Config)
+
+			h := opt(func(_ context.Context, hh []heartbeat.Heartbeat) ([]heartbeat.Result, error) {
+				assert.Equal(t, test.Expected, hh[0].Category)
+
+				return []heartbeat.Result{}, nil
+			})
+
+			_, err := h(context.Background(), []heartbeat.Heartbeat{test.Heartbeat})
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestParseCategoryRule(t *testing.T) {
+	rule, err := heartbeat.ParseCategoryRule(`(?i)/notes/ =>  writing docs `)
+	require.NoError(t, err)
+
+	assert.Equal(t, `(?i)/notes/`, rule.Pattern.String())
+	assert.Equal(t, heartbeat.WritingDocsCategory, rule.Category)
+	assert.Equal(t, `(?i)/notes/ => writing docs`, rule.String())
+}
+
+func TestParseCategoryRule_Invalid(t *testing.T) {
+	tests := map[string]struct {
+		Value    string
+		Expected string
+	}{
+		"missing separator": {
+			Value:    `^/work/e2e/ writing tests`,
+			Expected: `invalid category rule "^/work/e2e/ writing tests", expected a regex pattern followed by => and a category`,
+		},
+		"missing pattern": {
+			Value:    ` => writing tests`,
+			Expected: `invalid category rule " => writing tests": missing regex pattern`,
+		},
+		"invalid pattern": {
+			Value:    `^/work/[e2e/ => writing tests`,
+			Expected: "invalid category rule \"^/work/[e2e/ => writing tests\": failed to compile regex \"^/work/[e2e/\": error parsing regexp: unterminated [] set in `^/work/[e2e/`",
+		},
+		"invalid category": {
+			Value:    `^/work/e2e/ => testing`,
+			Expected: `invalid category rule "^/work/e2e/ => testing": invalid category "testing"`,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := heartbeat.ParseCategoryRule(test.Value)
+
+			assert.EqualError(t, err, test.Expected)
+		})
+	}
+}
+
+func mustParseCategoryRule(t *testing.T, s string) heartbeat.CategoryRule {
+	rule, err := heartbeat.ParseCategoryRule(s)
+	require.NoError(t, err)
+
+	return rule
+}
@@ -0,0 +1,92 @@
+package heartbeat
+
+import (
+	"context"
+	"sync"
+)
+
+// OutcomeStatus is what happened to a heartbeat handled by the heartbeat command.
+type OutcomeStatus string
+
+const (
+	// OutcomeSent means the api accepted the heartbeat.
+	OutcomeSent OutcomeStatus = "sent"
+	// OutcomeQueued means the heartbeat was saved to the offline queue, to be sent later.
+	OutcomeQueued OutcomeStatus = "queued"
+	// OutcomeFiltered means a processing step dropped the heartbeat, for ex. an exclude pattern.
+	OutcomeFiltered OutcomeStatus = "filtered"
+	// OutcomeRejected means the api rejected the heartbeat, which won't be retried.
+	OutcomeRejected OutcomeStatus = "rejected"
+	// OutcomeFailed means sending failed and the heartbeat wasn't saved to the offline queue.
+	OutcomeFailed OutcomeStatus = "failed"
+	// OutcomeUnknown means no processing step recorded what happened to the heartbeat.
+	OutcomeUnknown OutcomeStatus = "unknown"
+)
+
+// Outcome describes what happened to a heartbeat.
+type Outcome struct {
+	Status OutcomeStatus `json:"status"`
+	// Reason explains why a heartbeat was filtered, queued or failed.
+	Reason string `json:"reason,omitempty"`
+	// Errors are the errors returned by the api for the heartbeat.
+	Errors []string `json:"errors,omitempty"`
+}
+
+// Outcomes records the outcome of heartbeats by their Ref. It's safe for
+// concurrent use.
+type Outcomes struct {
+	data map[int]Outcome
+	mu   sync.Mutex
+}
+
+// NewOutcomes creates a new empty Outcomes.
+func NewOutcomes() *Outcomes {
+	return &Outcomes{
+		data: make(map[int]Outcome),
+	}
+}
+
+// Get returns the last recorded outcome of the heartbeat with the ref.
+func (o *Outcomes) Get(ref int) (Outcome, bool) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	outcome, ok := o.data[ref]
+
+	return outcome, ok
+}
+
+// record saves the outcome, keeping earlier api errors if the outcome has none,
+// like for heartbeats queued after the api returned an error.
+func (o *Outcomes) record(ref int, outcome Outcome) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if len(outcome.Errors) == 0 {
+		outcome.Errors = o.data[ref].Errors
+	}
+
+	o.data[ref] = outcome
+}
+
+type ctxOutcomesMarker struct{}
+
+// nolint:gochecknoglobals
+var ctxOutcomesKey = &ctxOutcomesMarker{}
+
+// OutcomesToContext adds the outcomes to the context, so processing steps can
+// record what happened to heartbeats. Returning the new context that has been created.
+func OutcomesToContext(ctx context.Context, outcomes *Outcomes) context.Context {
+	return context.WithValue(ctx, ctxOutcomesKey, outcomes)
+}
+
+// RecordOutcome records the outcome of the heartbeat, if outcomes were added to
+// the context. Otherwise it does nothing.
+func RecordOutcome(ctx context.Context, h Heartbeat, outcome Outcome) {
+	outcomes, ok := ctx.Value(ctxOutcomesKey).(*Outcomes)
+	if !ok || outcomes == nil {
+		return
+	}
+
+	outcomes.record(h.Ref, outcome)
+}
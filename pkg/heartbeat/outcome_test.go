@@ -0,0 +1,50 @@
+package heartbeat_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/wakatime/wakatime-cli/pkg/heartbeat"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecordOutcome(t *testing.T) {
+	outcomes := heartbeat.NewOutcomes()
+	ctx := heartbeat.OutcomesToContext(context.Background(), outcomes)
+
+	h := testHeartbeat()
+	h.Ref = 2
+
+	heartbeat.RecordOutcome(ctx, h, heartbeat.Outcome{
+		Status: heartbeat.OutcomeRejected,
+		Reason: "api returned status 500",
+		Errors: []string{"internal error"},
+	})
+	heartbeat.RecordOutcome(ctx, h, heartbeat.Outcome{
+		Status: heartbeat.OutcomeQueued,
+		Reason: "api returned an error status",
+	})
+
+	outcome, ok := outcomes.Get(2)
+	require.True(t, ok)
+
+	// api errors are kept when the heartbeat is queued afterwards
+	assert.Equal(t, heartbeat.Outcome{
+		Status: heartbeat.OutcomeQueued,
+		Reason: "api returned an error status",
+		Errors: []string{"internal error"},
+	}, outcome)
+
+	_, ok = outcomes.Get(0)
+	assert.False(t, ok)
+}
+
+func TestRecordOutcome_NoOutcomesInContext(t *testing.T) {
+	assert.NotPanics(t, func() {
+		heartbeat.RecordOutcome(context.Background(), testHeartbeat(), heartbeat.Outcome{
+			Status: heartbeat.OutcomeSent,
+		})
+	})
+}
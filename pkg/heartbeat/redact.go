@@ -0,0 +1,87 @@
+package heartbeat
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/wakatime/wakatime-cli/pkg/log"
+	"github.com/wakatime/wakatime-cli/pkg/regex"
+)
+
+// RedactRule rewrites the parts of a value matching a regex pattern. The
+// replacement can reference capture groups, like $1 or ${name}.
+type RedactRule struct {
+	Pattern     regex.Regex
+	Replacement string
+}
+
+// ParseRedactRule parses a rule from a regex pattern and a replacement,
+// separated by "=>". For ex:
+//
+//	^/work/clients/[^/]+/ => /work/clients/client/
+//	^/home/[^/]+/ => ~/
+//	(?i)^(feature|bugfix)/.* => $1
+//
+// Whitespace around the pattern and the replacement is ignored, and an empty
+// replacement removes the matching parts.
+func ParseRedactRule(s string) (RedactRule, error) {
+	pattern, replacement, err := parseRule("redact", "replacement", s)
+	if err != nil {
+		return RedactRule{}, err
+	}
+
+	return RedactRule{
+		Pattern:     pattern,
+		Replacement: replacement,
+	}, nil
+}
+
+// String implements fmt.Stringer interface.
+func (r RedactRule) String() string {
+	return fmt.Sprintf("%s => %s", r.Pattern, r.Replacement)
+}
+
+// redact applies the rules in order to the entity, project, branch and
+// dependencies. Every rule is applied to the result of the previous rules.
+func redact(ctx context.Context, h Heartbeat, rules []RedactRule) Heartbeat {
+	if len(rules) == 0 {
+		return h
+	}
+
+	h.Entity = redactValue(ctx, "entity", h.Entity, rules)
+
+	if h.Project != nil {
+		project := redactValue(ctx, "project", *h.Project, rules)
+		h.Project = &project
+	}
+
+	if h.Branch != nil {
+		branch := redactValue(ctx, "branch", *h.Branch, rules)
+		h.Branch = &branch
+	}
+
+	if len(h.Dependencies) > 0 {
+		dependencies := make([]string, len(h.Dependencies))
+		for i, dep := range h.Dependencies {
+			dependencies[i] = redactValue(ctx, "dependency", dep, rules)
+		}
+
+		h.Dependencies = dependencies
+	}
+
+	return h
+}
+
+func redactValue(ctx context.Context, field, value string, rules []RedactRule) string {
+	for _, rule := range rules {
+		if !rule.Pattern.MatchString(ctx, value) {
+			continue
+		}
+
+		log.Extract(ctx).Debugf("redacting %s because matches redact rule %q", field, rule.String())
+
+		value = rule.Pattern.ReplaceAllString(ctx, value, rule.Replacement)
+	}
+
+	return value
+}
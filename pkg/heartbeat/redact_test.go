@@ -0,0 +1,201 @@
+package heartbeat_test
+
+import (
+	"context"
+	"regexp"
+	"testing"
+
+	"github.com/wakatime/wakatime-cli/pkg/heartbeat"
+	"github.com/wakatime/wakatime-cli/pkg/regex"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSanitize_Redact(t *testing.T) {
+	h := testHeartbeat()
+	h.Branch = heartbeat.PointerTo("feature/acme-login")
+	h.Dependencies = []string{"github.com/acme/sdk", "fmt"}
+	h.Entity = "/work/clients/acme/src/main.go"
+	h.Project = heartbeat.PointerTo("acme-portal")
+
+	r := heartbeat.Sanitize(context.Background(), h, heartbeat.SanitizeConfig{
+		RedactRules: []heartbeat.RedactRule{
+			mustParseRedactRule(t, `^/work/clients/[^/]+/ => /work/clients/client/`),
+			mustParseRedactRule(t, `acme => client`),
+		},
+	})
+
+	assert.Equal(t, "/work/clients/client/src/main.go", r.Entity)
+	assert.Equal(t, "client-portal", *r.Project)
+	assert.Equal(t, "feature/client-login", *r.Branch)
+	assert.Equal(t, []string{"github.com/client/sdk", "fmt"}, r.Dependencies)
+
+	// the original heartbeat is left unchanged
+	assert.Equal(t, "acme-portal", *h.Project)
+	assert.Equal(t, []string{"github.com/acme/sdk", "fmt"}, h.Dependencies)
+}
+
+func TestSanitize_Redact_CaptureGroups(t *testing.T) {
+	h := testHeartbeat()
+	h.Entity = "/home/alice/projects/wakatime/main.go"
+
+	r := heartbeat.Sanitize(context.Background(), h, heartbeat.SanitizeConfig{
+		RedactRules: []heartbeat.RedactRule{
+			mustParseRedactRule(t, `^/home/[^/]+/ => ~/`),
+			mustParseRedactRule(t, `^~/(?P<folder>[^/]+)/[^/]+/ => ~/${folder}/project/`),
+		},
+	})
+
+	assert.Equal(t, "~/projects/project/main.go", r.Entity)
+}
+
+func TestSanitize_Redact_Lookahead(t *testing.T) {
+	h := testHeartbeat()
+	h.Entity = "/home/alice/projects/wakatime/main.go"
+	h.Branch = heartbeat.PointerTo("/home/root/main.go")
+
+	r := heartbeat.Sanitize(context.Background(), h, heartbeat.SanitizeConfig{
+		RedactRules: []heartbeat.RedactRule{
+			mustParseRedactRule(t, `^/home/(?!root/)[^/]+/(?<rest>.*) => ~/${rest}`),
+		},
+	})
+
+	assert.Equal(t, "~/projects/wakatime/main.go", r.Entity)
+	assert.Equal(t, "/home/root/main.go", *r.Branch)
+}
+
+func TestSanitize_Redact_Order(t *testing.T) {
+	tests := map[string]struct {
+		Rules    []string
+		Expected string
+	}{
+		"specific rule first": {
+			Rules: []string{
+				`^/work/clients/acme/ => /work/acme/`,
+				`^/work/clients/[^/]+/ => /work/client/`,
+			},
+			Expected: "/work/acme/main.go",
+		},
+		"generic rule first": {
+			Rules: []string{
+				`^/work/clients/[^/]+/ => /work/client/`,
+				`^/work/clients/acme/ => /work/acme/`,
+			},
+			Expected: "/work/client/main.go",
+		},
+		"rule applied to result of previous rule": {
+			Rules: []string{
+				`^/work/clients/[^/]+/ => /work/client/`,
+				`^/work/client/ => ~/`,
+			},
+			Expected: "~/main.go",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			h := testHeartbeat()
+			h.Entity = "/work/clients/acme/main.go"
+
+			var rules []heartbeat.RedactRule
+			for _, s := range test.Rules {
+				rules = append(rules, mustParseRedactRule(t, s))
+			}
+
+			r := heartbeat.Sanitize(context.Background(), h, heartbeat.SanitizeConfig{
+				RedactRules: rules,
+			})
+
+			assert.Equal(t, test.Expected, r.Entity)
+		})
+	}
+}
+
+func TestSanitize_Redact_AfterHiding(t *testing.T) {
+	h := testHeartbeat()
+	h.Entity = "/work/clients/acme/src/main.go"
+	h.ProjectPath = "/work/clients/acme"
+
+	r := heartbeat.Sanitize(context.Background(), h, heartbeat.SanitizeConfig{
+		BranchPatterns:    []regex.Regex{regex.NewRegexpWrap(regexp.MustCompile("^heartbeat$"))},
+		HideProjectFolder: true,
+		RedactRules: []heartbeat.RedactRule{
+			// hide patterns are matched against the original values
+			mustParseRedactRule(t, `heartbeat => redacted`),
+			// redaction is applied to the entity relative to the project folder
+			mustParseRedactRule(t, `^src/ => source/`),
+		},
+	})
+
+	assert.Nil(t, r.Branch)
+	assert.Equal(t, "source/main.go", r.Entity)
+}
+
+func TestParseRedactRule(t *testing.T) {
+	tests := map[string]struct {
+		Value               string
+		ExpectedPattern     string
+		ExpectedReplacement string
+	}{
+		"replacement": {
+			Value:               `^/home/[^/]+/ => ~/`,
+			ExpectedPattern:     `^/home/[^/]+/`,
+			ExpectedReplacement: `~/`,
+		},
+		"no whitespace": {
+			Value:               `(?i)acme=>client`,
+			ExpectedPattern:     `(?i)acme`,
+			ExpectedReplacement: `client`,
+		},
+		"empty replacement": {
+			Value:           `-acme$ =>`,
+			ExpectedPattern: `-acme$`,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			rule, err := heartbeat.ParseRedactRule(test.Value)
+			require.NoError(t, err)
+
+			assert.Equal(t, test.ExpectedPattern, rule.Pattern.String())
+			assert.Equal(t, test.ExpectedReplacement, rule.Replacement)
+		})
+	}
+}
+
+func TestParseRedactRule_Invalid(t *testing.T) {
+	tests := map[string]struct {
+		Value    string
+		Expected string
+	}{
+		"missing separator": {
+			Value:    `^/home/[^/]+/ ~/`,
+			Expected: `invalid redact rule "^/home/[^/]+/ ~/", expected a regex pattern followed by => and a replacement`,
+		},
+		"missing pattern": {
+			Value:    ` => ~/`,
+			Expected: `invalid redact rule " => ~/": missing regex pattern`,
+		},
+		"invalid pattern": {
+			Value:    `^/home/[^/+/ => ~/`,
+			Expected: "invalid redact rule \"^/home/[^/+/ => ~/\": failed to compile regex \"^/home/[^/+/\": error parsing regexp: unterminated [] set in `^/home/[^/+/`",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := heartbeat.ParseRedactRule(test.Value)
+
+			assert.EqualError(t, err, test.Expected)
+		})
+	}
+}
+
+func mustParseRedactRule(t *testing.T, s string) heartbeat.RedactRule {
+	rule, err := heartbeat.ParseRedactRule(s)
+	require.NoError(t, err)
+
+	return rule
+}
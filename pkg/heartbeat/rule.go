@@ -0,0 +1,30 @@
+package heartbeat
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/wakatime/wakatime-cli/pkg/regex"
+)
+
+// parseRule parses a rule line of a regex pattern and a value, separated by
+// "=>", like the redact and category rules. Whitespace around the pattern and
+// the value is ignored. The kind and value names are used in error messages.
+func parseRule(kind, valueName, s string) (regex.Regex, string, error) {
+	pattern, value, ok := strings.Cut(s, "=>")
+	if !ok {
+		return nil, "", fmt.Errorf("invalid %s rule %q, expected a regex pattern followed by => and a %s", kind, s, valueName)
+	}
+
+	pattern = strings.TrimSpace(pattern)
+	if pattern == "" {
+		return nil, "", fmt.Errorf("invalid %s rule %q: missing regex pattern", kind, s)
+	}
+
+	compiled, err := regex.Compile(pattern)
+	if err != nil {
+		return nil, "", fmt.Errorf("invalid %s rule %q: %s", kind, s, err)
+	}
+
+	return compiled, strings.TrimSpace(value), nil
+}
@@ -2,6 +2,7 @@ package heartbeat
 
 import (
 	"context"
+	"fmt"
 	"path/filepath"
 	"strings"
 
@@ -9,18 +10,62 @@ import (
 	"github.com/wakatime/wakatime-cli/pkg/regex"
 )
 
+// HideMode defines how hidden names are obfuscated.
+type HideMode string
+
+const (
+	// HideModeHidden replaces hidden file names with HIDDEN and removes hidden branch names.
+	HideModeHidden HideMode = "hidden"
+	// HideModeHash replaces hidden file, branch and project names with a keyed digest,
+	// which can be revealed locally.
+	HideModeHash HideMode = "hash"
+)
+
+// ParseHideMode parses a hide mode. Empty string is returned as is, and
+// defaults to hidden.
+func ParseHideMode(s string) (HideMode, error) {
+	switch m := HideMode(strings.ToLower(strings.TrimSpace(s))); m {
+	case "", HideModeHidden, HideModeHash:
+		return m, nil
+	default:
+		return "", fmt.Errorf("invalid hide mode %q, expected one of %q or %q", s, HideModeHidden, HideModeHash)
+	}
+}
+
+// Hasher replaces names with a digest, which doesn't reveal the name.
+type Hasher interface {
+	Hash(ctx context.Context, name string) (string, error)
+}
+
 // SanitizeConfig defines how a heartbeat should be sanitized.
 type SanitizeConfig struct {
 	// BranchPatterns will be matched against the branch and if matching, will obfuscate it.
 	BranchPatterns []regex.Regex
+	// DomainPatterns will be matched against the domain of domain and url entities and if
+	// matching, will obfuscate the domain and remove the url path.
+	DomainPatterns []regex.Regex
 	// FilePatterns will be matched against a file entity's name and if matching will obfuscate
 	// the file name and common heartbeat meta data (cursor position, dependencies, line number and lines).
 	FilePatterns []regex.Regex
+	// Hasher, when set, replaces obfuscated file and branch names with a digest,
+	// instead of using HIDDEN and removing the branch.
+	Hasher Hasher
 	// HideProjectFolder determines if project folder should be obfuscated.
 	HideProjectFolder bool
 	// ProjectPatterns will be matched against the project name and if matching will obfuscate
 	// common heartbeat meta data (cursor position, dependencies, line number and lines).
 	ProjectPatterns []regex.Regex
+	// RedactRules rewrite the entity, project, branch and dependencies. They are applied
+	// in order, after hiding, so hide patterns are matched against the original values.
+	RedactRules []RedactRule
+	// URLPathPatterns will be matched against url entities and if matching, will remove
+	// the path, keeping only the scheme and host.
+	URLPathPatterns []regex.Regex
+	// URLQueryKeep are the names of url query params, which are never removed.
+	URLQueryKeep []string
+	// URLQueryPatterns will be matched against the names of url query params and if
+	// matching, will remove the param.
+	URLQueryPatterns []regex.Regex
 }
 
 // WithSanitization initializes and returns a heartbeat handle option, which
@@ -43,39 +88,113 @@ func WithSanitization(config SanitizeConfig) HandleOption {
 // Sanitize accepts a heartbeat sanitizes it's sensitive data following passed
 // in configuration and returns the sanitized version. On empty config will do nothing.
 func Sanitize(ctx context.Context, h Heartbeat, config SanitizeConfig) Heartbeat {
+	logger := log.Extract(ctx)
+
 	if len(h.Dependencies) == 0 {
 		h.Dependencies = nil
 	}
 
-	switch {
-	case ShouldSanitize(ctx, h.Entity, config.FilePatterns):
-		if h.EntityType == FileType {
-			h.Entity = "HIDDEN" + filepath.Ext(h.Entity)
-		} else {
-			h.Entity = "HIDDEN"
-		}
+	if pattern, ok := matchingPattern(ctx, h.Entity, config.FilePatterns); ok {
+		logger.Debugf("hiding entity because matches hide file names pattern %q", pattern.String())
 
-		h = sanitizeMetaData(h)
+		h = HideEntity(ctx, h, config.BranchPatterns, config.Hasher)
+	} else if h.Project != nil {
+		if pattern, ok := matchingPattern(ctx, *h.Project, config.ProjectPatterns); ok {
+			logger.Debugf("hiding metadata because project matches hide project names pattern %q", pattern.String())
 
-		if h.Branch != nil && (len(config.BranchPatterns) == 0 || ShouldSanitize(ctx, *h.Branch, config.BranchPatterns)) {
-			h.Branch = nil
-		}
-	case h.Project != nil && ShouldSanitize(ctx, *h.Project, config.ProjectPatterns):
-		h = sanitizeMetaData(h)
-		if h.Branch != nil && (len(config.BranchPatterns) == 0 || ShouldSanitize(ctx, *h.Branch, config.BranchPatterns)) {
-			h.Branch = nil
+			h = sanitizeMetaData(h)
+			h = hideBranch(ctx, h, config.BranchPatterns, true, config.Hasher)
+		} else {
+			h = hideBranch(ctx, h, config.BranchPatterns, false, config.Hasher)
 		}
-	case h.Branch != nil && ShouldSanitize(ctx, *h.Branch, config.BranchPatterns):
-		h.Branch = nil
+	} else {
+		h = hideBranch(ctx, h, config.BranchPatterns, false, config.Hasher)
 	}
 
 	h = hideProjectFolder(h, config.HideProjectFolder)
 
 	h = hideCredentials(h)
 
+	switch h.EntityType {
+	case DomainType:
+		h = sanitizeDomain(ctx, h, config)
+	case URLType:
+		h = sanitizeURL(ctx, h, config)
+	}
+
+	h = redact(ctx, h, config.RedactRules)
+
 	return h
 }
 
+// HideEntity obfuscates the entity and common heartbeat meta data (cursor position,
+// dependencies, line number and lines), the same way as for entities matching
+// hide file names patterns. The branch is removed, when no branch patterns are
+// passed in or the branch matches one of them. When a hasher is passed in, the
+// entity and branch are replaced with their digest instead.
+func HideEntity(ctx context.Context, h Heartbeat, branchPatterns []regex.Regex, hasher Hasher) Heartbeat {
+	entity := "HIDDEN"
+
+	if hashed, ok := hashName(ctx, h.Entity, hasher); ok {
+		entity = hashed
+	}
+
+	if h.EntityType == FileType {
+		entity += filepath.Ext(h.Entity)
+	}
+
+	h.Entity = entity
+
+	h = sanitizeMetaData(h)
+
+	return hideBranch(ctx, h, branchPatterns, true, hasher)
+}
+
+// hideBranch removes the branch if it matches the branch patterns. When the
+// entity or project is hidden, the branch is also removed without any branch patterns.
+// When a hasher is passed in, the branch is replaced with its digest instead.
+func hideBranch(ctx context.Context, h Heartbeat, patterns []regex.Regex, hidden bool, hasher Hasher) Heartbeat {
+	if h.Branch == nil {
+		return h
+	}
+
+	if !hidden || len(patterns) > 0 {
+		pattern, ok := matchingPattern(ctx, *h.Branch, patterns)
+		if !ok {
+			return h
+		}
+
+		log.Extract(ctx).Debugf("hiding branch because matches hide branch names pattern %q", pattern.String())
+	}
+
+	if hashed, ok := hashName(ctx, *h.Branch, hasher); ok {
+		h.Branch = &hashed
+
+		return h
+	}
+
+	h.Branch = nil
+
+	return h
+}
+
+// hashName returns the digest of the name, or false if no hasher is passed in
+// or hashing failed.
+func hashName(ctx context.Context, name string, hasher Hasher) (string, bool) {
+	if hasher == nil {
+		return "", false
+	}
+
+	hashed, err := hasher.Hash(ctx, name)
+	if err != nil {
+		log.Extract(ctx).Warnf("failed to hash name, hiding it instead: %s", err)
+
+		return "", false
+	}
+
+	return hashed, true
+}
+
 // hideProjectFolder makes entity relative to project folder if we're hiding the project folder.
 func hideProjectFolder(h Heartbeat, hideProjectFolder bool) Heartbeat {
 	if h.EntityType != FileType || !hideProjectFolder {
@@ -145,11 +264,18 @@ func sanitizeMetaData(h Heartbeat) Heartbeat {
 // checks it against the passed in regex patterns to determine, if this heartbeat
 // should be sanitized.
 func ShouldSanitize(ctx context.Context, subject string, patterns []regex.Regex) bool {
+	_, ok := matchingPattern(ctx, subject, patterns)
+
+	return ok
+}
+
+// matchingPattern returns the first pattern matching the subject.
+func matchingPattern(ctx context.Context, subject string, patterns []regex.Regex) (regex.Regex, bool) {
 	for _, p := range patterns {
 		if p.MatchString(ctx, subject) {
-			return true
+			return p, true
 		}
 	}
 
-	return false
+	return nil, false
 }
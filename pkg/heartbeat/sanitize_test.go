@@ -2,6 +2,7 @@ package heartbeat_test
 
 import (
 	"context"
+	"errors"
 	"regexp"
 	"testing"
 
@@ -390,6 +391,79 @@ func TestSanitize_ObfuscateCredentials_RemoteFile(t *testing.T) {
 	}, r)
 }
 
+func TestSanitize_Hash_File(t *testing.T) {
+	r := heartbeat.Sanitize(context.Background(), testHeartbeat(), heartbeat.SanitizeConfig{
+		FilePatterns: []regex.Regex{regex.NewRegexpWrap(regexp.MustCompile(".*"))},
+		Hasher:       fakeHasher{},
+	})
+
+	assert.Equal(t, heartbeat.Heartbeat{
+		Branch:     heartbeat.PointerTo("hash(heartbeat)"),
+		Category:   heartbeat.CodingCategory,
+		Entity:     "hash(/tmp/main.go).go",
+		EntityType: heartbeat.FileType,
+		IsWrite:    heartbeat.PointerTo(true),
+		Language:   heartbeat.PointerTo("Go"),
+		Project:    heartbeat.PointerTo("wakatime"),
+		Time:       1585598060,
+		UserAgent:  "wakatime/13.0.7",
+	}, r)
+}
+
+func TestSanitize_Hash_Branch(t *testing.T) {
+	r := heartbeat.Sanitize(context.Background(), testHeartbeat(), heartbeat.SanitizeConfig{
+		BranchPatterns: []regex.Regex{regex.NewRegexpWrap(regexp.MustCompile("^heart"))},
+		Hasher:         fakeHasher{},
+	})
+
+	expected := testHeartbeat()
+	expected.Branch = heartbeat.PointerTo("hash(heartbeat)")
+
+	assert.Equal(t, expected, r)
+}
+
+func TestSanitize_Hash_Error(t *testing.T) {
+	r := heartbeat.Sanitize(context.Background(), testHeartbeat(), heartbeat.SanitizeConfig{
+		FilePatterns: []regex.Regex{regex.NewRegexpWrap(regexp.MustCompile(".*"))},
+		Hasher:       fakeHasher{Err: errors.New("failed")},
+	})
+
+	assert.Equal(t, heartbeat.Heartbeat{
+		Category:   heartbeat.CodingCategory,
+		Entity:     "HIDDEN.go",
+		EntityType: heartbeat.FileType,
+		IsWrite:    heartbeat.PointerTo(true),
+		Language:   heartbeat.PointerTo("Go"),
+		Project:    heartbeat.PointerTo("wakatime"),
+		Time:       1585598060,
+		UserAgent:  "wakatime/13.0.7",
+	}, r)
+}
+
+func TestParseHideMode(t *testing.T) {
+	tests := map[string]heartbeat.HideMode{
+		"":         "",
+		"hidden":   heartbeat.HideModeHidden,
+		" Hash ":   heartbeat.HideModeHash,
+		"HIDDEN\n": heartbeat.HideModeHidden,
+	}
+
+	for value, expected := range tests {
+		t.Run(value, func(t *testing.T) {
+			mode, err := heartbeat.ParseHideMode(value)
+			require.NoError(t, err)
+
+			assert.Equal(t, expected, mode)
+		})
+	}
+}
+
+func TestParseHideMode_Invalid(t *testing.T) {
+	_, err := heartbeat.ParseHideMode("hashed")
+
+	assert.EqualError(t, err, `invalid hide mode "hashed", expected one of "hidden" or "hash"`)
+}
+
 func TestShouldSanitize(t *testing.T) {
 	ctx := context.Background()
 
@@ -432,6 +506,18 @@ func TestShouldSanitize(t *testing.T) {
 	}
 }
 
+type fakeHasher struct {
+	Err error
+}
+
+func (h fakeHasher) Hash(_ context.Context, name string) (string, error) {
+	if h.Err != nil {
+		return "", h.Err
+	}
+
+	return "hash(" + name + ")", nil
+}
+
 func testHeartbeat() heartbeat.Heartbeat {
 	return heartbeat.Heartbeat{
 		Branch:         heartbeat.PointerTo("heartbeat"),
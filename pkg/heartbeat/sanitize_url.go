@@ -0,0 +1,150 @@
+package heartbeat
+
+import (
+	"context"
+	"net/url"
+	"strings"
+
+	"github.com/wakatime/wakatime-cli/pkg/log"
+	"github.com/wakatime/wakatime-cli/pkg/regex"
+)
+
+// sanitizeDomain hides the domain of a domain entity, if it matches the domain patterns.
+func sanitizeDomain(ctx context.Context, h Heartbeat, config SanitizeConfig) Heartbeat {
+	pattern, ok := matchingPattern(ctx, h.Entity, config.DomainPatterns)
+	if !ok {
+		return h
+	}
+
+	log.Extract(ctx).Debugf("hiding domain because matches hide domains pattern %q", pattern.String())
+
+	h.Entity = hideDomainName(ctx, h.Entity, config.Hasher)
+
+	return h
+}
+
+// sanitizeURL removes credentials of a url entity, and depending on the passed
+// in configuration, hides its domain, removes its path or removes query params,
+// including params passed in the fragment.
+// Entities which can't be parsed as an absolute url are left unchanged.
+func sanitizeURL(ctx context.Context, h Heartbeat, config SanitizeConfig) Heartbeat {
+	logger := log.Extract(ctx)
+
+	u, err := url.Parse(h.Entity)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return h
+	}
+
+	// the entity is only re-serialized when something was removed, as that
+	// changes its escaping
+	var changed bool
+
+	if u.User != nil {
+		logger.Debugln("removing credentials from url")
+
+		u.User = nil
+		changed = true
+	}
+
+	if pattern, ok := matchingPattern(ctx, u.Hostname(), config.DomainPatterns); ok {
+		logger.Debugf("hiding domain because matches hide domains pattern %q", pattern.String())
+
+		h.Entity = u.Scheme + "://" + hideDomainName(ctx, u.Hostname(), config.Hasher)
+
+		return h
+	}
+
+	if pattern, ok := matchingPattern(ctx, u.String(), config.URLPathPatterns); ok {
+		logger.Debugf("removing url path because matches hide url paths pattern %q", pattern.String())
+
+		h.Entity = u.Scheme + "://" + u.Host
+
+		return h
+	}
+
+	if len(config.URLQueryPatterns) > 0 && u.RawQuery != "" {
+		if filtered := filterQuery(ctx, u.RawQuery, config.URLQueryPatterns, config.URLQueryKeep); filtered != u.RawQuery {
+			u.RawQuery = filtered
+			u.ForceQuery = false
+			changed = true
+		}
+	}
+
+	// fragments like #access_token=abc&state=xyz carry params the same way as
+	// the query, for ex. in oauth implicit flow redirects
+	if len(config.URLQueryPatterns) > 0 && strings.Contains(u.Fragment, "=") {
+		rawFragment := u.EscapedFragment()
+
+		if filtered := filterQuery(ctx, rawFragment, config.URLQueryPatterns, config.URLQueryKeep); filtered != rawFragment {
+			u.RawFragment = filtered
+
+			u.Fragment, err = url.PathUnescape(filtered)
+			if err != nil {
+				u.Fragment = filtered
+			}
+
+			changed = true
+		}
+	}
+
+	if !changed {
+		return h
+	}
+
+	h.Entity = u.String()
+
+	return h
+}
+
+// hideDomainName returns the digest of the domain when a hasher is passed in, or HIDDEN otherwise.
+func hideDomainName(ctx context.Context, domain string, hasher Hasher) string {
+	if hashed, ok := hashName(ctx, domain, hasher); ok {
+		return hashed
+	}
+
+	return "HIDDEN"
+}
+
+// filterQuery removes the params of a raw url query whose name matches any of
+// the patterns, unless it's one of the names to keep. The order of the
+// remaining params is kept.
+func filterQuery(ctx context.Context, rawQuery string, patterns []regex.Regex, keep []string) string {
+	var params []string
+
+	for _, param := range strings.Split(rawQuery, "&") {
+		if param == "" {
+			continue
+		}
+
+		name, _, _ := strings.Cut(param, "=")
+		if unescaped, err := url.QueryUnescape(name); err == nil {
+			name = unescaped
+		}
+
+		if !containsFold(keep, name) {
+			if pattern, ok := matchingPattern(ctx, name, patterns); ok {
+				log.Extract(ctx).Debugf(
+					"removing url query param %q because matches hide url query params pattern %q",
+					name,
+					pattern.String(),
+				)
+
+				continue
+			}
+		}
+
+		params = append(params, param)
+	}
+
+	return strings.Join(params, "&")
+}
+
+func containsFold(values []string, s string) bool {
+	for _, value := range values {
+		if strings.EqualFold(value, s) {
+			return true
+		}
+	}
+
+	return false
+}
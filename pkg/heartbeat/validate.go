@@ -0,0 +1,153 @@
+package heartbeat
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/wakatime/wakatime-cli/pkg/log"
+)
+
+const (
+	// maxEntityLength is the maximum length of an entity accepted by the api.
+	maxEntityLength = 2048
+	// maxDependencies is the maximum number of dependencies of a heartbeat.
+	maxDependencies = 1000
+	// maxDependencyLength is the maximum length of a dependency.
+	maxDependencyLength = 200
+	// maxFutureSkew is the tolerated clock skew for heartbeats in the future.
+	maxFutureSkew = time.Minute
+	// DefaultMaxAgeDays is the default maximum age of heartbeats in days.
+	DefaultMaxAgeDays = 365
+)
+
+// ValidateConfig contains validation configurations.
+type ValidateConfig struct {
+	// MaxAge is the maximum age of heartbeats, like the retention window of the api.
+	// Zero disables the check.
+	MaxAge time.Duration
+}
+
+// Invalid is a heartbeat failing validation, along with the reason.
+type Invalid struct {
+	Heartbeat Heartbeat
+	Reason    string
+}
+
+// WithValidation initializes and returns a heartbeat handle option, which
+// can be used in a heartbeat processing pipeline to drop heartbeats, which
+// would be rejected by the api.
+func WithValidation(config ValidateConfig) HandleOption {
+	return func(next Handle) Handle {
+		return func(ctx context.Context, hh []Heartbeat) ([]Result, error) {
+			logger := log.Extract(ctx)
+			logger.Debugln("execute heartbeat validation")
+
+			valid, invalid := SplitInvalid(hh, config, time.Now())
+
+			for _, i := range invalid {
+				logger.Warnf("dropping invalid heartbeat for entity %q: %s", i.Heartbeat.Entity, i.Reason)
+
+				RecordOutcome(ctx, i.Heartbeat, Outcome{
+					Status: OutcomeFiltered,
+					Reason: "invalid heartbeat: " + i.Reason,
+				})
+			}
+
+			return next(ctx, valid)
+		}
+	}
+}
+
+// SplitInvalid validates the heartbeats, returning valid and invalid heartbeats.
+func SplitInvalid(hh []Heartbeat, config ValidateConfig, now time.Time) ([]Heartbeat, []Invalid) {
+	var (
+		valid   = make([]Heartbeat, 0, len(hh))
+		invalid []Invalid
+	)
+
+	for _, h := range hh {
+		if err := Validate(h, config, now); err != nil {
+			invalid = append(invalid, Invalid{Heartbeat: h, Reason: err.Error()})
+			continue
+		}
+
+		valid = append(valid, h)
+	}
+
+	return valid, invalid
+}
+
+// Validate checks if a heartbeat would be accepted by the api, returning an
+// error describing the first problem found.
+func Validate(h Heartbeat, config ValidateConfig, now time.Time) error {
+	if h.Entity == "" {
+		return errors.New("entity is empty")
+	}
+
+	if len(h.Entity) > maxEntityLength {
+		return fmt.Errorf("entity length %d exceeds maximum of %d", len(h.Entity), maxEntityLength)
+	}
+
+	if h.Category.String() == "" {
+		return fmt.Errorf("invalid category %d", h.Category)
+	}
+
+	if h.EntityType.String() == "" {
+		return fmt.Errorf("invalid entity type %d", h.EntityType)
+	}
+
+	if err := validateTime(h.Time, config, now); err != nil {
+		return err
+	}
+
+	for _, field := range []struct {
+		name  string
+		value *int
+	}{
+		{"cursorpos", h.CursorPosition},
+		{"line_additions", h.LineAdditions},
+		{"line_deletions", h.LineDeletions},
+		{"lineno", h.LineNumber},
+		{"lines", h.Lines},
+	} {
+		if field.value != nil && *field.value < 0 {
+			return fmt.Errorf("%s %d is negative", field.name, *field.value)
+		}
+	}
+
+	if len(h.Dependencies) > maxDependencies {
+		return fmt.Errorf("%d dependencies exceed maximum of %d", len(h.Dependencies), maxDependencies)
+	}
+
+	for _, d := range h.Dependencies {
+		if d == "" || len(d) > maxDependencyLength {
+			return fmt.Errorf("dependency %q must be between 1 and %d characters", d, maxDependencyLength)
+		}
+	}
+
+	return nil
+}
+
+func validateTime(t float64, config ValidateConfig, now time.Time) error {
+	if t <= 0 {
+		return fmt.Errorf("invalid time %f", t)
+	}
+
+	at := time.Unix(0, int64(t*float64(time.Second)))
+
+	if at.After(now.Add(maxFutureSkew)) {
+		return fmt.Errorf("time %s is in the future", at.UTC().Format(time.RFC3339))
+	}
+
+	if config.MaxAge > 0 && at.Before(now.Add(-config.MaxAge)) {
+		return fmt.Errorf(
+			"time %s is older than %d days",
+			at.UTC().Format(time.RFC3339),
+			int(config.MaxAge.Hours()/24),
+		)
+	}
+
+	return nil
+}
@@ -0,0 +1,162 @@
+package heartbeat_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/wakatime/wakatime-cli/pkg/heartbeat"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidate(t *testing.T) {
+	now := time.Unix(1592868400, 0)
+
+	tests := map[string]struct {
+		Modify   func(h *heartbeat.Heartbeat)
+		Expected string
+	}{
+		"empty entity": {
+			Modify:   func(h *heartbeat.Heartbeat) { h.Entity = "" },
+			Expected: "entity is empty",
+		},
+		"entity too long": {
+			Modify:   func(h *heartbeat.Heartbeat) { h.Entity = "/" + strings.Repeat("a", 2048) },
+			Expected: "entity length 2049 exceeds maximum of 2048",
+		},
+		"invalid category": {
+			Modify:   func(h *heartbeat.Heartbeat) { h.Category = heartbeat.Category(999) },
+			Expected: "invalid category 999",
+		},
+		"invalid entity type": {
+			Modify:   func(h *heartbeat.Heartbeat) { h.EntityType = heartbeat.EntityType(999) },
+			Expected: "invalid entity type 999",
+		},
+		"zero time": {
+			Modify:   func(h *heartbeat.Heartbeat) { h.Time = 0 },
+			Expected: "invalid time 0.000000",
+		},
+		"time in the future": {
+			Modify:   func(h *heartbeat.Heartbeat) { h.Time = 1592872000 },
+			Expected: "time 2020-06-23T00:26:40Z is in the future",
+		},
+		"time too old": {
+			Modify:   func(h *heartbeat.Heartbeat) { h.Time = 1561246000 },
+			Expected: "time 2019-06-22T23:26:40Z is older than 365 days",
+		},
+		"negative cursor position": {
+			Modify:   func(h *heartbeat.Heartbeat) { h.CursorPosition = heartbeat.PointerTo(-1) },
+			Expected: "cursorpos -1 is negative",
+		},
+		"negative line number": {
+			Modify:   func(h *heartbeat.Heartbeat) { h.LineNumber = heartbeat.PointerTo(-42) },
+			Expected: "lineno -42 is negative",
+		},
+		"too many dependencies": {
+			Modify: func(h *heartbeat.Heartbeat) {
+				h.Dependencies = make([]string, 1001)
+				for i := range h.Dependencies {
+					h.Dependencies[i] = "dep"
+				}
+			},
+			Expected: "1001 dependencies exceed maximum of 1000",
+		},
+		"empty dependency": {
+			Modify:   func(h *heartbeat.Heartbeat) { h.Dependencies = []string{"dep", ""} },
+			Expected: `dependency "" must be between 1 and 200 characters`,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			h := validHeartbeat()
+			test.Modify(&h)
+
+			err := heartbeat.Validate(h, heartbeat.ValidateConfig{MaxAge: 365 * 24 * time.Hour}, now)
+
+			assert.EqualError(t, err, test.Expected)
+		})
+	}
+}
+
+func TestValidate_Valid(t *testing.T) {
+	now := time.Unix(1592868400, 0)
+
+	err := heartbeat.Validate(validHeartbeat(), heartbeat.ValidateConfig{MaxAge: 365 * 24 * time.Hour}, now)
+	require.NoError(t, err)
+
+	// zero max age disables the age check
+	h := validHeartbeat()
+	h.Time = 1000000000
+
+	err = heartbeat.Validate(h, heartbeat.ValidateConfig{}, now)
+	require.NoError(t, err)
+}
+
+func TestWithValidation(t *testing.T) {
+	opt := heartbeat.WithValidation(heartbeat.ValidateConfig{})
+
+	invalid := validHeartbeat()
+	invalid.Entity = ""
+
+	handle := opt(func(_ context.Context, hh []heartbeat.Heartbeat) ([]heartbeat.Result, error) {
+		assert.Equal(t, []heartbeat.Heartbeat{validHeartbeat()}, hh)
+
+		return []heartbeat.Result{
+			{
+				Status:    201,
+				Heartbeat: validHeartbeat(),
+			},
+		}, nil
+	})
+
+	results, err := handle(context.Background(), []heartbeat.Heartbeat{invalid, validHeartbeat()})
+	require.NoError(t, err)
+
+	assert.Len(t, results, 1)
+}
+
+func TestSplitInvalid(t *testing.T) {
+	now := time.Unix(1592868400, 0)
+
+	future := validHeartbeat()
+	future.Time = 1592872000
+
+	negative := validHeartbeat()
+	negative.Lines = heartbeat.PointerTo(-3)
+
+	valid, invalid := heartbeat.SplitInvalid(
+		[]heartbeat.Heartbeat{future, validHeartbeat(), negative},
+		heartbeat.ValidateConfig{},
+		now,
+	)
+
+	assert.Equal(t, []heartbeat.Heartbeat{validHeartbeat()}, valid)
+	assert.Equal(t, []heartbeat.Invalid{
+		{
+			Heartbeat: future,
+			Reason:    "time 2020-06-23T00:26:40Z is in the future",
+		},
+		{
+			Heartbeat: negative,
+			Reason:    "lines -3 is negative",
+		},
+	}, invalid)
+}
+
+func validHeartbeat() heartbeat.Heartbeat {
+	return heartbeat.Heartbeat{
+		Category:       heartbeat.CodingCategory,
+		CursorPosition: heartbeat.PointerTo(12),
+		Dependencies:   []string{"dep1", "dep2"},
+		Entity:         "/tmp/main.go",
+		EntityType:     heartbeat.FileType,
+		LineNumber:     heartbeat.PointerTo(42),
+		Lines:          heartbeat.PointerTo(100),
+		Time:           1592868367.219124,
+		UserAgent:      "wakatime/13.0.6",
+	}
+}
@@ -0,0 +1,106 @@
+package ini
+
+import (
+	"context"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/wakatime/wakatime-cli/pkg/log"
+
+	"github.com/spf13/viper"
+)
+
+// envPrefix is the prefix of environment variables overriding config values.
+const envPrefix = "WAKATIME_"
+
+// envSections contains the config sections which can be overridden via environment
+// variables. Map sections use patterns as keys, which can't be part of an environment
+// variable name, so their values are expected in the format "pattern=value".
+// nolint:gochecknoglobals
+var envSections = []struct {
+	Name  string
+	IsMap bool
+}{
+	// sorted by length, so sections sharing a prefix are matched correctly
+	{Name: "project_api_key", IsMap: true},
+	{Name: "projectmap", IsMap: true},
+	{Name: "settings"},
+	{Name: "git"},
+}
+
+// EnvOverride is a config value read from an environment variable.
+type EnvOverride struct {
+	EnvVar  string
+	Section string
+	Key     string
+	Value   string
+}
+
+// EnvVarName returns the name of the environment variable overriding the given
+// section and key. For ex: settings.hide_file_names is WAKATIME_SETTINGS_HIDE_FILE_NAMES.
+func EnvVarName(section, key string) string {
+	return envPrefix + strings.ToUpper(section) + "_" + strings.ToUpper(key)
+}
+
+// EnvOverrides parses config overrides from the given environment, in the
+// format returned by os.Environ(). Results are sorted by environment variable name.
+func EnvOverrides(environ []string) []EnvOverride {
+	var overrides []EnvOverride
+
+	for _, kv := range environ {
+		name, value, ok := strings.Cut(kv, "=")
+		if !ok || !strings.HasPrefix(name, envPrefix) {
+			continue
+		}
+
+		rest := strings.ToLower(strings.TrimPrefix(name, envPrefix))
+
+		for _, section := range envSections {
+			if !strings.HasPrefix(rest, section.Name+"_") {
+				continue
+			}
+
+			key := strings.TrimPrefix(rest, section.Name+"_")
+			if key == "" {
+				break
+			}
+
+			if section.IsMap {
+				// split at the last equal sign, as patterns may contain one
+				i := strings.LastIndex(value, "=")
+				if i < 1 {
+					break
+				}
+
+				key, value = strings.TrimSpace(value[:i]), strings.TrimSpace(value[i+1:])
+			}
+
+			overrides = append(overrides, EnvOverride{
+				EnvVar:  name,
+				Section: section.Name,
+				Key:     key,
+				Value:   value,
+			})
+
+			break
+		}
+	}
+
+	sort.Slice(overrides, func(i, j int) bool { return overrides[i].EnvVar < overrides[j].EnvVar })
+
+	return overrides
+}
+
+// ReadInEnv overrides config values loaded from config files with values from
+// WAKATIME_<SECTION>_<KEY> environment variables. Command line arguments still
+// take precedence, as they are stored under different keys.
+func ReadInEnv(ctx context.Context, v *viper.Viper) {
+	logger := log.Extract(ctx)
+
+	for _, o := range EnvOverrides(os.Environ()) {
+		logger.Debugf("overriding %s.%s from env var %s", o.Section, o.Key, o.EnvVar)
+
+		v.Set(o.Section+"."+o.Key, o.Value)
+	}
+}
@@ -27,6 +27,8 @@ const (
 	defaultFolder = ".wakatime"
 	// defaultFile is the name of the default wakatime config file.
 	defaultFile = ".wakatime.cfg"
+	// defaultSection is the main section of the wakatime config file.
+	defaultSection = "settings"
 	// defaultInternalFile is the name of the default wakatime internal config file.
 	defaultInternalFile = "wakatime-internal.cfg"
 	// DateFormat is the default format for date in config file.
@@ -94,22 +96,146 @@ func NewWriter(
 	}, nil
 }
 
+// Deleter defines the methods to delete keys from config file.
+type Deleter interface {
+	Delete(ctx context.Context, section string, keys []string) error
+}
+
+// ListWriter defines the methods to modify multi-line list values in config file,
+// like the exclude and include settings.
+type ListWriter interface {
+	AppendToList(ctx context.Context, section, key string, values []string) error
+	RemoveFromList(ctx context.Context, section, key string, values []string) error
+}
+
+// MapWriter defines the methods to modify sections whose keys are regex patterns
+// mapped to a value, like the [projectmap] section.
+type MapWriter interface {
+	RemoveEntries(ctx context.Context, section string, entries map[string][]string) error
+}
+
 // Write persists key(s) and value(s) on disk.
 func (w *WriterConfig) Write(ctx context.Context, section string, keyValue map[string]string) error {
+	return w.update(ctx, func() error {
+		for key, value := range keyValue {
+			// prevent writing null characters
+			key = strings.ReplaceAll(key, "\x00", "")
+			value = strings.ReplaceAll(value, "\x00", "")
+
+			w.File.Section(section).Key(key).SetValue(value)
+		}
+
+		return nil
+	})
+}
+
+// Delete removes key(s) from a section and persists on disk. The section
+// itself is removed when no keys are left. Missing keys are ignored.
+func (w *WriterConfig) Delete(ctx context.Context, section string, keys []string) error {
+	return w.update(ctx, func() error {
+		s, err := w.File.GetSection(section)
+		if err != nil {
+			return nil
+		}
+
+		for _, key := range keys {
+			s.DeleteKey(key)
+		}
+
+		if len(s.Keys()) == 0 {
+			w.File.DeleteSection(section)
+		}
+
+		return nil
+	})
+}
+
+// AppendToList adds value(s) to a multi-line list value and persists on disk.
+// Values already in the list are skipped. A boolean value is replaced by the list.
+func (w *WriterConfig) AppendToList(ctx context.Context, section, key string, values []string) error {
+	return w.update(ctx, func() error {
+		k := w.File.Section(section).Key(key)
+		items := ParseList(k.Value())
+
+		for _, value := range values {
+			value = strings.TrimSpace(strings.ReplaceAll(value, "\x00", ""))
+			if value == "" || containsString(items, value) {
+				continue
+			}
+
+			items = append(items, value)
+		}
+
+		k.SetValue(FormatList(items))
+
+		return nil
+	})
+}
+
+// RemoveFromList removes value(s) from a multi-line list value and persists on disk.
+// The key is removed when the list becomes empty.
+func (w *WriterConfig) RemoveFromList(ctx context.Context, section, key string, values []string) error {
+	return w.update(ctx, func() error {
+		s, err := w.File.GetSection(section)
+		if err != nil || !s.HasKey(key) {
+			return nil
+		}
+
+		var items []string
+
+		for _, item := range ParseList(s.Key(key).Value()) {
+			if containsString(values, item) {
+				continue
+			}
+
+			items = append(items, item)
+		}
+
+		if len(items) == 0 {
+			s.DeleteKey(key)
+			return nil
+		}
+
+		s.Key(key).SetValue(FormatList(items))
+
+		return nil
+	})
+}
+
+// RemoveEntries removes the keys of a section whose value is one of the given
+// values for the key, and persists on disk. The section itself is removed when
+// no keys are left. Missing keys and keys with another value are ignored.
+func (w *WriterConfig) RemoveEntries(ctx context.Context, section string, entries map[string][]string) error {
+	return w.update(ctx, func() error {
+		s, err := w.File.GetSection(section)
+		if err != nil {
+			return nil
+		}
+
+		for key, values := range entries {
+			if s.HasKey(key) && containsString(values, strings.TrimSpace(s.Key(key).Value())) {
+				s.DeleteKey(key)
+			}
+		}
+
+		if len(s.Keys()) == 0 {
+			w.File.DeleteSection(section)
+		}
+
+		return nil
+	})
+}
+
+// update reloads the config file, applies the given modification and saves it back
+// to disk, all while holding the config file mutex. Reloading prevents overwriting
+// changes made by other processes since the file was first loaded.
+func (w *WriterConfig) update(ctx context.Context, modify func() error) error {
 	logger := log.Extract(ctx)
 
 	if w.File == nil || w.ConfigFilepath == "" {
 		return errors.New("got undefined wakatime config file instance")
 	}
 
-	for key, value := range keyValue {
-		// prevent writing null characters
-		key = strings.ReplaceAll(key, "\x00", "")
-		value = strings.ReplaceAll(value, "\x00", "")
-
-		w.File.Section(section).Key(key).SetValue(value)
-	}
-
 	releaser, err := mutex.Acquire(mutex.Spec{
 		Name:    "wakatime-cli-config-mutex",
 		Delay:   time.Millisecond,
@@ -126,6 +252,14 @@ func (w *WriterConfig) Write(ctx context.Context, section string, keyValue map[s
 		}
 	}()
 
+	if err := w.File.Reload(); err != nil {
+		return fmt.Errorf("error reloading wakatime config: %s", err)
+	}
+
+	if err := modify(); err != nil {
+		return err
+	}
+
 	if err := w.File.SaveTo(w.ConfigFilepath); err != nil {
 		return fmt.Errorf("error saving wakatime config: %s", err)
 	}
@@ -133,6 +267,37 @@ func (w *WriterConfig) Write(ctx context.Context, section string, keyValue map[s
 	return nil
 }
 
+// ParseList splits a multi-line list value into its items. Boolean values, which
+// are allowed by settings like exclude, are treated as an empty list.
+func ParseList(value string) []string {
+	if _, err := strconv.ParseBool(strings.TrimSpace(value)); err == nil {
+		return nil
+	}
+
+	var items []string
+
+	for _, line := range strings.Split(value, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			items = append(items, line)
+		}
+	}
+
+	return items
+}
+
+// FormatList formats list items as a multi-line value, with each item
+// indented on its own line.
+func FormatList(items []string) string {
+	var b strings.Builder
+
+	for _, item := range items {
+		b.WriteString("\n    ")
+		b.WriteString(item)
+	}
+
+	return b.String()
+}
+
 // ReadInConfig reads wakatime config file in memory.
 func ReadInConfig(v *viper.Viper, configFilePath string) error {
 	v.SetConfigType("ini")
@@ -266,6 +431,17 @@ func (*mutexClock) Now() time.Time {
 	return time.Now()
 }
 
+// containsString checks if a string is part of a slice.
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+
+	return false
+}
+
 // fileExists checks if a file or directory exist.
 func fileExists(fp string) bool {
 	_, err := os.Stat(fp)
@@ -378,6 +378,172 @@ func TestWriteErr(t *testing.T) {
 	assert.Equal(t, "got undefined wakatime config file instance", err.Error())
 }
 
+func TestDelete(t *testing.T) {
+	tmpFile := copyToTemp(t, "testdata/wakatime-lists.cfg")
+
+	w, err := ini.NewWriter(context.Background(), viper.New(), func(_ context.Context, _ *viper.Viper) (string, error) {
+		return tmpFile, nil
+	})
+	require.NoError(t, err)
+
+	err = w.Delete(context.Background(), "settings", []string{"debug", "missing"})
+	require.NoError(t, err)
+
+	err = w.Delete(context.Background(), "projectmap", []string{"projects/foo"})
+	require.NoError(t, err)
+
+	f, err := iniv1.LoadSources(iniv1.LoadOptions{AllowPythonMultilineValues: true}, tmpFile)
+	require.NoError(t, err)
+
+	assert.False(t, f.Section("settings").HasKey("debug"))
+	assert.True(t, f.Section("settings").HasKey("exclude"))
+	assert.False(t, f.HasSection("projectmap"))
+}
+
+func TestAppendToList(t *testing.T) {
+	tmpFile := copyToTemp(t, "testdata/wakatime-lists.cfg")
+
+	w, err := ini.NewWriter(context.Background(), viper.New(), func(_ context.Context, _ *viper.Viper) (string, error) {
+		return tmpFile, nil
+	})
+	require.NoError(t, err)
+
+	err = w.AppendToList(context.Background(), "settings", "exclude", []string{"^build/", "^dist/"})
+	require.NoError(t, err)
+
+	err = w.AppendToList(context.Background(), "settings", "include", []string{".*"})
+	require.NoError(t, err)
+
+	settings, err := ini.ReadSettings(tmpFile)
+	require.NoError(t, err)
+
+	values := map[string][]string{}
+	for _, s := range settings {
+		values[s.Key] = ini.ParseList(s.Value)
+	}
+
+	assert.Equal(t, []string{"^vendor/", "^build/", "^dist/"}, values["exclude"])
+	assert.Equal(t, []string{".*"}, values["include"])
+}
+
+func TestRemoveFromList(t *testing.T) {
+	tmpFile := copyToTemp(t, "testdata/wakatime-lists.cfg")
+
+	w, err := ini.NewWriter(context.Background(), viper.New(), func(_ context.Context, _ *viper.Viper) (string, error) {
+		return tmpFile, nil
+	})
+	require.NoError(t, err)
+
+	err = w.RemoveFromList(context.Background(), "settings", "exclude", []string{"^vendor/"})
+	require.NoError(t, err)
+
+	settings, err := ini.ReadSection(tmpFile, "settings")
+	require.NoError(t, err)
+
+	require.Len(t, settings, 3)
+	assert.Equal(t, "exclude", settings[1].Key)
+	assert.Equal(t, []string{"^build/"}, ini.ParseList(settings[1].Value))
+
+	err = w.RemoveFromList(context.Background(), "settings", "exclude", []string{"^build/"})
+	require.NoError(t, err)
+
+	settings, err = ini.ReadSection(tmpFile, "settings")
+	require.NoError(t, err)
+
+	require.Len(t, settings, 2)
+	assert.Equal(t, "debug", settings[0].Key)
+	assert.Equal(t, "include", settings[1].Key)
+}
+
+func TestRemoveEntries(t *testing.T) {
+	tmpFile := copyToTemp(t, "testdata/wakatime-lists.cfg")
+
+	w, err := ini.NewWriter(context.Background(), viper.New(), func(_ context.Context, _ *viper.Viper) (string, error) {
+		return tmpFile, nil
+	})
+	require.NoError(t, err)
+
+	err = w.RemoveEntries(context.Background(), "projectmap", map[string][]string{
+		"projects/foo": {"other project"},
+		"missing":      {"project"},
+	})
+	require.NoError(t, err)
+
+	settings, err := ini.ReadSection(tmpFile, "projectmap")
+	require.NoError(t, err)
+
+	require.Len(t, settings, 1)
+	assert.Equal(t, "new project name", settings[0].Value)
+
+	err = w.RemoveEntries(context.Background(), "projectmap", map[string][]string{
+		"projects/foo": {"other project", "new project name"},
+	})
+	require.NoError(t, err)
+
+	f, err := iniv1.LoadSources(iniv1.LoadOptions{AllowPythonMultilineValues: true}, tmpFile)
+	require.NoError(t, err)
+
+	assert.False(t, f.HasSection("projectmap"))
+}
+
+func TestWrite_KeepsConcurrentChanges(t *testing.T) {
+	tmpFile := copyToTemp(t, "testdata/wakatime-lists.cfg")
+
+	w, err := ini.NewWriter(context.Background(), viper.New(), func(_ context.Context, _ *viper.Viper) (string, error) {
+		return tmpFile, nil
+	})
+	require.NoError(t, err)
+
+	// simulate another process modifying the file after it was loaded
+	other, err := iniv1.LoadSources(iniv1.LoadOptions{AllowPythonMultilineValues: true}, tmpFile)
+	require.NoError(t, err)
+
+	other.Section("settings").Key("hostname").SetValue("my-computer")
+	require.NoError(t, other.SaveTo(tmpFile))
+
+	err = w.Write(context.Background(), "settings", map[string]string{"debug": "false"})
+	require.NoError(t, err)
+
+	f, err := iniv1.LoadSources(iniv1.LoadOptions{AllowPythonMultilineValues: true}, tmpFile)
+	require.NoError(t, err)
+
+	assert.Equal(t, "false", f.Section("settings").Key("debug").String())
+	assert.Equal(t, "my-computer", f.Section("settings").Key("hostname").String())
+}
+
+func TestSectionNames(t *testing.T) {
+	names, err := ini.SectionNames("testdata/wakatime-lists.cfg")
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"projectmap", "settings"}, names)
+}
+
+func TestParseList(t *testing.T) {
+	tests := map[string]struct {
+		Value    string
+		Expected []string
+	}{
+		"empty":      {Value: "", Expected: nil},
+		"boolean":    {Value: "true", Expected: nil},
+		"single":     {Value: "^vendor/", Expected: []string{"^vendor/"}},
+		"multi line": {Value: "\n  ^vendor/\n\n  ^build/", Expected: []string{"^vendor/", "^build/"}},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, test.Expected, ini.ParseList(test.Value))
+		})
+	}
+}
+
+func copyToTemp(t *testing.T, source string) string {
+	fp := filepath.Join(t.TempDir(), "wakatime.cfg")
+
+	copyFile(t, source, fp)
+
+	return fp
+}
+
 func copyFile(t *testing.T, source, destination string) {
 	input, err := os.ReadFile(source)
 	require.NoError(t, err)
@@ -0,0 +1,299 @@
+package ini
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/wakatime/wakatime-cli/pkg/log"
+
+	"github.com/spf13/viper"
+	"gopkg.in/ini.v1"
+)
+
+// localSettingsKeys contains the [settings] keys a per-directory config file is allowed
+// to override. Keys which could be abused by a config file checked into a repository,
+// like api_url, api_key_vault_cmd, log_file, import_cfg, no_ssl_verify or ssl_certs_file,
+// are intentionally left out.
+// nolint:gochecknoglobals
+var localSettingsKeys = map[string]struct{}{
+	"api_key":                        {},
+	"apikey":                         {},
+	"category_rules":                 {},
+	"exclude":                        {},
+	"exclude_globs":                  {},
+	"exclude_unknown_project":        {},
+	"guess_language":                 {},
+	"hide_branch_names":              {},
+	"hide_branchnames":               {},
+	"hide_file_names":                {},
+	"hide_filenames":                 {},
+	"hide_mode":                      {},
+	"hide_project_folder":            {},
+	"hide_project_names":             {},
+	"hide_projectnames":              {},
+	"hide_secret_files":              {},
+	"hidebranchnames":                {},
+	"hidefilenames":                  {},
+	"hideprojectnames":               {},
+	"honor_ignore_files":             {},
+	"ignore":                         {},
+	"include":                        {},
+	"include_globs":                  {},
+	"include_only_with_project_file": {},
+	"infer_category":                 {},
+	"no_proxy":                       {},
+	"proxy":                          {},
+	"request_compression":            {},
+	"timeout":                        {},
+}
+
+// Setting is a [settings] value along with the config file it was read from.
+type Setting struct {
+	Key    string
+	Value  string
+	Source string
+}
+
+// LocalFilePaths returns the paths of per-directory .wakatime.cfg files found in the
+// directory of the given entity and its parent directories, ordered from the outermost
+// to the innermost directory. The global config file is never returned. Returns nil
+// if the entity doesn't exist on disk.
+func LocalFilePaths(ctx context.Context, v *viper.Viper, entity string) ([]string, error) {
+	skip, err := globalFilePaths(ctx, v)
+	if err != nil {
+		return nil, err
+	}
+
+	return localFilePaths(entity, skip)
+}
+
+// globalFilePaths returns the paths of the global and the imported config file,
+// which are never used as per-directory config files.
+func globalFilePaths(ctx context.Context, v *viper.Viper) ([]string, error) {
+	globalFilepath, err := FilePath(ctx, v)
+	if err != nil {
+		return nil, fmt.Errorf("error getting config file path: %s", err)
+	}
+
+	importFilepath, err := ImportFilePath(ctx, v)
+	if err != nil {
+		return nil, fmt.Errorf("error getting import config file path: %s", err)
+	}
+
+	return []string{globalFilepath, importFilepath}, nil
+}
+
+func localFilePaths(entity string, skip []string) ([]string, error) {
+	if entity == "" {
+		return nil, nil
+	}
+
+	info, err := os.Stat(entity)
+	if err != nil {
+		return nil, nil
+	}
+
+	dir := entity
+	if !info.IsDir() {
+		dir = filepath.Dir(entity)
+	}
+
+	dir, err = filepath.Abs(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get absolute path of %q: %s", dir, err)
+	}
+
+	var found []string
+
+	for {
+		fp := filepath.Join(dir, defaultFile)
+
+		if !slices.Contains(skip, fp) && isRegularFile(fp) {
+			found = append(found, fp)
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			break
+		}
+
+		dir = parent
+	}
+
+	// reverse so the innermost file is applied last and wins
+	for i, j := 0, len(found)-1; i < j; i, j = i+1, j-1 {
+		found[i], found[j] = found[j], found[i]
+	}
+
+	return found, nil
+}
+
+// ReadSettings reads all [settings] values from a config file.
+func ReadSettings(configFilepath string) ([]Setting, error) {
+	return ReadSection(configFilepath, defaultSection)
+}
+
+// ReadSection reads all values of a section from a config file, sorted by key.
+// Returns nil if the section doesn't exist.
+func ReadSection(configFilepath, section string) ([]Setting, error) {
+	f, err := load(configFilepath)
+	if err != nil {
+		return nil, err
+	}
+
+	s, err := f.GetSection(section)
+	if err != nil {
+		return nil, nil
+	}
+
+	var settings []Setting
+
+	for _, key := range s.Keys() {
+		settings = append(settings, Setting{
+			Key:    strings.ToLower(key.Name()),
+			Value:  key.Value(),
+			Source: configFilepath,
+		})
+	}
+
+	sort.Slice(settings, func(i, j int) bool { return settings[i].Key < settings[j].Key })
+
+	return settings, nil
+}
+
+// SectionNames returns the names of all non-empty sections of a config file, sorted.
+func SectionNames(configFilepath string) ([]string, error) {
+	f, err := load(configFilepath)
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+
+	for _, s := range f.Sections() {
+		if s.Name() == ini.DefaultSection || len(s.Keys()) == 0 {
+			continue
+		}
+
+		names = append(names, s.Name())
+	}
+
+	sort.Strings(names)
+
+	return names, nil
+}
+
+func load(configFilepath string) (*ini.File, error) {
+	f, err := ini.LoadSources(ini.LoadOptions{
+		AllowPythonMultilineValues: true,
+		SkipUnrecognizableLines:    true,
+	}, configFilepath)
+	if err != nil {
+		return nil, fmt.Errorf("error loading config file: %s", err)
+	}
+
+	return f, nil
+}
+
+// ReadLocalSettings reads the overridable [settings] values from a per-directory
+// config file. Values for keys which are not allowed to be overridden are skipped.
+func ReadLocalSettings(ctx context.Context, configFilepath string) ([]Setting, error) {
+	settings, err := ReadSettings(configFilepath)
+	if err != nil {
+		return nil, err
+	}
+
+	logger := log.Extract(ctx)
+
+	var allowed []Setting
+
+	for _, s := range settings {
+		if _, ok := localSettingsKeys[s.Key]; !ok {
+			logger.Warnf("ignoring setting %q from %q, as it can't be overridden per directory", s.Key, configFilepath)
+			continue
+		}
+
+		allowed = append(allowed, s)
+	}
+
+	return allowed, nil
+}
+
+// ReadInLocalConfigs discovers per-directory config files for the given entity and
+// overrides the [settings] values loaded from the global config files in memory.
+// Command line arguments still take precedence, as they are stored under different keys.
+func ReadInLocalConfigs(ctx context.Context, v *viper.Viper, entity string) error {
+	configFiles, err := LocalFilePaths(ctx, v, entity)
+	if err != nil {
+		return err
+	}
+
+	logger := log.Extract(ctx)
+
+	for _, configFile := range configFiles {
+		settings, err := ReadLocalSettings(ctx, configFile)
+		if err != nil {
+			return fmt.Errorf("failed to load local configuration file %q: %s", configFile, err)
+		}
+
+		logger.Debugf("overriding %d setting(s) from local config file %q", len(settings), configFile)
+
+		for _, s := range settings {
+			v.Set(defaultSection+"."+s.Key, s.Value)
+		}
+	}
+
+	return nil
+}
+
+// LocalScope holds the per-directory config files applied for the main entity.
+// Overrides are only resolved for the main entity, so other heartbeats are only
+// handled with the same settings when they share the same config files.
+type LocalScope struct {
+	Files []string
+	skip  []string
+}
+
+// NewLocalScope returns the scope of the per-directory config files found for the entity.
+func NewLocalScope(ctx context.Context, v *viper.Viper, entity string) (LocalScope, error) {
+	skip, err := globalFilePaths(ctx, v)
+	if err != nil {
+		return LocalScope{}, err
+	}
+
+	files, err := localFilePaths(entity, skip)
+	if err != nil {
+		return LocalScope{}, err
+	}
+
+	return LocalScope{
+		Files: files,
+		skip:  skip,
+	}, nil
+}
+
+// Contains returns true if the per-directory config files found for the entity
+// are the ones of the scope.
+func (s LocalScope) Contains(entity string) (bool, error) {
+	files, err := localFilePaths(entity, s.skip)
+	if err != nil {
+		return false, err
+	}
+
+	return slices.Equal(files, s.Files), nil
+}
+
+// isRegularFile checks if a path exists and is not a directory.
+func isRegularFile(fp string) bool {
+	info, err := os.Stat(fp)
+	if err != nil {
+		return false
+	}
+
+	return !info.IsDir()
+}
@@ -0,0 +1,149 @@
+package ini_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/wakatime/wakatime-cli/pkg/ini"
+	"github.com/wakatime/wakatime-cli/pkg/vipertools"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLocalFilePaths(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	entity := setupLocalConfigs(t, tmpDir)
+
+	v := viper.New()
+	v.Set("config", filepath.Join(tmpDir, "global.cfg"))
+
+	paths, err := ini.LocalFilePaths(context.Background(), v, entity)
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{
+		filepath.Join(tmpDir, "projects", ".wakatime.cfg"),
+		filepath.Join(tmpDir, "projects", "foo", ".wakatime.cfg"),
+	}, paths)
+}
+
+func TestLocalFilePaths_SkipsGlobalConfig(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	entity := setupLocalConfigs(t, tmpDir)
+
+	v := viper.New()
+	v.Set("config", filepath.Join(tmpDir, "projects", ".wakatime.cfg"))
+
+	paths, err := ini.LocalFilePaths(context.Background(), v, entity)
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{
+		filepath.Join(tmpDir, "projects", "foo", ".wakatime.cfg"),
+	}, paths)
+}
+
+func TestLocalFilePaths_NonExistingEntity(t *testing.T) {
+	v := viper.New()
+
+	paths, err := ini.LocalFilePaths(context.Background(), v, "/path/to/nowhere.go")
+	require.NoError(t, err)
+
+	assert.Nil(t, paths)
+}
+
+func TestReadLocalSettings(t *testing.T) {
+	settings, err := ini.ReadLocalSettings(context.Background(), "testdata/wakatime-local.cfg")
+	require.NoError(t, err)
+
+	assert.Equal(t, []ini.Setting{
+		{Key: "exclude", Value: "\n  ^vendor/\n  ^build/", Source: "testdata/wakatime-local.cfg"},
+		{Key: "hide_file_names", Value: "true", Source: "testdata/wakatime-local.cfg"},
+	}, settings)
+}
+
+func TestReadInLocalConfigs(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	entity := setupLocalConfigs(t, tmpDir)
+
+	v := viper.New()
+	v.Set("config", filepath.Join(tmpDir, "global.cfg"))
+	v.Set("settings.hide_file_names", "false")
+	v.Set("settings.proxy", "https://global:8080")
+	v.Set("settings.api_url", "https://global.example.org")
+	v.Set("settings.no_ssl_verify", "false")
+
+	err := ini.ReadInLocalConfigs(context.Background(), v, entity)
+	require.NoError(t, err)
+
+	assert.Equal(t, "true", vipertools.GetString(v, "settings.hide_file_names"))
+	assert.Equal(t, "https://inner:8080", vipertools.GetString(v, "settings.proxy"))
+	assert.Equal(t, "https://global.example.org", vipertools.GetString(v, "settings.api_url"))
+	assert.Equal(t, "false", vipertools.GetString(v, "settings.no_ssl_verify"))
+	assert.Empty(t, vipertools.GetString(v, "settings.ssl_certs_file"))
+}
+
+func TestLocalScope_Contains(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	entity := setupLocalConfigs(t, tmpDir)
+
+	err := os.MkdirAll(filepath.Join(tmpDir, "projects", "bar"), 0750)
+	require.NoError(t, err)
+
+	other := filepath.Join(tmpDir, "projects", "bar", "main.go")
+
+	err = os.WriteFile(other, []byte("package main\n"), 0600)
+	require.NoError(t, err)
+
+	v := viper.New()
+	v.Set("config", filepath.Join(tmpDir, "global.cfg"))
+
+	scope, err := ini.NewLocalScope(context.Background(), v, entity)
+	require.NoError(t, err)
+
+	contained, err := scope.Contains(filepath.Join(tmpDir, "projects", "foo", "src"))
+	require.NoError(t, err)
+
+	assert.True(t, contained)
+
+	// only the outer config file applies to files in projects/bar
+	contained, err = scope.Contains(other)
+	require.NoError(t, err)
+
+	assert.False(t, contained)
+}
+
+// setupLocalConfigs creates a directory tree with two nested per-directory
+// config files and returns the path of a file inside the innermost folder.
+func setupLocalConfigs(t *testing.T, dir string) string {
+	err := os.MkdirAll(filepath.Join(dir, "projects", "foo", "src"), 0750)
+	require.NoError(t, err)
+
+	err = os.WriteFile(
+		filepath.Join(dir, "projects", ".wakatime.cfg"),
+		[]byte("[settings]\nhide_file_names = true\nproxy = https://outer:8080\n"),
+		0600,
+	)
+	require.NoError(t, err)
+
+	err = os.WriteFile(
+		filepath.Join(dir, "projects", "foo", ".wakatime.cfg"),
+		[]byte("[settings]\nproxy = https://inner:8080\napi_url = https://evil.example.org\n"+
+			"no_ssl_verify = true\nssl_certs_file = /tmp/evil.pem\n"),
+		0600,
+	)
+	require.NoError(t, err)
+
+	entity := filepath.Join(dir, "projects", "foo", "src", "main.go")
+
+	err = os.WriteFile(entity, []byte("package main\n"), 0600)
+	require.NoError(t, err)
+
+	return entity
+}
@@ -0,0 +1,77 @@
+package keystore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+const (
+	// TypeSecretService stores the api key in the Linux Secret Service, like
+	// GNOME Keyring or KWallet, over D-Bus.
+	TypeSecretService = "secret-service"
+	// TypePass stores the api key in the pass password store.
+	TypePass = "pass"
+	// DefaultEntry is the default name of the api key entry.
+	DefaultEntry = "wakatime/api_key"
+)
+
+var (
+	// ErrNotFound is returned when no api key was found in the store.
+	ErrNotFound = errors.New("api key not found in store")
+	// ErrLocked is returned when the store is locked and prompting the user
+	// to unlock it is not allowed.
+	ErrLocked = errors.New("store is locked")
+)
+
+// Store reads and writes the api key from a secure storage.
+type Store interface {
+	// Get returns the stored api key or ErrNotFound.
+	Get(ctx context.Context) (string, error)
+	// Set stores the api key, replacing an existing one.
+	Set(ctx context.Context, apiKey string) error
+}
+
+// Option is a functional option for New.
+type Option func(*options)
+
+type options struct {
+	prompt bool
+}
+
+// WithPrompt allows the store to prompt the user to unlock it. Must only be
+// used by interactive commands, as the prompt blocks until the user answers.
+func WithPrompt() Option {
+	return func(o *options) {
+		o.prompt = true
+	}
+}
+
+// New returns the store of the given type. The entry is the name the api
+// key is stored under, and defaults to DefaultEntry if empty. Without
+// WithPrompt, a locked store fails fast instead of prompting the user.
+func New(storeType, entry string, opts ...Option) (Store, error) {
+	if entry == "" {
+		entry = DefaultEntry
+	}
+
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	switch storeType {
+	case TypeSecretService:
+		store := NewSecretService(entry)
+		store.Prompt = o.prompt
+
+		return store, nil
+	case TypePass:
+		store := NewPass(entry)
+		store.Prompt = o.prompt
+
+		return store, nil
+	default:
+		return nil, fmt.Errorf("unsupported api key store %q, expected %q or %q", storeType, TypeSecretService, TypePass)
+	}
+}
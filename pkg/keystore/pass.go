@@ -0,0 +1,87 @@
+package keystore
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// passTimeout is the maximum time to wait for pass, which may ask for the gpg passphrase.
+const passTimeout = 30 * time.Second
+
+// Pass stores the api key in the pass password store, https://www.passwordstore.org.
+type Pass struct {
+	// Command is the pass executable. Defaults to "pass".
+	Command string
+	// Entry is the name of the password store entry.
+	Entry string
+	// Prompt allows gpg to ask for the passphrase. Otherwise pass fails if the
+	// passphrase is not cached by gpg-agent.
+	Prompt bool
+}
+
+// NewPass creates a new pass store for the given entry.
+func NewPass(entry string) *Pass {
+	return &Pass{
+		Command: "pass",
+		Entry:   entry,
+	}
+}
+
+// Get returns the first line of the password store entry.
+func (p *Pass) Get(ctx context.Context) (string, error) {
+	var stderr bytes.Buffer
+
+	out, err := p.run(ctx, nil, &stderr, "show", p.Entry)
+	if err != nil {
+		if strings.Contains(stderr.String(), "is not in the password store") {
+			return "", ErrNotFound
+		}
+
+		return "", fmt.Errorf("failed to run pass show: %s: %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	apiKey, _, _ := strings.Cut(string(out), "\n")
+
+	apiKey = strings.TrimSpace(apiKey)
+	if apiKey == "" {
+		return "", ErrNotFound
+	}
+
+	return apiKey, nil
+}
+
+// Set inserts or overwrites the password store entry.
+func (p *Pass) Set(ctx context.Context, apiKey string) error {
+	var stderr bytes.Buffer
+
+	// the api key is passed over stdin to keep it out of the process list
+	_, err := p.run(ctx, strings.NewReader(apiKey+"\n"), &stderr, "insert", "--multiline", "--force", p.Entry)
+	if err != nil {
+		return fmt.Errorf("failed to run pass insert: %s: %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	return nil
+}
+
+func (p *Pass) run(ctx context.Context, stdin io.Reader, stderr *bytes.Buffer, args ...string) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, passTimeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, p.Command, args...) // nolint:gosec
+	cmd.Stdin = stdin
+	cmd.Stderr = stderr
+
+	if !p.Prompt {
+		// pass appends PASSWORD_STORE_GPG_OPTS to the gpg options
+		cmd.Env = append(os.Environ(), "PASSWORD_STORE_GPG_OPTS="+
+			strings.TrimSpace(os.Getenv("PASSWORD_STORE_GPG_OPTS")+" --batch --pinentry-mode=error"))
+	}
+
+	return cmd.Output()
+}
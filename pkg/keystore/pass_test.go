@@ -0,0 +1,66 @@
+package keystore_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/wakatime/wakatime-cli/pkg/keystore"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPass(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Skipping because OS is windows.")
+	}
+
+	dir := t.TempDir()
+	t.Setenv("PASSWORD_STORE_DIR", dir)
+
+	store := keystore.NewPass("wakatime/api_key")
+	store.Command = "testdata/pass.sh"
+
+	_, err := store.Get(context.Background())
+	require.ErrorIs(t, err, keystore.ErrNotFound)
+
+	err = store.Set(context.Background(), "00000000-0000-4000-8000-000000000000")
+	require.NoError(t, err)
+
+	data, err := os.ReadFile(filepath.Join(dir, "wakatime", "api_key"))
+	require.NoError(t, err)
+
+	assert.Equal(t, "00000000-0000-4000-8000-000000000000\n", string(data))
+
+	err = os.WriteFile(filepath.Join(dir, "wakatime", "api_key"), []byte("00000000-0000-4000-8000-000000000001\nuser: me\n"), 0600)
+	require.NoError(t, err)
+
+	apiKey, err := store.Get(context.Background())
+	require.NoError(t, err)
+
+	assert.Equal(t, "00000000-0000-4000-8000-000000000001", apiKey)
+}
+
+func TestNew(t *testing.T) {
+	store, err := keystore.New(keystore.TypePass, "")
+	require.NoError(t, err)
+
+	assert.Equal(t, keystore.DefaultEntry, store.(*keystore.Pass).Entry)
+	assert.False(t, store.(*keystore.Pass).Prompt)
+
+	store, err = keystore.New(keystore.TypeSecretService, "custom")
+	require.NoError(t, err)
+
+	assert.Equal(t, "custom", store.(*keystore.SecretService).Entry)
+
+	store, err = keystore.New(keystore.TypeSecretService, "", keystore.WithPrompt())
+	require.NoError(t, err)
+
+	assert.True(t, store.(*keystore.SecretService).Prompt)
+
+	_, err = keystore.New("invalid", "")
+	assert.EqualError(t, err, `unsupported api key store "invalid", expected "secret-service" or "pass"`)
+}
@@ -0,0 +1,281 @@
+package keystore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+)
+
+const (
+	secretServiceName      = "org.freedesktop.secrets"
+	secretServicePath      = dbus.ObjectPath("/org/freedesktop/secrets")
+	secretServiceInterface = "org.freedesktop.Secret.Service"
+	secretItemInterface    = "org.freedesktop.Secret.Item"
+	secretPromptInterface  = "org.freedesktop.Secret.Prompt"
+	secretSessionInterface = "org.freedesktop.Secret.Session"
+	defaultCollectionPath  = dbus.ObjectPath("/org/freedesktop/secrets/aliases/default")
+	// noPrompt is the object path returned when no user interaction is needed.
+	noPrompt = dbus.ObjectPath("/")
+	// promptTimeout is the maximum time to wait for the user to unlock the keyring.
+	promptTimeout = 2 * time.Minute
+)
+
+// secret is the Secret Service secret struct, (oayays) in D-Bus notation.
+type secret struct {
+	Session     dbus.ObjectPath
+	Parameters  []byte
+	Value       []byte
+	ContentType string
+}
+
+// SecretService stores the api key in the Linux Secret Service over D-Bus,
+// https://specifications.freedesktop.org/secret-service/latest/.
+type SecretService struct {
+	// Address is the D-Bus address to connect to. Defaults to the session bus.
+	Address string
+	// Entry is stored in the item attributes to find the api key.
+	Entry string
+	// Prompt allows prompting the user to unlock the keyring. Otherwise
+	// ErrLocked is returned if the keyring is locked.
+	Prompt bool
+}
+
+// NewSecretService creates a new Secret Service store for the given entry.
+func NewSecretService(entry string) *SecretService {
+	return &SecretService{
+		Entry: entry,
+	}
+}
+
+// Get searches the api key item by its attributes and returns its secret.
+func (s *SecretService) Get(ctx context.Context) (string, error) {
+	conn, err := s.connect()
+	if err != nil {
+		return "", fmt.Errorf("failed to connect to session bus: %s", err)
+	}
+
+	defer conn.Close()
+
+	service := conn.Object(secretServiceName, secretServicePath)
+
+	var unlocked, locked []dbus.ObjectPath
+
+	err = service.CallWithContext(ctx, secretServiceInterface+".SearchItems", 0, s.attributes()).
+		Store(&unlocked, &locked)
+	if err != nil {
+		return "", fmt.Errorf("failed to search items: %s", err)
+	}
+
+	if len(unlocked) == 0 && len(locked) == 0 {
+		return "", ErrNotFound
+	}
+
+	item := unlocked
+
+	if len(item) == 0 {
+		if item, err = unlock(ctx, conn, locked[:1], s.Prompt); err != nil {
+			return "", err
+		}
+	}
+
+	session, err := openSession(ctx, conn)
+	if err != nil {
+		return "", err
+	}
+
+	defer closeSession(ctx, conn, session)
+
+	var sec secret
+
+	err = conn.Object(secretServiceName, item[0]).
+		CallWithContext(ctx, secretItemInterface+".GetSecret", 0, session).
+		Store(&sec)
+	if err != nil {
+		return "", fmt.Errorf("failed to get secret: %s", err)
+	}
+
+	return string(sec.Value), nil
+}
+
+// Set creates or replaces the api key item in the default collection.
+func (s *SecretService) Set(ctx context.Context, apiKey string) error {
+	conn, err := s.connect()
+	if err != nil {
+		return fmt.Errorf("failed to connect to session bus: %s", err)
+	}
+
+	defer conn.Close()
+
+	collection, err := unlock(ctx, conn, []dbus.ObjectPath{defaultCollectionPath}, s.Prompt)
+	if err != nil {
+		return err
+	}
+
+	session, err := openSession(ctx, conn)
+	if err != nil {
+		return err
+	}
+
+	defer closeSession(ctx, conn, session)
+
+	properties := map[string]dbus.Variant{
+		secretItemInterface + ".Label":      dbus.MakeVariant("WakaTime API Key"),
+		secretItemInterface + ".Attributes": dbus.MakeVariant(s.attributes()),
+	}
+
+	sec := secret{
+		Session:     session,
+		Parameters:  []byte{},
+		Value:       []byte(apiKey),
+		ContentType: "text/plain",
+	}
+
+	var item, prompt dbus.ObjectPath
+
+	err = conn.Object(secretServiceName, collection[0]).
+		CallWithContext(ctx, "org.freedesktop.Secret.Collection.CreateItem", 0, properties, sec, true).
+		Store(&item, &prompt)
+	if err != nil {
+		return fmt.Errorf("failed to create item: %s", err)
+	}
+
+	if prompt != noPrompt {
+		if !s.Prompt {
+			return ErrLocked
+		}
+
+		if _, err := waitForPrompt(ctx, conn, prompt); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *SecretService) connect() (*dbus.Conn, error) {
+	if s.Address == "" {
+		return dbus.ConnectSessionBus()
+	}
+
+	return dbus.Connect(s.Address)
+}
+
+func (s *SecretService) attributes() map[string]string {
+	return map[string]string{
+		"application": "wakatime-cli",
+		"entry":       s.Entry,
+	}
+}
+
+// openSession opens a session without transport encryption, which is fine
+// as the session bus is only reachable by the current user.
+func openSession(ctx context.Context, conn *dbus.Conn) (dbus.ObjectPath, error) {
+	var (
+		output  dbus.Variant
+		session dbus.ObjectPath
+	)
+
+	err := conn.Object(secretServiceName, secretServicePath).
+		CallWithContext(ctx, secretServiceInterface+".OpenSession", 0, "plain", dbus.MakeVariant("")).
+		Store(&output, &session)
+	if err != nil {
+		return "", fmt.Errorf("failed to open session: %s", err)
+	}
+
+	return session, nil
+}
+
+func closeSession(ctx context.Context, conn *dbus.Conn, session dbus.ObjectPath) {
+	_ = conn.Object(secretServiceName, session).CallWithContext(ctx, secretSessionInterface+".Close", 0).Err
+}
+
+// unlock unlocks the given objects, prompting the user if needed and allowed, and
+// returns the unlocked objects. Returns ErrLocked if a prompt is needed but not allowed.
+func unlock(ctx context.Context, conn *dbus.Conn, objects []dbus.ObjectPath, allowPrompt bool) ([]dbus.ObjectPath, error) {
+	var (
+		unlocked []dbus.ObjectPath
+		prompt   dbus.ObjectPath
+	)
+
+	err := conn.Object(secretServiceName, secretServicePath).
+		CallWithContext(ctx, secretServiceInterface+".Unlock", 0, objects).
+		Store(&unlocked, &prompt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unlock: %s", err)
+	}
+
+	if prompt == noPrompt {
+		if len(unlocked) == 0 {
+			return nil, errors.New("failed to unlock: no objects unlocked")
+		}
+
+		return unlocked, nil
+	}
+
+	if !allowPrompt {
+		return nil, ErrLocked
+	}
+
+	result, err := waitForPrompt(ctx, conn, prompt)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := dbus.Store([]interface{}{result.Value()}, &unlocked); err != nil || len(unlocked) == 0 {
+		return nil, errors.New("failed to unlock: no objects unlocked")
+	}
+
+	return unlocked, nil
+}
+
+// waitForPrompt shows the prompt to the user and waits until it's completed.
+func waitForPrompt(ctx context.Context, conn *dbus.Conn, prompt dbus.ObjectPath) (dbus.Variant, error) {
+	ctx, cancel := context.WithTimeout(ctx, promptTimeout)
+	defer cancel()
+
+	matchOptions := []dbus.MatchOption{
+		dbus.WithMatchObjectPath(prompt),
+		dbus.WithMatchInterface(secretPromptInterface),
+		dbus.WithMatchMember("Completed"),
+	}
+
+	if err := conn.AddMatchSignalContext(ctx, matchOptions...); err != nil {
+		return dbus.Variant{}, fmt.Errorf("failed to watch prompt: %s", err)
+	}
+
+	defer func() {
+		_ = conn.RemoveMatchSignalContext(ctx, matchOptions...)
+	}()
+
+	signals := make(chan *dbus.Signal, 1)
+	conn.Signal(signals)
+
+	defer conn.RemoveSignal(signals)
+
+	err := conn.Object(secretServiceName, prompt).CallWithContext(ctx, secretPromptInterface+".Prompt", 0, "").Err
+	if err != nil {
+		return dbus.Variant{}, fmt.Errorf("failed to prompt: %s", err)
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return dbus.Variant{}, errors.New("timed out waiting for prompt")
+		case signal := <-signals:
+			if signal.Path != prompt || signal.Name != secretPromptInterface+".Completed" || len(signal.Body) != 2 {
+				continue
+			}
+
+			if dismissed, _ := signal.Body[0].(bool); dismissed {
+				return dbus.Variant{}, errors.New("prompt dismissed by user")
+			}
+
+			result, _ := signal.Body[1].(dbus.Variant)
+
+			return result, nil
+		}
+	}
+}
@@ -0,0 +1,321 @@
+package keystore_test
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/wakatime/wakatime-cli/pkg/keystore"
+
+	"github.com/godbus/dbus/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSecretService(t *testing.T) {
+	address := startDBusDaemon(t)
+	service := startMockSecretService(t, address, false)
+
+	store := keystore.NewSecretService("wakatime/api_key")
+	store.Address = address
+
+	_, err := store.Get(context.Background())
+	require.ErrorIs(t, err, keystore.ErrNotFound)
+
+	err = store.Set(context.Background(), "00000000-0000-4000-8000-000000000000")
+	require.NoError(t, err)
+
+	err = store.Set(context.Background(), "00000000-0000-4000-8000-000000000001")
+	require.NoError(t, err)
+
+	assert.Len(t, service.items, 1)
+
+	apiKey, err := store.Get(context.Background())
+	require.NoError(t, err)
+
+	assert.Equal(t, "00000000-0000-4000-8000-000000000001", apiKey)
+}
+
+func TestSecretService_LockedPrompt(t *testing.T) {
+	address := startDBusDaemon(t)
+	service := startMockSecretService(t, address, true)
+
+	store := keystore.NewSecretService("wakatime/api_key")
+	store.Address = address
+	store.Prompt = true
+
+	err := store.Set(context.Background(), "00000000-0000-4000-8000-000000000000")
+	require.NoError(t, err)
+
+	service.mu.Lock()
+	service.locked = true
+	service.mu.Unlock()
+
+	apiKey, err := store.Get(context.Background())
+	require.NoError(t, err)
+
+	assert.Equal(t, "00000000-0000-4000-8000-000000000000", apiKey)
+	assert.Equal(t, 2, service.prompts)
+}
+
+func TestSecretService_LockedNoPrompt(t *testing.T) {
+	address := startDBusDaemon(t)
+	service := startMockSecretService(t, address, true)
+
+	store := keystore.NewSecretService("wakatime/api_key")
+	store.Address = address
+
+	err := store.Set(context.Background(), "00000000-0000-4000-8000-000000000000")
+	require.ErrorIs(t, err, keystore.ErrLocked)
+
+	store.Prompt = true
+
+	err = store.Set(context.Background(), "00000000-0000-4000-8000-000000000000")
+	require.NoError(t, err)
+
+	service.mu.Lock()
+	service.locked = true
+	service.mu.Unlock()
+
+	store.Prompt = false
+
+	_, err = store.Get(context.Background())
+	require.ErrorIs(t, err, keystore.ErrLocked)
+}
+
+func TestSecretService_Dismissed(t *testing.T) {
+	address := startDBusDaemon(t)
+	service := startMockSecretService(t, address, true)
+	service.dismiss = true
+
+	store := keystore.NewSecretService("wakatime/api_key")
+	store.Address = address
+	store.Prompt = true
+
+	err := store.Set(context.Background(), "00000000-0000-4000-8000-000000000000")
+	require.EqualError(t, err, "prompt dismissed by user")
+}
+
+// startDBusDaemon starts a private session bus and returns its address.
+func startDBusDaemon(t *testing.T) string {
+	if _, err := exec.LookPath("dbus-daemon"); err != nil {
+		t.Skip("Skipping because dbus-daemon is not installed.")
+	}
+
+	cmd := exec.Command("dbus-daemon", "--session", "--nofork", "--print-address=1")
+
+	stdout, err := cmd.StdoutPipe()
+	require.NoError(t, err)
+
+	require.NoError(t, cmd.Start())
+
+	t.Cleanup(func() {
+		_ = cmd.Process.Kill()
+		_ = cmd.Wait()
+	})
+
+	address, err := bufio.NewReader(stdout).ReadString('\n')
+	require.NoError(t, err)
+
+	return strings.TrimSpace(address)
+}
+
+type mockSecret struct {
+	Session     dbus.ObjectPath
+	Parameters  []byte
+	Value       []byte
+	ContentType string
+}
+
+type mockItem struct {
+	attributes map[string]string
+	value      []byte
+}
+
+// mockSecretService implements the parts of the Secret Service api used by the store.
+type mockSecretService struct {
+	conn    *dbus.Conn
+	mu      sync.Mutex
+	items   map[dbus.ObjectPath]*mockItem
+	locked  bool
+	dismiss bool
+	prompts int
+}
+
+func startMockSecretService(t *testing.T, address string, locked bool) *mockSecretService {
+	conn, err := dbus.Connect(address)
+	require.NoError(t, err)
+
+	t.Cleanup(func() { conn.Close() })
+
+	reply, err := conn.RequestName("org.freedesktop.secrets", dbus.NameFlagDoNotQueue)
+	require.NoError(t, err)
+	require.Equal(t, dbus.RequestNameReplyPrimaryOwner, reply)
+
+	m := &mockSecretService{
+		conn:   conn,
+		items:  map[dbus.ObjectPath]*mockItem{},
+		locked: locked,
+	}
+
+	require.NoError(t, conn.Export(m, "/org/freedesktop/secrets", "org.freedesktop.Secret.Service"))
+	require.NoError(t, conn.Export(
+		&mockCollection{m},
+		"/org/freedesktop/secrets/aliases/default",
+		"org.freedesktop.Secret.Collection",
+	))
+
+	return m
+}
+
+func (m *mockSecretService) OpenSession(algorithm string, _ dbus.Variant) (dbus.Variant, dbus.ObjectPath, *dbus.Error) {
+	if algorithm != "plain" {
+		return dbus.Variant{}, "", dbus.MakeFailedError(fmt.Errorf("unsupported algorithm %q", algorithm))
+	}
+
+	path := dbus.ObjectPath("/org/freedesktop/secrets/session/1")
+
+	if err := m.conn.Export(mockSession{}, path, "org.freedesktop.Secret.Session"); err != nil {
+		return dbus.Variant{}, "", dbus.MakeFailedError(err)
+	}
+
+	return dbus.MakeVariant(""), path, nil
+}
+
+func (m *mockSecretService) SearchItems(attributes map[string]string) ([]dbus.ObjectPath, []dbus.ObjectPath, *dbus.Error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	found := []dbus.ObjectPath{}
+
+	for path, item := range m.items {
+		if fmt.Sprint(item.attributes) == fmt.Sprint(attributes) {
+			found = append(found, path)
+		}
+	}
+
+	if m.locked {
+		return []dbus.ObjectPath{}, found, nil
+	}
+
+	return found, []dbus.ObjectPath{}, nil
+}
+
+func (m *mockSecretService) Unlock(objects []dbus.ObjectPath) ([]dbus.ObjectPath, dbus.ObjectPath, *dbus.Error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if !m.locked {
+		return objects, "/", nil
+	}
+
+	m.prompts++
+
+	path := dbus.ObjectPath(fmt.Sprintf("/org/freedesktop/secrets/prompt/%d", m.prompts))
+
+	prompt := &mockPrompt{
+		conn:    m.conn,
+		path:    path,
+		dismiss: m.dismiss,
+		objects: objects,
+		unlock: func() {
+			m.mu.Lock()
+			defer m.mu.Unlock()
+
+			m.locked = false
+		},
+	}
+
+	if err := m.conn.Export(prompt, path, "org.freedesktop.Secret.Prompt"); err != nil {
+		return nil, "", dbus.MakeFailedError(err)
+	}
+
+	return []dbus.ObjectPath{}, path, nil
+}
+
+type mockCollection struct {
+	service *mockSecretService
+}
+
+func (c *mockCollection) CreateItem(
+	properties map[string]dbus.Variant,
+	secret mockSecret,
+	replace bool,
+) (dbus.ObjectPath, dbus.ObjectPath, *dbus.Error) {
+	m := c.service
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	attributes, ok := properties["org.freedesktop.Secret.Item.Attributes"].Value().(map[string]string)
+	if !ok {
+		return "", "", dbus.MakeFailedError(fmt.Errorf("missing attributes"))
+	}
+
+	for path, item := range m.items {
+		if replace && fmt.Sprint(item.attributes) == fmt.Sprint(attributes) {
+			item.value = secret.Value
+			return path, "/", nil
+		}
+	}
+
+	path := dbus.ObjectPath(fmt.Sprintf("/org/freedesktop/secrets/collection/login/%d", len(m.items)+1))
+	item := &mockItem{attributes: attributes, value: secret.Value}
+
+	if err := m.conn.Export(&mockItemObject{item}, path, "org.freedesktop.Secret.Item"); err != nil {
+		return "", "", dbus.MakeFailedError(err)
+	}
+
+	m.items[path] = item
+
+	return path, "/", nil
+}
+
+type mockItemObject struct {
+	item *mockItem
+}
+
+func (o *mockItemObject) GetSecret(session dbus.ObjectPath) (mockSecret, *dbus.Error) {
+	return mockSecret{
+		Session:     session,
+		Parameters:  []byte{},
+		Value:       o.item.value,
+		ContentType: "text/plain",
+	}, nil
+}
+
+type mockSession struct{}
+
+func (mockSession) Close() *dbus.Error {
+	return nil
+}
+
+type mockPrompt struct {
+	conn    *dbus.Conn
+	path    dbus.ObjectPath
+	dismiss bool
+	objects []dbus.ObjectPath
+	unlock  func()
+}
+
+func (p *mockPrompt) Prompt(_ string) *dbus.Error {
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+
+		if p.dismiss {
+			_ = p.conn.Emit(p.path, "org.freedesktop.Secret.Prompt.Completed", true, dbus.MakeVariant(""))
+			return
+		}
+
+		p.unlock()
+
+		_ = p.conn.Emit(p.path, "org.freedesktop.Secret.Prompt.Completed", false, dbus.MakeVariant(p.objects))
+	}()
+
+	return nil
+}
@@ -0,0 +1,20 @@
+#!/bin/sh
+# fake pass executable storing entries as plain files in $PASSWORD_STORE_DIR
+set -e
+
+case "$1" in
+show)
+	if [ ! -f "$PASSWORD_STORE_DIR/$2" ]; then
+		echo "Error: $2 is not in the password store." >&2
+		exit 1
+	fi
+	cat "$PASSWORD_STORE_DIR/$2"
+	;;
+insert)
+	mkdir -p "$(dirname "$PASSWORD_STORE_DIR/$4")"
+	cat > "$PASSWORD_STORE_DIR/$4"
+	;;
+*)
+	exit 1
+	;;
+esac
@@ -2,8 +2,6 @@ package language
 
 import (
 	"context"
-	"fmt"
-	"io"
 	"path/filepath"
 	"sort"
 	"strings"
@@ -17,18 +15,20 @@ import (
 	"github.com/danwakefield/fnmatch"
 )
 
-// Max file size supporting reading from file. Default is 512Kb.
-const maxFileSize = 512000
-
 // detectChromaCustomized returns the best by filename matching lexer. Best lexer is determined
 // by customized priority.
 // If guessLanguage is true, the file content will be used to detect the language.
 // This is a modified implementation of chroma.lexers.internal.api:Match().
-func detectChromaCustomized(ctx context.Context, fp string, guessLanguage bool) (heartbeat.Language, float32, bool) {
+func detectChromaCustomized(
+	ctx context.Context,
+	fp string,
+	guessLanguage bool,
+	heads *file.Heads,
+) (heartbeat.Language, float32, bool) {
 	logger := log.Extract(ctx)
 
-	_, file := filepath.Split(fp)
-	filename := filepath.Base(file)
+	_, name := filepath.Split(fp)
+	filename := filepath.Base(name)
 	matched := chroma.PrioritisedLexers{}
 
 	// First, try primary filename matches.
@@ -42,7 +42,7 @@ func detectChromaCustomized(ctx context.Context, fp string, guessLanguage bool)
 	}
 
 	if len(matched) > 0 {
-		bestLexer, weight := selectByCustomizedPriority(ctx, fp, matched)
+		bestLexer, weight := selectByCustomizedPriority(ctx, fp, matched, heads)
 
 		language, ok := heartbeat.ParseLanguageFromChroma(bestLexer.Config().Name)
 		if !ok {
@@ -64,7 +64,7 @@ func detectChromaCustomized(ctx context.Context, fp string, guessLanguage bool)
 	}
 
 	if len(matched) > 0 {
-		bestLexer, weight := selectByCustomizedPriority(ctx, fp, matched)
+		bestLexer, weight := selectByCustomizedPriority(ctx, fp, matched, heads)
 
 		language, ok := heartbeat.ParseLanguageFromChroma(bestLexer.Config().Name)
 		if !ok {
@@ -80,7 +80,7 @@ func detectChromaCustomized(ctx context.Context, fp string, guessLanguage bool)
 	}
 
 	// Finally, try matching by file content.
-	head, err := fileHead(ctx, fp)
+	head, err := heads.Read(fp)
 	if err != nil {
 		logger.Warnf("failed to load head from file %q: %s", fp, err)
 		return heartbeat.LanguageUnknown, 0, false
@@ -111,7 +111,12 @@ type weightedLexer struct {
 }
 
 // selectByCustomizedPriority selects the best matching lexer by customized priority evaluation.
-func selectByCustomizedPriority(ctx context.Context, fp string, lexers chroma.PrioritisedLexers) (chroma.Lexer, float32) {
+func selectByCustomizedPriority(
+	ctx context.Context,
+	fp string,
+	lexers chroma.PrioritisedLexers,
+	heads *file.Heads,
+) (chroma.Lexer, float32) {
 	logger := log.Extract(ctx)
 
 	sort.Slice(lexers, func(i, j int) bool {
@@ -133,7 +138,7 @@ func selectByCustomizedPriority(ctx context.Context, fp string, lexers chroma.Pr
 		logger.Warnf("failed to load folder files extensions: %s", err)
 	}
 
-	head, err := fileHead(ctx, fp)
+	head, err := heads.Read(fp)
 	if err != nil {
 		logger.Warnf("failed to load head from file %q: %s", fp, err)
 	}
@@ -204,29 +209,6 @@ func selectByCustomizedPriority(ctx context.Context, fp string, lexers chroma.Pr
 	return weighted[0].Lexer, weighted[0].Weight
 }
 
-// fileHead returns the first `maxFileSize` bytes of the file's content.
-func fileHead(ctx context.Context, filepath string) ([]byte, error) {
-	logger := log.Extract(ctx)
-
-	f, err := file.OpenNoLock(filepath) // nolint:gosec
-	if err != nil {
-		return nil, fmt.Errorf("failed to open file: %s", err)
-	}
-
-	defer func() {
-		if err := f.Close(); err != nil {
-			logger.Debugf("failed to close file '%s': %s", filepath, err)
-		}
-	}()
-
-	data, err := io.ReadAll(io.LimitReader(f, maxFileSize))
-	if err != nil && err != io.EOF {
-		return nil, fmt.Errorf("failed to read bytes from file: %s", err)
-	}
-
-	return data, nil
-}
-
 // objectiveCWeight determines the weight of objective-c by the provided same folder file extensions.
 func objectiveCWeight(weight float32, extensions []string) float32 {
 	var matFileExists bool
@@ -7,6 +7,7 @@ import (
 	"path/filepath"
 	"strings"
 
+	"github.com/wakatime/wakatime-cli/pkg/file"
 	"github.com/wakatime/wakatime-cli/pkg/heartbeat"
 	"github.com/wakatime/wakatime-cli/pkg/log"
 )
@@ -15,6 +16,9 @@ import (
 type Config struct {
 	// GuessLanguage enables detecting lexer language from file contents.
 	GuessLanguage bool
+	// Heads caches file contents shared with other processing steps. When
+	// nil, a new cache is used.
+	Heads *file.Heads
 }
 
 // WithDetection initializes and returns a heartbeat handle option, which
@@ -26,6 +30,11 @@ func WithDetection(config Config) heartbeat.HandleOption {
 			logger := log.Extract(ctx)
 			logger.Debugln("execute language detection")
 
+			heads := config.Heads
+			if heads == nil {
+				heads = file.NewHeads()
+			}
+
 			for n, h := range hh {
 				if hh[n].Language != nil {
 					continue
@@ -37,7 +46,7 @@ func WithDetection(config Config) heartbeat.HandleOption {
 					filepath = h.LocalFile
 				}
 
-				language, err := Detect(ctx, filepath, config.GuessLanguage)
+				language, err := detect(ctx, filepath, config.GuessLanguage, heads)
 				if err != nil && hh[n].LanguageAlternate != "" {
 					hh[n].Language = heartbeat.PointerTo(hh[n].LanguageAlternate)
 
@@ -61,13 +70,17 @@ func WithDetection(config Config) heartbeat.HandleOption {
 // Detect detects the language of a specific file. If guessLanguage is true,
 // Chroma will be used to detect a language from the file contents.
 func Detect(ctx context.Context, fp string, guessLanguage bool) (heartbeat.Language, error) {
+	return detect(ctx, fp, guessLanguage, file.NewHeads())
+}
+
+func detect(ctx context.Context, fp string, guessLanguage bool, heads *file.Heads) (heartbeat.Language, error) {
 	if language, ok := detectSpecialCases(ctx, fp); ok {
 		return language, nil
 	}
 
 	var language heartbeat.Language
 
-	languageChroma, weight, ok := detectChromaCustomized(ctx, fp, guessLanguage)
+	languageChroma, weight, ok := detectChromaCustomized(ctx, fp, guessLanguage, heads)
 	if ok {
 		language = languageChroma
 	}
@@ -0,0 +1,200 @@
+package namehash
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/wakatime/wakatime-cli/pkg/ini"
+	"github.com/wakatime/wakatime-cli/pkg/log"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+const (
+	// dbFilename is the default filename of the db storing the hmac key and hashed names.
+	dbFilename = "hashed_names.bdb"
+	// hashLength is the number of hex characters of a digest.
+	hashLength = 16
+	// keyBucket is the bucket of the hmac key.
+	keyBucket = "key"
+	// keySize is the size of the hmac key in bytes.
+	keySize = 32
+	// namesBucket is the bucket of hashed names, keyed by digest.
+	namesBucket = "names"
+)
+
+// ErrNotFound is returned when revealing an unknown digest.
+var ErrNotFound = errors.New("hash not found")
+
+// Hasher replaces names with a keyed hmac digest. The key is created on first
+// use and stored in a local db, along with the hashed names, so digests are
+// stable across runs and can be revealed locally.
+type Hasher struct {
+	filepath string
+	key      []byte
+	known    map[string]string
+	mu       sync.Mutex
+}
+
+// NewHasher creates a new Hasher storing its key and hashed names in the db file.
+func NewHasher(filepath string) *Hasher {
+	return &Hasher{
+		filepath: filepath,
+		known:    make(map[string]string),
+	}
+}
+
+// Filepath returns the path of the db storing the hmac key and hashed names.
+func Filepath(ctx context.Context) (string, error) {
+	folder, err := ini.WakaResourcesDir(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed getting resource directory: %s", err)
+	}
+
+	return filepath.Join(folder, dbFilename), nil
+}
+
+// Hash returns the digest of the name, saving the name to be revealed later.
+func (h *Hasher) Hash(ctx context.Context, name string) (string, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if hashed, ok := h.known[name]; ok {
+		return hashed, nil
+	}
+
+	db, err := openDB(h.filepath)
+	if err != nil {
+		return "", err
+	}
+
+	defer closeDB(ctx, db)
+
+	var (
+		hashed string
+		key    = h.key
+	)
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		if key == nil {
+			key, err = loadKey(tx)
+			if err != nil {
+				return err
+			}
+		}
+
+		hashed = digest(key, name)
+
+		b, err := tx.CreateBucketIfNotExists([]byte(namesBucket))
+		if err != nil {
+			return fmt.Errorf("failed to create bucket: %s", err)
+		}
+
+		return b.Put([]byte(hashed), []byte(name))
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to save hashed name: %s", err)
+	}
+
+	// only keep the key once it's saved, so digests stay the same across runs
+	h.key = key
+	h.known[name] = hashed
+
+	return hashed, nil
+}
+
+// Reveal returns the name of a digest saved in the db file. A file extension
+// after the digest, like in "0123456789abcdef.go", is ignored.
+func Reveal(ctx context.Context, filepath, hashed string) (string, error) {
+	hashed, _, _ = strings.Cut(strings.TrimSpace(hashed), ".")
+
+	if _, err := os.Stat(filepath); os.IsNotExist(err) {
+		return "", ErrNotFound
+	}
+
+	db, err := openDB(filepath)
+	if err != nil {
+		return "", err
+	}
+
+	defer closeDB(ctx, db)
+
+	var name string
+
+	err = db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(namesBucket))
+		if b == nil {
+			return ErrNotFound
+		}
+
+		value := b.Get([]byte(strings.ToLower(hashed)))
+		if value == nil {
+			return ErrNotFound
+		}
+
+		name = string(value)
+
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return name, nil
+}
+
+// loadKey returns the hmac key, creating a random key if none exists yet.
+func loadKey(tx *bolt.Tx) ([]byte, error) {
+	b, err := tx.CreateBucketIfNotExists([]byte(keyBucket))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create bucket: %s", err)
+	}
+
+	if key := b.Get([]byte("hmac")); key != nil {
+		return append([]byte{}, key...), nil
+	}
+
+	key := make([]byte, keySize)
+	if _, err := rand.Read(key); err != nil {
+		return nil, fmt.Errorf("failed to generate key: %s", err)
+	}
+
+	if err := b.Put([]byte("hmac"), key); err != nil {
+		return nil, fmt.Errorf("failed to save key: %s", err)
+	}
+
+	return key, nil
+}
+
+// digest returns the truncated hex encoded hmac-sha256 digest of the name.
+func digest(key []byte, name string) string {
+	mac := hmac.New(sha256.New, key)
+	_, _ = mac.Write([]byte(name))
+
+	return hex.EncodeToString(mac.Sum(nil))[:hashLength]
+}
+
+func openDB(filepath string) (*bolt.DB, error) {
+	db, err := bolt.Open(filepath, 0600, &bolt.Options{Timeout: 30 * time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open db file: %s", err)
+	}
+
+	return db, nil
+}
+
+func closeDB(ctx context.Context, db *bolt.DB) {
+	if err := db.Close(); err != nil {
+		log.Extract(ctx).Debugf("failed to close db file: %s", err)
+	}
+}
@@ -0,0 +1,86 @@
+package namehash_test
+
+import (
+	"context"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/wakatime/wakatime-cli/pkg/namehash"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHasher_Hash(t *testing.T) {
+	ctx := context.Background()
+	fp := filepath.Join(t.TempDir(), "hashed_names.bdb")
+
+	hasher := namehash.NewHasher(fp)
+
+	hashed, err := hasher.Hash(ctx, "/path/to/secret.go")
+	require.NoError(t, err)
+
+	assert.Regexp(t, regexp.MustCompile("^[0-9a-f]{16}$"), hashed)
+
+	other, err := hasher.Hash(ctx, "/path/to/other.go")
+	require.NoError(t, err)
+
+	assert.NotEqual(t, hashed, other)
+
+	// the key is reused by other hashers of the same db
+	again, err := namehash.NewHasher(fp).Hash(ctx, "/path/to/secret.go")
+	require.NoError(t, err)
+
+	assert.Equal(t, hashed, again)
+}
+
+func TestHasher_Hash_DifferentKeys(t *testing.T) {
+	ctx := context.Background()
+
+	hashed, err := namehash.NewHasher(filepath.Join(t.TempDir(), "a.bdb")).Hash(ctx, "feature/secret")
+	require.NoError(t, err)
+
+	other, err := namehash.NewHasher(filepath.Join(t.TempDir(), "b.bdb")).Hash(ctx, "feature/secret")
+	require.NoError(t, err)
+
+	assert.NotEqual(t, hashed, other)
+}
+
+func TestReveal(t *testing.T) {
+	ctx := context.Background()
+	fp := filepath.Join(t.TempDir(), "hashed_names.bdb")
+
+	hashed, err := namehash.NewHasher(fp).Hash(ctx, "/path/to/secret.go")
+	require.NoError(t, err)
+
+	tests := map[string]string{
+		"digest":         hashed,
+		"with extension": hashed + ".go",
+		"upper case":     "  " + strings.ToUpper(hashed) + "\n",
+	}
+
+	for name, value := range tests {
+		t.Run(name, func(t *testing.T) {
+			revealed, err := namehash.Reveal(ctx, fp, value)
+			require.NoError(t, err)
+
+			assert.Equal(t, "/path/to/secret.go", revealed)
+		})
+	}
+}
+
+func TestReveal_NotFound(t *testing.T) {
+	ctx := context.Background()
+	fp := filepath.Join(t.TempDir(), "hashed_names.bdb")
+
+	_, err := namehash.Reveal(ctx, fp, "0123456789abcdef")
+	assert.ErrorIs(t, err, namehash.ErrNotFound)
+
+	_, err = namehash.NewHasher(fp).Hash(ctx, "main")
+	require.NoError(t, err)
+
+	_, err = namehash.Reveal(ctx, fp, "0123456789abcdef")
+	assert.ErrorIs(t, err, namehash.ErrNotFound)
+}
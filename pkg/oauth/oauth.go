@@ -0,0 +1,262 @@
+package oauth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/wakatime/wakatime-cli/pkg/log"
+)
+
+const (
+	// grantTypeDeviceCode is the grant type of device access token requests, see RFC 8628.
+	grantTypeDeviceCode = "urn:ietf:params:oauth:grant-type:device_code"
+	// grantTypeRefreshToken is the grant type of refresh token requests, see RFC 6749.
+	grantTypeRefreshToken = "refresh_token"
+	// defaultPollInterval is the polling interval used when the server doesn't specify one.
+	defaultPollInterval = 5 * time.Second
+	// slowDownInterval is added to the polling interval on slow_down errors.
+	slowDownInterval = 5 * time.Second
+)
+
+// Config contains the oauth client and endpoints used to authenticate with the api.
+type Config struct {
+	ClientID      string
+	DeviceAuthURL string
+	Scope         string
+	// StoreType is the secret store of the token, see keystore.New. The token is
+	// saved in a file in the wakatime resources folder when empty.
+	StoreType string
+	TokenURL  string
+}
+
+// Enabled returns true if oauth is configured.
+func (c Config) Enabled() bool {
+	return c.ClientID != ""
+}
+
+// Doer sends http requests, like api.Client.
+type Doer interface {
+	Do(ctx context.Context, req *http.Request) (*http.Response, error)
+}
+
+// Token contains the access and refresh tokens.
+type Token struct {
+	AccessToken  string    `json:"access_token"`
+	RefreshToken string    `json:"refresh_token"`
+	Expiry       time.Time `json:"expiry,omitempty"`
+}
+
+// Valid returns true if the access token is set and doesn't expire within the next minute.
+// Tokens without expiry never expire.
+func (t Token) Valid(now time.Time) bool {
+	return t.AccessToken != "" && (t.Expiry.IsZero() || t.Expiry.After(now.Add(time.Minute)))
+}
+
+// DeviceCode is the device authorization response, see RFC 8628 section 3.2.
+type DeviceCode struct {
+	DeviceCode              string `json:"device_code"`
+	UserCode                string `json:"user_code"`
+	VerificationURI         string `json:"verification_uri"`
+	VerificationURIComplete string `json:"verification_uri_complete"`
+	ExpiresIn               int    `json:"expires_in"`
+	Interval                int    `json:"interval"`
+}
+
+// Error is an error response of the token endpoint, see RFC 6749 section 5.2.
+type Error struct {
+	Code        string `json:"error"`
+	Description string `json:"error_description"`
+}
+
+// Error method to implement error interface.
+func (e Error) Error() string {
+	if e.Description == "" {
+		return e.Code
+	}
+
+	return fmt.Sprintf("%s: %s", e.Code, e.Description)
+}
+
+// tokenResponse is the successful response of the token endpoint.
+type tokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int    `json:"expires_in"`
+	RefreshToken string `json:"refresh_token"`
+}
+
+// RequestDeviceCode starts the device authorization flow.
+func RequestDeviceCode(ctx context.Context, doer Doer, config Config) (DeviceCode, error) {
+	form := url.Values{"client_id": {config.ClientID}}
+	if config.Scope != "" {
+		form.Set("scope", config.Scope)
+	}
+
+	var code DeviceCode
+
+	status, body, err := postForm(ctx, doer, config.DeviceAuthURL, form)
+	if err != nil {
+		return DeviceCode{}, err
+	}
+
+	if status != http.StatusOK {
+		return DeviceCode{}, parseError(config.DeviceAuthURL, status, body)
+	}
+
+	if err := json.Unmarshal(body, &code); err != nil {
+		return DeviceCode{}, fmt.Errorf("failed to parse device authorization response: %s", err)
+	}
+
+	if code.DeviceCode == "" || code.UserCode == "" || code.VerificationURI == "" {
+		return DeviceCode{}, fmt.Errorf("invalid device authorization response: %q", string(body))
+	}
+
+	return code, nil
+}
+
+// PollToken polls the token endpoint until the user authorized the device, denied access
+// or the device code expired.
+func PollToken(ctx context.Context, doer Doer, config Config, code DeviceCode) (Token, error) {
+	logger := log.Extract(ctx)
+
+	interval := defaultPollInterval
+	if code.Interval > 0 {
+		interval = time.Duration(code.Interval) * time.Second
+	}
+
+	if code.ExpiresIn > 0 {
+		var cancel context.CancelFunc
+
+		ctx, cancel = context.WithTimeout(ctx, time.Duration(code.ExpiresIn)*time.Second)
+		defer cancel()
+	}
+
+	form := url.Values{
+		"client_id":   {config.ClientID},
+		"device_code": {code.DeviceCode},
+		"grant_type":  {grantTypeDeviceCode},
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return Token{}, fmt.Errorf("device code expired before authorization: %s", ctx.Err())
+		case <-time.After(interval):
+		}
+
+		token, err := requestToken(ctx, doer, config.TokenURL, form)
+		if err == nil {
+			return token, nil
+		}
+
+		var oauthErr Error
+		if !errors.As(err, &oauthErr) {
+			return Token{}, err
+		}
+
+		switch oauthErr.Code {
+		case "authorization_pending":
+			logger.Debugln("waiting for device authorization")
+		case "slow_down":
+			interval += slowDownInterval
+		default:
+			return Token{}, err
+		}
+	}
+}
+
+// RefreshToken requests a new access token using the refresh token. The refresh token
+// is kept when the server doesn't rotate it.
+func RefreshToken(ctx context.Context, doer Doer, config Config, refreshToken string) (Token, error) {
+	form := url.Values{
+		"client_id":     {config.ClientID},
+		"grant_type":    {grantTypeRefreshToken},
+		"refresh_token": {refreshToken},
+	}
+
+	token, err := requestToken(ctx, doer, config.TokenURL, form)
+	if err != nil {
+		return Token{}, err
+	}
+
+	if token.RefreshToken == "" {
+		token.RefreshToken = refreshToken
+	}
+
+	return token, nil
+}
+
+func requestToken(ctx context.Context, doer Doer, tokenURL string, form url.Values) (Token, error) {
+	status, body, err := postForm(ctx, doer, tokenURL, form)
+	if err != nil {
+		return Token{}, err
+	}
+
+	if status != http.StatusOK {
+		return Token{}, parseError(tokenURL, status, body)
+	}
+
+	var resp tokenResponse
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return Token{}, fmt.Errorf("failed to parse token response: %s", err)
+	}
+
+	if resp.AccessToken == "" {
+		return Token{}, errors.New("token response contains no access token")
+	}
+
+	if resp.TokenType != "" && !strings.EqualFold(resp.TokenType, "bearer") {
+		return Token{}, fmt.Errorf("unsupported token type %q", resp.TokenType)
+	}
+
+	token := Token{
+		AccessToken:  resp.AccessToken,
+		RefreshToken: resp.RefreshToken,
+	}
+
+	if resp.ExpiresIn > 0 {
+		token.Expiry = time.Now().Add(time.Duration(resp.ExpiresIn) * time.Second)
+	}
+
+	return token, nil
+}
+
+// postForm sends an url encoded form and returns the response status and body.
+func postForm(ctx context.Context, doer Doer, u string, form url.Values) (int, []byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u, strings.NewReader(form.Encode()))
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed to create request: %s", err)
+	}
+
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := doer.Do(ctx, req)
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed making request to %q: %s", u, err)
+	}
+	defer resp.Body.Close() // nolint:errcheck,gosec
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed reading response body from %q: %s", u, err)
+	}
+
+	return resp.StatusCode, body, nil
+}
+
+// parseError returns the oauth error of the response body, if any.
+func parseError(u string, status int, body []byte) error {
+	var oauthErr Error
+	if err := json.Unmarshal(body, &oauthErr); err == nil && oauthErr.Code != "" {
+		return oauthErr
+	}
+
+	return fmt.Errorf("invalid response status from %q. got: %d, want: %d. body: %q", u, status, http.StatusOK, string(body))
+}
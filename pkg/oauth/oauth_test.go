@@ -0,0 +1,270 @@
+package oauth_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/wakatime/wakatime-cli/pkg/api"
+	"github.com/wakatime/wakatime-cli/pkg/oauth"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRequestDeviceCode(t *testing.T) {
+	srv := newTestOAuthServer(t)
+
+	code, err := oauth.RequestDeviceCode(context.Background(), api.NewClient(""), srv.Config())
+	require.NoError(t, err)
+
+	assert.Equal(t, oauth.DeviceCode{
+		DeviceCode:              "device-code",
+		UserCode:                "ABCD-EFGH",
+		VerificationURI:         srv.URL + "/device",
+		VerificationURIComplete: srv.URL + "/device?user_code=ABCD-EFGH",
+		ExpiresIn:               60,
+		Interval:                1,
+	}, code)
+}
+
+func TestRequestDeviceCode_Err(t *testing.T) {
+	srv := newTestOAuthServer(t)
+
+	config := srv.Config()
+	config.ClientID = "unknown"
+
+	_, err := oauth.RequestDeviceCode(context.Background(), api.NewClient(""), config)
+
+	assert.EqualError(t, err, "invalid_client: unknown client")
+}
+
+func TestPollToken(t *testing.T) {
+	srv := newTestOAuthServer(t)
+	srv.pending = 1
+
+	client := api.NewClient("")
+
+	code, err := oauth.RequestDeviceCode(context.Background(), client, srv.Config())
+	require.NoError(t, err)
+
+	token, err := oauth.PollToken(context.Background(), client, srv.Config(), code)
+	require.NoError(t, err)
+
+	assert.Equal(t, "access-1", token.AccessToken)
+	assert.Equal(t, "refresh-1", token.RefreshToken)
+	assert.WithinDuration(t, time.Now().Add(time.Hour), token.Expiry, time.Minute)
+	assert.Equal(t, 2, srv.Calls("device_code"))
+}
+
+func TestPollToken_AccessDenied(t *testing.T) {
+	srv := newTestOAuthServer(t)
+	srv.denied = true
+
+	client := api.NewClient("")
+
+	code, err := oauth.RequestDeviceCode(context.Background(), client, srv.Config())
+	require.NoError(t, err)
+
+	_, err = oauth.PollToken(context.Background(), client, srv.Config(), code)
+
+	var oauthErr oauth.Error
+
+	require.ErrorAs(t, err, &oauthErr)
+	assert.Equal(t, "access_denied", oauthErr.Code)
+}
+
+func TestRefreshToken(t *testing.T) {
+	srv := newTestOAuthServer(t)
+
+	token, err := oauth.RefreshToken(context.Background(), api.NewClient(""), srv.Config(), "refresh-0")
+	require.NoError(t, err)
+
+	assert.Equal(t, "access-1", token.AccessToken)
+	assert.Equal(t, "refresh-1", token.RefreshToken)
+}
+
+func TestRefreshToken_NotRotated(t *testing.T) {
+	srv := newTestOAuthServer(t)
+	srv.noRotation = true
+
+	token, err := oauth.RefreshToken(context.Background(), api.NewClient(""), srv.Config(), "refresh-0")
+	require.NoError(t, err)
+
+	assert.Equal(t, "access-1", token.AccessToken)
+	assert.Equal(t, "refresh-0", token.RefreshToken)
+}
+
+func TestRefreshToken_Invalid(t *testing.T) {
+	srv := newTestOAuthServer(t)
+
+	_, err := oauth.RefreshToken(context.Background(), api.NewClient(""), srv.Config(), "revoked")
+
+	assert.EqualError(t, err, "invalid_grant: refresh token revoked")
+}
+
+func TestToken_Valid(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := map[string]struct {
+		Token    oauth.Token
+		Expected bool
+	}{
+		"valid": {
+			Token:    oauth.Token{AccessToken: "access", Expiry: now.Add(time.Hour)},
+			Expected: true,
+		},
+		"no expiry": {
+			Token:    oauth.Token{AccessToken: "access"},
+			Expected: true,
+		},
+		"expires soon": {
+			Token: oauth.Token{AccessToken: "access", Expiry: now.Add(30 * time.Second)},
+		},
+		"expired": {
+			Token: oauth.Token{AccessToken: "access", Expiry: now.Add(-time.Hour)},
+		},
+		"no access token": {
+			Token: oauth.Token{RefreshToken: "refresh"},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, test.Expected, test.Token.Valid(now))
+		})
+	}
+}
+
+// testOAuthServer is a local stand-in oauth server, supporting the device code
+// and refresh token grants. Access and refresh tokens are numbered by issue order.
+type testOAuthServer struct {
+	*httptest.Server
+
+	mu sync.Mutex
+	// pending is the number of device token polls answered with authorization_pending.
+	pending int
+	// denied answers device token polls with access_denied.
+	denied bool
+	// noRotation omits the refresh token in refresh responses.
+	noRotation bool
+	issued     int
+	calls      map[string]int
+}
+
+func newTestOAuthServer(t *testing.T) *testOAuthServer {
+	srv := &testOAuthServer{calls: map[string]int{}}
+
+	router := http.NewServeMux()
+
+	router.HandleFunc("/device/code", func(w http.ResponseWriter, req *http.Request) {
+		assert.Equal(t, http.MethodPost, req.Method)
+		assert.Equal(t, "application/x-www-form-urlencoded", req.Header.Get("Content-Type"))
+
+		if req.PostFormValue("client_id") != "wakatime-cli" {
+			writeJSON(w, http.StatusUnauthorized, map[string]any{"error": "invalid_client", "error_description": "unknown client"})
+			return
+		}
+
+		assert.Equal(t, "heartbeats", req.PostFormValue("scope"))
+
+		writeJSON(w, http.StatusOK, map[string]any{
+			"device_code":               "device-code",
+			"user_code":                 "ABCD-EFGH",
+			"verification_uri":          srv.URL + "/device",
+			"verification_uri_complete": srv.URL + "/device?user_code=ABCD-EFGH",
+			"expires_in":                60,
+			"interval":                  1,
+		})
+	})
+
+	router.HandleFunc("/token", func(w http.ResponseWriter, req *http.Request) {
+		srv.mu.Lock()
+		defer srv.mu.Unlock()
+
+		assert.Equal(t, "wakatime-cli", req.PostFormValue("client_id"))
+
+		switch req.PostFormValue("grant_type") {
+		case "urn:ietf:params:oauth:grant-type:device_code":
+			srv.calls["device_code"]++
+
+			assert.Equal(t, "device-code", req.PostFormValue("device_code"))
+
+			if srv.denied {
+				writeJSON(w, http.StatusBadRequest, map[string]any{"error": "access_denied"})
+				return
+			}
+
+			if srv.pending > 0 {
+				srv.pending--
+				writeJSON(w, http.StatusBadRequest, map[string]any{"error": "authorization_pending"})
+
+				return
+			}
+		case "refresh_token":
+			srv.calls["refresh_token"]++
+
+			if req.PostFormValue("refresh_token") == "revoked" {
+				writeJSON(w, http.StatusBadRequest, map[string]any{
+					"error":             "invalid_grant",
+					"error_description": "refresh token revoked",
+				})
+
+				return
+			}
+		default:
+			writeJSON(w, http.StatusBadRequest, map[string]any{"error": "unsupported_grant_type"})
+			return
+		}
+
+		srv.issued++
+
+		resp := map[string]any{
+			"access_token": "access-" + strconv.Itoa(srv.issued),
+			"token_type":   "Bearer",
+			"expires_in":   3600,
+		}
+
+		if !srv.noRotation || req.PostFormValue("grant_type") != "refresh_token" {
+			resp["refresh_token"] = "refresh-" + strconv.Itoa(srv.issued)
+		}
+
+		writeJSON(w, http.StatusOK, resp)
+	})
+
+	srv.Server = httptest.NewServer(router)
+
+	t.Cleanup(srv.Close)
+
+	return srv
+}
+
+// Config returns the oauth config of the server.
+func (s *testOAuthServer) Config() oauth.Config {
+	return oauth.Config{
+		ClientID:      "wakatime-cli",
+		DeviceAuthURL: s.URL + "/device/code",
+		Scope:         "heartbeats",
+		TokenURL:      s.URL + "/token",
+	}
+}
+
+// Calls returns the number of token requests of the given grant type.
+func (s *testOAuthServer) Calls(grantType string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.calls[grantType]
+}
+
+func writeJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	_ = json.NewEncoder(w).Encode(body)
+}
@@ -0,0 +1,108 @@
+package oauth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/wakatime/wakatime-cli/pkg/log"
+)
+
+// Source provides access tokens for api.WithBearerAuth. Expired or rejected access
+// tokens are refreshed with the stored refresh token, and the new token is saved.
+type Source struct {
+	config Config
+	doer   Doer
+	store  Store
+	// mu guards token, as requests may be sent concurrently.
+	mu     sync.Mutex
+	token  Token
+	loaded bool
+}
+
+// NewSource creates a new token source. The doer must not authenticate with this source,
+// as it's used to refresh tokens.
+func NewSource(config Config, doer Doer, store Store) *Source {
+	return &Source{
+		config: config,
+		doer:   doer,
+		store:  store,
+	}
+}
+
+// Token returns the current access token, refreshing it if expired.
+func (s *Source) Token(ctx context.Context) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.load(ctx); err != nil {
+		return "", err
+	}
+
+	if s.token.Valid(time.Now()) {
+		return s.token.AccessToken, nil
+	}
+
+	return s.refresh(ctx)
+}
+
+// Refresh returns a new access token after the api rejected the passed in one. If the
+// token was already refreshed meanwhile, like by a concurrent request, the current one
+// is returned.
+func (s *Source) Refresh(ctx context.Context, rejected string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.load(ctx); err != nil {
+		return "", err
+	}
+
+	if s.token.AccessToken != rejected && s.token.Valid(time.Now()) {
+		return s.token.AccessToken, nil
+	}
+
+	return s.refresh(ctx)
+}
+
+// load reads the token from the store once.
+func (s *Source) load(ctx context.Context) error {
+	if s.loaded {
+		return nil
+	}
+
+	token, err := s.store.Load(ctx)
+	if err != nil {
+		return err
+	}
+
+	s.token = token
+	s.loaded = true
+
+	return nil
+}
+
+func (s *Source) refresh(ctx context.Context) (string, error) {
+	logger := log.Extract(ctx)
+
+	if s.token.RefreshToken == "" {
+		return "", errors.New("access token expired and no refresh token found, login with --login")
+	}
+
+	token, err := RefreshToken(ctx, s.doer, s.config, s.token.RefreshToken)
+	if err != nil {
+		return "", fmt.Errorf("failed to refresh oauth token: %w", err)
+	}
+
+	s.token = token
+
+	if err := s.store.Save(ctx, token); err != nil {
+		// the new token is still used for this run
+		logger.Warnf("failed to save refreshed oauth token: %s", err)
+	}
+
+	logger.Debugln("refreshed oauth access token")
+
+	return token.AccessToken, nil
+}
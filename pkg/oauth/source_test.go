@@ -0,0 +1,112 @@
+package oauth_test
+
+import (
+	"context"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/wakatime/wakatime-cli/pkg/api"
+	"github.com/wakatime/wakatime-cli/pkg/oauth"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSource_Token(t *testing.T) {
+	srv := newTestOAuthServer(t)
+
+	store := &oauth.FileStore{Filepath: filepath.Join(t.TempDir(), "token.json")}
+
+	err := store.Save(context.Background(), oauth.Token{
+		AccessToken:  "access-0",
+		RefreshToken: "refresh-0",
+		Expiry:       time.Now().Add(time.Hour),
+	})
+	require.NoError(t, err)
+
+	source := oauth.NewSource(srv.Config(), api.NewClient(""), store)
+
+	token, err := source.Token(context.Background())
+	require.NoError(t, err)
+
+	assert.Equal(t, "access-0", token)
+	assert.Zero(t, srv.Calls("refresh_token"))
+}
+
+func TestSource_Token_Expired(t *testing.T) {
+	srv := newTestOAuthServer(t)
+
+	store := &oauth.FileStore{Filepath: filepath.Join(t.TempDir(), "token.json")}
+
+	err := store.Save(context.Background(), oauth.Token{
+		AccessToken:  "access-0",
+		RefreshToken: "refresh-0",
+		Expiry:       time.Now().Add(-time.Hour),
+	})
+	require.NoError(t, err)
+
+	source := oauth.NewSource(srv.Config(), api.NewClient(""), store)
+
+	token, err := source.Token(context.Background())
+	require.NoError(t, err)
+
+	assert.Equal(t, "access-1", token)
+
+	// refreshed token is saved
+	saved, err := store.Load(context.Background())
+	require.NoError(t, err)
+
+	assert.Equal(t, "access-1", saved.AccessToken)
+	assert.Equal(t, "refresh-1", saved.RefreshToken)
+}
+
+func TestSource_Token_NotLoggedIn(t *testing.T) {
+	srv := newTestOAuthServer(t)
+
+	store := &oauth.FileStore{Filepath: filepath.Join(t.TempDir(), "token.json")}
+
+	source := oauth.NewSource(srv.Config(), api.NewClient(""), store)
+
+	_, err := source.Token(context.Background())
+
+	assert.ErrorIs(t, err, oauth.ErrNotLoggedIn)
+}
+
+func TestSource_Refresh_Concurrent(t *testing.T) {
+	srv := newTestOAuthServer(t)
+
+	store := &oauth.FileStore{Filepath: filepath.Join(t.TempDir(), "token.json")}
+
+	err := store.Save(context.Background(), oauth.Token{
+		AccessToken:  "access-0",
+		RefreshToken: "refresh-0",
+	})
+	require.NoError(t, err)
+
+	source := oauth.NewSource(srv.Config(), api.NewClient(""), store)
+
+	var wg sync.WaitGroup
+
+	tokens := make([]string, 5)
+
+	for i := range tokens {
+		wg.Add(1)
+
+		go func(i int) {
+			defer wg.Done()
+
+			token, err := source.Refresh(context.Background(), "access-0")
+			assert.NoError(t, err)
+
+			tokens[i] = token
+		}(i)
+	}
+
+	wg.Wait()
+
+	// the rejected token is refreshed once
+	assert.Equal(t, []string{"access-1", "access-1", "access-1", "access-1", "access-1"}, tokens)
+	assert.Equal(t, 1, srv.Calls("refresh_token"))
+}
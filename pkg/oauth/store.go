@@ -0,0 +1,134 @@
+package oauth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/wakatime/wakatime-cli/pkg/ini"
+	"github.com/wakatime/wakatime-cli/pkg/keystore"
+)
+
+const (
+	// defaultTokenFile is the name of the file storing the token, when no secret store is configured.
+	defaultTokenFile = "wakatime-oauth-token.json"
+	// defaultStoreEntry is the secret store entry of the token.
+	defaultStoreEntry = "wakatime/oauth_token"
+)
+
+// ErrNotLoggedIn is returned when no token was stored yet.
+var ErrNotLoggedIn = errors.New("no oauth token found, login with --login")
+
+// Store loads and saves the token.
+type Store interface {
+	// Load returns the stored token or ErrNotLoggedIn.
+	Load(ctx context.Context) (Token, error)
+	// Save stores the token, replacing an existing one.
+	Save(ctx context.Context, token Token) error
+}
+
+// NewStore returns the store of the given secret store type, or a file store in the
+// wakatime resources folder if empty. The options are passed to keystore.New.
+func NewStore(ctx context.Context, storeType string, opts ...keystore.Option) (Store, error) {
+	if storeType != "" {
+		store, err := keystore.New(storeType, defaultStoreEntry, opts...)
+		if err != nil {
+			return nil, err
+		}
+
+		return &SecretStore{Store: store}, nil
+	}
+
+	folder, err := ini.WakaResourcesDir(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get wakatime resources folder: %s", err)
+	}
+
+	return &FileStore{Filepath: filepath.Join(folder, defaultTokenFile)}, nil
+}
+
+// FileStore stores the token as json file, readable only by the current user.
+type FileStore struct {
+	Filepath string
+}
+
+// Load reads the token from the file.
+func (s *FileStore) Load(_ context.Context) (Token, error) {
+	data, err := os.ReadFile(s.Filepath)
+	if errors.Is(err, os.ErrNotExist) {
+		return Token{}, ErrNotLoggedIn
+	}
+
+	if err != nil {
+		return Token{}, fmt.Errorf("failed to read oauth token file: %s", err)
+	}
+
+	return parseToken(data)
+}
+
+// Save writes the token to the file.
+func (s *FileStore) Save(_ context.Context, token Token) error {
+	data, err := json.Marshal(token)
+	if err != nil {
+		return fmt.Errorf("failed to json encode oauth token: %s", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(s.Filepath), 0700); err != nil {
+		return fmt.Errorf("failed to create oauth token folder: %s", err)
+	}
+
+	if err := os.WriteFile(s.Filepath, data, 0600); err != nil {
+		return fmt.Errorf("failed to write oauth token file: %s", err)
+	}
+
+	return nil
+}
+
+// SecretStore stores the token as json in a secret store.
+type SecretStore struct {
+	Store keystore.Store
+}
+
+// Load reads the token from the secret store.
+func (s *SecretStore) Load(ctx context.Context) (Token, error) {
+	data, err := s.Store.Get(ctx)
+	if errors.Is(err, keystore.ErrNotFound) {
+		return Token{}, ErrNotLoggedIn
+	}
+
+	if err != nil {
+		return Token{}, fmt.Errorf("failed to read oauth token from store: %s", err)
+	}
+
+	return parseToken([]byte(data))
+}
+
+// Save writes the token to the secret store.
+func (s *SecretStore) Save(ctx context.Context, token Token) error {
+	data, err := json.Marshal(token)
+	if err != nil {
+		return fmt.Errorf("failed to json encode oauth token: %s", err)
+	}
+
+	if err := s.Store.Set(ctx, string(data)); err != nil {
+		return fmt.Errorf("failed to write oauth token to store: %s", err)
+	}
+
+	return nil
+}
+
+func parseToken(data []byte) (Token, error) {
+	var token Token
+	if err := json.Unmarshal(data, &token); err != nil {
+		return Token{}, fmt.Errorf("failed to parse oauth token: %s", err)
+	}
+
+	if token.RefreshToken == "" && token.AccessToken == "" {
+		return Token{}, ErrNotLoggedIn
+	}
+
+	return token, nil
+}
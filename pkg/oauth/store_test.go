@@ -0,0 +1,78 @@
+package oauth_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/wakatime/wakatime-cli/pkg/keystore"
+	"github.com/wakatime/wakatime-cli/pkg/oauth"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileStore(t *testing.T) {
+	fp := filepath.Join(t.TempDir(), "nested", "token.json")
+
+	store := &oauth.FileStore{Filepath: fp}
+
+	_, err := store.Load(context.Background())
+	require.ErrorIs(t, err, oauth.ErrNotLoggedIn)
+
+	token := oauth.Token{
+		AccessToken:  "access",
+		RefreshToken: "refresh",
+		Expiry:       time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC),
+	}
+
+	err = store.Save(context.Background(), token)
+	require.NoError(t, err)
+
+	loaded, err := store.Load(context.Background())
+	require.NoError(t, err)
+
+	assert.Equal(t, token, loaded)
+
+	if runtime.GOOS != "windows" {
+		info, err := os.Stat(fp)
+		require.NoError(t, err)
+
+		assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+	}
+}
+
+func TestSecretStore(t *testing.T) {
+	store := &oauth.SecretStore{Store: &mockKeystore{}}
+
+	_, err := store.Load(context.Background())
+	require.ErrorIs(t, err, oauth.ErrNotLoggedIn)
+
+	err = store.Save(context.Background(), oauth.Token{RefreshToken: "refresh"})
+	require.NoError(t, err)
+
+	loaded, err := store.Load(context.Background())
+	require.NoError(t, err)
+
+	assert.Equal(t, oauth.Token{RefreshToken: "refresh"}, loaded)
+}
+
+type mockKeystore struct {
+	value string
+}
+
+func (m *mockKeystore) Get(_ context.Context) (string, error) {
+	if m.value == "" {
+		return "", keystore.ErrNotFound
+	}
+
+	return m.value, nil
+}
+
+func (m *mockKeystore) Set(_ context.Context, value string) error {
+	m.value = value
+	return nil
+}
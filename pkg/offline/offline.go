@@ -69,6 +69,16 @@ func WithQueue(filepath string) heartbeat.HandleOption {
 			}
 
 			results, err := next(ctx, hh)
+
+			var errpartial api.ErrPartial
+			if errors.As(err, &errpartial) {
+				if err := handlePartialFailure(ctx, filepath, errpartial); err != nil {
+					return nil, fmt.Errorf("failed to handle partial failure: %s", err)
+				}
+
+				return results, err
+			}
+
 			if err != nil {
 				logger.Debugf("pushing %d heartbeat(s) to queue after error: %s", len(hh), err)
 
@@ -80,6 +90,8 @@ func WithQueue(filepath string) heartbeat.HandleOption {
 					)
 				}
 
+				recordQueued(ctx, hh, fmt.Sprintf("sending failed: %s", err))
+
 				return nil, err
 			}
 
@@ -176,6 +188,16 @@ func Sync(ctx context.Context, filepath string, syncLimit int) func(next heartbe
 			logger.Debugf("send %d heartbeats on sync run %d", len(hh), run)
 
 			results, err := next(ctx, hh)
+
+			var errpartial api.ErrPartial
+			if errors.As(err, &errpartial) {
+				if err := handlePartialFailure(ctx, filepath, errpartial); err != nil {
+					logger.Warnf("failed to handle partial failure: %s", err)
+				}
+
+				return err
+			}
+
 			if err != nil {
 				requeueErr := pushHeartbeatsWithRetry(ctx, filepath, hh)
 				if requeueErr != nil {
@@ -195,6 +217,34 @@ func Sync(ctx context.Context, filepath string, syncLimit int) func(next heartbe
 	}
 }
 
+// handlePartialFailure pushes the heartbeats of failed api key groups to queue and
+// handles the results of succeeded groups, so accepted heartbeats are not sent twice.
+func handlePartialFailure(ctx context.Context, filepath string, errpartial api.ErrPartial) error {
+	logger := log.Extract(ctx)
+
+	failed := errpartial.Failed()
+
+	logger.Debugf("pushing %d heartbeat(s) of failed api key(s) to queue after error: %s", len(failed), errpartial)
+
+	if err := pushHeartbeatsWithRetry(ctx, filepath, failed); err != nil {
+		return fmt.Errorf("failed to push heartbeats to queue: %s", err)
+	}
+
+	for _, g := range errpartial.Groups {
+		if g.Err != nil {
+			recordQueued(ctx, g.Heartbeats, fmt.Sprintf("sending failed: %s", g.Err))
+
+			continue
+		}
+
+		if err := handleResults(ctx, filepath, g.Results, g.Heartbeats); err != nil {
+			return fmt.Errorf("failed to handle results: %s", err)
+		}
+	}
+
+	return nil
+}
+
 func handleResults(ctx context.Context, filepath string, results []heartbeat.Result, hh []heartbeat.Heartbeat) error {
 	var (
 		err               error
@@ -236,6 +286,8 @@ func handleResults(ctx context.Context, filepath string, results []heartbeat.Res
 		err = pushHeartbeatsWithRetry(ctx, filepath, withInvalidStatus)
 		if err != nil {
 			logger.Warnf("failed to push heartbeats with invalid status to queue: %s", err)
+		} else {
+			recordQueued(ctx, withInvalidStatus, "api returned an error status")
 		}
 	}
 
@@ -249,12 +301,25 @@ func handleResults(ctx context.Context, filepath string, results []heartbeat.Res
 		err = pushHeartbeatsWithRetry(ctx, filepath, hh[start:])
 		if err != nil {
 			logger.Warnf("failed to push leftover heartbeats to queue: %s", err)
+		} else {
+			recordQueued(ctx, hh[start:], "missing result from api")
 		}
 	}
 
 	return err
 }
 
+// recordQueued records the heartbeats as saved to the queue, to be reported
+// by the heartbeat command.
+func recordQueued(ctx context.Context, hh []heartbeat.Heartbeat, reason string) {
+	for _, h := range hh {
+		heartbeat.RecordOutcome(ctx, h, heartbeat.Outcome{
+			Status: heartbeat.OutcomeQueued,
+			Reason: reason,
+		})
+	}
+}
+
 func popHeartbeats(ctx context.Context, filepath string, limit int) ([]heartbeat.Heartbeat, error) {
 	db, close, err := openDB(ctx, filepath)
 	if err != nil {
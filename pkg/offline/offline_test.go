@@ -13,6 +13,7 @@ import (
 	"time"
 
 	"github.com/spf13/viper"
+	"github.com/wakatime/wakatime-cli/pkg/api"
 	"github.com/wakatime/wakatime-cli/pkg/heartbeat"
 	"github.com/wakatime/wakatime-cli/pkg/ini"
 	"github.com/wakatime/wakatime-cli/pkg/offline"
@@ -230,6 +231,76 @@ func TestWithQueue_ApiError(t *testing.T) {
 	assert.JSONEq(t, string(dataPy), stored[1].Heartbeat)
 }
 
+func TestWithQueue_PartialFailure(t *testing.T) {
+	// setup
+	f, err := os.CreateTemp(t.TempDir(), "")
+	require.NoError(t, err)
+
+	defer f.Close()
+
+	opt := offline.WithQueue(f.Name())
+
+	handle := opt(func(_ context.Context, hh []heartbeat.Heartbeat) ([]heartbeat.Result, error) {
+		results := []heartbeat.Result{{Status: http.StatusCreated, Heartbeat: hh[0]}}
+
+		return results, api.ErrPartial{
+			Groups: []api.GroupResult{
+				{
+					Heartbeats: hh[:1],
+					Results:    results,
+				},
+				{
+					Heartbeats: hh[1:],
+					Err:        api.Err{Err: errors.New("error")},
+				},
+			},
+		}
+	})
+
+	// run
+	results, err := handle(context.Background(), []heartbeat.Heartbeat{
+		testHeartbeats()[0],
+		testHeartbeats()[1],
+	})
+
+	var errpartial api.ErrPartial
+
+	require.ErrorAs(t, err, &errpartial)
+
+	assert.Len(t, results, 1)
+
+	// check only the heartbeat of the failed group was queued
+	db, err := bolt.Open(f.Name(), 0600, nil)
+	require.NoError(t, err)
+
+	var stored []heartbeatRecord
+
+	err = db.View(func(tx *bolt.Tx) error {
+		c := tx.Bucket([]byte("heartbeats")).Cursor()
+
+		for key, value := c.First(); key != nil; key, value = c.Next() {
+			stored = append(stored, heartbeatRecord{
+				ID:        string(key),
+				Heartbeat: string(value),
+			})
+		}
+
+		return nil
+	})
+	require.NoError(t, err)
+
+	err = db.Close()
+	require.NoError(t, err)
+
+	dataPy, err := os.ReadFile("testdata/heartbeat_py.json")
+	require.NoError(t, err)
+
+	require.Len(t, stored, 1)
+
+	assert.Equal(t, "1592868386.079084-13-file-debugging-wakatime-summary-/tmp/main.py-false", stored[0].ID)
+	assert.JSONEq(t, string(dataPy), stored[0].Heartbeat)
+}
+
 func TestWithQueue_InvalidResults(t *testing.T) {
 	// setup
 	f, err := os.CreateTemp(t.TempDir(), "")
@@ -0,0 +1,213 @@
+package offline
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/wakatime/wakatime-cli/pkg/heartbeat"
+	"github.com/wakatime/wakatime-cli/pkg/log"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+const (
+	// quarantineBucket is the bolt db bucket name of invalid heartbeats.
+	quarantineBucket = "quarantine"
+	// maxQuarantined is the maximum number of heartbeats kept in quarantine.
+	// The oldest heartbeats are deleted first.
+	maxQuarantined = 1000
+)
+
+// Quarantined is a heartbeat, which failed validation before sending.
+type Quarantined struct {
+	Heartbeat     heartbeat.Heartbeat `json:"heartbeat"`
+	Reason        string              `json:"reason"`
+	QuarantinedAt time.Time           `json:"quarantined_at"`
+}
+
+// WithQuarantine initializes and returns a heartbeat handle option, which
+// can be used in a heartbeat processing pipeline to move heartbeats failing
+// validation to a quarantine bucket in the offline db, instead of sending
+// them to the api where they would be rejected.
+func WithQuarantine(filepath string, config heartbeat.ValidateConfig) heartbeat.HandleOption {
+	return func(next heartbeat.Handle) heartbeat.Handle {
+		return func(ctx context.Context, hh []heartbeat.Heartbeat) ([]heartbeat.Result, error) {
+			logger := log.Extract(ctx)
+			logger.Debugln("execute heartbeat validation")
+
+			now := time.Now()
+
+			valid, invalid := heartbeat.SplitInvalid(hh, config, now)
+			if len(invalid) == 0 {
+				return next(ctx, valid)
+			}
+
+			for _, i := range invalid {
+				logger.Warnf("quarantining invalid heartbeat for entity %q: %s", i.Heartbeat.Entity, i.Reason)
+
+				heartbeat.RecordOutcome(ctx, i.Heartbeat, heartbeat.Outcome{
+					Status: heartbeat.OutcomeFiltered,
+					Reason: "invalid heartbeat moved to quarantine: " + i.Reason,
+				})
+			}
+
+			if err := quarantineHeartbeats(ctx, filepath, invalid, now); err != nil {
+				logger.Errorf("failed to quarantine invalid heartbeats: %s", err)
+			}
+
+			return next(ctx, valid)
+		}
+	}
+}
+
+func quarantineHeartbeats(ctx context.Context, filepath string, invalid []heartbeat.Invalid, now time.Time) error {
+	db, close, err := openDB(ctx, filepath)
+	if err != nil {
+		return err
+	}
+
+	defer close()
+
+	tx, err := db.Begin(true)
+	if err != nil {
+		return fmt.Errorf("failed to start db transaction: %s", err)
+	}
+
+	if err := NewQuarantine(tx).PushMany(invalid, now); err != nil {
+		_ = tx.Rollback()
+
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit db transaction: %s", err)
+	}
+
+	return nil
+}
+
+// ReadQuarantined reads up to limit quarantined heartbeats from the offline db.
+func ReadQuarantined(ctx context.Context, filepath string, limit int) ([]Quarantined, error) {
+	db, close, err := openDB(ctx, filepath)
+	if err != nil {
+		return nil, err
+	}
+
+	defer close()
+
+	tx, err := db.Begin(true)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start db transaction: %s", err)
+	}
+
+	logger := log.Extract(ctx)
+
+	defer func() {
+		if err := tx.Rollback(); err != nil {
+			logger.Warnf("failed to rollback transaction: %s", err)
+		}
+	}()
+
+	return NewQuarantine(tx).ReadMany(limit)
+}
+
+// Quarantine is a db client to store heartbeats failing validation in bolt db.
+// Transaction handling is left to the user via the passed in transaction.
+type Quarantine struct {
+	Bucket string
+	tx     *bolt.Tx
+}
+
+// NewQuarantine creates a new instance of Quarantine.
+func NewQuarantine(tx *bolt.Tx) *Quarantine {
+	return &Quarantine{
+		Bucket: quarantineBucket,
+		tx:     tx,
+	}
+}
+
+// PushMany stores the invalid heartbeats in the db, deleting the oldest heartbeats
+// when exceeding the maximum number of quarantined heartbeats.
+func (q *Quarantine) PushMany(invalid []heartbeat.Invalid, now time.Time) error {
+	b, err := q.tx.CreateBucketIfNotExists([]byte(q.Bucket))
+	if err != nil {
+		return fmt.Errorf("failed to create/load bucket: %s", err)
+	}
+
+	for _, i := range invalid {
+		h := i.Heartbeat
+
+		// invalid enums can't be json encoded, the reason keeps the original value
+		if h.Category.String() == "" {
+			h.Category = heartbeat.CodingCategory
+		}
+
+		if h.EntityType.String() == "" {
+			h.EntityType = heartbeat.FileType
+		}
+
+		data, err := json.Marshal(Quarantined{
+			Heartbeat:     h,
+			Reason:        i.Reason,
+			QuarantinedAt: now,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to json marshal quarantined heartbeat: %s", err)
+		}
+
+		// prefix with the quarantine time, to keep keys in insertion order
+		key := fmt.Sprintf("%020d-%s", now.UnixNano(), h.ID())
+
+		if err := b.Put([]byte(key), data); err != nil {
+			return fmt.Errorf("failed to store quarantined heartbeat with id %q: %s", h.ID(), err)
+		}
+	}
+
+	var count int
+
+	c := b.Cursor()
+
+	for key, _ := c.First(); key != nil; key, _ = c.Next() {
+		count++
+	}
+
+	for key, _ := c.First(); key != nil && count > maxQuarantined; key, _ = c.First() {
+		if err := b.Delete(key); err != nil {
+			return fmt.Errorf("failed to delete key %q: %s", key, err)
+		}
+
+		count--
+	}
+
+	return nil
+}
+
+// ReadMany reads quarantined heartbeats from db without deleting them, oldest first.
+func (q *Quarantine) ReadMany(limit int) ([]Quarantined, error) {
+	b, err := q.tx.CreateBucketIfNotExists([]byte(q.Bucket))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create/load bucket: %s", err)
+	}
+
+	var quarantined = make([]Quarantined, 0)
+
+	c := b.Cursor()
+
+	for key, value := c.First(); key != nil; key, value = c.Next() {
+		if len(quarantined) >= limit {
+			break
+		}
+
+		var q Quarantined
+
+		if err := json.Unmarshal(value, &q); err != nil {
+			return nil, fmt.Errorf("failed to json unmarshal quarantined heartbeat: %s", err)
+		}
+
+		quarantined = append(quarantined, q)
+	}
+
+	return quarantined, nil
+}
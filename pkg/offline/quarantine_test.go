@@ -0,0 +1,157 @@
+package offline_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/wakatime/wakatime-cli/pkg/heartbeat"
+	"github.com/wakatime/wakatime-cli/pkg/offline"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithQuarantine(t *testing.T) {
+	// setup
+	f, err := os.CreateTemp(t.TempDir(), "")
+	require.NoError(t, err)
+
+	defer f.Close()
+
+	invalid := testHeartbeats()[1]
+	invalid.LineNumber = heartbeat.PointerTo(-1)
+
+	opt := offline.WithQuarantine(f.Name(), heartbeat.ValidateConfig{})
+
+	handle := opt(func(_ context.Context, hh []heartbeat.Heartbeat) ([]heartbeat.Result, error) {
+		assert.Equal(t, []heartbeat.Heartbeat{testHeartbeats()[0]}, hh)
+
+		return []heartbeat.Result{
+			{
+				Status:    http.StatusCreated,
+				Heartbeat: testHeartbeats()[0],
+			},
+		}, nil
+	})
+
+	// run
+	results, err := handle(context.Background(), []heartbeat.Heartbeat{
+		testHeartbeats()[0],
+		invalid,
+	})
+	require.NoError(t, err)
+
+	// check
+	assert.Equal(t, []heartbeat.Result{
+		{
+			Status:    http.StatusCreated,
+			Heartbeat: testHeartbeats()[0],
+		},
+	}, results)
+
+	quarantined, err := offline.ReadQuarantined(context.Background(), f.Name(), 10)
+	require.NoError(t, err)
+
+	require.Len(t, quarantined, 1)
+
+	assert.Equal(t, invalid, quarantined[0].Heartbeat)
+	assert.Equal(t, "lineno -1 is negative", quarantined[0].Reason)
+	assert.WithinDuration(t, time.Now(), quarantined[0].QuarantinedAt, time.Minute)
+
+	count, err := offline.CountHeartbeats(context.Background(), f.Name())
+	require.NoError(t, err)
+
+	assert.Zero(t, count)
+}
+
+func TestWithQuarantine_InvalidCategory(t *testing.T) {
+	// setup
+	f, err := os.CreateTemp(t.TempDir(), "")
+	require.NoError(t, err)
+
+	defer f.Close()
+
+	invalid := testHeartbeats()[0]
+	invalid.Category = heartbeat.Category(999)
+
+	opt := offline.WithQuarantine(f.Name(), heartbeat.ValidateConfig{})
+
+	handle := opt(func(_ context.Context, hh []heartbeat.Heartbeat) ([]heartbeat.Result, error) {
+		assert.Empty(t, hh)
+
+		return nil, nil
+	})
+
+	// run
+	_, err = handle(context.Background(), []heartbeat.Heartbeat{invalid})
+	require.NoError(t, err)
+
+	// check
+	quarantined, err := offline.ReadQuarantined(context.Background(), f.Name(), 10)
+	require.NoError(t, err)
+
+	require.Len(t, quarantined, 1)
+
+	assert.Equal(t, "invalid category 999", quarantined[0].Reason)
+	assert.Equal(t, "/tmp/main.go", quarantined[0].Heartbeat.Entity)
+}
+
+func TestQuarantine_PushMany_Trim(t *testing.T) {
+	// setup
+	db, cleanup := initDB(t)
+	defer cleanup()
+
+	var invalid []heartbeat.Invalid
+
+	for i := 0; i < 1001; i++ {
+		h := testHeartbeats()[0]
+		h.Entity = fmt.Sprintf("/tmp/%d.go", i)
+
+		invalid = append(invalid, heartbeat.Invalid{Heartbeat: h, Reason: "invalid"})
+	}
+
+	first := time.Unix(1592868400, 0)
+
+	tx, err := db.Begin(true)
+	require.NoError(t, err)
+
+	// run
+	q := offline.NewQuarantine(tx)
+
+	err = q.PushMany(invalid[:1000], first)
+	require.NoError(t, err)
+
+	err = q.PushMany(invalid[1000:], first.Add(time.Second))
+	require.NoError(t, err)
+
+	// check
+	quarantined, err := q.ReadMany(2000)
+	require.NoError(t, err)
+
+	err = tx.Commit()
+	require.NoError(t, err)
+
+	require.Len(t, quarantined, 1000)
+
+	assert.Equal(t, "/tmp/1000.go", quarantined[len(quarantined)-1].Heartbeat.Entity)
+
+	for _, q := range quarantined {
+		assert.NotEqual(t, "/tmp/0.go", q.Heartbeat.Entity)
+	}
+}
+
+func TestReadQuarantined_Empty(t *testing.T) {
+	f, err := os.CreateTemp(t.TempDir(), "")
+	require.NoError(t, err)
+
+	defer f.Close()
+
+	quarantined, err := offline.ReadQuarantined(context.Background(), f.Name(), 10)
+	require.NoError(t, err)
+
+	assert.Empty(t, quarantined)
+}
@@ -31,6 +31,11 @@ func WithFiltering(config FilterConfig) heartbeat.HandleOption {
 				if err != nil {
 					logger.Debugln(err.Error())
 
+					heartbeat.RecordOutcome(ctx, h, heartbeat.Outcome{
+						Status: heartbeat.OutcomeFiltered,
+						Reason: err.Error(),
+					})
+
 					if h.LocalFileNeedsCleanup {
 						err = os.Remove(h.LocalFile)
 						if err != nil {
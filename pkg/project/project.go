@@ -105,6 +105,11 @@ type (
 
 	// Config contains project detection configurations.
 	Config struct {
+		// DryRun skips saving random obfuscated project names to .wakatime-project files.
+		DryRun bool
+		// Hasher, when set, replaces obfuscated project names with a digest,
+		// instead of a random project name saved to a .wakatime-project file.
+		Hasher heartbeat.Hasher
 		// HideProjectNames determines if the project name should be obfuscated by matching its path.
 		HideProjectNames []regex.Regex
 		// Patterns contains the overridden project name per path.
@@ -203,7 +208,7 @@ func WithDetection(config Config) heartbeat.HandleOption {
 				// finally, obfuscate project name if necessary
 				if heartbeat.ShouldSanitize(ctx, result.Folder, config.HideProjectNames) &&
 					result.Project != "" && detector != FileDetector {
-					result.Project = obfuscateProjectName(ctx, result.Folder)
+					result.Project = obfuscateProjectName(ctx, result.Project, result.Folder, config.Hasher, config.DryRun)
 				}
 
 				result.Folder = FormatProjectFolder(ctx, result.Folder)
@@ -317,14 +322,30 @@ func DetectWithRevControl(
 	return Result{}
 }
 
-func obfuscateProjectName(ctx context.Context, folder string) string {
+func obfuscateProjectName(ctx context.Context, project, folder string, hasher heartbeat.Hasher, dryRun bool) string {
+	logger := log.Extract(ctx)
+
+	if hasher != nil {
+		hashed, err := hasher.Hash(ctx, project)
+		if err == nil {
+			return hashed
+		}
+
+		logger.Warnf("failed to hash project name, using a random name instead: %s", err)
+	}
+
 	// prevent overwriting existing project files, use Unknown Project instead
 	if fileOrDirExists(filepath.Join(folder, WakaTimeProjectFile)) {
 		return ""
 	}
 
-	logger := log.Extract(ctx)
-	project := generateProjectName()
+	project = generateProjectName()
+
+	if dryRun {
+		logger.Debugf("dry run, not saving obfuscated project name to %s", filepath.Join(folder, WakaTimeProjectFile))
+
+		return project
+	}
 
 	err := Write(folder, project)
 	if err != nil {
@@ -490,6 +490,39 @@ func TestWithDetection_ObfuscateProject(t *testing.T) {
 	assert.FileExists(t, filepath.Join(fp, "wakatime-cli/.wakatime-project"))
 }
 
+func TestWithDetection_ObfuscateProject_Hash(t *testing.T) {
+	fp := setupTestGitBasic(t)
+
+	ctx := context.Background()
+
+	entity := filepath.Join(fp, "wakatime-cli/src/pkg/file.go")
+
+	if runtime.GOOS == "windows" {
+		entity = windows.FormatFilePath(entity)
+	}
+
+	opt := project.WithDetection(project.Config{
+		Hasher:           fakeHasher{},
+		HideProjectNames: []regex.Regex{regex.MustCompile(".*")},
+	})
+
+	handle := opt(func(_ context.Context, hh []heartbeat.Heartbeat) ([]heartbeat.Result, error) {
+		assert.Equal(t, heartbeat.PointerTo("hash(wakatime-cli)"), hh[0].Project)
+
+		return nil, nil
+	})
+
+	_, err := handle(ctx, []heartbeat.Heartbeat{
+		{
+			EntityType: heartbeat.FileType,
+			Entity:     entity,
+		},
+	})
+	require.NoError(t, err)
+
+	assert.NoFileExists(t, filepath.Join(fp, "wakatime-cli/.wakatime-project"))
+}
+
 func TestDetect_FileDetected(t *testing.T) {
 	tmpDir, err := realpath.Realpath(t.TempDir())
 	require.NoError(t, err)
@@ -730,3 +763,9 @@ func (m *mockSender) SendHeartbeats(ctx context.Context, hh []heartbeat.Heartbea
 	m.SendHeartbeatsFnInvoked = true
 	return m.SendHeartbeatsFn(ctx, hh)
 }
+
+type fakeHasher struct{}
+
+func (fakeHasher) Hash(_ context.Context, name string) (string, error) {
+	return "hash(" + name + ")", nil
+}
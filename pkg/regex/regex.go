@@ -14,6 +14,7 @@ import (
 type Regex interface {
 	FindStringSubmatch(ctx context.Context, s string) []string
 	MatchString(ctx context.Context, s string) bool
+	ReplaceAllString(ctx context.Context, src, repl string) string
 	String() string
 }
 
@@ -77,6 +78,13 @@ func (re *RegexpWrap) MatchString(_ context.Context, s string) bool {
 	return re.rgx.MatchString(s)
 }
 
+// ReplaceAllString returns a copy of src, replacing matches of the regular
+// expression with the replacement string repl. Inside repl, $ signs are
+// interpreted as in regexp.Regexp.Expand, so $1 or ${name} reference submatches.
+func (re *RegexpWrap) ReplaceAllString(_ context.Context, src, repl string) string {
+	return re.rgx.ReplaceAllString(src, repl)
+}
+
 // String returns the source text used to compile the regular expression.
 func (re *RegexpWrap) String() string {
 	return re.rgx.String()
@@ -130,6 +138,21 @@ func (re *regexp2Wrap) MatchString(ctx context.Context, s string) bool {
 	return matched
 }
 
+// ReplaceAllString returns a copy of src, replacing matches of the regular
+// expression with the replacement string repl. Inside repl, $1 or ${name}
+// reference submatches. Returns src unchanged on failure.
+func (re *regexp2Wrap) ReplaceAllString(ctx context.Context, src, repl string) string {
+	logger := log.Extract(ctx)
+
+	replaced, err := re.rgx.Replace(src, repl, -1, -1)
+	if err != nil {
+		logger.Warnf("failed to replace string %q: %s", src, err)
+		return src
+	}
+
+	return replaced
+}
+
 // String returns the source text used to compile the regular expression.
 func (re *regexp2Wrap) String() string {
 	return re.rgx.String()
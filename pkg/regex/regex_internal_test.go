@@ -65,3 +65,42 @@ func TestRegexp2Wrap_FindStringSubmatch(t *testing.T) {
 		})
 	}
 }
+
+func TestRegexp2Wrap_ReplaceAllString(t *testing.T) {
+	ctx := context.Background()
+
+	tests := map[string]struct {
+		String      string
+		Replacement string
+		Expected    string
+	}{
+		"numbered group": {
+			String:      "/home/john/projects/app",
+			Replacement: "~/$1",
+			Expected:    "~/projects/app",
+		},
+		"named group": {
+			String:      "/home/john/projects/app",
+			Replacement: "~/${rest}",
+			Expected:    "~/projects/app",
+		},
+		"no match": {
+			String:      "/var/www/app",
+			Replacement: "~/$1",
+			Expected:    "/var/www/app",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			r2, err := regexp2.Compile(`^/home/(?!root/)[^/]+/(?<rest>.*)`, 0)
+			require.NoError(t, err)
+
+			r := &regexp2Wrap{
+				rgx: r2,
+			}
+
+			assert.Equal(t, test.Expected, r.ReplaceAllString(ctx, test.String, test.Replacement))
+		})
+	}
+}
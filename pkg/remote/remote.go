@@ -33,6 +33,12 @@ const (
 	defaultPort = 22
 )
 
+// Config contains remote file detection configurations.
+type Config struct {
+	// DryRun skips downloading remote files, keeping heartbeats unchanged.
+	DryRun bool
+}
+
 // Client communicates using sftp protocol.
 type Client struct {
 	User         string
@@ -47,7 +53,7 @@ type Client struct {
 // WithDetection initializes and returns a heartbeat handle option, which
 // can be used in a heartbeat processing pipeline to detect remote file and
 // download to a temporary directory.
-func WithDetection() heartbeat.HandleOption {
+func WithDetection(config Config) heartbeat.HandleOption {
 	return func(next heartbeat.Handle) heartbeat.Handle {
 		return func(ctx context.Context, hh []heartbeat.Heartbeat) ([]heartbeat.Result, error) {
 			logger := log.Extract(ctx)
@@ -61,9 +67,20 @@ func WithDetection() heartbeat.HandleOption {
 					continue
 				}
 
+				if config.DryRun {
+					logger.Debugf("dry run, skipping download of remote file: %s", h.Entity)
+
+					filtered = append(filtered, h)
+
+					continue
+				}
+
 				tmpFile, err := os.CreateTemp("", fmt.Sprintf("*_%s", filepath.Base(h.Entity)))
 				if err != nil {
 					logger.Errorf("failed to create temporary file: %s", err)
+
+					recordFiltered(ctx, h, fmt.Sprintf("failed to create temporary file: %s", err))
+
 					continue
 				}
 
@@ -71,6 +88,8 @@ func WithDetection() heartbeat.HandleOption {
 				if err != nil {
 					logger.Errorf("failed to create new remote client: %s", err)
 
+					recordFiltered(ctx, h, fmt.Sprintf("failed to create new remote client: %s", err))
+
 					deleteLocalFile(ctx, tmpFile.Name())
 
 					continue
@@ -85,6 +104,8 @@ func WithDetection() heartbeat.HandleOption {
 						logger.Errorf("failed to download remote file using fallback option: %s", err)
 					}
 
+					recordFiltered(ctx, h, "failed to download remote file")
+
 					deleteLocalFile(ctx, tmpFile.Name())
 
 					continue
@@ -101,6 +122,15 @@ func WithDetection() heartbeat.HandleOption {
 	}
 }
 
+// recordFiltered records a remote file heartbeat skipped because the file
+// couldn't be downloaded.
+func recordFiltered(ctx context.Context, h heartbeat.Heartbeat, reason string) {
+	heartbeat.RecordOutcome(ctx, h, heartbeat.Outcome{
+		Status: heartbeat.OutcomeFiltered,
+		Reason: reason,
+	})
+}
+
 // WithCleanup initializes and returns a heartbeat handle option, which
 // deletes a local temporary file if downloaded from a remote file.
 func WithCleanup() heartbeat.HandleOption {
@@ -128,7 +128,7 @@ func TestWithDetection_SshConfig_Hostname(t *testing.T) {
 	}
 
 	opts := []heartbeat.HandleOption{
-		remote.WithDetection(),
+		remote.WithDetection(remote.Config{}),
 	}
 
 	handle := heartbeat.NewHandle(&sender, opts...)
@@ -213,7 +213,7 @@ func TestWithDetection_SshConfig_UserKnownHostsFile_Mismatch(t *testing.T) {
 		filter.WithFiltering(filter.Config{
 			IncludeOnlyWithProjectFile: true,
 		}),
-		remote.WithDetection(),
+		remote.WithDetection(remote.Config{}),
 	}
 
 	handle := heartbeat.NewHandle(&sender, opts...)
@@ -305,7 +305,7 @@ func TestWithDetection_SshConfig_UserKnownHostsFile_Match(t *testing.T) {
 			Include:                    nil,
 			IncludeOnlyWithProjectFile: true,
 		}),
-		remote.WithDetection(),
+		remote.WithDetection(remote.Config{}),
 	}
 
 	handle := heartbeat.NewHandle(&sender, opts...)
@@ -364,7 +364,7 @@ func TestWithDetection_Filtered(t *testing.T) {
 			Include:                    nil,
 			IncludeOnlyWithProjectFile: true,
 		}),
-		remote.WithDetection(),
+		remote.WithDetection(remote.Config{}),
 	}
 
 	handle := heartbeat.NewHandle(&sender, opts...)
@@ -0,0 +1,255 @@
+package schedule
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/wakatime/wakatime-cli/pkg/heartbeat"
+	"github.com/wakatime/wakatime-cli/pkg/log"
+	"github.com/wakatime/wakatime-cli/pkg/regex"
+)
+
+// Action is what happens to heartbeats matching a rule.
+type Action int
+
+const (
+	// DropAction skips matching heartbeats.
+	DropAction Action = iota
+	// CategoryAction changes the category of matching heartbeats.
+	CategoryAction
+	// APIKeyAction sends matching heartbeats with a different api key.
+	APIKeyAction
+)
+
+// Config contains schedule filtering configurations.
+type Config struct {
+	// Rules are checked in order and the first matching rule is applied.
+	Rules []Rule
+}
+
+// Rule applies an action to heartbeats matching all of its conditions.
+type Rule struct {
+	Action   Action
+	APIKey   string
+	Category heartbeat.Category
+	// During matches heartbeats inside any of the windows.
+	During []Window
+	// Outside matches heartbeats outside all of the windows.
+	Outside []Window
+	Path    regex.Regex
+	Project regex.Regex
+	raw     string
+}
+
+// ParseRule parses a rule from an action followed by conditions, separated
+// by whitespace. Windows are referenced by name. For ex:
+//
+//	drop project=^personal$ during=work
+//	category=learning path=/oss/ during=work,weekend
+//	api_key=waka_... path=^/home/me/clients/acme/ outside=work
+//
+// Conditions are path=<regex>, project=<regex>, during=<windows> and
+// outside=<windows>, and at least one is required. Regexes are case insensitive.
+func ParseRule(s string, windows map[string]Window) (Rule, error) {
+	fields := strings.Fields(s)
+	if len(fields) < 2 {
+		return Rule{}, fmt.Errorf("invalid schedule rule %q, expected an action followed by conditions", s)
+	}
+
+	rule := Rule{raw: strings.Join(fields, " ")}
+
+	action, arg, _ := strings.Cut(fields[0], "=")
+
+	switch strings.ToLower(action) {
+	case "drop":
+		rule.Action = DropAction
+	case "category":
+		category, err := heartbeat.ParseCategory(strings.ReplaceAll(arg, "_", " "))
+		if err != nil {
+			return Rule{}, fmt.Errorf("invalid schedule rule %q: %s", s, err)
+		}
+
+		rule.Action = CategoryAction
+		rule.Category = category
+	case "api_key":
+		if arg == "" {
+			return Rule{}, fmt.Errorf("invalid schedule rule %q: missing api key", s)
+		}
+
+		rule.Action = APIKeyAction
+		rule.APIKey = arg
+
+		// keep api keys out of logs
+		if len(arg) > 4 {
+			fields[0] = fmt.Sprintf("api_key=<hidden>%s", arg[len(arg)-4:])
+		}
+
+		rule.raw = strings.Join(fields, " ")
+	default:
+		return Rule{}, fmt.Errorf(
+			"invalid schedule rule %q: unknown action %q, expected one of \"drop\", \"category=\" or \"api_key=\"",
+			s,
+			fields[0],
+		)
+	}
+
+	for _, field := range fields[1:] {
+		key, value, ok := strings.Cut(field, "=")
+		if !ok || value == "" {
+			return Rule{}, fmt.Errorf("invalid schedule rule %q: invalid condition %q", rule.raw, field)
+		}
+
+		var err error
+
+		switch strings.ToLower(key) {
+		case "path":
+			rule.Path, err = compileCaseInsensitive(value)
+		case "project":
+			rule.Project, err = compileCaseInsensitive(value)
+		case "during":
+			rule.During, err = lookupWindows(value, windows)
+		case "outside":
+			rule.Outside, err = lookupWindows(value, windows)
+		default:
+			err = fmt.Errorf("unknown condition %q", key)
+		}
+
+		if err != nil {
+			return Rule{}, fmt.Errorf("invalid schedule rule %q: %s", rule.raw, err)
+		}
+	}
+
+	return rule, nil
+}
+
+// Match reports whether the heartbeat matches all conditions of the rule.
+func (r Rule) Match(ctx context.Context, h heartbeat.Heartbeat) bool {
+	if r.Path != nil && !r.Path.MatchString(ctx, h.Entity) {
+		return false
+	}
+
+	if r.Project != nil && (h.Project == nil || !r.Project.MatchString(ctx, *h.Project)) {
+		return false
+	}
+
+	t := time.Unix(0, int64(h.Time*float64(time.Second)))
+
+	if len(r.During) > 0 && !anyContains(r.During, t) {
+		return false
+	}
+
+	if len(r.Outside) > 0 && anyContains(r.Outside, t) {
+		return false
+	}
+
+	return true
+}
+
+// String returns the rule as written, with a hidden api key.
+func (r Rule) String() string {
+	return r.raw
+}
+
+// WithFiltering initializes and returns a heartbeat handle option, which
+// can be used in a heartbeat processing pipeline to drop, re-categorize or
+// reroute heartbeats to another api key, depending on the time they were
+// sent and their project or path.
+func WithFiltering(config Config) heartbeat.HandleOption {
+	return func(next heartbeat.Handle) heartbeat.Handle {
+		return func(ctx context.Context, hh []heartbeat.Heartbeat) ([]heartbeat.Result, error) {
+			logger := log.Extract(ctx)
+			logger.Debugln("execute schedule filtering")
+
+			var filtered []heartbeat.Heartbeat
+
+			for _, h := range hh {
+				applied, ok := Apply(ctx, h, config)
+				if !ok {
+					if h.LocalFileNeedsCleanup {
+						if err := os.Remove(h.LocalFile); err != nil {
+							logger.Warnf("unable to delete tmp file: %s", err)
+						}
+					}
+
+					continue
+				}
+
+				filtered = append(filtered, applied)
+			}
+
+			return next(ctx, filtered)
+		}
+	}
+}
+
+// Apply applies the first rule matching the heartbeat. Returns false when
+// the heartbeat should be skipped.
+func Apply(ctx context.Context, h heartbeat.Heartbeat, config Config) (heartbeat.Heartbeat, bool) {
+	logger := log.Extract(ctx)
+
+	for _, rule := range config.Rules {
+		if !rule.Match(ctx, h) {
+			continue
+		}
+
+		switch rule.Action {
+		case DropAction:
+			logger.Debugf("skipping because matches schedule rule %q", rule.String())
+
+			heartbeat.RecordOutcome(ctx, h, heartbeat.Outcome{
+				Status: heartbeat.OutcomeFiltered,
+				Reason: fmt.Sprintf("skipping because matches schedule rule %q", rule.String()),
+			})
+
+			return h, false
+		case CategoryAction:
+			logger.Debugf("changing category to %q because matches schedule rule %q", rule.Category, rule.String())
+
+			h.Category = rule.Category
+		case APIKeyAction:
+			logger.Debugf("changing api key because matches schedule rule %q", rule.String())
+
+			h.APIKey = rule.APIKey
+		}
+
+		return h, true
+	}
+
+	return h, true
+}
+
+func anyContains(windows []Window, t time.Time) bool {
+	for _, w := range windows {
+		if w.Contains(t) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func compileCaseInsensitive(s string) (regex.Regex, error) {
+	if !strings.HasPrefix(s, "(?i)") {
+		s = "(?i)" + s
+	}
+
+	return regex.Compile(s)
+}
+
+func lookupWindows(s string, windows map[string]Window) ([]Window, error) {
+	var found []Window
+
+	for _, name := range strings.Split(s, ",") {
+		w, ok := windows[strings.ToLower(name)]
+		if !ok {
+			return nil, fmt.Errorf("unknown schedule window %q", name)
+		}
+
+		found = append(found, w)
+	}
+
+	return found, nil
+}
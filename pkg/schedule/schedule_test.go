@@ -0,0 +1,138 @@
+package schedule_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/wakatime/wakatime-cli/pkg/heartbeat"
+	"github.com/wakatime/wakatime-cli/pkg/schedule"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithFiltering(t *testing.T) {
+	windows := testWindows(t)
+
+	var rules []schedule.Rule
+
+	for _, s := range []string{
+		"drop project=^personal$ during=work",
+		"category=writing_docs path=/docs/ during=work",
+		"api_key=00000000-0000-4000-8000-000000000001 project=^client-x$ outside=work",
+		"drop project=^client-x$",
+	} {
+		rule, err := schedule.ParseRule(s, windows)
+		require.NoError(t, err)
+
+		rules = append(rules, rule)
+	}
+
+	// monday 10:00 utc and monday 20:00 utc
+	workHours := float64(time.Date(2024, 6, 3, 10, 0, 0, 0, time.UTC).Unix())
+	evening := float64(time.Date(2024, 6, 3, 20, 0, 0, 0, time.UTC).Unix())
+
+	personalAtWork := testHeartbeat("/home/me/personal/main.go", "personal", workHours)
+	personalInEvening := testHeartbeat("/home/me/personal/main.go", "personal", evening)
+	docsAtWork := testHeartbeat("/home/me/work/docs/index.md", "work", workHours)
+	clientInEvening := testHeartbeat("/home/me/client-x/main.go", "client-x", evening)
+	clientAtWork := testHeartbeat("/home/me/client-x/main.go", "client-x", workHours)
+
+	opt := schedule.WithFiltering(schedule.Config{Rules: rules})
+
+	handle := opt(func(_ context.Context, hh []heartbeat.Heartbeat) ([]heartbeat.Result, error) {
+		expectedDocs := docsAtWork
+		expectedDocs.Category = heartbeat.WritingDocsCategory
+
+		expectedClient := clientInEvening
+		expectedClient.APIKey = "00000000-0000-4000-8000-000000000001"
+
+		assert.Equal(t, []heartbeat.Heartbeat{personalInEvening, expectedDocs, expectedClient}, hh)
+
+		return []heartbeat.Result{
+			{
+				Status: http.StatusCreated,
+			},
+		}, nil
+	})
+
+	_, err := handle(context.Background(), []heartbeat.Heartbeat{
+		personalAtWork,
+		personalInEvening,
+		docsAtWork,
+		clientInEvening,
+		clientAtWork,
+	})
+	require.NoError(t, err)
+}
+
+func TestApply_NoRules(t *testing.T) {
+	h := testHeartbeat("/home/me/personal/main.go", "personal", 1717408800)
+
+	applied, ok := schedule.Apply(context.Background(), h, schedule.Config{})
+	require.True(t, ok)
+
+	assert.Equal(t, h, applied)
+}
+
+func TestParseRule_Err(t *testing.T) {
+	tests := map[string]struct {
+		Rule     string
+		Expected string
+	}{
+		"without conditions": {
+			Rule:     "drop",
+			Expected: `invalid schedule rule "drop", expected an action followed by conditions`,
+		},
+		"unknown action": {
+			Rule: "skip during=work",
+			Expected: `invalid schedule rule "skip during=work": unknown action "skip",` +
+				` expected one of "drop", "category=" or "api_key="`,
+		},
+		"invalid category": {
+			Rule:     "category=sleeping during=work",
+			Expected: `invalid schedule rule "category=sleeping during=work": invalid category "sleeping"`,
+		},
+		"unknown condition": {
+			Rule:     "drop branch=main",
+			Expected: `invalid schedule rule "drop branch=main": unknown condition "branch"`,
+		},
+		"unknown window": {
+			Rule:     "drop during=work,holidays",
+			Expected: `invalid schedule rule "drop during=work,holidays": unknown schedule window "holidays"`,
+		},
+		"hidden api key": {
+			Rule: "api_key=00000000-0000-4000-8000-000000000001 during",
+			Expected: `invalid schedule rule "api_key=<hidden>0001 during":` +
+				` invalid condition "during"`,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := schedule.ParseRule(test.Rule, testWindows(t))
+
+			assert.EqualError(t, err, test.Expected)
+		})
+	}
+}
+
+func testWindows(t *testing.T) map[string]schedule.Window {
+	work, err := schedule.ParseWindow("work", "Mon-Fri 09:00-17:00", time.UTC)
+	require.NoError(t, err)
+
+	return map[string]schedule.Window{"work": work}
+}
+
+func testHeartbeat(entity, project string, at float64) heartbeat.Heartbeat {
+	return heartbeat.Heartbeat{
+		APIKey:     "00000000-0000-4000-8000-000000000000",
+		Category:   heartbeat.CodingCategory,
+		Entity:     entity,
+		EntityType: heartbeat.FileType,
+		Project:    heartbeat.PointerTo(project),
+		Time:       at,
+	}
+}
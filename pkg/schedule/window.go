@@ -0,0 +1,238 @@
+package schedule
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const day = 24 * time.Hour
+
+// Window is a named, weekly recurring time window, like working hours.
+type Window struct {
+	Name     string
+	Location *time.Location
+	Ranges   []Range
+}
+
+// Range is a time of day range on a set of weekdays. When End is before
+// Start, the range spans midnight and belongs to the weekday it starts on.
+type Range struct {
+	Weekdays [7]bool
+	Start    time.Duration
+	End      time.Duration
+}
+
+// ParseWindow parses a window from a list of ranges separated by ";". Each
+// range has a list of weekdays, a time of day range or both, for ex:
+// "Mon-Fri 09:00-17:00; Sat 10:00-12:00", "Sat,Sun" or "22:00-06:00".
+// Times are interpreted in the passed in location.
+func ParseWindow(name, value string, loc *time.Location) (Window, error) {
+	w := Window{
+		Name:     name,
+		Location: loc,
+	}
+
+	for _, s := range strings.Split(value, ";") {
+		s = strings.TrimSpace(s)
+		if s == "" {
+			continue
+		}
+
+		r, err := parseRange(s)
+		if err != nil {
+			return Window{}, fmt.Errorf("invalid schedule window %q: %s", name, err)
+		}
+
+		w.Ranges = append(w.Ranges, r)
+	}
+
+	if len(w.Ranges) == 0 {
+		return Window{}, fmt.Errorf("invalid schedule window %q: no weekdays or times", name)
+	}
+
+	return w, nil
+}
+
+// Contains reports whether the time is inside the window.
+func (w Window) Contains(t time.Time) bool {
+	if w.Location != nil {
+		t = t.In(w.Location)
+	}
+
+	for _, r := range w.Ranges {
+		if r.contains(t) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (r Range) contains(t time.Time) bool {
+	offset := time.Duration(t.Hour())*time.Hour +
+		time.Duration(t.Minute())*time.Minute +
+		time.Duration(t.Second())*time.Second
+	weekday := t.Weekday()
+
+	if r.Start < r.End {
+		return r.Weekdays[weekday] && offset >= r.Start && offset < r.End
+	}
+
+	// range spanning midnight, like 22:00-06:00
+	if offset >= r.Start {
+		return r.Weekdays[weekday]
+	}
+
+	if offset < r.End {
+		return r.Weekdays[(weekday+6)%7]
+	}
+
+	return false
+}
+
+// parseRange parses a range like "Mon-Fri 09:00-17:00". Weekdays default to
+// every day and times default to the whole day.
+func parseRange(s string) (Range, error) {
+	r := Range{
+		Start: 0,
+		End:   day,
+	}
+
+	fields := strings.Fields(s)
+	if len(fields) > 2 {
+		return Range{}, fmt.Errorf("invalid range %q, expected weekdays and time range like \"Mon-Fri 09:00-17:00\"", s)
+	}
+
+	var weekdaysSet, timeSet bool
+
+	for _, field := range fields {
+		if strings.Contains(field, ":") {
+			if timeSet {
+				return Range{}, fmt.Errorf("invalid range %q, multiple time ranges", s)
+			}
+
+			start, end, err := parseTimeRange(field)
+			if err != nil {
+				return Range{}, err
+			}
+
+			r.Start, r.End = start, end
+			timeSet = true
+
+			continue
+		}
+
+		if weekdaysSet {
+			return Range{}, fmt.Errorf("invalid range %q, multiple weekday lists", s)
+		}
+
+		weekdays, err := parseWeekdays(field)
+		if err != nil {
+			return Range{}, err
+		}
+
+		r.Weekdays = weekdays
+		weekdaysSet = true
+	}
+
+	if !weekdaysSet {
+		for i := range r.Weekdays {
+			r.Weekdays[i] = true
+		}
+	}
+
+	return r, nil
+}
+
+// parseWeekdays parses a comma separated list of weekdays and weekday ranges,
+// like "Mon,Wed" or "Mon-Fri". Ranges can wrap around the week, like "Fri-Mon".
+func parseWeekdays(s string) ([7]bool, error) {
+	var weekdays [7]bool
+
+	for _, item := range strings.Split(s, ",") {
+		from, to, isRange := strings.Cut(item, "-")
+
+		start, err := parseWeekday(from)
+		if err != nil {
+			return weekdays, err
+		}
+
+		end := start
+
+		if isRange {
+			end, err = parseWeekday(to)
+			if err != nil {
+				return weekdays, err
+			}
+		}
+
+		for d := start; ; d = (d + 1) % 7 {
+			weekdays[d] = true
+
+			if d == end {
+				break
+			}
+		}
+	}
+
+	return weekdays, nil
+}
+
+func parseWeekday(s string) (time.Weekday, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+
+	for d := time.Sunday; d <= time.Saturday; d++ {
+		name := strings.ToLower(d.String())
+		if s == name || s == name[:3] {
+			return d, nil
+		}
+	}
+
+	return 0, fmt.Errorf("invalid weekday %q", s)
+}
+
+// parseTimeRange parses a time of day range like "09:00-17:30". The end
+// can be "24:00" to include the rest of the day.
+func parseTimeRange(s string) (time.Duration, time.Duration, error) {
+	from, to, ok := strings.Cut(s, "-")
+	if !ok {
+		return 0, 0, fmt.Errorf("invalid time range %q, expected format like \"09:00-17:00\"", s)
+	}
+
+	start, err := parseTimeOfDay(from)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	end, err := parseTimeOfDay(to)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	if start == day || start == end {
+		return 0, 0, fmt.Errorf("invalid time range %q", s)
+	}
+
+	return start, end, nil
+}
+
+func parseTimeOfDay(s string) (time.Duration, error) {
+	h, m, ok := strings.Cut(s, ":")
+	if !ok {
+		return 0, fmt.Errorf("invalid time %q, expected format like \"09:00\"", s)
+	}
+
+	hours, err := strconv.Atoi(h)
+	if err != nil || hours < 0 || hours > 24 {
+		return 0, fmt.Errorf("invalid time %q, expected format like \"09:00\"", s)
+	}
+
+	minutes, err := strconv.Atoi(m)
+	if err != nil || len(m) != 2 || minutes < 0 || minutes > 59 || (hours == 24 && minutes != 0) {
+		return 0, fmt.Errorf("invalid time %q, expected format like \"09:00\"", s)
+	}
+
+	return time.Duration(hours)*time.Hour + time.Duration(minutes)*time.Minute, nil
+}
@@ -0,0 +1,130 @@
+package schedule_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/wakatime/wakatime-cli/pkg/schedule"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWindow_Contains(t *testing.T) {
+	tests := map[string]struct {
+		Value    string
+		Time     time.Time
+		Expected bool
+	}{
+		"inside work hours": {
+			Value:    "Mon-Fri 09:00-17:00",
+			Time:     time.Date(2024, 6, 3, 9, 0, 0, 0, time.UTC), // monday
+			Expected: true,
+		},
+		"end is exclusive": {
+			Value: "Mon-Fri 09:00-17:00",
+			Time:  time.Date(2024, 6, 3, 17, 0, 0, 0, time.UTC),
+		},
+		"weekend": {
+			Value: "Mon-Fri 09:00-17:00",
+			Time:  time.Date(2024, 6, 8, 10, 0, 0, 0, time.UTC), // saturday
+		},
+		"weekdays only": {
+			Value:    "Sat,Sun",
+			Time:     time.Date(2024, 6, 9, 23, 59, 0, 0, time.UTC), // sunday
+			Expected: true,
+		},
+		"times only": {
+			Value:    "12:00-13:00",
+			Time:     time.Date(2024, 6, 8, 12, 30, 0, 0, time.UTC),
+			Expected: true,
+		},
+		"multiple ranges": {
+			Value:    "Mon-Fri 09:00-12:00; Sat 10:00-24:00",
+			Time:     time.Date(2024, 6, 8, 23, 0, 0, 0, time.UTC),
+			Expected: true,
+		},
+		"weekday range wrapping around the week": {
+			Value:    "Fri-Mon",
+			Time:     time.Date(2024, 6, 9, 10, 0, 0, 0, time.UTC), // sunday
+			Expected: true,
+		},
+		"overnight before midnight": {
+			Value:    "Fri 22:00-06:00",
+			Time:     time.Date(2024, 6, 7, 23, 0, 0, 0, time.UTC), // friday
+			Expected: true,
+		},
+		"overnight after midnight belongs to previous day": {
+			Value:    "Fri 22:00-06:00",
+			Time:     time.Date(2024, 6, 8, 5, 0, 0, 0, time.UTC), // saturday
+			Expected: true,
+		},
+		"overnight after midnight of other day": {
+			Value: "Fri 22:00-06:00",
+			Time:  time.Date(2024, 6, 7, 5, 0, 0, 0, time.UTC), // friday
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			w, err := schedule.ParseWindow("work", test.Value, time.UTC)
+			require.NoError(t, err)
+
+			assert.Equal(t, test.Expected, w.Contains(test.Time))
+		})
+	}
+}
+
+func TestWindow_Contains_Location(t *testing.T) {
+	loc, err := time.LoadLocation("America/New_York")
+	require.NoError(t, err)
+
+	w, err := schedule.ParseWindow("work", "Mon-Fri 09:00-17:00", loc)
+	require.NoError(t, err)
+
+	// 14:00 utc is 10:00 in new york during daylight saving time
+	assert.True(t, w.Contains(time.Date(2024, 6, 3, 14, 0, 0, 0, time.UTC)))
+	// 09:00 utc is 05:00 in new york
+	assert.False(t, w.Contains(time.Date(2024, 6, 3, 9, 0, 0, 0, time.UTC)))
+}
+
+func TestParseWindow_Err(t *testing.T) {
+	tests := map[string]struct {
+		Value    string
+		Expected string
+	}{
+		"empty": {
+			Value:    " ; ",
+			Expected: `invalid schedule window "work": no weekdays or times`,
+		},
+		"invalid weekday": {
+			Value:    "Mon-Fry 09:00-17:00",
+			Expected: `invalid schedule window "work": invalid weekday "fry"`,
+		},
+		"invalid time": {
+			Value:    "9-17",
+			Expected: `invalid schedule window "work": invalid weekday "9"`,
+		},
+		"invalid hour": {
+			Value:    "Mon-Fri 09:00-25:00",
+			Expected: `invalid schedule window "work": invalid time "25:00", expected format like "09:00"`,
+		},
+		"empty time range": {
+			Value:    "09:00-09:00",
+			Expected: `invalid schedule window "work": invalid time range "09:00-09:00"`,
+		},
+		"too many fields": {
+			Value: "Mon 09:00-12:00 13:00-17:00",
+			Expected: `invalid schedule window "work": invalid range "Mon 09:00-12:00 13:00-17:00",` +
+				` expected weekdays and time range like "Mon-Fri 09:00-17:00"`,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := schedule.ParseWindow("work", test.Value, time.UTC)
+
+			assert.EqualError(t, err, test.Expected)
+		})
+	}
+}
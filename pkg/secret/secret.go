@@ -0,0 +1,164 @@
+package secret
+
+import (
+	"context"
+	"path/filepath"
+	"regexp"
+
+	"github.com/wakatime/wakatime-cli/pkg/file"
+	"github.com/wakatime/wakatime-cli/pkg/heartbeat"
+	"github.com/wakatime/wakatime-cli/pkg/log"
+	"github.com/wakatime/wakatime-cli/pkg/regex"
+)
+
+// Config defines secret detection options.
+type Config struct {
+	// BranchPatterns will be matched against the branch of hidden files and if
+	// matching, will remove it. When empty, the branch is always removed.
+	BranchPatterns []regex.Regex
+	// Enabled enables detecting credentials files.
+	Enabled bool
+	// Hasher, when set, replaces the names of hidden files with a digest.
+	Hasher heartbeat.Hasher
+	// Heads caches file contents shared with other processing steps. When
+	// nil, a new cache is used.
+	Heads *file.Heads
+}
+
+// Rule detects a kind of credentials file by its path or content.
+type Rule struct {
+	Name string
+	// Path is matched against the slash separated file path.
+	Path *regexp.Regexp
+	// PathExclude is matched against the slash separated file path and if
+	// matching, skips the path match, like for .env.example files.
+	PathExclude *regexp.Regexp
+	// Content is matched against the head of the file.
+	Content *regexp.Regexp
+}
+
+// rules are the built-in rules detecting credentials files.
+var rules = []Rule{
+	{
+		Name:        "dotenv file",
+		Path:        regexp.MustCompile(`(?i)(^|/)\.env(\.[^/]+)?$`),
+		PathExclude: regexp.MustCompile(`(?i)\.(dist|example|sample|template)$`),
+	},
+	{
+		Name: "ssh private key file",
+		Path: regexp.MustCompile(`(^|/)id_(dsa|ecdsa|ecdsa_sk|ed25519|ed25519_sk|rsa)$`),
+	},
+	{
+		Name: "pkcs12 key store",
+		Path: regexp.MustCompile(`(?i)\.(p12|pfx)$`),
+	},
+	{
+		Name:    "private key",
+		Content: regexp.MustCompile(`-----BEGIN ((DSA|EC|ENCRYPTED|OPENSSH|PGP|RSA) )?PRIVATE KEY( BLOCK)?-----`),
+	},
+	{
+		Name: "aws credentials file",
+		Path: regexp.MustCompile(`(^|/)\.aws/credentials$`),
+	},
+	{
+		Name:    "aws secret access key",
+		Content: regexp.MustCompile(`(?i)aws_?secret_?access_?key["']?\s*[:=]\s*["']?[A-Za-z0-9/+]{40}\b`),
+	},
+	{
+		Name: "gcp application default credentials",
+		Path: regexp.MustCompile(`(^|/)application_default_credentials\.json$`),
+	},
+	{
+		Name:    "gcp service account key",
+		Content: regexp.MustCompile(`"private_key_id"\s*:\s*"[0-9a-f]{40}"`),
+	},
+	{
+		Name: "kubeconfig file",
+		Path: regexp.MustCompile(`(^|/)(\.kube/config|kubeconfig|[^/]+\.kubeconfig)$`),
+	},
+	{
+		Name:    "kubeconfig",
+		Content: regexp.MustCompile(`(?m)^\s*(client-key-data|client-certificate-data):\s*\S+`),
+	},
+}
+
+// WithDetection initializes and returns a heartbeat handle option, which
+// can be used in a heartbeat processing pipeline to hide the entity and meta
+// data of credentials files, like heartbeat.Sanitize does for files matching
+// hide file names patterns.
+func WithDetection(config Config) heartbeat.HandleOption {
+	return func(next heartbeat.Handle) heartbeat.Handle {
+		return func(ctx context.Context, hh []heartbeat.Heartbeat) ([]heartbeat.Result, error) {
+			logger := log.Extract(ctx)
+			logger.Debugln("execute secret detection")
+
+			if !config.Enabled {
+				return next(ctx, hh)
+			}
+
+			heads := config.Heads
+			if heads == nil {
+				heads = file.NewHeads()
+			}
+
+			for n, h := range hh {
+				if h.EntityType != heartbeat.FileType {
+					continue
+				}
+
+				rule, ok := Detect(ctx, h, heads)
+				if !ok {
+					continue
+				}
+
+				logger.Debugf("hiding entity because matches secret detection rule %q", rule.Name)
+
+				hh[n] = heartbeat.HideEntity(ctx, h, config.BranchPatterns, config.Hasher)
+			}
+
+			return next(ctx, hh)
+		}
+	}
+}
+
+// Detect returns the first rule detecting the heartbeat's file as a
+// credentials file. The file content is only read for local files.
+func Detect(ctx context.Context, h heartbeat.Heartbeat, heads *file.Heads) (Rule, bool) {
+	fp := filepath.ToSlash(h.Entity)
+
+	for _, rule := range rules {
+		if rule.Path == nil || !rule.Path.MatchString(fp) {
+			continue
+		}
+
+		if rule.PathExclude != nil && rule.PathExclude.MatchString(fp) {
+			continue
+		}
+
+		return rule, true
+	}
+
+	if h.IsUnsavedEntity || (h.IsRemote() && h.LocalFile == "") {
+		return Rule{}, false
+	}
+
+	local := h.Entity
+	if h.LocalFile != "" {
+		local = h.LocalFile
+	}
+
+	head, err := heads.Read(local)
+	if err != nil {
+		log.Extract(ctx).Debugf("failed to read file %q for secret detection: %s", local, err)
+
+		return Rule{}, false
+	}
+
+	for _, rule := range rules {
+		if rule.Content != nil && rule.Content.Match(head) {
+			return rule, true
+		}
+	}
+
+	return Rule{}, false
+}